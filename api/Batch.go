@@ -0,0 +1,226 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nuagenetworks/libvrsdk/ovsdb"
+	"github.com/nuagenetworks/libvrsdk/tracing"
+	"github.com/socketplane/libovsdb"
+)
+
+// CreatePorts creates the ports in transactions of up to ovsdb.DefaultBatchSize inserts and returns the error of
+// each port, in the order of specs, nil for the ports created. A port failing does not prevent the creation of
+// the others. The ports named twice in specs are created once, their duplicates reported in error. The ports are
+// then added to alubr0 in batches as well by AddPortsToAlubr0.
+func (vrsConnection *VRSConnection) CreatePorts(specs []PortSpec) []error {
+	return vrsConnection.CreatePortsContext(context.Background(), specs)
+}
+
+// CreatePortsContext is CreatePorts traced as a child of ctx. The resolution of each port is traced as for
+// CreatePortContext.
+func (vrsConnection *VRSConnection) CreatePortsContext(ctx context.Context, specs []PortSpec) []error {
+
+	ctx, span := vrsConnection.tracer.Start(ctx, "vrs.CreatePorts")
+	span.SetAttributes(tracing.A("vrs.ports", len(specs)))
+
+	errs := make([]error, len(specs))
+	var rows []ovsdb.NuageTableRow
+	var indexes []int
+	names := make(map[string]bool)
+	for i, spec := range specs {
+		if names[spec.Name] {
+			errs[i] = fmt.Errorf("Port %s specified twice", spec.Name)
+			continue
+		}
+		names[spec.Name] = true
+
+		_, resolution := vrsConnection.tracer.Start(context.Background(), "vrs.PortResolution", ctx)
+		resolution.SetAttributes(tracing.A("vrs.port", spec.Name))
+		vrsConnection.resolutions.start(spec.Name, resolution)
+
+		rows = append(rows, vrsConnection.portRow(spec.Name, spec.Attributes, spec.Metadata))
+		indexes = append(indexes, i)
+	}
+
+	insertErrs := vrsConnection.portTable.InsertRowsContext(ctx, vrsConnection.ovsdbClient, rows,
+		ovsdb.DefaultBatchSize)
+	for i, err := range insertErrs {
+		if err != nil {
+			name := specs[indexes[i]].Name
+			vrsConnection.resolutions.end(name, err)
+			errs[indexes[i]] = fmt.Errorf("Problem adding port %s info to VRS %v", name, err)
+		}
+	}
+
+	tracing.End(span, batchError(errs))
+	return errs
+}
+
+// AddPortsToAlubr0 adds the ports to alubr0 in transactions of up to ovsdb.DefaultBatchSize ports and returns
+// the error of each port, in the order of specs, nil for the ports added. A port failing does not prevent the
+// others from being added, except when the transaction of its batch is lost: the ports of the batch are then
+// reported in error without being added again, they may have been added. The ports named twice in specs are
+// added once, their duplicates reported in error.
+func (vrsConnection *VRSConnection) AddPortsToAlubr0(specs []BridgePortSpec) []error {
+	return vrsConnection.AddPortsToAlubr0Context(context.Background(), specs)
+}
+
+// AddPortsToAlubr0Context is AddPortsToAlubr0 traced as a child of ctx
+func (vrsConnection *VRSConnection) AddPortsToAlubr0Context(ctx context.Context, specs []BridgePortSpec) []error {
+
+	ctx, span := vrsConnection.tracer.Start(ctx, "vrs.AddPortsToAlubr0")
+	span.SetAttributes(tracing.A("vrs.ports", len(specs)), tracing.A("vrs.bridge", bridgeName))
+
+	errs := make([]error, len(specs))
+	operations := make([][]libovsdb.Operation, len(specs))
+	var pending []int
+	names := make(map[string]bool)
+	for i, spec := range specs {
+		if names[spec.Name] {
+			errs[i] = fmt.Errorf("Port %s specified twice", spec.Name)
+			continue
+		}
+		names[spec.Name] = true
+
+		var err error
+		operations[i], err = alubr0PortOperations(spec.Name, spec.Entity, nil, fmt.Sprintf("%d", i))
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		pending = append(pending, i)
+	}
+
+	for start := 0; start < len(pending); start += ovsdb.DefaultBatchSize {
+		end := start + ovsdb.DefaultBatchSize
+		if end > len(pending) {
+			end = len(pending)
+		}
+		vrsConnection.addAlubr0Batch(ctx, specs, operations, pending[start:end], errs)
+	}
+
+	tracing.End(span, batchError(errs))
+	return errs
+}
+
+// addAlubr0Batch adds the ports of batch, indexes in specs and operations, to alubr0 in a single transaction and
+// records the error of each port in errs
+func (vrsConnection *VRSConnection) addAlubr0Batch(ctx context.Context, specs []BridgePortSpec,
+	operations [][]libovsdb.Operation, batch []int, errs []error) {
+
+	for len(batch) > 0 {
+		var batchOperations []libovsdb.Operation
+		var namedPortUUIDs []string
+		for _, index := range batch {
+			batchOperations = append(batchOperations, operations[index]...)
+			namedPortUUIDs = append(namedPortUUIDs, operations[index][1].UUIDName)
+		}
+		batchOperations = append(batchOperations, alubr0MutateOperation(namedPortUUIDs))
+
+		reply, err := vrsConnection.transact(ctx, "insert", batchOperations...)
+
+		// The transaction could not be sent or its reply was lost, it may have been committed and adding its
+		// ports again could duplicate them
+		if err != nil || len(reply) < len(batchOperations) {
+			for _, index := range batch {
+				errs[index] = fmt.Errorf("Problem adding port %s to alubr0, the port may have been added %v",
+					specs[index].Name, err)
+			}
+			return
+		}
+
+		// The port whose insert is reported in error is left out and the others added again. A failing mutation
+		// of alubr0 fails all the ports, a commit failing after all the operations succeeded does not tell which
+		// ports are at fault, nothing was added and the ports are then added one at a time.
+		failed := -1
+		for i, result := range reply {
+			if result.Error != "" {
+				failed = i
+				break
+			}
+		}
+		switch {
+		case failed < 0:
+			return
+		case failed < len(batchOperations)-1:
+			index := batch[failed/2]
+			errs[index] = fmt.Errorf("Problem adding port %s to alubr0 %s %s", specs[index].Name,
+				reply[failed].Error, reply[failed].Details)
+			batch = append(batch[:failed/2:failed/2], batch[failed/2+1:]...)
+		case failed == len(batchOperations)-1 || len(batch) == 1:
+			for _, index := range batch {
+				errs[index] = fmt.Errorf("Problem adding port %s to alubr0 %s %s", specs[index].Name,
+					reply[failed].Error, reply[failed].Details)
+			}
+			return
+		default:
+			for _, index := range batch {
+				vrsConnection.addAlubr0Batch(ctx, specs, operations, []int{index}, errs)
+			}
+			return
+		}
+	}
+}
+
+// CreateEntities creates the entities in transactions of up to ovsdb.DefaultBatchSize inserts and returns the
+// error of each entity, in the order of infos, nil for the entities created. An entity failing does not prevent
+// the creation of the others. The entities whose UUID appears twice in infos are created once, their
+// duplicates reported in error.
+func (vrsConnection *VRSConnection) CreateEntities(infos []EntityInfo) []error {
+	return vrsConnection.CreateEntitiesContext(context.Background(), infos)
+}
+
+// CreateEntitiesContext is CreateEntities traced as a child of ctx
+func (vrsConnection *VRSConnection) CreateEntitiesContext(ctx context.Context, infos []EntityInfo) []error {
+
+	ctx, span := vrsConnection.tracer.Start(ctx, "vrs.CreateEntities")
+	span.SetAttributes(tracing.A("vrs.entities", len(infos)))
+
+	errs := make([]error, len(infos))
+	var rows []ovsdb.NuageTableRow
+	var indexes []int
+	uuids := make(map[string]bool)
+	for i, info := range infos {
+		if uuids[info.UUID] {
+			errs[i] = fmt.Errorf("Entity %s specified twice", info.UUID)
+			continue
+		}
+
+		row, err := vrsConnection.entityRow(info)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		uuids[info.UUID] = true
+
+		rows = append(rows, row)
+		indexes = append(indexes, i)
+	}
+
+	insertErrs := vrsConnection.vmTable.InsertRowsContext(ctx, vrsConnection.ovsdbClient, rows,
+		ovsdb.DefaultBatchSize)
+	for i, err := range insertErrs {
+		if err != nil {
+			errs[indexes[i]] = fmt.Errorf("Problem adding entity %s info to VRS %v", infos[indexes[i]].UUID, err)
+		}
+	}
+
+	tracing.End(span, batchError(errs))
+	return errs
+}
+
+// batchError returns the error ending the span of a batch, the number of items failed if any
+func batchError(errs []error) error {
+	failed := 0
+	for _, err := range errs {
+		if err != nil {
+			failed++
+		}
+	}
+
+	if failed == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d of %d failed", failed, len(errs))
+}
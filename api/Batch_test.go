@@ -0,0 +1,159 @@
+package api
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/nuagenetworks/libvrsdk/api/entity"
+	"github.com/nuagenetworks/libvrsdk/api/port"
+	"github.com/nuagenetworks/libvrsdk/ovsdb"
+)
+
+// testPortSpecs returns the specifications of count ports
+func testPortSpecs(count int) []PortSpec {
+	specs := make([]PortSpec, count)
+	for i := range specs {
+		specs[i] = PortSpec{
+			Name:       fmt.Sprintf("port%d", i),
+			Attributes: port.Attributes{MAC: "76:22:F6:70:4E:47", Platform: entity.Docker, Bridge: bridgeName},
+			Metadata:   map[port.MetadataKey]string{port.MetadataKeyDomain: "domain", port.MetadataKeyZone: "zone"},
+		}
+	}
+	return specs
+}
+
+// testBridgePortSpecs returns the alubr0 ports of count ports, each of an entity of its own
+func testBridgePortSpecs(count int) []BridgePortSpec {
+	specs := make([]BridgePortSpec, count)
+	for i := range specs {
+		name := fmt.Sprintf("vm%d", i)
+		specs[i] = BridgePortSpec{Name: fmt.Sprintf("port%d", i), Entity: EntityInfo{UUID: name, Name: name}}
+	}
+	return specs
+}
+
+func TestCreatePorts(t *testing.T) {
+	server, vrsConnection := testConnection(t)
+	defer server.Close()
+	defer vrsConnection.Disconnect()
+
+	specs := testPortSpecs(ovsdb.DefaultBatchSize + 10)
+	specs[3].Name = specs[1].Name
+	errs := vrsConnection.CreatePorts(specs)
+	for i, err := range errs {
+		if (i == 3) != (err != nil) {
+			t.Fatalf("Unexpected result of port %d %v", i, err)
+		}
+	}
+
+	names, err := vrsConnection.GetAllPorts()
+	if err != nil || len(names) != len(specs)-1 {
+		t.Fatalf("Unexpected ports created %d %v", len(names), err)
+	}
+}
+
+func TestAddPortsToAlubr0(t *testing.T) {
+	server, vrsConnection := testConnection(t)
+	defer server.Close()
+	defer vrsConnection.Disconnect()
+	createBridge(t, server)
+
+	if err := vrsConnection.AddPortToAlubr0("port5", EntityInfo{UUID: "vm5", Name: "vm5"}); err != nil {
+		t.Fatalf("Unable to add the port %v", err)
+	}
+
+	// The port already in alubr0 fails the commit of its batch, the other ports of the batch are added one
+	// at a time
+	specs := testBridgePortSpecs(ovsdb.DefaultBatchSize + 10)
+	specs[3].Name = specs[1].Name
+	errs := vrsConnection.AddPortsToAlubr0(specs)
+	for i, err := range errs {
+		if (i == 3 || i == 5) != (err != nil) {
+			t.Fatalf("Unexpected result of port %d %v", i, err)
+		}
+	}
+
+	names, err := vrsConnection.GetAlubr0Ports()
+	if err != nil || len(names) != len(specs)-1 {
+		t.Fatalf("Unexpected ports added %d %v", len(names), err)
+	}
+	if name, err := vrsConnection.FindAlubr0Port("vm-uuid", "vm7"); err != nil || name != "port7" {
+		t.Fatalf("Unexpected entity of port7 %s %v", name, err)
+	}
+}
+
+func TestCreateEntities(t *testing.T) {
+	server, vrsConnection := testConnection(t)
+	defer server.Close()
+	defer vrsConnection.Disconnect()
+
+	infos := []EntityInfo{
+		{UUID: "vm1", Name: "vm1", Type: entity.Container, Domain: entity.Docker, Ports: []string{"port1"}},
+		{UUID: "vm2", Name: "vm2", Type: entity.Container, Domain: entity.Docker},
+		{UUID: "vm3", Type: entity.Container, Domain: entity.Docker},
+		{UUID: "vm1", Name: "vm1", Type: entity.Container, Domain: entity.Docker},
+		{UUID: "vm4", Name: "vm4", Type: entity.Container, Domain: entity.Docker, DeleteMode: "LATER"},
+	}
+	errs := vrsConnection.CreateEntities(infos)
+	for i, err := range errs {
+		if (i >= 2) != (err != nil) {
+			t.Fatalf("Unexpected result of entity %d %v", i, err)
+		}
+	}
+
+	created, err := vrsConnection.GetAllEntityInfo()
+	if err != nil || len(created) != 2 {
+		t.Fatalf("Unexpected entities created %v %v", created, err)
+	}
+}
+
+// benchmarkPorts is the number of ports created by each iteration of the benchmarks
+const benchmarkPorts = 500
+
+func BenchmarkCreatePortsSequential(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		b.StopTimer()
+		server, vrsConnection := testConnection(b)
+		createBridge(b, server)
+		b.StartTimer()
+
+		for _, spec := range testPortSpecs(benchmarkPorts) {
+			if err := vrsConnection.CreatePort(spec.Name, spec.Attributes, spec.Metadata); err != nil {
+				b.Fatalf("Unable to create the port %v", err)
+			}
+		}
+		for _, spec := range testBridgePortSpecs(benchmarkPorts) {
+			if err := vrsConnection.AddPortToAlubr0(spec.Name, spec.Entity); err != nil {
+				b.Fatalf("Unable to add the port to alubr0 %v", err)
+			}
+		}
+
+		b.StopTimer()
+		vrsConnection.Disconnect()
+		server.Close()
+	}
+}
+
+func BenchmarkCreatePortsBatched(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		b.StopTimer()
+		server, vrsConnection := testConnection(b)
+		createBridge(b, server)
+		b.StartTimer()
+
+		for _, err := range vrsConnection.CreatePorts(testPortSpecs(benchmarkPorts)) {
+			if err != nil {
+				b.Fatalf("Unable to create the port %v", err)
+			}
+		}
+		for _, err := range vrsConnection.AddPortsToAlubr0(testBridgePortSpecs(benchmarkPorts)) {
+			if err != nil {
+				b.Fatalf("Unable to add the port to alubr0 %v", err)
+			}
+		}
+
+		b.StopTimer()
+		vrsConnection.Disconnect()
+		server.Close()
+	}
+}
@@ -0,0 +1,286 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/nuagenetworks/libvrsdk/api/port"
+	"github.com/nuagenetworks/libvrsdk/ovsdb"
+	"github.com/nuagenetworks/libvrsdk/tracing"
+)
+
+// ensureAttempts is the number of times EnsurePort and EnsureEntity look for the row, a row created by another
+// client between the lookup and the insertion is looked for again
+const ensureAttempts = 2
+
+// EnsureResult tells what EnsurePort and EnsureEntity did
+type EnsureResult string
+
+// Results of EnsurePort and EnsureEntity
+const (
+	// EnsureCreated is returned when the row was absent and was created
+	EnsureCreated EnsureResult = "created"
+	// EnsureUpdated is returned when the row was present and the columns differing were updated
+	EnsureUpdated EnsureResult = "updated"
+	// EnsureUnchanged is returned when the row was present and up to date
+	EnsureUnchanged EnsureResult = "unchanged"
+)
+
+// portEnsuredColumns are the columns of a port compared by EnsurePort, the others are set by the VRS
+var portEnsuredColumns = []string{
+	ovsdb.NuagePortTableColumnMAC,
+	ovsdb.NuagePortTableColumnBridge,
+	ovsdb.NuagePortTableColumnNuageDomain,
+	ovsdb.NuagePortTableColumnNuageNetwork,
+	ovsdb.NuagePortTableColumnNuageNetworkType,
+	ovsdb.NuagePortTableColumnNuageZone,
+	ovsdb.NuagePortTableColumnVMDomain,
+	ovsdb.NuagePortTableColumnMetadata,
+}
+
+// entityEnsuredColumns are the columns of an entity always compared by EnsureEntity
+var entityEnsuredColumns = []string{
+	ovsdb.NuageVMTableColumnVMName,
+	ovsdb.NuageVMTableColumnType,
+	ovsdb.NuageVMTableColumnDomain,
+	ovsdb.NuageVMTableColumnUser,
+	ovsdb.NuageVMTableColumnEnterprise,
+	ovsdb.NuageVMTableColumnMetadata,
+}
+
+// entityEventColumns are the columns of an entity compared by EnsureEntity when the events are given
+var entityEventColumns = []string{
+	ovsdb.NuageVMTableColumnEventCategory,
+	ovsdb.NuageVMTableColumnEventType,
+	ovsdb.NuageVMTableColumnState,
+	ovsdb.NuageVMTableColumnReason,
+}
+
+// EnsurePort creates the port if it is absent, or updates the columns differing from attributes and metadata
+// if it is present, and reports which happened. The columns set by the VRS, the resolution of the port, are
+// left unchanged. Unlike CreatePort it can be retried safely.
+func (vrsConnection *VRSConnection) EnsurePort(name string, attributes port.Attributes,
+	metadata map[port.MetadataKey]string) (EnsureResult, error) {
+	return vrsConnection.EnsurePortContext(context.Background(), name, attributes, metadata)
+}
+
+// EnsurePortContext is EnsurePort traced as a child of ctx. The resolution of a port created is traced as for
+// CreatePortContext.
+func (vrsConnection *VRSConnection) EnsurePortContext(ctx context.Context, name string, attributes port.Attributes,
+	metadata map[port.MetadataKey]string) (result EnsureResult, err error) {
+
+	ctx, span := vrsConnection.tracer.Start(ctx, "vrs.EnsurePort")
+	span.SetAttributes(tracing.A("vrs.port", name))
+	defer func() {
+		span.SetAttributes(tracing.A("vrs.result", string(result)))
+		tracing.End(span, err)
+	}()
+
+	desired := make(map[string]interface{})
+	nuagePortRow := vrsConnection.portRow(name, attributes, metadata)
+	if err := nuagePortRow.CreateOVSDBRow(desired); err != nil {
+		return "", err
+	}
+
+	condition := []string{ovsdb.NuagePortTableColumnName, "==", name}
+	for attempt := 0; attempt < ensureAttempts; attempt++ {
+		rows, err := vrsConnection.portTable.ReadRowsContext(ctx, vrsConnection.ovsdbClient,
+			ovsdb.ReadRowArgs{Condition: condition})
+		if err != nil {
+			return "", fmt.Errorf("Unable to look for the port %s %v", name, err)
+		}
+
+		switch len(rows) {
+		case 0:
+			_, resolution := vrsConnection.tracer.Start(context.Background(), "vrs.PortResolution", ctx)
+			resolution.SetAttributes(tracing.A("vrs.port", name))
+			vrsConnection.resolutions.start(name, resolution)
+
+			inserted, err := vrsConnection.portTable.InsertRowIfAbsentContext(ctx, vrsConnection.ovsdbClient,
+				nuagePortRow, ovsdb.NuagePortTableColumnName)
+			if err != nil {
+				vrsConnection.resolutions.end(name, err)
+				return "", fmt.Errorf("Problem adding port info to VRS %v", err)
+			}
+			if inserted {
+				return EnsureCreated, nil
+			}
+			vrsConnection.resolutions.end(name, fmt.Errorf("Port %s created by another client", name))
+		case 1:
+			var existingRow ovsdb.NuagePortTableRow
+			if err := existingRow.ReadOVSDBRow(rows[0]); err != nil {
+				return "", fmt.Errorf("Unable to parse the port row %v", err)
+			}
+			existingRow.Capabilities = vrsConnection.capabilities
+			existing := make(map[string]interface{})
+			if err := existingRow.CreateOVSDBRow(existing); err != nil {
+				return "", err
+			}
+
+			return vrsConnection.ensureColumns(ctx, vrsConnection.portTable, desired, existing, portEnsuredColumns,
+				condition)
+		default:
+			return "", fmt.Errorf("Port %s present %d times", name, len(rows))
+		}
+	}
+
+	return "", fmt.Errorf("Port %s created and deleted concurrently", name)
+}
+
+// EnsureEntity creates the entity if it is absent, or updates the columns differing from info if it is present,
+// and reports which happened. The ports of the entity are only compared when info.Ports is not empty and its
+// events when info.Events is set, so that the ports added with AddEntityPort and the events posted since the
+// creation are kept. Unlike CreateEntity it can be retried safely.
+func (vrsConnection *VRSConnection) EnsureEntity(info EntityInfo) (EnsureResult, error) {
+	return vrsConnection.EnsureEntityContext(context.Background(), info)
+}
+
+// EnsureEntityContext is EnsureEntity traced as a child of ctx
+func (vrsConnection *VRSConnection) EnsureEntityContext(ctx context.Context, info EntityInfo) (result EnsureResult,
+	err error) {
+
+	ctx, span := vrsConnection.tracer.Start(ctx, "vrs.EnsureEntity")
+	span.SetAttributes(tracing.A("vrs.entity", info.UUID))
+	defer func() {
+		span.SetAttributes(tracing.A("vrs.result", string(result)))
+		tracing.End(span, err)
+	}()
+
+	// The ports are a set, they are sorted to be compared with the ones read
+	info.Ports = append([]string(nil), info.Ports...)
+	sort.Strings(info.Ports)
+
+	nuageVMTableRow, err := vrsConnection.entityRow(info)
+	if err != nil {
+		return "", err
+	}
+	desired := make(map[string]interface{})
+	if err := nuageVMTableRow.CreateOVSDBRow(desired); err != nil {
+		return "", err
+	}
+
+	columns := append([]string(nil), entityEnsuredColumns...)
+	if len(info.Ports) != 0 {
+		columns = append(columns, ovsdb.NuageVMTableColumnPorts)
+	}
+	if info.Events != nil {
+		columns = append(columns, entityEventColumns...)
+	}
+
+	condition := []string{ovsdb.NuageVMTableColumnVMUUID, "==", info.UUID}
+	for attempt := 0; attempt < ensureAttempts; attempt++ {
+		rows, err := vrsConnection.vmTable.ReadRowsContext(ctx, vrsConnection.ovsdbClient,
+			ovsdb.ReadRowArgs{Condition: condition})
+		if err != nil {
+			return "", fmt.Errorf("Unable to look for the entity %s %v", info.UUID, err)
+		}
+
+		switch len(rows) {
+		case 0:
+			inserted, err := vrsConnection.vmTable.InsertRowIfAbsentContext(ctx, vrsConnection.ovsdbClient,
+				nuageVMTableRow, ovsdb.NuageVMTableColumnVMUUID)
+			if err != nil {
+				return "", fmt.Errorf("Problem adding entity info to VRS %v", err)
+			}
+			if inserted {
+				return EnsureCreated, nil
+			}
+		case 1:
+			var existingRow ovsdb.NuageVMTableRow
+			if err := existingRow.ReadOVSDBRow(rows[0]); err != nil {
+				return "", fmt.Errorf("Unable to parse the entity row %v", err)
+			}
+			sort.Strings(existingRow.Ports)
+			existingRow.Capabilities = vrsConnection.capabilities
+			existing := make(map[string]interface{})
+			if err := existingRow.CreateOVSDBRow(existing); err != nil {
+				return "", err
+			}
+
+			return vrsConnection.ensureColumns(ctx, vrsConnection.vmTable, desired, existing, columns, condition)
+		default:
+			return "", fmt.Errorf("Entity %s present %d times", info.UUID, len(rows))
+		}
+	}
+
+	return "", fmt.Errorf("Entity %s created and deleted concurrently", info.UUID)
+}
+
+// ensureColumns updates the columns of the row matching condition whose existing value differs from the
+// desired one. The columns the VRS does not support are absent from both rows and left out.
+func (vrsConnection *VRSConnection) ensureColumns(ctx context.Context, table ovsdb.ExtendedNuageTableOps,
+	desired map[string]interface{}, existing map[string]interface{}, columns []string,
+	condition []string) (EnsureResult, error) {
+
+	changed := make(map[string]interface{})
+	for _, column := range columns {
+		value, ok := desired[column]
+		if ok && !reflect.DeepEqual(value, existing[column]) {
+			changed[column] = value
+		}
+	}
+
+	if len(changed) == 0 {
+		return EnsureUnchanged, nil
+	}
+
+	if err := table.UpdateRowContext(ctx, vrsConnection.ovsdbClient, changed, condition); err != nil {
+		return "", fmt.Errorf("Unable to update %v %v", condition, err)
+	}
+
+	return EnsureUpdated, nil
+}
+
+// DeletePortIfExists deletes the port and returns true if it was present, the absence of the port is not an
+// error. Unlike DestroyPort it can be retried safely.
+func (vrsConnection *VRSConnection) DeletePortIfExists(name string) (bool, error) {
+	return vrsConnection.DeletePortIfExistsContext(context.Background(), name)
+}
+
+// DeletePortIfExistsContext is DeletePortIfExists traced as a child of ctx
+func (vrsConnection *VRSConnection) DeletePortIfExistsContext(ctx context.Context, name string) (deleted bool,
+	err error) {
+
+	ctx, span := vrsConnection.tracer.Start(ctx, "vrs.DeletePortIfExists")
+	span.SetAttributes(tracing.A("vrs.port", name))
+	defer func() {
+		span.SetAttributes(tracing.A("vrs.deleted", deleted))
+		tracing.End(span, err)
+	}()
+
+	condition := []string{ovsdb.NuagePortTableColumnName, "==", name}
+	count, err := vrsConnection.portTable.DeleteRowsContext(ctx, vrsConnection.ovsdbClient, condition)
+	if err != nil {
+		return false, fmt.Errorf("Unable to remove the port from VRS %v", err)
+	}
+
+	return count != 0, nil
+}
+
+// DeleteEntityIfExists deletes the entity and returns true if it was present, the absence of the entity is not
+// an error. Unlike DestroyEntity it can be retried safely.
+func (vrsConnection *VRSConnection) DeleteEntityIfExists(uuid string) (bool, error) {
+	return vrsConnection.DeleteEntityIfExistsContext(context.Background(), uuid)
+}
+
+// DeleteEntityIfExistsContext is DeleteEntityIfExists traced as a child of ctx
+func (vrsConnection *VRSConnection) DeleteEntityIfExistsContext(ctx context.Context, uuid string) (deleted bool,
+	err error) {
+
+	ctx, span := vrsConnection.tracer.Start(ctx, "vrs.DeleteEntityIfExists")
+	span.SetAttributes(tracing.A("vrs.entity", uuid))
+	defer func() {
+		span.SetAttributes(tracing.A("vrs.deleted", deleted))
+		tracing.End(span, err)
+	}()
+
+	condition := []string{ovsdb.NuageVMTableColumnVMUUID, "==", uuid}
+	count, err := vrsConnection.vmTable.DeleteRowsContext(ctx, vrsConnection.ovsdbClient, condition)
+	if err != nil {
+		return false, fmt.Errorf("Unable to delete the entity from VRS %v", err)
+	}
+
+	return count != 0, nil
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/nuagenetworks/libvrsdk/api/entity"
+	"github.com/nuagenetworks/libvrsdk/api/port"
+	"github.com/nuagenetworks/libvrsdk/ovsdb"
+	"github.com/socketplane/libovsdb"
+)
+
+func TestEnsurePort(t *testing.T) {
+	server, vrsConnection := testConnection(t)
+	defer server.Close()
+	defer vrsConnection.Disconnect()
+
+	attributes := port.Attributes{MAC: "76:22:F6:70:4E:47", Platform: entity.Docker, Bridge: bridgeName}
+	metadata := map[port.MetadataKey]string{port.MetadataKeyDomain: "domain", port.MetadataKeyZone: "zone"}
+	for _, expected := range []EnsureResult{EnsureCreated, EnsureUnchanged} {
+		if result, err := vrsConnection.EnsurePort("p1", attributes, metadata); err != nil || result != expected {
+			t.Fatalf("Unexpected result %s instead of %s %v", result, expected, err)
+		}
+	}
+
+	// The VRS resolves the port
+	resolveOp := libovsdb.Operation{Op: "update", Table: ovsdb.NuagePortTable,
+		Row:   map[string]interface{}{ovsdb.NuagePortTableColumnIPAddress: "10.0.0.2"},
+		Where: []interface{}{libovsdb.NewCondition(ovsdb.NuagePortTableColumnName, "==", "p1")}}
+	if _, err := server.Transact(resolveOp); err != nil {
+		t.Fatalf("Unable to resolve the port %v", err)
+	}
+
+	metadata[port.MetadataKeyZone] = "zone2"
+	if result, err := vrsConnection.EnsurePort("p1", attributes, metadata); err != nil || result != EnsureUpdated {
+		t.Fatalf("Unexpected result %s %v", result, err)
+	}
+
+	rows := server.Rows(ovsdb.NuagePortTable)
+	if len(rows) != 1 || fmt.Sprint(rows[0][ovsdb.NuagePortTableColumnIPAddress]) != "10.0.0.2" ||
+		fmt.Sprint(rows[0][ovsdb.NuagePortTableColumnNuageZone]) != "zone2" {
+		t.Fatalf("Unexpected rows %v", rows)
+	}
+
+	for _, expected := range []bool{true, false} {
+		if deleted, err := vrsConnection.DeletePortIfExists("p1"); err != nil || deleted != expected {
+			t.Fatalf("Unexpected deletion %t %v", deleted, err)
+		}
+	}
+
+	// Duplicates created by CreatePort are reported
+	for i := 0; i < 2; i++ {
+		if err := vrsConnection.CreatePort("p2", attributes, metadata); err != nil {
+			t.Fatalf("Unable to create the port %v", err)
+		}
+	}
+	if _, err := vrsConnection.EnsurePort("p2", attributes, metadata); err == nil {
+		t.Fatalf("Duplicate port not reported")
+	}
+	if deleted, err := vrsConnection.DeletePortIfExists("p2"); err != nil || !deleted {
+		t.Fatalf("Unable to delete the duplicates %v", err)
+	}
+	if rows = server.Rows(ovsdb.NuagePortTable); len(rows) != 0 {
+		t.Fatalf("Duplicates left %v", rows)
+	}
+}
+
+func TestEnsureEntity(t *testing.T) {
+	server, vrsConnection := testConnection(t)
+	defer server.Close()
+	defer vrsConnection.Disconnect()
+
+	info := EntityInfo{UUID: "vm1", Name: "vm1", Type: entity.Container, Domain: entity.Docker,
+		Ports: []string{"p2", "p1"}}
+	for _, expected := range []EnsureResult{EnsureCreated, EnsureUnchanged} {
+		if result, err := vrsConnection.EnsureEntity(info); err != nil || result != expected {
+			t.Fatalf("Unexpected result %s instead of %s %v", result, expected, err)
+		}
+	}
+
+	if err := vrsConnection.AddEntityPort("vm1", "p3"); err != nil {
+		t.Fatalf("Unable to add the port %v", err)
+	}
+
+	// The ports added are kept when the ports are not given
+	info.Ports = nil
+	info.Name = "vm1-renamed"
+	if result, err := vrsConnection.EnsureEntity(info); err != nil || result != EnsureUpdated {
+		t.Fatalf("Unexpected result %s %v", result, err)
+	}
+
+	entities, err := vrsConnection.GetAllEntityInfo()
+	if err != nil || len(entities) != 1 || entities[0].Name != "vm1-renamed" || len(entities[0].Ports) != 3 {
+		t.Fatalf("Unexpected entities %v %v", entities, err)
+	}
+
+	if _, err = vrsConnection.EnsureEntity(EntityInfo{UUID: "vm2"}); err == nil {
+		t.Fatalf("Entity without name ensured")
+	}
+
+	for _, expected := range []bool{true, false} {
+		if deleted, err := vrsConnection.DeleteEntityIfExists("vm1"); err != nil || deleted != expected {
+			t.Fatalf("Unexpected deletion %t %v", deleted, err)
+		}
+	}
+}
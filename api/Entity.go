@@ -1,11 +1,13 @@
 package api
 
 import (
+	"context"
 	"fmt"
-	"strings"
+	"time"
 
 	"github.com/nuagenetworks/libvrsdk/api/entity"
 	"github.com/nuagenetworks/libvrsdk/ovsdb"
+	"github.com/nuagenetworks/libvrsdk/tracing"
 	"github.com/socketplane/libovsdb"
 )
 
@@ -18,32 +20,135 @@ type EntityInfo struct {
 	Ports    []string
 	Metadata map[entity.MetadataKey]string
 	Events   *entity.EntityEvents
+
+	// DeleteMode and DeleteExpiry are passed to the VRS as the nuage-delete-mode and nuage-delete-expiry
+	// metadata. When set they take precedence over the corresponding keys of Metadata.
+	DeleteMode   entity.DeleteMode
+	DeleteExpiry time.Duration
+}
+
+// AllMetadata returns the metadata of the entity along with its delete mode and expiry
+func (info EntityInfo) AllMetadata() map[entity.MetadataKey]string {
+	metadata := make(map[entity.MetadataKey]string)
+	for k, v := range info.Metadata {
+		metadata[k] = v
+	}
+
+	if info.DeleteMode != entity.DeleteModeImmediate {
+		metadata[entity.MetadataKeyDeleteMode] = string(info.DeleteMode)
+	}
+
+	if info.DeleteExpiry != 0 {
+		metadata[entity.MetadataKeyDeleteExpiry] = entity.FormatDeleteExpiry(info.DeleteExpiry)
+	}
+
+	return metadata
+}
+
+// validateDeleteMetadata checks the nuage-delete-mode and nuage-delete-expiry metadata. An expiry is
+// required by the timer mode and meaningless with any other mode.
+func validateDeleteMetadata(metadata map[entity.MetadataKey]string) error {
+	mode := entity.DeleteMode(metadata[entity.MetadataKeyDeleteMode])
+	if !entity.ValidateDeleteMode(mode) {
+		return fmt.Errorf("Invalid delete mode %q", mode)
+	}
+
+	expiry, ok := metadata[entity.MetadataKeyDeleteExpiry]
+	if !ok {
+		if mode == entity.DeleteModeTimer {
+			return fmt.Errorf("Delete mode %s requires a delete expiry", mode)
+		}
+		return nil
+	}
+
+	if mode != entity.DeleteModeTimer {
+		return fmt.Errorf("Delete expiry %s requires delete mode %s", expiry, entity.DeleteModeTimer)
+	}
+
+	_, err := entity.ParseDeleteExpiry(expiry)
+	return err
+}
+
+// validateDeletePatch checks the delete mode and expiry resulting from a patch of the metadata. They are
+// validated together, a patch setting or removing only one of them could leave a state CreateEntity rejects.
+func validateDeletePatch(set map[entity.MetadataKey]string, remove []entity.MetadataKey) error {
+	touched := make(map[entity.MetadataKey]bool)
+	result := make(map[entity.MetadataKey]string)
+	for _, k := range remove {
+		touched[k] = true
+	}
+	for _, k := range []entity.MetadataKey{entity.MetadataKeyDeleteMode, entity.MetadataKeyDeleteExpiry} {
+		if v, ok := set[k]; ok {
+			touched[k] = true
+			result[k] = v
+		}
+	}
+
+	mode, expiry := touched[entity.MetadataKeyDeleteMode], touched[entity.MetadataKeyDeleteExpiry]
+	if !mode && !expiry {
+		return nil
+	}
+	if mode != expiry {
+		return fmt.Errorf("The delete mode and the delete expiry must be patched together")
+	}
+
+	return validateDeleteMetadata(result)
 }
 
 // CreateEntity adds an entity to the Nuage VRS
 func (vrsConnection *VRSConnection) CreateEntity(info EntityInfo) error {
+	return vrsConnection.CreateEntityContext(context.Background(), info)
+}
+
+// CreateEntityContext is CreateEntity traced as a child of ctx
+func (vrsConnection *VRSConnection) CreateEntityContext(ctx context.Context, info EntityInfo) (err error) {
+
+	ctx, span := vrsConnection.tracer.Start(ctx, "vrs.CreateEntity")
+	span.SetAttributes(tracing.A("vrs.entity", info.UUID))
+	defer func() { tracing.End(span, err) }()
+
+	nuageVMTableRow, err := vrsConnection.entityRow(info)
+	if err != nil {
+		return err
+	}
+
+	if err := vrsConnection.vmTable.InsertRowContext(ctx, vrsConnection.ovsdbClient, nuageVMTableRow); err != nil {
+		return fmt.Errorf("Problem adding entity info to VRS %v", err)
+	}
+
+	return nil
+}
+
+// entityRow validates the entity and builds its Nuage_VM_Table row
+func (vrsConnection *VRSConnection) entityRow(info EntityInfo) (*ovsdb.NuageVMTableRow, error) {
 
 	if len(info.UUID) == 0 {
-		return fmt.Errorf("Uuid absent")
+		return nil, fmt.Errorf("Uuid absent")
 	}
 
 	if len(info.Name) == 0 {
-		return fmt.Errorf("Name absent")
+		return nil, fmt.Errorf("Name absent")
+	}
+
+	if info.DeleteExpiry != 0 && !entity.ValidateDeleteExpiry(info.DeleteExpiry) {
+		return nil, fmt.Errorf("Invalid delete expiry %s, whole seconds are required", info.DeleteExpiry)
+	}
+
+	allMetadata := info.AllMetadata()
+	if err := validateDeleteMetadata(allMetadata); err != nil {
+		return nil, err
 	}
 
 	// The Nuage_VM_Table has separate columns for enterprise and user.
 	// Hence make a copy of the metadata and delete these keys.
-	var metadata map[string]string
-	if info.Metadata != nil {
-		metadata = make(map[string]string)
-		for k, v := range info.Metadata {
-			metadata[string(k)] = v
-		}
+	metadata := make(map[string]string)
+	for k, v := range allMetadata {
+		metadata[string(k)] = v
 	}
 	//delete(metadata, string(entity.MetadataKeyEnterprise))
 	delete(metadata, string(entity.MetadataKeyUser))
 
-	nuageVMTableRow := ovsdb.NuageVMTableRow{
+	nuageVMTableRow := &ovsdb.NuageVMTableRow{
 		Type:            int(info.Type),
 		VMName:          info.Name,
 		VMUuid:          info.UUID,
@@ -56,6 +161,7 @@ func (vrsConnection *VRSConnection) CreateEntity(info EntityInfo) error {
 		EventType:       int(entity.EventDefinedAdded),
 		State:           int(entity.Running),
 		Reason:          int(entity.RunningUnknown),
+		Capabilities:    vrsConnection.capabilities,
 	}
 
 	if info.Events != nil {
@@ -65,11 +171,7 @@ func (vrsConnection *VRSConnection) CreateEntity(info EntityInfo) error {
 		nuageVMTableRow.Reason = int(info.Events.EntityReason)
 	}
 
-	if err := vrsConnection.vmTable.InsertRow(vrsConnection.ovsdbClient, &nuageVMTableRow); err != nil {
-		return fmt.Errorf("Problem adding entity info to VRS %v", err)
-	}
-
-	return nil
+	return nuageVMTableRow, nil
 }
 
 // DestroyEntityByVMName removes entity from the Nuage VRS based on the name
@@ -85,9 +187,18 @@ func (vrsConnection *VRSConnection) DestroyEntityByVMName(VMName string) error {
 
 // DestroyEntity removes an entity from the Nuage VRS
 func (vrsConnection *VRSConnection) DestroyEntity(uuid string) error {
+	return vrsConnection.DestroyEntityContext(context.Background(), uuid)
+}
+
+// DestroyEntityContext is DestroyEntity traced as a child of ctx
+func (vrsConnection *VRSConnection) DestroyEntityContext(ctx context.Context, uuid string) (err error) {
+
+	ctx, span := vrsConnection.tracer.Start(ctx, "vrs.DestroyEntity")
+	span.SetAttributes(tracing.A("vrs.entity", uuid))
+	defer func() { tracing.End(span, err) }()
 
 	condition := []string{ovsdb.NuageVMTableColumnVMUUID, "==", uuid}
-	if err := vrsConnection.vmTable.DeleteRow(vrsConnection.ovsdbClient, condition); err != nil {
+	if err := vrsConnection.vmTable.DeleteRowContext(ctx, vrsConnection.ovsdbClient, condition); err != nil {
 		return fmt.Errorf("Unable to delete the entity from VRS %v", err)
 	}
 
@@ -96,60 +207,89 @@ func (vrsConnection *VRSConnection) DestroyEntity(uuid string) error {
 
 // AddEntityPort adds a port to the Entity
 func (vrsConnection *VRSConnection) AddEntityPort(uuid string, portName string) error {
-	var ports []string
-	var err error
-	if ports, err = vrsConnection.GetEntityPorts(uuid); err != nil {
-		return fmt.Errorf("Unable to get existing ports %s %s", uuid, err)
-	}
+	return vrsConnection.AddEntityPorts(uuid, []string{portName})
+}
 
-	ports = append(ports, portName)
-	row := make(map[string]interface{})
-	row[ovsdb.NuageVMTableColumnPorts], err = libovsdb.NewOvsSet(ports)
-	if err != nil {
-		return err
+// AddEntityPorts adds several ports to the Entity in a single transaction
+func (vrsConnection *VRSConnection) AddEntityPorts(uuid string, portNames []string) error {
+	if err := vrsConnection.mutateEntityPorts(uuid, "insert", portNames); err != nil {
+		return fmt.Errorf("Unable to add ports %s %v %s", uuid, portNames, err)
 	}
 
-	condition := []string{ovsdb.NuageVMTableColumnVMUUID, "==", uuid}
+	return nil
+}
+
+// RemoveEntityPort removes port from the Entity
+func (vrsConnection *VRSConnection) RemoveEntityPort(uuid string, portName string) error {
+	return vrsConnection.RemoveEntityPorts(uuid, []string{portName})
+}
 
-	if err = vrsConnection.vmTable.UpdateRow(vrsConnection.ovsdbClient, row, condition); err != nil {
-		return fmt.Errorf("Unable to add port %s %s %s", uuid, portName, err)
+// RemoveEntityPorts removes several ports from the Entity in a single transaction. Nothing is removed if
+// any of the ports is not attached to the Entity.
+func (vrsConnection *VRSConnection) RemoveEntityPorts(uuid string, portNames []string) error {
+	if err := vrsConnection.mutateEntityPorts(uuid, "delete", portNames); err != nil {
+		return fmt.Errorf("Unable to remove ports %s %v %s", uuid, portNames, err)
 	}
 
 	return nil
 }
 
-// RemoveEntityPort removes port from the Entity
-func (vrsConnection *VRSConnection) RemoveEntityPort(uuid string, portName string) error {
-	var ports []string
-	var err error
-	if ports, err = vrsConnection.GetEntityPorts(uuid); err != nil {
-		return fmt.Errorf("Unable to get existing ports %s %s", uuid, err)
+// mutateEntityPorts inserts or deletes ports of the ports set of the Entity. The server applies the mutation
+// to its current copy of the set, so concurrent calls for the same Entity do not lose each other's ports.
+// The mutation is guarded by a wait on the existence of the Entity, and on the presence of the ports when
+// deleting them, which aborts the transaction instead of silently mutating nothing.
+func (vrsConnection *VRSConnection) mutateEntityPorts(uuid string, mutator string, portNames []string) error {
+	if len(portNames) == 0 {
+		return nil
 	}
 
-	portIndex := -1
-	for i, port := range ports {
-		if strings.Compare(port, portName) == 0 {
-			portIndex = i
-			break
-		}
+	ports, err := libovsdb.NewOvsSet(portNames)
+	if err != nil {
+		return err
 	}
 
-	if portIndex == -1 {
-		return fmt.Errorf("%s port %s not found", uuid, portName)
+	condition := libovsdb.NewCondition(ovsdb.NuageVMTableColumnVMUUID, "==", uuid)
+	where := []interface{}{condition}
+	if mutator == "delete" {
+		where = append(where, libovsdb.NewCondition(ovsdb.NuageVMTableColumnPorts, "includes", ports))
 	}
 
-	ports = append(ports[:portIndex], ports[(portIndex+1):]...)
+	// A wait without timeout blocks until the condition holds, use the smallest one to fail right away
+	waitOp := libovsdb.Operation{
+		Op:      "wait",
+		Table:   ovsdb.NuageVMTable,
+		Where:   where,
+		Columns: []string{ovsdb.NuageVMTableColumnVMUUID},
+		Until:   "==",
+		Rows:    []map[string]interface{}{{ovsdb.NuageVMTableColumnVMUUID: uuid}},
+		Timeout: 1,
+	}
 
-	row := make(map[string]interface{})
-	row[ovsdb.NuageVMTableColumnPorts], err = libovsdb.NewOvsSet(ports)
-	if err != nil {
-		return err
+	mutateOp := libovsdb.Operation{
+		Op:        "mutate",
+		Table:     ovsdb.NuageVMTable,
+		Mutations: []interface{}{libovsdb.NewMutation(ovsdb.NuageVMTableColumnPorts, mutator, ports)},
+		Where:     []interface{}{condition},
 	}
 
-	condition := []string{ovsdb.NuageVMTableColumnVMUUID, "==", uuid}
+	operations := []libovsdb.Operation{waitOp, mutateOp}
+	reply, err := vrsConnection.transact(context.Background(), "mutate", operations...)
+	if err != nil || len(reply) < len(operations) {
+		return fmt.Errorf("Problem mutating the ports %v", err)
+	}
 
-	if err = vrsConnection.vmTable.UpdateRow(vrsConnection.ovsdbClient, row, condition); err != nil {
-		return fmt.Errorf("Unable to remove port %s %s %s", uuid, portName, err)
+	if reply[0].Error != "" {
+		vrsConnection.metrics.ObserveError(ovsdb.NuageVMTable, "mutate", "not-found")
+		if mutator == "delete" {
+			return fmt.Errorf("Entity %s or ports %v not found", uuid, portNames)
+		}
+		return fmt.Errorf("Entity %s not found", uuid)
+	}
+
+	for _, result := range reply {
+		if result.Error != "" {
+			return fmt.Errorf("Problem mutating the ports %s %s", result.Error, result.Details)
+		}
 	}
 
 	return nil
@@ -225,8 +365,18 @@ func (vrsConnection *VRSConnection) PostEntityEvent(uuid string, evtCategory ent
 
 // SetEntityMetadata applies Nuage specific metadata to the Entity
 func (vrsConnection *VRSConnection) SetEntityMetadata(uuid string, metadata map[entity.MetadataKey]string) error {
+	if err := validateDeleteMetadata(metadata); err != nil {
+		return err
+	}
+
 	row := make(map[string]interface{})
-	row[ovsdb.NuageVMTableColumnMetadata] = metadata
+
+	metadataOVSDB, err := libovsdb.NewOvsMap(metadata)
+	if err != nil {
+		return fmt.Errorf("Unable to create OVSDB map %v", err)
+	}
+
+	row[ovsdb.NuageVMTableColumnMetadata] = metadataOVSDB
 
 	condition := []string{ovsdb.NuageVMTableColumnVMUUID, "==", uuid}
 
@@ -237,6 +387,71 @@ func (vrsConnection *VRSConnection) SetEntityMetadata(uuid string, metadata map[
 	return nil
 }
 
+// PatchEntityMetadata sets the keys of set and removes the keys of remove from the metadata of the entity,
+// leaving the other keys untouched. The change is applied by the OVSDB server in a single mutation so that
+// concurrent patches of different keys do not overwrite each other. The delete mode and expiry depend on each
+// other, a patch touching one of them must set or remove both, see MarkEntityForDeferredDelete.
+func (vrsConnection *VRSConnection) PatchEntityMetadata(uuid string, set map[entity.MetadataKey]string,
+	remove []entity.MetadataKey) error {
+
+	if err := validateDeletePatch(set, remove); err != nil {
+		return err
+	}
+
+	values := make(map[string]string)
+	for k, v := range set {
+		values[string(k)] = v
+	}
+
+	var keys []string
+	for _, k := range remove {
+		keys = append(keys, string(k))
+	}
+
+	mutations, err := ovsdb.MapMutations(ovsdb.NuageVMTableColumnMetadata, values, keys)
+	if err != nil {
+		return fmt.Errorf("Unable to create OVSDB mutations %v", err)
+	}
+
+	condition := []string{ovsdb.NuageVMTableColumnVMUUID, "==", uuid}
+
+	if err := vrsConnection.vmTable.MutateRow(vrsConnection.ovsdbClient, nil, mutations, condition); err != nil {
+		// The values may be sensitive, only the keys are reported
+		var keys []entity.MetadataKey
+		for k := range set {
+			keys = append(keys, k)
+		}
+		return fmt.Errorf("Unable to patch the metadata %s %v %v %v", uuid, keys, remove, err)
+	}
+
+	return nil
+}
+
+// MarkEntityForDeferredDelete sets the delete mode and expiry of an existing entity, leaving the rest of its
+// metadata untouched. With DeleteModeTimer the VRS holds the ports of the entity for expiry after the entity
+// goes away, DeleteModeImmediate restores the default behaviour and ignores the expiry.
+func (vrsConnection *VRSConnection) MarkEntityForDeferredDelete(uuid string, mode entity.DeleteMode, expiry time.Duration) error {
+	keys := []entity.MetadataKey{entity.MetadataKeyDeleteMode, entity.MetadataKeyDeleteExpiry}
+
+	if mode == entity.DeleteModeImmediate {
+		return vrsConnection.PatchEntityMetadata(uuid, nil, keys)
+	}
+
+	if expiry != 0 && !entity.ValidateDeleteExpiry(expiry) {
+		return fmt.Errorf("Invalid delete expiry %s, whole seconds are required", expiry)
+	}
+
+	metadata := map[entity.MetadataKey]string{
+		entity.MetadataKeyDeleteMode:   string(mode),
+		entity.MetadataKeyDeleteExpiry: entity.FormatDeleteExpiry(expiry),
+	}
+	if err := validateDeleteMetadata(metadata); err != nil {
+		return err
+	}
+
+	return vrsConnection.PatchEntityMetadata(uuid, metadata, nil)
+}
+
 // GetAllEntities retrives a slice of all the UUIDs of the entities associated with the VRS
 func (vrsConnection *VRSConnection) GetAllEntities() ([]string, error) {
 	readRowArgs := ovsdb.ReadRowArgs{
@@ -301,3 +516,87 @@ func (vrsConnection *VRSConnection) GetEntityName(uuid string) (string, error) {
 
 	return row[ovsdb.NuageVMTableColumnVMName].(string), err
 }
+
+// GetAllEntityInfo retrieves the information of all the entities associated with the VRS
+func (vrsConnection *VRSConnection) GetAllEntityInfo() ([]EntityInfo, error) {
+	readRowArgs := ovsdb.ReadRowArgs{
+		Condition: []string{ovsdb.NuageVMTableColumnVMUUID, "!=", "xxxx"},
+	}
+
+	var rows []map[string]interface{}
+	var err error
+	if rows, err = vrsConnection.vmTable.ReadRows(vrsConnection.ovsdbClient, readRowArgs); err != nil {
+		return []EntityInfo{}, fmt.Errorf("Unable to obtain the entities %v", err)
+	}
+
+	var entities []EntityInfo
+	for _, row := range rows {
+		info, err := entityInfoFromRow(row)
+		if err != nil {
+			return []EntityInfo{}, fmt.Errorf("Unable to parse the entity row %+v %v", row, err)
+		}
+		entities = append(entities, info)
+	}
+
+	return entities, nil
+}
+
+// entityInfoFromRow converts a Nuage_VM_Table row into the EntityInfo it was created from
+func entityInfoFromRow(row map[string]interface{}) (EntityInfo, error) {
+	var info EntityInfo
+	var ok bool
+	var err error
+
+	if info.UUID, ok = row[ovsdb.NuageVMTableColumnVMUUID].(string); !ok {
+		return info, fmt.Errorf("Invalid %s", ovsdb.NuageVMTableColumnVMUUID)
+	}
+
+	if info.Name, ok = row[ovsdb.NuageVMTableColumnVMName].(string); !ok {
+		return info, fmt.Errorf("Invalid %s", ovsdb.NuageVMTableColumnVMName)
+	}
+
+	var columns [6]int
+	for i, column := range []string{ovsdb.NuageVMTableColumnType, ovsdb.NuageVMTableColumnDomain,
+		ovsdb.NuageVMTableColumnEventCategory, ovsdb.NuageVMTableColumnEventType,
+		ovsdb.NuageVMTableColumnState, ovsdb.NuageVMTableColumnReason} {
+		if columns[i], err = ovsdb.UnMarshallOVSInt(row[column]); err != nil {
+			return info, fmt.Errorf("Invalid %s %v", column, err)
+		}
+	}
+	info.Type = entity.Type(columns[0])
+	info.Domain = entity.Domain(columns[1])
+	info.Events = &entity.EntityEvents{
+		EntityEventCategory: entity.EventCategory(columns[2]),
+		EntityEventType:     entity.Event(columns[3]),
+		EntityState:         entity.State(columns[4]),
+		EntityReason:        entity.SubState(columns[5]),
+	}
+
+	if info.Ports, err = ovsdb.UnMarshallOVSStringSet(row[ovsdb.NuageVMTableColumnPorts]); err != nil {
+		return info, fmt.Errorf("Invalid %s %v", ovsdb.NuageVMTableColumnPorts, err)
+	}
+
+	metadata, err := ovsdb.UnMarshallOVSStringMap(row[ovsdb.NuageVMTableColumnMetadata])
+	if err != nil {
+		return info, fmt.Errorf("Invalid %s %v", ovsdb.NuageVMTableColumnMetadata, err)
+	}
+
+	info.Metadata = make(map[entity.MetadataKey]string)
+	for k, v := range metadata {
+		info.Metadata[entity.MetadataKey(k)] = v
+	}
+
+	// The user is kept in a separate column, see CreateEntity
+	if user, _ := row[ovsdb.NuageVMTableColumnUser].(string); len(user) != 0 {
+		info.Metadata[entity.MetadataKeyUser] = user
+	}
+
+	// The metadata may be written by other agents, an invalid expiry is left as is in the metadata rather than
+	// failing the reads of the whole table
+	info.DeleteMode = entity.DeleteMode(metadata[string(entity.MetadataKeyDeleteMode)])
+	if expiry, ok := metadata[string(entity.MetadataKeyDeleteExpiry)]; ok {
+		info.DeleteExpiry, _ = entity.ParseDeleteExpiry(expiry)
+	}
+
+	return info, nil
+}
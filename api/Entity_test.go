@@ -0,0 +1,230 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nuagenetworks/libvrsdk/api/entity"
+	"github.com/nuagenetworks/libvrsdk/ovsdb"
+	"github.com/nuagenetworks/libvrsdk/ovsdb/ovsdbtest"
+	"github.com/socketplane/libovsdb"
+)
+
+// testConnection starts an in-memory OVSDB server and connects to it
+func testConnection(t testing.TB) (*ovsdbtest.Server, VRSConnection) {
+	server, err := ovsdbtest.NewServer()
+	if err != nil {
+		t.Fatalf("Unable to start the OVSDB server %v", err)
+	}
+
+	vrsConnection, err := NewUnixSocketConnection(server.SocketPath())
+	if err != nil {
+		server.Close()
+		t.Fatalf("Unable to connect to the VRS %v", err)
+	}
+
+	return server, vrsConnection
+}
+
+func TestDeferredDelete(t *testing.T) {
+	server, vrsConnection := testConnection(t)
+	defer server.Close()
+	defer vrsConnection.Disconnect()
+
+	info := EntityInfo{
+		UUID:     "vm",
+		Name:     "vm",
+		Metadata: map[entity.MetadataKey]string{entity.MetadataKeySiteID: "site"},
+	}
+
+	invalid := []map[entity.MetadataKey]string{
+		{entity.MetadataKeyDeleteMode: "LATER"},
+		{entity.MetadataKeyDeleteMode: string(entity.DeleteModeTimer)},
+		{entity.MetadataKeyDeleteExpiry: "60"},
+		{entity.MetadataKeyDeleteMode: string(entity.DeleteModeTimer), entity.MetadataKeyDeleteExpiry: "soon"},
+	}
+	for _, metadata := range invalid {
+		if err := vrsConnection.CreateEntity(EntityInfo{UUID: "bad", Name: "bad", Metadata: metadata}); err == nil {
+			t.Fatalf("Entity created with invalid metadata %v", metadata)
+		}
+	}
+
+	info.DeleteMode = entity.DeleteModeTimer
+	info.DeleteExpiry = 1500 * time.Millisecond
+	if err := vrsConnection.CreateEntity(info); err == nil {
+		t.Fatalf("Entity created with a delete expiry of %s", info.DeleteExpiry)
+	}
+	info.DeleteExpiry = 90 * time.Second
+	if err := vrsConnection.CreateEntity(info); err != nil {
+		t.Fatalf("Unable to create the entity %v", err)
+	}
+
+	entities, err := vrsConnection.GetAllEntityInfo()
+	if err != nil || len(entities) != 1 {
+		t.Fatalf("Unable to read the entity %v %v", entities, err)
+	}
+	if entities[0].DeleteMode != entity.DeleteModeTimer || entities[0].DeleteExpiry != 90*time.Second ||
+		entities[0].Metadata[entity.MetadataKeyDeleteExpiry] != "90" {
+		t.Fatalf("Unexpected delete settings %+v", entities[0])
+	}
+
+	if err = vrsConnection.MarkEntityForDeferredDelete("vm", entity.DeleteModeImmediate, 0); err != nil {
+		t.Fatalf("Unable to clear the delete mode %v", err)
+	}
+	if err = vrsConnection.MarkEntityForDeferredDelete("vm", entity.DeleteModeTimer, 0); err == nil {
+		t.Fatalf("Timer delete mode accepted without an expiry")
+	}
+	if err = vrsConnection.MarkEntityForDeferredDelete("vm", entity.DeleteModeTimer, 500*time.Millisecond); err == nil {
+		t.Fatalf("Delete expiry of less than a second accepted")
+	}
+	if err = vrsConnection.MarkEntityForDeferredDelete("vm", entity.DeleteModeTimer, 5*time.Minute); err != nil {
+		t.Fatalf("Unable to set the delete mode %v", err)
+	}
+
+	entities, err = vrsConnection.GetAllEntityInfo()
+	if err != nil || len(entities) != 1 {
+		t.Fatalf("Unable to read the entity %v %v", entities, err)
+	}
+	if entities[0].DeleteExpiry != 5*time.Minute || entities[0].Metadata[entity.MetadataKeySiteID] != "site" {
+		t.Fatalf("Unexpected metadata %+v", entities[0])
+	}
+
+	// An invalid expiry written by another agent does not prevent reading the entities
+	metadata, _ := libovsdb.NewOvsMap(map[string]string{string(entity.MetadataKeyDeleteMode): "TIMER",
+		string(entity.MetadataKeyDeleteExpiry): "soon"})
+	if _, err = server.Transact(libovsdb.Operation{
+		Op:    "update",
+		Table: ovsdb.NuageVMTable,
+		Where: []interface{}{libovsdb.NewCondition(ovsdb.NuageVMTableColumnVMUUID, "==", "vm")},
+		Row:   map[string]interface{}{ovsdb.NuageVMTableColumnMetadata: metadata},
+	}); err != nil {
+		t.Fatalf("Unable to write the metadata %v", err)
+	}
+	entities, err = vrsConnection.GetAllEntityInfo()
+	if err != nil || len(entities) != 1 {
+		t.Fatalf("Unable to read the entity %v %v", entities, err)
+	}
+	if entities[0].DeleteExpiry != 0 || entities[0].AllMetadata()[entity.MetadataKeyDeleteExpiry] != "soon" {
+		t.Fatalf("Unexpected invalid expiry %+v", entities[0])
+	}
+}
+
+func TestPatchEntityMetadata(t *testing.T) {
+	server, vrsConnection := testConnection(t)
+	defer server.Close()
+	defer vrsConnection.Disconnect()
+
+	info := EntityInfo{
+		UUID: "vm",
+		Name: "vm",
+		Metadata: map[entity.MetadataKey]string{
+			entity.MetadataKeySiteID:          "site",
+			entity.MetadataKeyOrchestrationID: "orchestrator",
+		},
+	}
+	if err := vrsConnection.CreateEntity(info); err != nil {
+		t.Fatalf("Unable to create the entity %v", err)
+	}
+
+	set := map[entity.MetadataKey]string{entity.MetadataKeySiteID: "other", entity.MetadataKeyExtension: "ext"}
+	remove := []entity.MetadataKey{entity.MetadataKeyOrchestrationID}
+	if err := vrsConnection.PatchEntityMetadata("vm", set, remove); err != nil {
+		t.Fatalf("Unable to patch the metadata %v", err)
+	}
+
+	entities, err := vrsConnection.GetAllEntityInfo()
+	if err != nil || len(entities) != 1 {
+		t.Fatalf("Unable to read the entity %v %v", entities, err)
+	}
+	metadata := entities[0].Metadata
+	if len(metadata) != 2 || metadata[entity.MetadataKeySiteID] != "other" || metadata[entity.MetadataKeyExtension] != "ext" {
+		t.Fatalf("Unexpected metadata %v", metadata)
+	}
+
+	// The delete mode and expiry are patched together into a valid state
+	timer := map[entity.MetadataKey]string{entity.MetadataKeyDeleteMode: string(entity.DeleteModeTimer)}
+	invalid := []struct {
+		set    map[entity.MetadataKey]string
+		remove []entity.MetadataKey
+	}{
+		{set: timer},
+		{remove: []entity.MetadataKey{entity.MetadataKeyDeleteExpiry}},
+		{set: timer, remove: []entity.MetadataKey{entity.MetadataKeyDeleteExpiry}},
+		{set: map[entity.MetadataKey]string{entity.MetadataKeyDeleteExpiry: "60"},
+			remove: []entity.MetadataKey{entity.MetadataKeyDeleteMode}},
+	}
+	for _, patch := range invalid {
+		if err = vrsConnection.PatchEntityMetadata("vm", patch.set, patch.remove); err == nil {
+			t.Fatalf("Invalid patch applied %v %v", patch.set, patch.remove)
+		}
+	}
+	timer[entity.MetadataKeyDeleteExpiry] = "60"
+	if err = vrsConnection.PatchEntityMetadata("vm", timer, nil); err != nil {
+		t.Fatalf("Unable to patch the delete mode %v", err)
+	}
+
+	err = vrsConnection.PatchEntityMetadata("missing", set, nil)
+	if err == nil {
+		t.Fatalf("Patched the metadata of a missing entity")
+	}
+	if strings.Contains(err.Error(), "other") {
+		t.Fatalf("Metadata values reported in the error %v", err)
+	}
+}
+
+func TestEntityPorts(t *testing.T) {
+	server, vrsConnection := testConnection(t)
+	defer server.Close()
+	defer vrsConnection.Disconnect()
+
+	if err := vrsConnection.CreateEntity(EntityInfo{UUID: "vm", Name: "vm"}); err != nil {
+		t.Fatalf("Unable to create the entity %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errors := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errors <- vrsConnection.AddEntityPort("vm", fmt.Sprintf("port%d", i))
+		}(i)
+	}
+	wg.Wait()
+	close(errors)
+	for err := range errors {
+		if err != nil {
+			t.Fatalf("Unable to add the port %v", err)
+		}
+	}
+
+	ports, err := vrsConnection.GetEntityPorts("vm")
+	if err != nil || len(ports) != 10 {
+		t.Fatalf("Lost ports with concurrent additions %v %v", ports, err)
+	}
+
+	if err = vrsConnection.RemoveEntityPorts("vm", []string{"port0", "missing"}); err == nil {
+		t.Fatalf("Removed a port that is not attached")
+	}
+	if err = vrsConnection.AddEntityPorts("missing", []string{"port0"}); err == nil {
+		t.Fatalf("Added a port to a missing entity")
+	}
+
+	var removed []string
+	for i := 0; i < 9; i++ {
+		removed = append(removed, fmt.Sprintf("port%d", i))
+	}
+	if err = vrsConnection.RemoveEntityPorts("vm", removed); err != nil {
+		t.Fatalf("Unable to remove the ports %v", err)
+	}
+
+	ports, err = vrsConnection.GetEntityPorts("vm")
+	sort.Strings(ports)
+	if err != nil || len(ports) != 1 || ports[0] != "port9" {
+		t.Fatalf("Unexpected ports %v %v", ports, err)
+	}
+}
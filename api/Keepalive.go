@@ -0,0 +1,145 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nuagenetworks/libvrsdk/logging"
+)
+
+// keepalive holds the probing loop of the connection and the ping in flight, shared between the copies of the
+// connection
+type keepalive struct {
+	mutex  sync.Mutex
+	stop   chan struct{}
+	ping   *ping
+	onDead func()
+}
+
+// ping is an empty transaction sent to the VRS, err is set once done is closed
+type ping struct {
+	done chan struct{}
+	err  error
+}
+
+// Echo is called when the VRS probes the session, libovsdb replies to the probe
+func (vrsConnection VRSConnection) Echo([]interface{}) {
+}
+
+// Ping checks that the VRS answers on the session of the connection before ctx is done.
+//
+// Unlike the OVSDB keepalive, Ping does not send an echo request: libovsdb does not let clients send one. The
+// VRS is probed with a transaction without operations instead, which goes through the same session and the
+// same request and reply path, and which the VRS answers without touching the database.
+//
+// libovsdb calls cannot be cancelled, a ping left unanswered when ctx is done stays in flight until the VRS
+// answers or the session is closed. The next calls wait for it rather than sending pings of their own, so that
+// at most one ping per connection is blocked on a VRS that stopped answering. The shared ping is not bound to
+// the ctx of the call that sent it, which would otherwise fail the calls waiting for it once that ctx is done.
+func (vrsConnection *VRSConnection) Ping(ctx context.Context) error {
+	if !vrsConnection.Connected() {
+		return fmt.Errorf("Connection closed")
+	}
+
+	vrsConnection.keepalive.mutex.Lock()
+	p := vrsConnection.keepalive.ping
+	if p == nil {
+		p = &ping{done: make(chan struct{})}
+		vrsConnection.keepalive.ping = p
+		go func() {
+			_, p.err = vrsConnection.transact(context.Background(), "ping")
+			vrsConnection.keepalive.mutex.Lock()
+			vrsConnection.keepalive.ping = nil
+			vrsConnection.keepalive.mutex.Unlock()
+			close(p.done)
+		}()
+	}
+	vrsConnection.keepalive.mutex.Unlock()
+
+	select {
+	case <-p.done:
+		if p.err != nil {
+			return fmt.Errorf("Unable to ping the VRS %v", p.err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// SetKeepalive probes the VRS with Ping every interval, each probe waiting up to interval for its reply. After
+// maxMisses probes in a row are left unanswered the connection is closed as by Disconnect: Connected then
+// returns false, the calls blocked on the session fail and the registrations for port updates are dropped. The
+// connection is not recreated, a half-open connection to a remote VRS is only detected: reconnecting is the job
+// of the caller, which creates a new connection from the function set by SetOnDead or once Connected returns
+// false, as the fleet package does. An interval of 0 stops the probing.
+func (vrsConnection *VRSConnection) SetKeepalive(interval time.Duration, maxMisses int) {
+	vrsConnection.keepalive.mutex.Lock()
+	defer vrsConnection.keepalive.mutex.Unlock()
+
+	if vrsConnection.keepalive.stop != nil {
+		close(vrsConnection.keepalive.stop)
+		vrsConnection.keepalive.stop = nil
+	}
+	if interval <= 0 {
+		return
+	}
+	if maxMisses < 1 {
+		maxMisses = 1
+	}
+
+	stop := make(chan struct{})
+	vrsConnection.keepalive.stop = stop
+	go vrsConnection.probe(interval, maxMisses, stop)
+}
+
+// SetOnDead sets the function called once the keepalive closed the connection, for the caller to create a new
+// one. It is called from the keepalive goroutine, nil removes it.
+func (vrsConnection *VRSConnection) SetOnDead(onDead func()) {
+	vrsConnection.keepalive.mutex.Lock()
+	defer vrsConnection.keepalive.mutex.Unlock()
+
+	vrsConnection.keepalive.onDead = onDead
+}
+
+// probe runs the keepalive until it is stopped or the connection closed
+func (vrsConnection *VRSConnection) probe(interval time.Duration, maxMisses int, stop chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	misses := 0
+	for {
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		case <-vrsConnection.disconnected:
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		err := vrsConnection.Ping(ctx)
+		cancel()
+		if err == nil {
+			misses = 0
+			continue
+		}
+
+		misses++
+		vrsConnection.logger.Warn("VRS did not answer the keepalive", logging.F("misses", misses), logging.Err(err))
+		if misses >= maxMisses {
+			vrsConnection.logger.Error("VRS not answering, closing the connection", logging.F("misses", misses))
+			vrsConnection.Disconnect()
+
+			vrsConnection.keepalive.mutex.Lock()
+			onDead := vrsConnection.keepalive.onDead
+			vrsConnection.keepalive.mutex.Unlock()
+			if onDead != nil {
+				onDead()
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nuagenetworks/libvrsdk/metrics"
+	"github.com/nuagenetworks/libvrsdk/ovsdb/ovsdbtest"
+)
+
+func TestKeepalive(t *testing.T) {
+	server, err := ovsdbtest.NewTCPServer()
+	if err != nil {
+		t.Fatalf("Unable to start the OVSDB server %v", err)
+	}
+	defer server.Close()
+
+	vrsConnection, err := NewTCPConnection(server.Host(), server.Port())
+	if err != nil {
+		t.Fatalf("Unable to connect to the VRS %v", err)
+	}
+	defer vrsConnection.Disconnect()
+
+	if err = vrsConnection.Ping(context.Background()); err != nil {
+		t.Fatalf("Unable to ping the VRS %v", err)
+	}
+	updates := make(chan *PortIPv4Info, 1)
+	vrsConnection.RegisterForPortUpdates("port1", updates)
+	waitSubscribers(t, vrsConnection, 1)
+
+	// The VRS stops answering as if the connection was half-open, the pings left unanswered share the one in
+	// flight instead of each blocking a goroutine of its own
+	server.Pause()
+	var inFlight *ping
+	for i := 0; i < 2; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		err = vrsConnection.Ping(ctx)
+		cancel()
+		if err != context.DeadlineExceeded {
+			server.Resume()
+			t.Fatalf("Ping answered by a paused VRS %v", err)
+		}
+
+		vrsConnection.keepalive.mutex.Lock()
+		p := vrsConnection.keepalive.ping
+		vrsConnection.keepalive.mutex.Unlock()
+		if p == nil || (inFlight != nil && p != inFlight) {
+			server.Resume()
+			t.Fatalf("Unexpected ping in flight %v %v", p, inFlight)
+		}
+		inFlight = p
+	}
+	dead := make(chan struct{})
+	vrsConnection.SetOnDead(func() { close(dead) })
+	vrsConnection.SetKeepalive(20*time.Millisecond, 3)
+
+	start := time.Now()
+	for vrsConnection.Connected() {
+		if time.Since(start) > 5*time.Second {
+			server.Resume()
+			t.Fatalf("Dead connection not detected")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	server.Resume()
+
+	if time.Since(start) < 40*time.Millisecond {
+		t.Fatalf("Connection closed before the misses allowed %v", time.Since(start))
+	}
+	if err = vrsConnection.Ping(context.Background()); err == nil {
+		t.Fatalf("Closed connection answered the ping")
+	}
+	select {
+	case <-dead:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Dead connection not reported")
+	}
+
+	// Closing the session ends the ping in flight and the monitoring of the port updates, dropping its
+	// registrations
+	select {
+	case <-inFlight.done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Ping still in flight on a closed connection")
+	}
+	if err = vrsConnection.RegisterForPortUpdates("port2", updates); err == nil {
+		t.Fatalf("Registration accepted by a closed connection")
+	}
+	waitSubscribers(t, vrsConnection, 0)
+}
+
+// waitSubscribers waits for the number of channels registered for port updates to reach count
+func waitSubscribers(t *testing.T, vrsConnection VRSConnection, count float64) {
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		subscribers := gather(t, vrsConnection.Collector())[metrics.Namespace+"_port_update_subscribers"]
+		if subscribers.GetGauge().GetValue() == count {
+			return
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("Unexpected port update subscribers %v", subscribers)
+		}
+	}
+}
+
+func TestKeepaliveStop(t *testing.T) {
+	server, vrsConnection := testConnection(t)
+	defer server.Close()
+	defer vrsConnection.Disconnect()
+
+	vrsConnection.SetKeepalive(10*time.Millisecond, 1)
+	vrsConnection.SetKeepalive(0, 0)
+
+	server.Pause()
+	time.Sleep(50 * time.Millisecond)
+	server.Resume()
+
+	if !vrsConnection.Connected() {
+		t.Fatalf("Connection closed by a stopped keepalive")
+	}
+}
@@ -0,0 +1,208 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/cenk/rpc2"
+	"github.com/cenk/rpc2/jsonrpc"
+	"github.com/nuagenetworks/libvrsdk/logging"
+)
+
+// lockEventBuffer is the number of lock events kept for a reader not keeping up, the next events are dropped
+const lockEventBuffer = 16
+
+// ErrLockHeld is returned by TryLock when the lock is owned by another client
+var ErrLockHeld = errors.New("Lock held by another client")
+
+// LockEvent reports a change of the ownership of a lock
+type LockEvent struct {
+	// Locked is true when the lock was acquired, false when it was stolen or its session lost
+	Locked bool
+	// Err tells why the lock was lost
+	Err error
+}
+
+// Lock is an OVSDB lock. Each lock is held on an OVSDB session of its own, opened to the server of the VRS
+// connection, so that the lock is released when the agent dies without affecting the connection. As a
+// consequence the lock can not be asserted by the transactions of the connection, it coordinates the agents
+// sharing the VRS, the leader running the garbage collection or the reconciliation for instance.
+type Lock struct {
+	name     string
+	client   *rpc2.Client
+	mutex    sync.Mutex
+	locked   bool
+	released bool
+	acquired chan struct{}
+	events   chan LockEvent
+}
+
+// lockReply is the reply of the server to the lock and steal requests
+type lockReply struct {
+	Locked bool `json:"locked"`
+}
+
+// AcquireLock waits until the lock is acquired
+func (vrsConnection *VRSConnection) AcquireLock(name string) (*Lock, error) {
+	return vrsConnection.AcquireLockContext(context.Background(), name)
+}
+
+// AcquireLockContext waits until the lock is acquired or ctx is done, the request for the lock is then cancelled
+func (vrsConnection *VRSConnection) AcquireLockContext(ctx context.Context, name string) (*Lock, error) {
+	lock, err := vrsConnection.requestLock("lock", name)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-lock.acquired:
+		return lock, nil
+	case <-lock.client.DisconnectNotify():
+		return nil, fmt.Errorf("Session of lock %s lost", name)
+	case <-ctx.Done():
+		lock.Release()
+		return nil, ctx.Err()
+	}
+}
+
+// TryLock acquires the lock if it is free, it returns ErrLockHeld otherwise
+func (vrsConnection *VRSConnection) TryLock(name string) (*Lock, error) {
+	lock, err := vrsConnection.requestLock("lock", name)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-lock.acquired:
+		return lock, nil
+	default:
+		lock.Release()
+		return nil, ErrLockHeld
+	}
+}
+
+// StealLock takes the lock from its owner, which is notified that its lock was stolen. It is meant for an
+// operator taking over from a hung agent, the agents competing for the lock use AcquireLock.
+func (vrsConnection *VRSConnection) StealLock(name string) (*Lock, error) {
+	return vrsConnection.requestLock("steal", name)
+}
+
+// requestLock opens the session of the lock and sends the lock or steal request
+func (vrsConnection *VRSConnection) requestLock(method string, name string) (*Lock, error) {
+	conn, err := net.Dial(vrsConnection.network, vrsConnection.address)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to open the session of lock %s %v", name, err)
+	}
+
+	lock := &Lock{
+		name:     name,
+		client:   rpc2.NewClientWithCodec(jsonrpc.NewJSONCodec(conn)),
+		acquired: make(chan struct{}),
+		events:   make(chan LockEvent, lockEventBuffer),
+	}
+	lock.client.Handle("echo", func(client *rpc2.Client, args []interface{}, reply *[]interface{}) error {
+		*reply = args
+		return nil
+	})
+	lock.client.Handle("locked", func(client *rpc2.Client, args []interface{}, reply *interface{}) error {
+		lock.setLocked(true, nil)
+		return nil
+	})
+	lock.client.Handle("stolen", func(client *rpc2.Client, args []interface{}, reply *interface{}) error {
+		lock.setLocked(false, fmt.Errorf("Lock %s stolen", name))
+		return nil
+	})
+	go lock.client.Run()
+	go lock.watch()
+
+	var reply lockReply
+	if err = lock.client.Call(method, []interface{}{name}, &reply); err != nil {
+		lock.client.Close()
+		return nil, fmt.Errorf("Unable to %s lock %s %v", method, name, err)
+	}
+	if reply.Locked {
+		lock.setLocked(true, nil)
+	}
+
+	vrsConnection.logger.Debug("Lock requested", logging.F("lock", name), logging.F("locked", reply.Locked))
+
+	return lock, nil
+}
+
+// setLocked records a change of the ownership of the lock, reported on the events channel
+func (lock *Lock) setLocked(locked bool, err error) {
+	lock.mutex.Lock()
+	defer lock.mutex.Unlock()
+
+	if lock.locked == locked || lock.released {
+		return
+	}
+	lock.locked = locked
+	if locked {
+		select {
+		case <-lock.acquired:
+		default:
+			close(lock.acquired)
+		}
+	}
+
+	select {
+	case lock.events <- LockEvent{Locked: locked, Err: err}:
+	default:
+	}
+}
+
+// watch reports the loss of the session of the lock and closes the events channel
+func (lock *Lock) watch() {
+	<-lock.client.DisconnectNotify()
+
+	lock.setLocked(false, fmt.Errorf("Session of lock %s lost", lock.name))
+
+	lock.mutex.Lock()
+	defer lock.mutex.Unlock()
+	lock.released = true
+	close(lock.events)
+}
+
+// Name returns the name of the lock
+func (lock *Lock) Name() string {
+	return lock.name
+}
+
+// Held returns true while the lock is owned
+func (lock *Lock) Held() bool {
+	lock.mutex.Lock()
+	defer lock.mutex.Unlock()
+
+	return lock.locked
+}
+
+// Events returns the channel receiving the changes of the ownership of the lock, closed once the lock is
+// released or its session lost. The events are dropped if the channel is full, Held is always accurate.
+func (lock *Lock) Events() <-chan LockEvent {
+	return lock.events
+}
+
+// Release releases the lock, or cancels its request, and closes its session
+func (lock *Lock) Release() error {
+	lock.mutex.Lock()
+	if lock.released {
+		lock.mutex.Unlock()
+		return nil
+	}
+	lock.released = true
+	lock.locked = false
+	lock.mutex.Unlock()
+
+	var reply interface{}
+	err := lock.client.Call("unlock", []interface{}{lock.name}, &reply)
+	lock.client.Close()
+	if err != nil {
+		return fmt.Errorf("Unable to unlock %s %v", lock.name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLock(t *testing.T) {
+	server, first := testConnection(t)
+	defer server.Close()
+	defer first.Disconnect()
+
+	second, err := NewUnixSocketConnection(server.SocketPath())
+	if err != nil {
+		t.Fatalf("Unable to connect to the VRS %v", err)
+	}
+	defer second.Disconnect()
+
+	leader, err := first.TryLock("gc")
+	if err != nil || !leader.Held() {
+		t.Fatalf("Unable to lock %v", err)
+	}
+	if event := <-leader.Events(); !event.Locked {
+		t.Fatalf("Unexpected event %+v", event)
+	}
+	if _, err = second.TryLock("gc"); err != ErrLockHeld {
+		t.Fatalf("Lock held twice %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err = second.AcquireLockContext(ctx, "gc"); err != context.DeadlineExceeded {
+		t.Fatalf("Lock held twice %v", err)
+	}
+
+	acquired := make(chan *Lock)
+	go func() {
+		lock, err := second.AcquireLock("gc")
+		if err != nil {
+			t.Errorf("Unable to acquire the lock %v", err)
+		}
+		acquired <- lock
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	if err = leader.Release(); err != nil {
+		t.Fatalf("Unable to release the lock %v", err)
+	}
+	if _, open := <-leader.Events(); open {
+		t.Fatalf("Events of a released lock not closed")
+	}
+
+	var follower *Lock
+	select {
+	case follower = <-acquired:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Released lock not acquired")
+	}
+	if follower == nil || !follower.Held() {
+		t.Fatalf("Released lock not acquired")
+	}
+	<-follower.Events()
+
+	thief, err := first.StealLock("gc")
+	if err != nil || !thief.Held() {
+		t.Fatalf("Unable to steal the lock %v", err)
+	}
+	select {
+	case event := <-follower.Events():
+		if event.Locked || event.Err == nil || follower.Held() {
+			t.Fatalf("Unexpected event %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Theft of the lock not reported")
+	}
+	follower.Release()
+	<-thief.Events()
+
+	// The lock is lost with its session
+	server.Close()
+	select {
+	case event := <-thief.Events():
+		if event.Locked || event.Err == nil || thief.Held() {
+			t.Fatalf("Unexpected event %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Loss of the session not reported")
+	}
+}
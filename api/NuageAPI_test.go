@@ -7,11 +7,11 @@ import (
 	"math/rand"
 	"os"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/docker/distribution/uuid"
-	"github.com/nuagenetworks/go-bambou/bambou"
 	"github.com/nuagenetworks/libvrsdk/api/entity"
 	"github.com/nuagenetworks/libvrsdk/api/port"
 	"github.com/nuagenetworks/libvrsdk/test/util"
@@ -42,14 +42,31 @@ const (
 	UnixSocketFile  = "/var/run/openvswitch/db.sock"
 )
 
-var VSDConnection *bambou.Session
-var Root *vspk.Me
+var vsdSession struct {
+	once sync.Once
+	root *vspk.Me
+	err  error
+}
 
-func init() {
-	VSDConnection, Root = vspk.NewSession(VSDUsername, VSDPassword, VSDOrganization, VSDURL)
-	if err1, err2 := VSDConnection.SetInsecureSkipVerify(true), VSDConnection.Start(); err1 != nil || err2 != nil {
-		log.Printf("Error while establishing remote session to VSD")
+// vsdRoot returns the root object of the session to the VSD, started on first use. The tests checking the
+// VSD are skipped unless $RUN_VSD_TESTS is set.
+func vsdRoot(t *testing.T) *vspk.Me {
+	if os.Getenv("RUN_VSD_TESTS") == "" {
+		t.Skip("Skipping execution of VSD test; $RUN_VSD_TESTS not set")
 	}
+
+	vsdSession.once.Do(func() {
+		connection, root := vspk.NewSession(VSDUsername, VSDPassword, VSDOrganization, VSDURL)
+		vsdSession.root = root
+		if err1, err2 := connection.SetInsecureSkipVerify(true), connection.Start(); err1 != nil || err2 != nil {
+			vsdSession.err = fmt.Errorf("Error while establishing remote session to VSD")
+		}
+	})
+	if vsdSession.err != nil {
+		t.Fatalf("%v", vsdSession.err)
+	}
+
+	return vsdSession.root
 }
 
 func execCMDOnRemoteHost(cmd string, host string) error {
@@ -422,6 +439,8 @@ func TestContainerCreateDelete(t *testing.T) {
 // in VRS-VM as well as on the VSD and gets removed from VRS and VSD when deleted
 func TestVMCreateDelete(t *testing.T) {
 
+	root := vsdRoot(t)
+
 	var vrsConnection VRSConnection
 	var err error
 
@@ -467,7 +486,7 @@ func TestVMCreateDelete(t *testing.T) {
 	}
 
 	// Verifying port got an IP on VSD
-	portIPOnVSD, vsdError := util.VerifyVSDPortResolution(Root, Enterprise, Domain, Zone, vmInfo["entityport"])
+	portIPOnVSD, vsdError := util.VerifyVSDPortResolution(root, Enterprise, Domain, Zone, vmInfo["entityport"])
 	if vsdError != nil || portIPOnVSD == "" || portIPOnVSD == "0.0.0.0" {
 		t.Fatal("IP resolution for port " + vmInfo["entityport"] + " failed on VSD.")
 	} else {
@@ -510,7 +529,7 @@ func TestVMCreateDelete(t *testing.T) {
 	time.Sleep(time.Duration(300) * time.Second)
 
 	// Verifying port deletion on VSD
-	if portDeletionFailure, vsdErr := util.VerifyVSDPortDeletion(Root, Enterprise, Domain, Zone, vmInfo["entityport"]); vsdErr != nil || portDeletionFailure {
+	if portDeletionFailure, vsdErr := util.VerifyVSDPortDeletion(root, Enterprise, Domain, Zone, vmInfo["entityport"]); vsdErr != nil || portDeletionFailure {
 		t.Fatal("Deleted VM port still present on VSD")
 	}
 
@@ -682,6 +701,8 @@ func TestVMMigrate(t *testing.T) {
 // TestVMHotNICAdd tests hot NIC addition on a VM
 func TestVMHotNICAdd(t *testing.T) {
 
+	root := vsdRoot(t)
+
 	var vrsConnection VRSConnection
 	var err error
 
@@ -727,7 +748,7 @@ func TestVMHotNICAdd(t *testing.T) {
 		t.Fatal("Unable to obtain an IP address for the VM from VRS")
 	}
 
-	portIPOnVSD, vsdError := util.VerifyVSDPortResolution(Root, Enterprise, Domain, Zone, vmInfo["entityport"])
+	portIPOnVSD, vsdError := util.VerifyVSDPortResolution(root, Enterprise, Domain, Zone, vmInfo["entityport"])
 	if vsdError != nil || portIPOnVSD == "" || portIPOnVSD == "0.0.0.0" {
 		t.Fatal("IP resolution for port " + vmInfo["entityport"] + " failed on VSD.")
 	} else {
@@ -793,7 +814,7 @@ func TestVMHotNICAdd(t *testing.T) {
 	}
 
 	// Verifying port got an IP on VSD
-	hotNICIPOnVSD, vsdError := util.VerifyVSDPortResolution(Root, Enterprise, Domain, Zone, hotNICEntityPort)
+	hotNICIPOnVSD, vsdError := util.VerifyVSDPortResolution(root, Enterprise, Domain, Zone, hotNICEntityPort)
 	if vsdError != nil || hotNICIPOnVSD == "" || hotNICIPOnVSD == "0.0.0.0" {
 		t.Fatal("IP resolution for port " + hotNICEntityPort + " failed on VSD.")
 	} else {
@@ -844,7 +865,7 @@ func TestVMHotNICAdd(t *testing.T) {
 	time.Sleep(time.Duration(300) * time.Second)
 
 	// Verifying port deletion on VSD
-	if portDeletionFailure, vsdErr := util.VerifyVSDPortDeletion(Root, Enterprise, Domain, Zone, hotNICEntityPort); vsdErr != nil || portDeletionFailure {
+	if portDeletionFailure, vsdErr := util.VerifyVSDPortDeletion(root, Enterprise, Domain, Zone, hotNICEntityPort); vsdErr != nil || portDeletionFailure {
 		t.Fatal("Port did not get removed on VSD")
 	}
 
@@ -855,6 +876,8 @@ func TestVMHotNICAdd(t *testing.T) {
 // in VRS-VM as well as on the VSD on VM reconfigure event
 func TestVMReconfigure(t *testing.T) {
 
+	root := vsdRoot(t)
+
 	var vrsConnection VRSConnection
 	var err error
 
@@ -901,7 +924,7 @@ func TestVMReconfigure(t *testing.T) {
 	}
 
 	// Verifying port got an IP on VSD
-	portIPOnVSD, vsdError := util.VerifyVSDPortResolution(Root, Enterprise, Domain, Zone, vmInfo["entityport"])
+	portIPOnVSD, vsdError := util.VerifyVSDPortResolution(root, Enterprise, Domain, Zone, vmInfo["entityport"])
 	if vsdError != nil || portIPOnVSD == "" || portIPOnVSD == "0.0.0.0" {
 		t.Fatal("IP resolution for port " + vmInfo["entityport"] + " failed on VSD.")
 	} else {
@@ -951,7 +974,7 @@ func TestVMReconfigure(t *testing.T) {
 	time.Sleep(time.Duration(5) * time.Second)
 
 	// Verifying port got an IP on VSD
-	reconfiguredPortIPOnVSD, vsdError := util.VerifyVSDPortResolution(Root, Enterprise, Domain, Zone, vmInfo["entityport"])
+	reconfiguredPortIPOnVSD, vsdError := util.VerifyVSDPortResolution(root, Enterprise, Domain, Zone, vmInfo["entityport"])
 	if vsdError != nil || reconfiguredPortIPOnVSD == "" || reconfiguredPortIPOnVSD == "0.0.0.0" {
 		t.Fatal("IP resolution for port " + vmInfo["entityport"] + " failed on VSD.")
 	} else {
@@ -1001,7 +1024,7 @@ func TestVMReconfigure(t *testing.T) {
 	}
 
 	// Verifying port deletion on VSD
-	if portDeletionFailure, vsdErr := util.VerifyVSDPortDeletion(Root, Enterprise, Domain, Zone, vmInfo["entityport"]); vsdErr != nil || portDeletionFailure {
+	if portDeletionFailure, vsdErr := util.VerifyVSDPortDeletion(root, Enterprise, Domain, Zone, vmInfo["entityport"]); vsdErr != nil || portDeletionFailure {
 		t.Fatal("Port did not get removed on VSD")
 	}
 
@@ -1012,6 +1035,8 @@ func TestVMReconfigure(t *testing.T) {
 // in VRS-VM as well as on the VSD and gets removed from VRS and VSD when deleted
 func TestVMPowerOff(t *testing.T) {
 
+	root := vsdRoot(t)
+
 	var vrsConnection VRSConnection
 	var err error
 
@@ -1058,7 +1083,7 @@ func TestVMPowerOff(t *testing.T) {
 	}
 
 	// Verifying port got an IP on VSD
-	portIPOnVSD, vsdError := util.VerifyVSDPortResolution(Root, Enterprise, Domain, Zone, vmInfo["entityport"])
+	portIPOnVSD, vsdError := util.VerifyVSDPortResolution(root, Enterprise, Domain, Zone, vmInfo["entityport"])
 	if vsdError != nil || portIPOnVSD == "" || portIPOnVSD == "0.0.0.0" {
 		t.Fatal("IP resolution for port " + vmInfo["entityport"] + " failed on VSD.")
 	} else {
@@ -1137,12 +1162,14 @@ func TestVMPowerOff(t *testing.T) {
 //TestSplitActivation tests the split activation mode using SDK
 func TestSplitActivation(t *testing.T) {
 
+	root := vsdRoot(t)
+
 	vrsConnection, err1 := NewUnixSocketConnection(UnixSocketFile)
 	if err1 != nil {
 		t.Skip("Unable to connect to the VRS")
 	}
 
-	enterprise, err := util.FetchEnterprise(Root, Enterprise)
+	enterprise, err := util.FetchEnterprise(root, Enterprise)
 	if err != nil {
 		t.Fatalf("%v", err)
 	}
@@ -1180,7 +1207,7 @@ func TestSplitActivation(t *testing.T) {
 	container.UUID = containerUUID
 	container.Name = containerName
 	container.Interfaces = interfaceList
-	err = Root.CreateContainer(container)
+	err = root.CreateContainer(container)
 	if err != nil {
 		t.Fatalf("Creating container failed with error: %v", err)
 	}
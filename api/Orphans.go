@@ -0,0 +1,551 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nuagenetworks/libvrsdk/ovsdb"
+	"github.com/socketplane/libovsdb"
+)
+
+// OrphanKind identifies an inconsistency between the Nuage tables and the alubr0 bridge
+type OrphanKind string
+
+// Inconsistencies detected by FindOrphans
+const (
+	// OrphanPortWithoutEntity is a Nuage_Port_Table row not listed in the ports of any Nuage_VM_Table row
+	OrphanPortWithoutEntity OrphanKind = "port-without-entity"
+	// OrphanEntityPortMissing is a port listed by a Nuage_VM_Table row with no Nuage_Port_Table row
+	OrphanEntityPortMissing OrphanKind = "entity-port-missing"
+	// OrphanBridgePortWithoutInterface is an alubr0 port whose interfaces the VRS could not open, their network
+	// device no longer existing on the VRS host
+	OrphanBridgePortWithoutInterface OrphanKind = "bridge-port-without-interface"
+)
+
+// Orphan describes an inconsistency found on the VRS. Entity is the UUID of the entity involved, if any,
+// Metadata the metadata of the Nuage row the orphan was found from and Since the first time the connection
+// found it.
+type Orphan struct {
+	Kind     OrphanKind
+	Port     string
+	Entity   string
+	Metadata map[string]string
+	Since    time.Time
+}
+
+// String describes the orphan in a human readable way
+func (orphan Orphan) String() string {
+	if len(orphan.Entity) != 0 {
+		return fmt.Sprintf("%s port %s entity %s", orphan.Kind, orphan.Port, orphan.Entity)
+	}
+	return fmt.Sprintf("%s port %s", orphan.Kind, orphan.Port)
+}
+
+// defaultOrphanKinds are the kinds collected when the policy lists none. The ports without entity are left
+// out: ports are created before the entities using them, so they include the ports being set up.
+var defaultOrphanKinds = []OrphanKind{OrphanEntityPortMissing, OrphanBridgePortWithoutInterface}
+
+// GarbageCollectionPolicy controls which orphans CollectGarbage acts on
+type GarbageCollectionPolicy struct {
+	// Kinds restricts the collection to the given kinds of orphans. If empty, every kind but
+	// OrphanPortWithoutEntity is collected.
+	Kinds []OrphanKind
+	// MinAge leaves alone the orphans found for less than MinAge by the previous calls to FindOrphans and
+	// CollectGarbage on the connection, e.g. to let an agent create the entity of its ports
+	MinAge time.Duration
+	// Owner restricts the collection to orphans whose metadata contains all of these key/value pairs,
+	// e.g. nuage-orchestrationID. Bridge ports are matched against their Nuage port or entity.
+	Owner map[string]string
+	// ReportOnly returns the orphans matching the policy without deleting them
+	ReportOnly bool
+}
+
+func (policy GarbageCollectionPolicy) matches(orphan Orphan, now time.Time) bool {
+	kinds := policy.Kinds
+	if len(kinds) == 0 {
+		kinds = defaultOrphanKinds
+	}
+
+	found := false
+	for _, kind := range kinds {
+		if kind == orphan.Kind {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return false
+	}
+
+	if now.Sub(orphan.Since) < policy.MinAge {
+		return false
+	}
+
+	for k, v := range policy.Owner {
+		if value, ok := orphan.Metadata[k]; !ok || value != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+// orphanAges records when each orphan was first found. Like portResolutions it is shared between the copies
+// of the connection, hence the mutex.
+type orphanAges struct {
+	mutex sync.Mutex
+	since map[string]time.Time
+}
+
+// age sets the Since of the orphans, and forgets the orphans no longer found
+func (ages *orphanAges) age(orphans []Orphan, now time.Time) {
+	ages.mutex.Lock()
+	defer ages.mutex.Unlock()
+
+	since := make(map[string]time.Time)
+	for i := range orphans {
+		key := orphans[i].String()
+		if found, ok := ages.since[key]; ok {
+			since[key] = found
+		} else {
+			since[key] = now
+		}
+		orphans[i].Since = since[key]
+	}
+	ages.since = since
+}
+
+// FindOrphans cross-references Nuage_VM_Table, Nuage_Port_Table and the alubr0 Bridge, Port and Interface
+// rows, read in a single transaction, and returns the inconsistencies found. Ports are created before the
+// entities using them, so a port created by an agent that has not created its entity yet is reported as well.
+func (vrsConnection *VRSConnection) FindOrphans() ([]Orphan, error) {
+	var orphans []Orphan
+
+	operations := []libovsdb.Operation{
+		{
+			Op:    "select",
+			Table: ovsdb.NuageVMTable,
+			Where: []interface{}{libovsdb.NewCondition(ovsdb.NuageVMTableColumnVMUUID, "!=", "xxxx")},
+		},
+		{
+			Op:    "select",
+			Table: ovsdb.NuagePortTable,
+			Where: []interface{}{libovsdb.NewCondition(ovsdb.NuagePortTableColumnName, "!=", "xxxx")},
+		},
+	}
+	operations = append(operations, bridgePortsOperations()...)
+
+	reply, err := vrsConnection.transact(context.Background(), "select", operations...)
+	if err != nil || len(reply) != len(operations) {
+		return nil, fmt.Errorf("Problem reading the VRS tables %v", err)
+	}
+	for _, result := range reply {
+		if result.Error != "" {
+			return nil, fmt.Errorf("Problem reading the VRS tables %s %s", result.Error, result.Details)
+		}
+	}
+
+	var entities []EntityInfo
+	for _, row := range reply[0].Rows {
+		info, err := entityInfoFromRow(row)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to parse the entity row %+v %v", row, err)
+		}
+		entities = append(entities, info)
+	}
+
+	var ports []PortSpec
+	for _, row := range reply[1].Rows {
+		spec, err := portSpecFromRow(row)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to parse the port row %+v %v", row, err)
+		}
+		ports = append(ports, spec)
+	}
+
+	portMetadata := make(map[string]map[string]string)
+	for _, spec := range ports {
+		metadata := make(map[string]string)
+		for k, v := range spec.Metadata {
+			metadata[string(k)] = v
+		}
+		portMetadata[spec.Name] = metadata
+	}
+
+	entityMetadata := make(map[string]map[string]string)
+	portEntity := make(map[string]string)
+	for _, info := range entities {
+		metadata := make(map[string]string)
+		for k, v := range info.Metadata {
+			metadata[string(k)] = v
+		}
+		entityMetadata[info.UUID] = metadata
+
+		for _, name := range info.Ports {
+			portEntity[name] = info.UUID
+			if _, ok := portMetadata[name]; !ok {
+				orphans = append(orphans, Orphan{Kind: OrphanEntityPortMissing, Port: name,
+					Entity: info.UUID, Metadata: metadata})
+			}
+		}
+	}
+
+	for _, spec := range ports {
+		if _, ok := portEntity[spec.Name]; !ok {
+			orphans = append(orphans, Orphan{Kind: OrphanPortWithoutEntity, Port: spec.Name,
+				Metadata: portMetadata[spec.Name]})
+		}
+	}
+
+	bridgePorts, err := bridgePortsFromResults(reply[2], reply[3], reply[4])
+	if err != nil {
+		return nil, err
+	}
+
+	for _, bridgePort := range bridgePorts {
+		if bridgePort.name == bridgeName || !bridgePort.stale() {
+			continue
+		}
+		orphan := Orphan{Kind: OrphanBridgePortWithoutInterface, Port: bridgePort.name, Entity: bridgePort.entity}
+		if metadata, ok := portMetadata[bridgePort.name]; ok {
+			orphan.Metadata = metadata
+		} else if metadata, ok := entityMetadata[bridgePort.entity]; ok {
+			orphan.Metadata = metadata
+		}
+		orphans = append(orphans, orphan)
+	}
+
+	sort.SliceStable(orphans, func(i, j int) bool {
+		if orphans[i].Kind != orphans[j].Kind {
+			return orphans[i].Kind < orphans[j].Kind
+		}
+		return orphans[i].Port < orphans[j].Port
+	})
+	vrsConnection.orphans.age(orphans, time.Now())
+
+	return orphans, nil
+}
+
+// CollectGarbage finds the orphans matching the policy and, unless the policy only asks for a report,
+// deletes them: orphan Nuage ports are destroyed, missing ports are removed from their entity and stale
+// ports are removed from alubr0. Each deletion is guarded by a wait re-checking the orphan, so that the
+// orphans fixed since they were found, e.g. a port created again, are left alone. The orphans acted upon are
+// returned.
+func (vrsConnection *VRSConnection) CollectGarbage(policy GarbageCollectionPolicy) ([]Orphan, error) {
+	orphans, err := vrsConnection.FindOrphans()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var collected []Orphan
+	for _, orphan := range orphans {
+		if !policy.matches(orphan, now) {
+			continue
+		}
+
+		if !policy.ReportOnly {
+			deleted, err := vrsConnection.collectOrphan(orphan)
+			if err != nil {
+				return collected, fmt.Errorf("Unable to collect %s %v", orphan, err)
+			}
+			if !deleted {
+				continue
+			}
+		}
+
+		collected = append(collected, orphan)
+	}
+
+	return collected, nil
+}
+
+// collectOrphan deletes the orphan in a transaction guarded by waits on the orphan condition. It returns false
+// if the orphan no longer holds.
+func (vrsConnection *VRSConnection) collectOrphan(orphan Orphan) (bool, error) {
+	var operations []libovsdb.Operation
+	switch orphan.Kind {
+	case OrphanPortWithoutEntity:
+		ports, err := libovsdb.NewOvsSet([]string{orphan.Port})
+		if err != nil {
+			return false, err
+		}
+		operations = []libovsdb.Operation{
+			waitRows(ovsdb.NuageVMTable, ovsdb.NuageVMTableColumnVMUUID, nil,
+				libovsdb.NewCondition(ovsdb.NuageVMTableColumnPorts, "includes", ports)),
+			{
+				Op:    "delete",
+				Table: ovsdb.NuagePortTable,
+				Where: []interface{}{libovsdb.NewCondition(ovsdb.NuagePortTableColumnName, "==", orphan.Port)},
+			},
+		}
+	case OrphanEntityPortMissing:
+		ports, err := libovsdb.NewOvsSet([]string{orphan.Port})
+		if err != nil {
+			return false, err
+		}
+		condition := libovsdb.NewCondition(ovsdb.NuageVMTableColumnVMUUID, "==", orphan.Entity)
+		operations = []libovsdb.Operation{
+			waitRows(ovsdb.NuagePortTable, ovsdb.NuagePortTableColumnName, nil,
+				libovsdb.NewCondition(ovsdb.NuagePortTableColumnName, "==", orphan.Port)),
+			waitRows(ovsdb.NuageVMTable, ovsdb.NuageVMTableColumnVMUUID,
+				[]map[string]interface{}{{ovsdb.NuageVMTableColumnVMUUID: orphan.Entity}}, condition,
+				libovsdb.NewCondition(ovsdb.NuageVMTableColumnPorts, "includes", ports)),
+			{
+				Op:        "mutate",
+				Table:     ovsdb.NuageVMTable,
+				Mutations: []interface{}{libovsdb.NewMutation(ovsdb.NuageVMTableColumnPorts, "delete", ports)},
+				Where:     []interface{}{condition},
+			},
+		}
+	case OrphanBridgePortWithoutInterface:
+		var err error
+		if operations, err = vrsConnection.staleBridgePortOperations(orphan.Port); err != nil || operations == nil {
+			return false, err
+		}
+	default:
+		return false, fmt.Errorf("Unknown orphan kind %s", orphan.Kind)
+	}
+
+	reply, err := vrsConnection.transact(context.Background(), "delete", operations...)
+	if err != nil || len(reply) < len(operations) {
+		return false, fmt.Errorf("Problem deleting the orphan %v", err)
+	}
+	for i, result := range reply {
+		if result.Error == "" {
+			continue
+		}
+		if operations[i].Op == "wait" {
+			return false, nil
+		}
+		return false, fmt.Errorf("Problem deleting the orphan %s %s", result.Error, result.Details)
+	}
+
+	return true, nil
+}
+
+// staleBridgePortOperations returns the operations removing the port from alubr0, guarded by waits on the
+// state of its interfaces as read now, or nil if the port is no longer stale
+func (vrsConnection *VRSConnection) staleBridgePortOperations(name string) ([]libovsdb.Operation, error) {
+	operations := bridgePortsOperations()
+	reply, err := vrsConnection.transact(context.Background(), "select", operations...)
+	if err != nil || len(reply) != len(operations) {
+		return nil, fmt.Errorf("Problem reading the alubr0 ports %v", err)
+	}
+	bridgePorts, err := bridgePortsFromResults(reply[0], reply[1], reply[2])
+	if err != nil {
+		return nil, err
+	}
+
+	for _, bridgePort := range bridgePorts {
+		if bridgePort.name != name {
+			continue
+		}
+		if !bridgePort.stale() {
+			return nil, nil
+		}
+
+		var guarded []libovsdb.Operation
+		for _, intf := range bridgePort.interfaces {
+			guarded = append(guarded, libovsdb.Operation{
+				Op:      "wait",
+				Table:   interfaceTable,
+				Where:   []interface{}{libovsdb.NewCondition("_uuid", "==", libovsdb.UUID{GoUUID: intf.uuid})},
+				Columns: []string{"ofport", "error"},
+				Until:   "==",
+				Rows:    []map[string]interface{}{{"ofport": intf.ofport, "error": intf.err}},
+				Timeout: 1,
+			})
+		}
+
+		portUUID := libovsdb.UUID{GoUUID: bridgePort.uuid}
+		ports, err := libovsdb.NewOvsSet([]libovsdb.UUID{portUUID})
+		if err != nil {
+			return nil, err
+		}
+		return append(guarded,
+			libovsdb.Operation{
+				Op:    "delete",
+				Table: portTable,
+				Where: []interface{}{libovsdb.NewCondition("_uuid", "==", portUUID)},
+			},
+			libovsdb.Operation{
+				Op:        "mutate",
+				Table:     bridgeTable,
+				Mutations: []interface{}{libovsdb.NewMutation("ports", "delete", ports)},
+				Where:     []interface{}{libovsdb.NewCondition("name", "==", bridgeName)},
+			}), nil
+	}
+
+	return nil, nil
+}
+
+// waitRows returns a wait failing right away unless the rows of table matching conditions are rows, compared on
+// column
+func waitRows(table string, column string, rows []map[string]interface{},
+	conditions ...interface{}) libovsdb.Operation {
+
+	if rows == nil {
+		rows = []map[string]interface{}{}
+	}
+
+	// A wait without timeout blocks until the condition holds, use the smallest one to fail right away
+	return libovsdb.Operation{
+		Op:      "wait",
+		Table:   table,
+		Where:   conditions,
+		Columns: []string{column},
+		Until:   "==",
+		Rows:    rows,
+		Timeout: 1,
+	}
+}
+
+// bridgeInterface is an interface of an alubr0 port along with the ofport and error columns telling whether the
+// VRS could open its network device, in the OVSDB notation
+type bridgeInterface struct {
+	uuid   string
+	name   string
+	ofport interface{}
+	err    interface{}
+}
+
+// stale returns true if the VRS could not open the network device of the interface: its ofport is -1 or it
+// reports an error
+func (intf bridgeInterface) stale() bool {
+	stale := false
+	ovsdb.UnMarshallOVSSet(intf.ofport, func(atom interface{}) error {
+		if ofport, ok := atom.(float64); ok && ofport == -1 {
+			stale = true
+		}
+		return nil
+	})
+	ovsdb.UnMarshallOVSSet(intf.err, func(atom interface{}) error {
+		if message, ok := atom.(string); ok && len(message) != 0 {
+			stale = true
+		}
+		return nil
+	})
+
+	return stale
+}
+
+// bridgePort is a port of alubr0 along with the kernel interfaces backing it
+type bridgePort struct {
+	uuid       string
+	name       string
+	entity     string
+	interfaces []bridgeInterface
+}
+
+// stale returns true if the VRS could open none of the interfaces of the port. It is decided from the Interface
+// rows rather than from the interfaces of the local kernel, the VRS may run on another host.
+func (port bridgePort) stale() bool {
+	if len(port.interfaces) == 0 {
+		return false
+	}
+
+	for _, intf := range port.interfaces {
+		if !intf.stale() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// bridgePortsOperations returns the selects of the alubr0 bridge and of the Port and Interface rows read by
+// bridgePortsFromResults
+func bridgePortsOperations() []libovsdb.Operation {
+	return []libovsdb.Operation{
+		{
+			Op:    "select",
+			Table: bridgeTable,
+			Where: []interface{}{libovsdb.NewCondition("name", "==", bridgeName)},
+		},
+		{
+			Op:    "select",
+			Table: portTable,
+			Where: []interface{}{libovsdb.NewCondition("name", "!=", "")},
+		},
+		{
+			Op:    "select",
+			Table: interfaceTable,
+			Where: []interface{}{libovsdb.NewCondition("name", "!=", "")},
+		},
+	}
+}
+
+// bridgePortsFromResults returns the ports of alubr0 whose interfaces are backed by a kernel network device
+func bridgePortsFromResults(bridgeResult, portResult, intfResult libovsdb.OperationResult) ([]bridgePort, error) {
+	// alubr0 does not exist on this VRS, it has no ports
+	if len(bridgeResult.Rows) != 1 {
+		return nil, nil
+	}
+
+	attached, err := ovsdb.UnMarshallOVSUUIDSet(bridgeResult.Rows[0]["ports"])
+	if err != nil {
+		return nil, fmt.Errorf("Invalid alubr0 ports %v", err)
+	}
+
+	// Only interfaces backed by a network device of the kernel can go stale
+	kernelInterfaces := make(map[string]bridgeInterface)
+	for _, row := range intfResult.Rows {
+		uuids, err := ovsdb.UnMarshallOVSUUIDSet(row["_uuid"])
+		if err != nil || len(uuids) != 1 {
+			return nil, fmt.Errorf("Invalid interface row %+v", row)
+		}
+		intfType, _ := row["type"].(string)
+		if intfType == "" || intfType == "system" {
+			intf := bridgeInterface{uuid: uuids[0], ofport: row["ofport"], err: row["error"]}
+			intf.name, _ = row["name"].(string)
+			kernelInterfaces[uuids[0]] = intf
+		}
+	}
+
+	portRows := make(map[string]map[string]interface{})
+	for _, row := range portResult.Rows {
+		uuids, err := ovsdb.UnMarshallOVSUUIDSet(row["_uuid"])
+		if err != nil || len(uuids) != 1 {
+			return nil, fmt.Errorf("Invalid port row %+v", row)
+		}
+		portRows[uuids[0]] = row
+	}
+
+	var ports []bridgePort
+	for _, uuid := range attached {
+		row, ok := portRows[uuid]
+		if !ok {
+			continue
+		}
+
+		port := bridgePort{uuid: uuid}
+		port.name, _ = row["name"].(string)
+		if externalIDs, err := ovsdb.UnMarshallOVSStringMap(row["external_ids"]); err == nil {
+			port.entity = externalIDs["vm-uuid"]
+		}
+
+		interfaces, err := ovsdb.UnMarshallOVSUUIDSet(row["interfaces"])
+		if err != nil {
+			return nil, fmt.Errorf("Invalid interfaces of port %s %v", port.name, err)
+		}
+
+		kernelBacked := true
+		for _, uuid := range interfaces {
+			intf, ok := kernelInterfaces[uuid]
+			if !ok {
+				kernelBacked = false
+				break
+			}
+			port.interfaces = append(port.interfaces, intf)
+		}
+		if kernelBacked {
+			ports = append(ports, port)
+		}
+	}
+
+	return ports, nil
+}
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nuagenetworks/libvrsdk/api/entity"
+	"github.com/nuagenetworks/libvrsdk/api/port"
+	"github.com/nuagenetworks/libvrsdk/ovsdb/ovsdbtest"
+	"github.com/socketplane/libovsdb"
+)
+
+// setOfport sets the ofport of the interface as the VRS does once it opened its network device, -1 if it could not
+func setOfport(t *testing.T, server *ovsdbtest.Server, name string, ofport int) {
+	if _, err := server.Transact(libovsdb.Operation{
+		Op:    "update",
+		Table: interfaceTable,
+		Where: []interface{}{libovsdb.NewCondition("name", "==", name)},
+		Row:   map[string]interface{}{"ofport": ofport},
+	}); err != nil {
+		t.Fatalf("Unable to set the ofport of %s %v", name, err)
+	}
+}
+
+func TestCollectGarbage(t *testing.T) {
+	server, vrsConnection := testConnection(t)
+	defer server.Close()
+	defer vrsConnection.Disconnect()
+
+	bridgeOp := libovsdb.Operation{
+		Op:    "insert",
+		Table: bridgeTable,
+		Row:   map[string]interface{}{"name": bridgeName},
+	}
+	if _, err := server.Transact(bridgeOp); err != nil {
+		t.Fatalf("Unable to create alubr0 %v", err)
+	}
+
+	owned := map[port.MetadataKey]string{port.MetadataKeyDomain: "d", port.MetadataKeyNetwork: "n",
+		port.MetadataKeyZone: "z", port.MetadataKey("owner"): "me"}
+	attributes := port.Attributes{MAC: "76:22:F6:70:4E:47", Platform: entity.Docker, Bridge: bridgeName}
+	for _, name := range []string{"live", "orphan", "foreign"} {
+		metadata := owned
+		if name == "foreign" {
+			metadata = map[port.MetadataKey]string{port.MetadataKey("owner"): "other"}
+		}
+		if err := vrsConnection.CreatePort(name, attributes, metadata); err != nil {
+			t.Fatalf("Unable to create port %s %v", name, err)
+		}
+	}
+
+	info := EntityInfo{
+		UUID:     "vm",
+		Name:     "vm",
+		Type:     entity.Container,
+		Domain:   entity.Docker,
+		Ports:    []string{"live", "missing"},
+		Metadata: map[entity.MetadataKey]string{entity.MetadataKey("owner"): "me"},
+	}
+	if err := vrsConnection.CreateEntity(info); err != nil {
+		t.Fatalf("Unable to create the entity %v", err)
+	}
+	for _, name := range []string{"live", "stale"} {
+		if err := vrsConnection.AddPortToAlubr0(name, info); err != nil {
+			t.Fatalf("Unable to add port %s to alubr0 %v", name, err)
+		}
+	}
+	// The VRS could not open the network device of the stale port, which may be on another host
+	setOfport(t, server, "live", 1)
+	setOfport(t, server, "stale", -1)
+
+	orphans, err := vrsConnection.FindOrphans()
+	if err != nil {
+		t.Fatalf("Unable to find the orphans %v", err)
+	}
+	expected := []Orphan{
+		{Kind: OrphanBridgePortWithoutInterface, Port: "stale", Entity: "vm"},
+		{Kind: OrphanEntityPortMissing, Port: "missing", Entity: "vm"},
+		{Kind: OrphanPortWithoutEntity, Port: "foreign"},
+		{Kind: OrphanPortWithoutEntity, Port: "orphan"},
+	}
+	if len(orphans) != len(expected) {
+		t.Fatalf("Unexpected orphans %v", orphans)
+	}
+	for i := range expected {
+		if orphans[i].Kind != expected[i].Kind || orphans[i].Port != expected[i].Port ||
+			orphans[i].Entity != expected[i].Entity || orphans[i].Since.IsZero() {
+			t.Fatalf("Unexpected orphans %v", orphans)
+		}
+	}
+
+	// The ports without entity are only collected when asked for
+	policy := GarbageCollectionPolicy{Owner: map[string]string{"owner": "me"}, ReportOnly: true}
+	if collected, err := vrsConnection.CollectGarbage(policy); err != nil || len(collected) != 2 {
+		t.Fatalf("Unexpected report %v %v", collected, err)
+	}
+	found, err := vrsConnection.FindOrphans()
+	if err != nil || len(found) != 4 {
+		t.Fatalf("Report only policy modified the VRS %v %v", found, err)
+	}
+	for i := range found {
+		if !found[i].Since.Equal(orphans[i].Since) {
+			t.Fatalf("Orphan %s aged again %v %v", found[i], found[i].Since, orphans[i].Since)
+		}
+	}
+
+	policy.Kinds = []OrphanKind{OrphanPortWithoutEntity, OrphanEntityPortMissing, OrphanBridgePortWithoutInterface}
+	policy.MinAge = time.Hour
+	if collected, err := vrsConnection.CollectGarbage(policy); err != nil || len(collected) != 0 {
+		t.Fatalf("Unexpected report of recent orphans %v %v", collected, err)
+	}
+
+	policy.MinAge = 0
+	policy.ReportOnly = false
+	if _, err = vrsConnection.CollectGarbage(policy); err != nil {
+		t.Fatalf("Unable to collect the garbage %v", err)
+	}
+	orphans, err = vrsConnection.FindOrphans()
+	if err != nil || len(orphans) != 1 || orphans[0].Port != "foreign" {
+		t.Fatalf("Unexpected orphans after collection %v %v", orphans, err)
+	}
+}
+
+func TestCollectGarbageGuards(t *testing.T) {
+	server, vrsConnection := testConnection(t)
+	defer server.Close()
+	defer vrsConnection.Disconnect()
+
+	bridgeOp := libovsdb.Operation{
+		Op:    "insert",
+		Table: bridgeTable,
+		Row:   map[string]interface{}{"name": bridgeName},
+	}
+	if _, err := server.Transact(bridgeOp); err != nil {
+		t.Fatalf("Unable to create alubr0 %v", err)
+	}
+
+	attributes := port.Attributes{MAC: "76:22:F6:70:4E:47", Platform: entity.Docker, Bridge: bridgeName}
+	if err := vrsConnection.CreatePort("orphan", attributes, nil); err != nil {
+		t.Fatalf("Unable to create the port %v", err)
+	}
+	info := EntityInfo{UUID: "vm", Name: "vm", Type: entity.Container, Domain: entity.Docker,
+		Ports: []string{"missing"}}
+	if err := vrsConnection.CreateEntity(info); err != nil {
+		t.Fatalf("Unable to create the entity %v", err)
+	}
+	if err := vrsConnection.AddPortToAlubr0("stale", info); err != nil {
+		t.Fatalf("Unable to add the port to alubr0 %v", err)
+	}
+	setOfport(t, server, "stale", -1)
+
+	orphans, err := vrsConnection.FindOrphans()
+	if err != nil || len(orphans) != 3 {
+		t.Fatalf("Unexpected orphans %v %v", orphans, err)
+	}
+
+	// Every orphan is fixed between FindOrphans and its deletion
+	if err = vrsConnection.AddEntityPort("vm", "orphan"); err != nil {
+		t.Fatalf("Unable to add the port to the entity %v", err)
+	}
+	if err = vrsConnection.CreatePort("missing", attributes, nil); err != nil {
+		t.Fatalf("Unable to create the port %v", err)
+	}
+	setOfport(t, server, "stale", 2)
+
+	for _, orphan := range orphans {
+		if deleted, err := vrsConnection.collectOrphan(orphan); err != nil || deleted {
+			t.Fatalf("Orphan %s collected once fixed %v", orphan, err)
+		}
+	}
+
+	ports, err := vrsConnection.GetAllPorts()
+	if err != nil || len(ports) != 2 {
+		t.Fatalf("Unexpected ports %v %v", ports, err)
+	}
+	if entityPorts, err := vrsConnection.GetEntityPorts("vm"); err != nil || len(entityPorts) != 2 {
+		t.Fatalf("Unexpected entity ports %v %v", entityPorts, err)
+	}
+	if bridgePorts, err := vrsConnection.GetAlubr0Ports(); err != nil || len(bridgePorts) != 1 {
+		t.Fatalf("Unexpected alubr0 ports %v %v", bridgePorts, err)
+	}
+}
@@ -1,13 +1,17 @@
 package api
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"reflect"
+	"time"
 
+	"github.com/nuagenetworks/libvrsdk/api/entity"
 	"github.com/nuagenetworks/libvrsdk/api/port"
 	"github.com/nuagenetworks/libvrsdk/ovsdb"
 	"github.com/nuagenetworks/libvrsdk/test/util"
+	"github.com/nuagenetworks/libvrsdk/tracing"
 	"github.com/socketplane/libovsdb"
 )
 
@@ -23,6 +27,19 @@ type PortIPv4Info struct {
 	Registered bool
 }
 
+// PortSpec describes a port as it is provided by the user to VRS
+type PortSpec struct {
+	Name       string
+	Attributes port.Attributes
+	Metadata   map[port.MetadataKey]string
+}
+
+// BridgePortSpec describes a port to add to alubr0 along with the entity recorded in its external IDs
+type BridgePortSpec struct {
+	Name   string
+	Entity EntityInfo
+}
+
 // Constants for OVSDB table names
 const (
 	bridgeTable    = "Bridge"
@@ -54,10 +71,108 @@ func (vrsConnection *VRSConnection) GetAllPorts() ([]string, error) {
 	return names, nil
 }
 
+// GetAllPortSpecs returns the specification of all the vports attached to the VRS
+func (vrsConnection *VRSConnection) GetAllPortSpecs() ([]PortSpec, error) {
+
+	readRowArgs := ovsdb.ReadRowArgs{
+		Condition: []string{ovsdb.NuagePortTableColumnName, "!=", "xxxx"},
+		Columns: []string{ovsdb.NuagePortTableColumnName, ovsdb.NuagePortTableColumnMAC,
+			ovsdb.NuagePortTableColumnBridge, ovsdb.NuagePortTableColumnMetadata},
+	}
+	if vrsConnection.capabilities.Supports(ovsdb.NuagePortTable, ovsdb.NuagePortTableColumnVMDomain) {
+		readRowArgs.Columns = append(readRowArgs.Columns, ovsdb.NuagePortTableColumnVMDomain)
+	}
+
+	var rows []map[string]interface{}
+	var err error
+	if rows, err = vrsConnection.portTable.ReadRows(vrsConnection.ovsdbClient, readRowArgs); err != nil {
+		return nil, fmt.Errorf("Unable to obtain the ports %v", err)
+	}
+
+	var specs []PortSpec
+	for _, row := range rows {
+		spec, err := portSpecFromRow(row)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to parse the port row %+v %v", row, err)
+		}
+		specs = append(specs, spec)
+	}
+
+	return specs, nil
+}
+
+// portSpecFromRow converts a Nuage_Port_Table row into the PortSpec it was created from
+func portSpecFromRow(row map[string]interface{}) (PortSpec, error) {
+	var spec PortSpec
+	var ok bool
+
+	if spec.Name, ok = row[ovsdb.NuagePortTableColumnName].(string); !ok {
+		return spec, fmt.Errorf("Invalid %s", ovsdb.NuagePortTableColumnName)
+	}
+
+	if spec.Attributes.MAC, ok = row[ovsdb.NuagePortTableColumnMAC].(string); !ok {
+		return spec, fmt.Errorf("Invalid %s", ovsdb.NuagePortTableColumnMAC)
+	}
+
+	if spec.Attributes.Bridge, ok = row[ovsdb.NuagePortTableColumnBridge].(string); !ok {
+		return spec, fmt.Errorf("Invalid %s", ovsdb.NuagePortTableColumnBridge)
+	}
+
+	// The platform is unknown on the VRS lacking the column
+	if value, present := row[ovsdb.NuagePortTableColumnVMDomain]; present {
+		platform, err := ovsdb.UnMarshallOVSInt(value)
+		if err != nil {
+			return spec, fmt.Errorf("Invalid %s %v", ovsdb.NuagePortTableColumnVMDomain, err)
+		}
+		spec.Attributes.Platform = entity.Domain(platform)
+	}
+
+	metadata, err := ovsdb.UnMarshallOVSStringMap(row[ovsdb.NuagePortTableColumnMetadata])
+	if err != nil {
+		return spec, fmt.Errorf("Invalid %s %v", ovsdb.NuagePortTableColumnMetadata, err)
+	}
+
+	spec.Metadata = make(map[port.MetadataKey]string)
+	for k, v := range metadata {
+		spec.Metadata[port.MetadataKey(k)] = v
+	}
+
+	return spec, nil
+}
+
 // CreatePort creates a new vPort in the Nuage VRS. The only mandatory inputs required to create
 // a port are it's name and MAC address
 func (vrsConnection *VRSConnection) CreatePort(name string, attributes port.Attributes,
 	metadata map[port.MetadataKey]string) error {
+	return vrsConnection.CreatePortContext(context.Background(), name, attributes, metadata)
+}
+
+// CreatePortContext is CreatePort traced as a child of ctx. The wait for the resolution of the port by the VRS
+// is traced as a separate span linked to the creation, ended by the first update giving an IP to the port.
+func (vrsConnection *VRSConnection) CreatePortContext(ctx context.Context, name string, attributes port.Attributes,
+	metadata map[port.MetadataKey]string) (err error) {
+
+	ctx, span := vrsConnection.tracer.Start(ctx, "vrs.CreatePort")
+	span.SetAttributes(tracing.A("vrs.port", name))
+	defer func() { tracing.End(span, err) }()
+
+	// The resolution span starts before the insertion so that an update following it closely is not missed
+	_, resolution := vrsConnection.tracer.Start(context.Background(), "vrs.PortResolution", ctx)
+	resolution.SetAttributes(tracing.A("vrs.port", name))
+	vrsConnection.resolutions.start(name, resolution)
+
+	nuagePortRow := vrsConnection.portRow(name, attributes, metadata)
+	if err := vrsConnection.portTable.InsertRowContext(ctx, vrsConnection.ovsdbClient, nuagePortRow); err != nil {
+		vrsConnection.resolutions.end(name, err)
+		return fmt.Errorf("Problem adding port info to VRS %v", err)
+	}
+
+	return nil
+}
+
+// portRow builds the Nuage_Port_Table row of a port
+func (vrsConnection *VRSConnection) portRow(name string, attributes port.Attributes,
+	metadata map[port.MetadataKey]string) *ovsdb.NuagePortTableRow {
 
 	portMetadata := make(map[string]string)
 
@@ -65,7 +180,7 @@ func (vrsConnection *VRSConnection) CreatePort(name string, attributes port.Attr
 		portMetadata[string(k)] = v
 	}
 
-	nuagePortRow := ovsdb.NuagePortTableRow{
+	return &ovsdb.NuagePortTableRow{
 		Name:             name,
 		Mac:              attributes.MAC,
 		Bridge:           attributes.Bridge,
@@ -75,20 +190,24 @@ func (vrsConnection *VRSConnection) CreatePort(name string, attributes port.Attr
 		NuageZone:        metadata[port.MetadataKeyZone],
 		VMDomain:         attributes.Platform,
 		Metadata:         portMetadata,
+		Capabilities:     vrsConnection.capabilities,
 	}
-
-	if err := vrsConnection.portTable.InsertRow(vrsConnection.ovsdbClient, &nuagePortRow); err != nil {
-		return fmt.Errorf("Problem adding port info to VRS %v", err)
-	}
-
-	return nil
 }
 
 // DestroyPort purges a port from the Nuage VRS
 func (vrsConnection *VRSConnection) DestroyPort(name string) error {
+	return vrsConnection.DestroyPortContext(context.Background(), name)
+}
+
+// DestroyPortContext is DestroyPort traced as a child of ctx
+func (vrsConnection *VRSConnection) DestroyPortContext(ctx context.Context, name string) (err error) {
+
+	ctx, span := vrsConnection.tracer.Start(ctx, "vrs.DestroyPort")
+	span.SetAttributes(tracing.A("vrs.port", name))
+	defer func() { tracing.End(span, err) }()
 
 	condition := []string{ovsdb.NuagePortTableColumnName, "==", name}
-	if err := vrsConnection.portTable.DeleteRow(vrsConnection.ovsdbClient, condition); err != nil {
+	if err := vrsConnection.portTable.DeleteRowContext(ctx, vrsConnection.ovsdbClient, condition); err != nil {
 		return fmt.Errorf("Unable to remove the port from VRS %v", err)
 	}
 
@@ -134,6 +253,7 @@ func (vrsConnection *VRSConnection) UpdatePortAttributes(name string, attrs port
 	row[ovsdb.NuagePortTableColumnBridge] = attrs.Bridge
 	row[ovsdb.NuagePortTableColumnMAC] = attrs.MAC
 	row[ovsdb.NuagePortTableColumnVMDomain] = attrs.Platform
+	vrsConnection.capabilities.Omit(ovsdb.NuagePortTable, row)
 
 	condition := []string{ovsdb.NuagePortTableColumnName, "==", name}
 
@@ -158,19 +278,16 @@ func (vrsConnection *VRSConnection) UpdatePortMetadata(name string, metadata map
 	key := string(port.MetadataKeyDomain)
 	if len(metadata[key]) != 0 {
 		row[ovsdb.NuagePortTableColumnNuageDomain] = metadata[key]
-		delete(metadata, key)
 	}
 
 	key = string(port.MetadataKeyNetwork)
 	if len(metadata[key]) != 0 {
 		row[ovsdb.NuagePortTableColumnNuageNetwork] = metadata[key]
-		delete(metadata, key)
 	}
 
 	key = string(port.MetadataKeyZone)
 	if len(metadata[key]) != 0 {
 		row[ovsdb.NuagePortTableColumnNuageZone] = metadata[key]
-		delete(metadata, key)
 	}
 
 	condition := []string{ovsdb.NuagePortTableColumnName, "==", name}
@@ -182,17 +299,88 @@ func (vrsConnection *VRSConnection) UpdatePortMetadata(name string, metadata map
 	return nil
 }
 
+// PatchPortMetadata sets the keys of set and removes the keys of remove from the metadata of the vPort, leaving
+// the other keys untouched. The domain, network and zone columns follow the corresponding keys. The change is
+// applied by the OVSDB server in a single transaction so that concurrent patches of different keys do not
+// overwrite each other.
+func (vrsConnection *VRSConnection) PatchPortMetadata(name string, set map[port.MetadataKey]string,
+	remove []port.MetadataKey) error {
+
+	columns := map[port.MetadataKey]string{
+		port.MetadataKeyDomain:  ovsdb.NuagePortTableColumnNuageDomain,
+		port.MetadataKeyNetwork: ovsdb.NuagePortTableColumnNuageNetwork,
+		port.MetadataKeyZone:    ovsdb.NuagePortTableColumnNuageZone,
+	}
+
+	row := make(map[string]interface{})
+	values := make(map[string]string)
+	for k, v := range set {
+		values[string(k)] = v
+		if column, ok := columns[k]; ok {
+			row[column] = v
+		}
+	}
+
+	var keys []string
+	for _, k := range remove {
+		keys = append(keys, string(k))
+		if column, ok := columns[k]; ok {
+			row[column] = ""
+		}
+	}
+
+	mutations, err := ovsdb.MapMutations(ovsdb.NuagePortTableColumnMetadata, values, keys)
+	if err != nil {
+		return fmt.Errorf("Unable to create OVSDB mutations %v", err)
+	}
+
+	condition := []string{ovsdb.NuagePortTableColumnName, "==", name}
+
+	if err := vrsConnection.portTable.MutateRow(vrsConnection.ovsdbClient, row, mutations, condition); err != nil {
+		return fmt.Errorf("Unable to patch the port metadata %s %v %v %v", name, set, remove, err)
+	}
+
+	return nil
+}
+
 // RegisterForPortUpdates will help register via channel
-// for VRS port table updates
+// for VRS port table updates. Several channels can be registered for the same port, each receives the updates.
 func (vrsConnection *VRSConnection) RegisterForPortUpdates(brport string, pnc chan *PortIPv4Info) error {
-	vrsConnection.registrationChannel <- &Registration{Brport: brport, Channel: pnc, Register: true}
-	return nil
+	return vrsConnection.register(&Registration{Brport: brport, Channel: pnc, Register: true})
 }
 
-// DeregisterForPortUpdates will help de-register for VRS port table updates
+// DeregisterForPortUpdates will help de-register for VRS port table updates. All the channels registered for
+// the port are de-registered, see DeregisterChannelForPortUpdates to only de-register one.
 func (vrsConnection *VRSConnection) DeregisterForPortUpdates(brport string) error {
-	vrsConnection.registrationChannel <- &Registration{Brport: brport, Channel: nil, Register: false}
-	return nil
+	return vrsConnection.register(&Registration{Brport: brport, Channel: nil, Register: false})
+}
+
+// DeregisterChannelForPortUpdates de-registers the channel registered for the port, leaving the channels of the
+// other subscribers of the port registered
+func (vrsConnection *VRSConnection) DeregisterChannelForPortUpdates(brport string, pnc chan *PortIPv4Info) error {
+	return vrsConnection.register(&Registration{Brport: brport, Channel: pnc, Register: false})
+}
+
+// register hands the registration to the monitoring of the port updates, it fails once the connection is closed
+func (vrsConnection *VRSConnection) register(registration *Registration) error {
+	select {
+	case vrsConnection.registrationChannel <- registration:
+		return nil
+	case <-vrsConnection.disconnected:
+		return fmt.Errorf("Connection closed")
+	}
+}
+
+// releaseRegistrations drops the channels registered for port updates once the connection is closed
+func (vrsConnection VRSConnection) releaseRegistrations() {
+	for brport := range vrsConnection.pncTable {
+		delete(vrsConnection.pncTable, brport)
+	}
+	for brport := range vrsConnection.pnpTable {
+		delete(vrsConnection.pnpTable, brport)
+	}
+	vrsConnection.setSubscribers()
+	vrsConnection.resolutions.endAll(fmt.Errorf("Connection closed"))
 }
 
 func (vrsConnection VRSConnection) handlePortRegistration(registration *Registration) error {
@@ -200,23 +388,48 @@ func (vrsConnection VRSConnection) handlePortRegistration(registration *Registra
 	register := registration.Register
 	pnc := registration.Channel
 	if register {
-		if _, ok := vrsConnection.pncTable[brport]; ok {
-			return fmt.Errorf("Already registered for this bridge port %s", brport)
+		for _, registered := range vrsConnection.pncTable[brport] {
+			if registered == pnc {
+				return fmt.Errorf("Already registered for this bridge port %s", brport)
+			}
 		}
-		vrsConnection.pncTable[brport] = pnc
+		vrsConnection.pncTable[brport] = append(vrsConnection.pncTable[brport], pnc)
 		if portInfo, exists := vrsConnection.pnpTable[brport]; exists {
 			select {
 			case pnc <- &portInfo:
 			default:
+				vrsConnection.metrics.DropPortUpdate("resolved")
 			}
 			delete(vrsConnection.pnpTable, brport)
 		}
-	} else {
+	} else if pnc == nil {
 		delete(vrsConnection.pncTable, brport)
+	} else {
+		var remaining []chan *PortIPv4Info
+		for _, registered := range vrsConnection.pncTable[brport] {
+			if registered != pnc {
+				remaining = append(remaining, registered)
+			}
+		}
+		if len(remaining) == 0 {
+			delete(vrsConnection.pncTable, brport)
+		} else {
+			vrsConnection.pncTable[brport] = remaining
+		}
 	}
+	vrsConnection.setSubscribers()
 	return nil
 }
 
+// setSubscribers records the number of channels registered for updates
+func (vrsConnection VRSConnection) setSubscribers() {
+	count := 0
+	for _, channels := range vrsConnection.pncTable {
+		count += len(channels)
+	}
+	vrsConnection.metrics.SetSubscribers(count)
+}
+
 func (vrsConnection VRSConnection) getPortInfo(row *libovsdb.Row) (*PortIPv4Info, error) {
 	portIPv4Info := PortIPv4Info{Registered: true}
 	if _, ok := row.Fields["ip_addr"]; ok {
@@ -262,13 +475,21 @@ func (vrsConnection VRSConnection) processUpdates(updates *libovsdb.TableUpdates
 			if !reflect.DeepEqual(row.New, empty) {
 				//check for whether the port is already registered for updates
 				portInfo, err := vrsConnection.getPortInfo(&(row.New))
+				portName, named := (row.New).Fields["name"].(string)
 				if err == nil {
-					if _, ok := (row.New).Fields["name"]; ok {
-						portName := (row.New).Fields["name"].(string)
-						if pncChannel, exists := vrsConnection.pncTable[portName]; exists {
-							select {
-							case pncChannel <- portInfo:
-							default:
+					if named {
+						started, created := vrsConnection.resolutions.end(portName, nil,
+							tracing.A("vrs.ip", portInfo.IPAddr))
+						if created {
+							vrsConnection.metrics.ObservePortResolution(time.Since(started))
+						}
+						if pncChannels, exists := vrsConnection.pncTable[portName]; exists {
+							for _, pncChannel := range pncChannels {
+								select {
+								case pncChannel <- portInfo:
+								default:
+									vrsConnection.metrics.DropPortUpdate("resolved")
+								}
 							}
 						} else {
 							vrsConnection.pnpTable[portName] = *portInfo
@@ -278,14 +499,19 @@ func (vrsConnection VRSConnection) processUpdates(updates *libovsdb.TableUpdates
 			} else { //delete case
 				if _, ok := (row.Old).Fields["name"]; ok {
 					portName := (row.Old).Fields["name"].(string)
-					if pncChannel, exists := vrsConnection.pncTable[portName]; exists {
-						select {
-						case pncChannel <- &PortIPv4Info{Registered: false}:
-						default:
+					if pncChannels, exists := vrsConnection.pncTable[portName]; exists {
+						for _, pncChannel := range pncChannels {
+							select {
+							case pncChannel <- &PortIPv4Info{Registered: false}:
+							default:
+								vrsConnection.metrics.DropPortUpdate("deleted")
+							}
 						}
 						delete(vrsConnection.pncTable, portName)
+						vrsConnection.setSubscribers()
 					}
 					delete(vrsConnection.pnpTable, portName)
+					vrsConnection.resolutions.end(portName, fmt.Errorf("Port %s deleted", portName))
 				}
 			}
 		}
@@ -295,20 +521,65 @@ func (vrsConnection VRSConnection) processUpdates(updates *libovsdb.TableUpdates
 
 // AddPortToAlubr0 adds Nuage port to alubr0 bridge
 func (vrsConnection *VRSConnection) AddPortToAlubr0(intfName string, entityInfo EntityInfo) error {
+	return vrsConnection.AddPortToAlubr0Context(context.Background(), intfName, entityInfo)
+}
 
-	namedPortUUID := "port"
-	namedIntfUUID := "intf"
-	var err error
+// AddPortToAlubr0Context is AddPortToAlubr0 traced as a child of ctx
+func (vrsConnection *VRSConnection) AddPortToAlubr0Context(ctx context.Context, intfName string,
+	entityInfo EntityInfo) error {
+	return vrsConnection.AddPortToAlubr0WithExternalIDsContext(ctx, intfName, entityInfo, nil)
+}
+
+// AddPortToAlubr0WithExternalIDs adds Nuage port to alubr0 bridge, recording externalIDs in the external IDs of
+// its Port and Interface rows along with the entity. FindAlubr0Port finds the port back from one of them.
+func (vrsConnection *VRSConnection) AddPortToAlubr0WithExternalIDs(intfName string, entityInfo EntityInfo,
+	externalIDs map[string]string) error {
+	return vrsConnection.AddPortToAlubr0WithExternalIDsContext(context.Background(), intfName, entityInfo,
+		externalIDs)
+}
+
+// AddPortToAlubr0WithExternalIDsContext is AddPortToAlubr0WithExternalIDs traced as a child of ctx
+func (vrsConnection *VRSConnection) AddPortToAlubr0WithExternalIDsContext(ctx context.Context, intfName string,
+	entityInfo EntityInfo, externalIDs map[string]string) (err error) {
 
+	ctx, span := vrsConnection.tracer.Start(ctx, "vrs.AddPortToAlubr0")
+	span.SetAttributes(tracing.A("vrs.port", intfName), tracing.A("vrs.bridge", bridgeName))
+	defer func() { tracing.End(span, err) }()
+
+	insertOps, err := alubr0PortOperations(intfName, entityInfo, externalIDs, "")
+	if err != nil {
+		return err
+	}
+
+	operations := append(insertOps, alubr0MutateOperation([]string{insertOps[1].UUIDName}))
+	reply, err := vrsConnection.transact(ctx, "insert", operations...)
+	if err != nil || len(reply) < len(operations) {
+		return fmt.Errorf("Problem mutating row in the OVSDB Bridge table for alubr0")
+	}
+
+	return nil
+}
+
+// alubr0PortOperations returns the inserts of the Interface and the Port rows of a Nuage port, in that order.
+// suffix tells apart the named UUIDs of the ports inserted in the same transaction.
+func alubr0PortOperations(intfName string, entityInfo EntityInfo, externalIDs map[string]string,
+	suffix string) ([]libovsdb.Operation, error) {
+
+	var err error
+	namedPortUUID := "port" + suffix
+	namedIntfUUID := "intf" + suffix
 	// 1) Insert a row for Nuage port in OVSDB Interface table
 	extIDMap := make(map[string]string)
+	for k, v := range externalIDs {
+		extIDMap[k] = v
+	}
 	intf := make(map[string]interface{})
 	intf["name"] = intfName
 	extIDMap["vm-name"] = entityInfo.Name
 	extIDMap["vm-uuid"] = entityInfo.UUID
 	intf["external_ids"], err = libovsdb.NewOvsMap(extIDMap)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	// interface table ops
 	intfOp := libovsdb.Operation{
@@ -326,7 +597,7 @@ func (vrsConnection *VRSConnection) AddPortToAlubr0(intfName string, entityInfo
 	}
 	port["external_ids"], err = libovsdb.NewOvsMap(extIDMap)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	portOp := libovsdb.Operation{
 		Op:       "insert",
@@ -335,29 +606,38 @@ func (vrsConnection *VRSConnection) AddPortToAlubr0(intfName string, entityInfo
 		UUIDName: namedPortUUID,
 	}
 
-	// 3) Mutate the Ports column of the row in the Bridge table with new Nuage port
-	mutateUUID := []libovsdb.UUID{{GoUUID: namedPortUUID}}
+	return []libovsdb.Operation{intfOp, portOp}, nil
+}
+
+// alubr0MutateOperation returns the mutation of the Ports column of the row in the Bridge table adding the Nuage
+// ports inserted under the named UUIDs
+func alubr0MutateOperation(namedPortUUIDs []string) libovsdb.Operation {
+	mutateUUID := make([]libovsdb.UUID, len(namedPortUUIDs))
+	for i, name := range namedPortUUIDs {
+		mutateUUID[i] = libovsdb.UUID{GoUUID: name}
+	}
 	mutateSet, _ := libovsdb.NewOvsSet(mutateUUID)
 	mutation := libovsdb.NewMutation("ports", "insert", mutateSet)
 	condition := libovsdb.NewCondition("name", "==", bridgeName)
-	mutateOp := libovsdb.Operation{
+	return libovsdb.Operation{
 		Op:        "mutate",
 		Table:     bridgeTable,
 		Mutations: []interface{}{mutation},
 		Where:     []interface{}{condition},
 	}
-
-	operations := []libovsdb.Operation{intfOp, portOp, mutateOp}
-	reply, err := vrsConnection.ovsdbClient.Transact(OvsDBName, operations...)
-	if err != nil || len(reply) < len(operations) {
-		return fmt.Errorf("Problem mutating row in the OVSDB Bridge table for alubr0")
-	}
-
-	return nil
 }
 
 // RemovePortFromAlubr0 will remove a port from alubr0 bridge
 func (vrsConnection *VRSConnection) RemovePortFromAlubr0(portName string) error {
+	return vrsConnection.RemovePortFromAlubr0Context(context.Background(), portName)
+}
+
+// RemovePortFromAlubr0Context is RemovePortFromAlubr0 traced as a child of ctx
+func (vrsConnection *VRSConnection) RemovePortFromAlubr0Context(ctx context.Context, portName string) (err error) {
+
+	ctx, span := vrsConnection.tracer.Start(ctx, "vrs.RemovePortFromAlubr0")
+	span.SetAttributes(tracing.A("vrs.port", portName), tracing.A("vrs.bridge", bridgeName))
+	defer func() { tracing.End(span, err) }()
 
 	condition := libovsdb.NewCondition("name", "==", portName)
 	selectOp := libovsdb.Operation{
@@ -367,7 +647,7 @@ func (vrsConnection *VRSConnection) RemovePortFromAlubr0(portName string) error
 	}
 
 	selectOperation := []libovsdb.Operation{selectOp}
-	reply, err := vrsConnection.ovsdbClient.Transact(OvsDBName, selectOperation...)
+	reply, err := vrsConnection.transact(ctx, "select", selectOperation...)
 	if err != nil || len(reply) != 1 || len(reply[0].Rows) != 1 {
 		return fmt.Errorf("Problem selecting row in the OVSDB Port table for alubr0")
 	}
@@ -400,10 +680,55 @@ func (vrsConnection *VRSConnection) RemovePortFromAlubr0(portName string) error
 	}
 
 	operations := []libovsdb.Operation{deleteOp, mutateOp}
-	reply, err = vrsConnection.ovsdbClient.Transact(OvsDBName, operations...)
+	reply, err = vrsConnection.transact(ctx, "delete", operations...)
 	if err != nil || len(reply) < len(operations) {
 		return fmt.Errorf("Problem mutating row in the OVSDB Bridge table for alubr0")
 	}
 
 	return nil
 }
+
+// FindAlubr0Port returns the name of the port whose external ID key is value, as recorded by
+// AddPortToAlubr0WithExternalIDs, or an empty name if there is none
+func (vrsConnection *VRSConnection) FindAlubr0Port(key string, value string) (string, error) {
+	externalIDs, err := libovsdb.NewOvsMap(map[string]string{key: value})
+	if err != nil {
+		return "", err
+	}
+
+	selectOp := libovsdb.Operation{
+		Op:      "select",
+		Table:   portTable,
+		Where:   []interface{}{libovsdb.NewCondition("external_ids", "includes", externalIDs)},
+		Columns: []string{"name"},
+	}
+	reply, err := vrsConnection.transact(context.Background(), "select", selectOp)
+	if err != nil || len(reply) != 1 {
+		return "", fmt.Errorf("Problem selecting row in the OVSDB Port table %v", err)
+	}
+	if reply[0].Error != "" {
+		return "", fmt.Errorf("Problem selecting row in the OVSDB Port table %s %s", reply[0].Error,
+			reply[0].Details)
+	}
+	if len(reply[0].Rows) == 0 {
+		return "", nil
+	}
+
+	name, _ := reply[0].Rows[0]["name"].(string)
+	return name, nil
+}
+
+// GetAlubr0Ports returns the names of the ports attached to alubr0
+func (vrsConnection *VRSConnection) GetAlubr0Ports() ([]string, error) {
+	attachments, err := vrsConnection.getAlubr0Attachments()
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, attachment := range attachments {
+		names = append(names, attachment.Port)
+	}
+
+	return names, nil
+}
@@ -0,0 +1,246 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nuagenetworks/libvrsdk/api/port"
+	"github.com/nuagenetworks/libvrsdk/logging"
+	"github.com/nuagenetworks/libvrsdk/ovsdb"
+	"github.com/socketplane/libovsdb"
+)
+
+// bindingAttempts is the number of times AddPortBinding and RemovePortBinding read and write the bindings, the
+// bindings changed by another client between the read and the write are read again
+const bindingAttempts = 5
+
+// BindingConflict is a binding of another port of the VRS using the same host port as a requested binding
+type BindingConflict struct {
+	Port     string
+	Binding  port.Binding
+	Conflict port.Binding
+}
+
+// String describes the conflict in a human readable way
+func (conflict BindingConflict) String() string {
+	return fmt.Sprintf("%s conflicts with %s of port %s", conflict.Binding, conflict.Conflict, conflict.Port)
+}
+
+// GetPortBindings returns the bindings stored in the nuage-port-mapping metadata of the vPort
+func (vrsConnection *VRSConnection) GetPortBindings(name string) ([]port.Binding, error) {
+	specs, err := vrsConnection.GetAllPortSpecs()
+	if err != nil {
+		return nil, err
+	}
+
+	return portBindings(specs, name)
+}
+
+// FindBindingConflicts returns the bindings of the other ports of the VRS that use the same host ports as
+// the given bindings of the vPort. Ports whose nuage-port-mapping cannot be parsed are skipped.
+func (vrsConnection *VRSConnection) FindBindingConflicts(name string, bindings []port.Binding) ([]BindingConflict, error) {
+	specs, err := vrsConnection.GetAllPortSpecs()
+	if err != nil {
+		return nil, err
+	}
+
+	return vrsConnection.bindingConflicts(specs, name, bindings), nil
+}
+
+func (vrsConnection *VRSConnection) bindingConflicts(specs []PortSpec, name string,
+	bindings []port.Binding) []BindingConflict {
+
+	var conflicts []BindingConflict
+
+	for _, spec := range specs {
+		if spec.Name == name {
+			continue
+		}
+
+		existing, err := port.ParseBindings(spec.Metadata[port.MetadataKeyPortBindings])
+		if err != nil {
+			vrsConnection.logger.Warn("Ignoring the bindings of port", logging.F("port", spec.Name), logging.Err(err))
+			continue
+		}
+
+		for _, binding := range bindings {
+			for _, other := range existing {
+				if binding.Conflicts(other) {
+					conflicts = append(conflicts, BindingConflict{Port: spec.Name, Binding: binding, Conflict: other})
+				}
+			}
+		}
+	}
+
+	return conflicts
+}
+
+// AddPortBinding adds a binding to the nuage-port-mapping metadata of the vPort. The binding is rejected if
+// its host port is already used by the vPort or by another port of the VRS.
+func (vrsConnection *VRSConnection) AddPortBinding(name string, binding port.Binding) error {
+	if err := binding.Validate(); err != nil {
+		return err
+	}
+
+	return vrsConnection.updatePortBindings(name, true, func(specs []PortSpec,
+		bindings []port.Binding) ([]port.Binding, error) {
+
+		for _, existing := range bindings {
+			if binding.Conflicts(existing) {
+				return nil, fmt.Errorf("Binding %s conflicts with %s of port %s", binding, existing, name)
+			}
+		}
+
+		if conflicts := vrsConnection.bindingConflicts(specs, name, []port.Binding{binding}); len(conflicts) != 0 {
+			return nil, fmt.Errorf("Binding %s", conflicts[0])
+		}
+
+		return append(bindings, binding), nil
+	})
+}
+
+// RemovePortBinding removes a binding from the nuage-port-mapping metadata of the vPort
+func (vrsConnection *VRSConnection) RemovePortBinding(name string, binding port.Binding) error {
+	return vrsConnection.updatePortBindings(name, false, func(specs []PortSpec,
+		bindings []port.Binding) ([]port.Binding, error) {
+
+		var remaining []port.Binding
+		for _, existing := range bindings {
+			if existing != binding {
+				remaining = append(remaining, existing)
+			}
+		}
+
+		if len(remaining) == len(bindings) {
+			return nil, fmt.Errorf("Binding %s not found on port %s", binding, name)
+		}
+
+		return remaining, nil
+	})
+}
+
+// updatePortBindings replaces the bindings of the vPort with the ones update returns from the ports of the VRS
+// and the current bindings. The bindings are read again and update called again when the metadata of the vPort,
+// or with checked the metadata of any port the conflicts were checked against, changed between the read and the
+// write.
+func (vrsConnection *VRSConnection) updatePortBindings(name string, checked bool,
+	update func(specs []PortSpec, bindings []port.Binding) ([]port.Binding, error)) error {
+
+	for attempt := 0; attempt < bindingAttempts; attempt++ {
+		specs, err := vrsConnection.GetAllPortSpecs()
+		if err != nil {
+			return err
+		}
+
+		bindings, err := portBindings(specs, name)
+		if err != nil {
+			return err
+		}
+
+		if bindings, err = update(specs, bindings); err != nil {
+			return err
+		}
+
+		written, err := vrsConnection.writePortBindings(specs, name, bindings, checked)
+		if err != nil || written {
+			return err
+		}
+	}
+
+	return fmt.Errorf("Bindings of port %s changed concurrently", name)
+}
+
+func portBindings(specs []PortSpec, name string) ([]port.Binding, error) {
+	for _, spec := range specs {
+		if spec.Name == name {
+			bindings, err := port.ParseBindings(spec.Metadata[port.MetadataKeyPortBindings])
+			if err != nil {
+				return nil, fmt.Errorf("Unable to parse the bindings of port %s %v", name, err)
+			}
+			return bindings, nil
+		}
+	}
+
+	return nil, fmt.Errorf("Port %s not found", name)
+}
+
+// writePortBindings stores the bindings in the metadata of the port and returns true, unless its metadata differs
+// from the one of specs. With all set the metadata of every port is compared, and a port created or deleted
+// since specs were read fails the write as well, so that two ports cannot claim the same host port at once. The
+// metadata is compared in the transaction of the write.
+func (vrsConnection *VRSConnection) writePortBindings(specs []PortSpec, name string, bindings []port.Binding,
+	all bool) (bool, error) {
+
+	var set map[string]string
+	var remove []string
+	if len(bindings) == 0 {
+		remove = []string{string(port.MetadataKeyPortBindings)}
+	} else {
+		set = map[string]string{string(port.MetadataKeyPortBindings): port.FormatBindings(bindings)}
+	}
+
+	mutations, err := ovsdb.MapMutations(ovsdb.NuagePortTableColumnMetadata, set, remove)
+	if err != nil {
+		return false, fmt.Errorf("Unable to create OVSDB mutations %v", err)
+	}
+
+	rows := []map[string]interface{}{}
+	for _, spec := range specs {
+		if !all && spec.Name != name {
+			continue
+		}
+		read := make(map[string]string)
+		for k, v := range spec.Metadata {
+			read[string(k)] = v
+		}
+		metadata, err := libovsdb.NewOvsMap(read)
+		if err != nil {
+			return false, fmt.Errorf("Unable to create OVSDB map %v", err)
+		}
+		rows = append(rows, map[string]interface{}{ovsdb.NuagePortTableColumnName: spec.Name,
+			ovsdb.NuagePortTableColumnMetadata: metadata})
+	}
+
+	condition := libovsdb.NewCondition(ovsdb.NuagePortTableColumnName, "==", name)
+	where := []interface{}{condition}
+	if all {
+		where = []interface{}{libovsdb.NewCondition(ovsdb.NuagePortTableColumnName, "!=", "xxxx")}
+	}
+
+	// A wait without timeout blocks until the condition holds, use the smallest one to fail right away
+	waitOp := libovsdb.Operation{
+		Op:      "wait",
+		Table:   ovsdb.NuagePortTable,
+		Where:   where,
+		Columns: []string{ovsdb.NuagePortTableColumnName, ovsdb.NuagePortTableColumnMetadata},
+		Until:   "==",
+		Rows:    rows,
+		Timeout: 1,
+	}
+
+	mutateOp := libovsdb.Operation{
+		Op:        "mutate",
+		Table:     ovsdb.NuagePortTable,
+		Mutations: mutations,
+		Where:     []interface{}{condition},
+	}
+
+	operations := []libovsdb.Operation{waitOp, mutateOp}
+	reply, err := vrsConnection.transact(context.Background(), "mutate", operations...)
+	if err != nil || len(reply) < len(operations) {
+		return false, fmt.Errorf("Unable to write the bindings of port %s %v", name, err)
+	}
+
+	if reply[0].Error != "" {
+		return false, nil
+	}
+
+	for _, result := range reply {
+		if result.Error != "" {
+			return false, fmt.Errorf("Unable to write the bindings of port %s %s %s", name, result.Error,
+				result.Details)
+		}
+	}
+
+	return true, nil
+}
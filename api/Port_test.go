@@ -0,0 +1,136 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/nuagenetworks/libvrsdk/api/entity"
+	"github.com/nuagenetworks/libvrsdk/api/port"
+)
+
+func TestPatchPortMetadata(t *testing.T) {
+	server, vrsConnection := testConnection(t)
+	defer server.Close()
+	defer vrsConnection.Disconnect()
+
+	attributes := port.Attributes{MAC: "76:22:F6:70:4E:47", Platform: entity.Docker, Bridge: bridgeName}
+	metadata := map[port.MetadataKey]string{
+		port.MetadataKeyDomain:                 "domain",
+		port.MetadataKeyNetwork:                "network",
+		port.MetadataKeyZone:                   "zone",
+		port.MetadataNuagePolicyGroup:          "pg1",
+		port.MetadataKeyNuageRedirectionTarget: "rt1",
+	}
+	if err := vrsConnection.CreatePort("p1", attributes, metadata); err != nil {
+		t.Fatalf("Unable to create the port %v", err)
+	}
+
+	set := map[port.MetadataKey]string{port.MetadataNuagePolicyGroup: "pg2", port.MetadataKeyZone: "zone2"}
+	remove := []port.MetadataKey{port.MetadataKeyNuageRedirectionTarget}
+	if err := vrsConnection.PatchPortMetadata("p1", set, remove); err != nil {
+		t.Fatalf("Unable to patch the metadata %v", err)
+	}
+
+	specs, err := vrsConnection.GetAllPortSpecs()
+	if err != nil || len(specs) != 1 {
+		t.Fatalf("Unable to read the port %v %v", specs, err)
+	}
+	patched := specs[0].Metadata
+	if len(patched) != 4 || patched[port.MetadataNuagePolicyGroup] != "pg2" || patched[port.MetadataKeyZone] != "zone2" ||
+		patched[port.MetadataKeyDomain] != "domain" {
+		t.Fatalf("Unexpected metadata %v", patched)
+	}
+
+	update := map[string]string{string(port.MetadataKeyDomain): "domain2"}
+	if err = vrsConnection.UpdatePortMetadata("p1", update); err != nil {
+		t.Fatalf("Unable to update the metadata %v", err)
+	}
+	if len(update) != 1 {
+		t.Fatalf("UpdatePortMetadata modified the metadata of the caller %v", update)
+	}
+}
+
+func TestPortBindings(t *testing.T) {
+	server, vrsConnection := testConnection(t)
+	defer server.Close()
+	defer vrsConnection.Disconnect()
+
+	attributes := port.Attributes{MAC: "76:22:F6:70:4E:47", Platform: entity.Docker, Bridge: bridgeName}
+	for _, name := range []string{"p1", "p2"} {
+		if err := vrsConnection.CreatePort(name, attributes, nil); err != nil {
+			t.Fatalf("Unable to create the port %v", err)
+		}
+	}
+
+	web := port.Binding{HostPort: 8080, ContainerPort: 80, Protocol: port.ProtocolTCP}
+	dns := port.Binding{HostPort: 53, ContainerPort: 53, Protocol: port.ProtocolUDP}
+	if err := vrsConnection.AddPortBinding("p1", web); err != nil {
+		t.Fatalf("Unable to add the binding %v", err)
+	}
+	if err := vrsConnection.AddPortBinding("p1", dns); err != nil {
+		t.Fatalf("Unable to add the binding %v", err)
+	}
+	if err := vrsConnection.AddPortBinding("p2", web); err == nil {
+		t.Fatalf("Added a conflicting binding")
+	}
+
+	conflicts, err := vrsConnection.FindBindingConflicts("p2", []port.Binding{web, {HostPort: 8081, ContainerPort: 80, Protocol: port.ProtocolTCP}})
+	if err != nil || len(conflicts) != 1 || conflicts[0].Port != "p1" {
+		t.Fatalf("Unexpected conflicts %v %v", conflicts, err)
+	}
+
+	if err = vrsConnection.RemovePortBinding("p1", web); err != nil {
+		t.Fatalf("Unable to remove the binding %v", err)
+	}
+	bindings, err := vrsConnection.GetPortBindings("p1")
+	if err != nil || len(bindings) != 1 || bindings[0] != dns {
+		t.Fatalf("Unexpected bindings %v %v", bindings, err)
+	}
+	if err = vrsConnection.AddPortBinding("p2", web); err != nil {
+		t.Fatalf("Unable to add the released binding %v", err)
+	}
+
+	// The bindings are not written over a metadata changed since it was read
+	specs, err := vrsConnection.GetAllPortSpecs()
+	if err != nil {
+		t.Fatalf("Unable to read the ports %v", err)
+	}
+	other := map[port.MetadataKey]string{port.MetadataKeyPortBindings: "2222:22/tcp"}
+	if err = vrsConnection.PatchPortMetadata("p1", other, nil); err != nil {
+		t.Fatalf("Unable to change the bindings %v", err)
+	}
+	for _, all := range []bool{false, true} {
+		if written, err := vrsConnection.writePortBindings(specs, "p1", []port.Binding{web}, all); err != nil ||
+			written {
+			t.Fatalf("Bindings written over a concurrent change %t %v", written, err)
+		}
+	}
+	if err = vrsConnection.AddPortBinding("p1", dns); err != nil {
+		t.Fatalf("Unable to add the binding after a concurrent change %v", err)
+	}
+	if bindings, err = vrsConnection.GetPortBindings("p1"); err != nil || len(bindings) != 2 {
+		t.Fatalf("Unexpected bindings after a concurrent change %v %v", bindings, err)
+	}
+
+	// Two ports claiming the same host port at once, only one of them gets it
+	for _, name := range []string{"p3", "p4"} {
+		if err := vrsConnection.CreatePort(name, attributes, nil); err != nil {
+			t.Fatalf("Unable to create the port %v", err)
+		}
+	}
+	for hostPort := uint16(9000); hostPort < 9020; hostPort++ {
+		binding := port.Binding{HostPort: hostPort, ContainerPort: 80, Protocol: port.ProtocolTCP}
+		errors := make(chan error, 2)
+		for _, name := range []string{"p3", "p4"} {
+			go func(name string) { errors <- vrsConnection.AddPortBinding(name, binding) }(name)
+		}
+		added := 0
+		for i := 0; i < 2; i++ {
+			if err := <-errors; err == nil {
+				added++
+			}
+		}
+		if added != 1 {
+			t.Fatalf("Host port %d bound %d times", hostPort, added)
+		}
+	}
+}
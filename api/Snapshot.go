@@ -0,0 +1,452 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/nuagenetworks/libvrsdk/api/entity"
+	"github.com/nuagenetworks/libvrsdk/ovsdb"
+	"github.com/socketplane/libovsdb"
+)
+
+// SnapshotVersion is the version of the documents written by ExportState. ImportState rejects documents of
+// a later version.
+const SnapshotVersion = 1
+
+// Snapshot is the Nuage state of a VRS exported by ExportState. The state resolved by the VRS, such as the IP
+// addresses of the ports, is not part of it since the VRS resolves the ports again once they are restored.
+type Snapshot struct {
+	Version     int                  `json:"version"`
+	Schema      string               `json:"schema,omitempty"`
+	Exported    time.Time            `json:"exported"`
+	Entities    []SnapshotEntity     `json:"entities"`
+	Ports       []SnapshotPort       `json:"ports"`
+	Attachments []SnapshotAttachment `json:"attachments"`
+}
+
+// SnapshotEntity is a row of the Nuage_VM_Table
+type SnapshotEntity struct {
+	UUID       string            `json:"uuid"`
+	Name       string            `json:"name"`
+	Type       entity.Type       `json:"type"`
+	Domain     entity.Domain     `json:"domain"`
+	User       string            `json:"user,omitempty"`
+	Enterprise string            `json:"enterprise,omitempty"`
+	Ports      []string          `json:"ports,omitempty"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+	Event      int               `json:"event"`
+	EventType  int               `json:"eventType"`
+	State      int               `json:"state"`
+	Reason     int               `json:"reason"`
+}
+
+// SnapshotPort is a row of the Nuage_Port_Table
+type SnapshotPort struct {
+	Name        string            `json:"name"`
+	MAC         string            `json:"mac"`
+	Bridge      string            `json:"bridge"`
+	Platform    entity.Domain     `json:"platform"`
+	Alias       string            `json:"alias,omitempty"`
+	Domain      string            `json:"domain,omitempty"`
+	Zone        string            `json:"zone,omitempty"`
+	Network     string            `json:"network,omitempty"`
+	NetworkType string            `json:"networkType,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+// SnapshotAttachment is a port of alubr0 along with the entity recorded in its external IDs
+type SnapshotAttachment struct {
+	Port       string `json:"port"`
+	EntityUUID string `json:"entityUUID"`
+	EntityName string `json:"entityName"`
+}
+
+// ImportMode tells ImportState what to do with the rows already present in the VRS
+type ImportMode int
+
+// Import modes
+const (
+	// ImportModeSkip leaves the existing rows unchanged
+	ImportModeSkip ImportMode = iota
+	// ImportModeOverwrite replaces the columns of the existing rows with the ones of the snapshot, the columns
+	// set by the VRS are kept
+	ImportModeOverwrite
+)
+
+// portSnapshotColumns are the columns of Nuage_Port_Table held by a SnapshotPort
+var portSnapshotColumns = []string{
+	ovsdb.NuagePortTableColumnMAC,
+	ovsdb.NuagePortTableColumnBridge,
+	ovsdb.NuagePortTableColumnVMDomain,
+	ovsdb.NuagePortTableColumnAlias,
+	ovsdb.NuagePortTableColumnNuageDomain,
+	ovsdb.NuagePortTableColumnNuageZone,
+	ovsdb.NuagePortTableColumnNuageNetwork,
+	ovsdb.NuagePortTableColumnNuageNetworkType,
+	ovsdb.NuagePortTableColumnMetadata,
+}
+
+// entitySnapshotColumns are the columns of Nuage_VM_Table held by a SnapshotEntity
+var entitySnapshotColumns = []string{
+	ovsdb.NuageVMTableColumnVMName,
+	ovsdb.NuageVMTableColumnType,
+	ovsdb.NuageVMTableColumnDomain,
+	ovsdb.NuageVMTableColumnUser,
+	ovsdb.NuageVMTableColumnEnterprise,
+	ovsdb.NuageVMTableColumnPorts,
+	ovsdb.NuageVMTableColumnMetadata,
+	ovsdb.NuageVMTableColumnEventCategory,
+	ovsdb.NuageVMTableColumnEventType,
+	ovsdb.NuageVMTableColumnState,
+	ovsdb.NuageVMTableColumnReason,
+}
+
+// ImportOptions controls ImportState
+type ImportOptions struct {
+	Mode ImportMode
+	// DryRun reports the changes without applying them
+	DryRun bool
+}
+
+// ImportResult lists the rows created, overwritten and skipped by ImportState, or that would be with DryRun,
+// as "entity <uuid>", "port <name>" and "attachment <port>". The existing rows equal to the ones of the
+// snapshot are skipped whatever the mode.
+type ImportResult struct {
+	Created     []string
+	Overwritten []string
+	Skipped     []string
+}
+
+// ExportState writes the entities, ports and alubr0 port attachments of the VRS to w as a JSON Snapshot
+func (vrsConnection *VRSConnection) ExportState(w io.Writer) error {
+	snapshot, err := vrsConnection.snapshot()
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(snapshot); err != nil {
+		return fmt.Errorf("Unable to write the snapshot %v", err)
+	}
+
+	return nil
+}
+
+// ImportState restores the entities, ports and alubr0 port attachments of a Snapshot read from r. The ports
+// are restored first since the entities refer to them. It stops at the first row it fails to restore.
+func (vrsConnection *VRSConnection) ImportState(r io.Reader, opts ImportOptions) (ImportResult, error) {
+	var result ImportResult
+
+	var snapshot Snapshot
+	if err := json.NewDecoder(r).Decode(&snapshot); err != nil {
+		return result, fmt.Errorf("Unable to read the snapshot %v", err)
+	}
+
+	if snapshot.Version < 1 || snapshot.Version > SnapshotVersion {
+		return result, fmt.Errorf("Unsupported snapshot version %d", snapshot.Version)
+	}
+
+	if opts.Mode != ImportModeSkip && opts.Mode != ImportModeOverwrite {
+		return result, fmt.Errorf("Invalid import mode %d", opts.Mode)
+	}
+
+	current, err := vrsConnection.snapshot()
+	if err != nil {
+		return result, err
+	}
+
+	// apply records the row and restores it unless it exists and is skipped or this is a dry run. existing is
+	// the current row and wanted the one of the snapshot.
+	apply := func(name string, exists bool, existing interface{}, wanted interface{}, create func() error,
+		overwrite func() error) error {
+
+		if exists && (opts.Mode == ImportModeSkip || sameSnapshotRow(existing, wanted)) {
+			result.Skipped = append(result.Skipped, name)
+			return nil
+		}
+
+		if !opts.DryRun {
+			restore := create
+			if exists {
+				restore = overwrite
+			}
+			if err := restore(); err != nil {
+				return fmt.Errorf("Unable to restore %s %v", name, err)
+			}
+		}
+
+		if exists {
+			result.Overwritten = append(result.Overwritten, name)
+		} else {
+			result.Created = append(result.Created, name)
+		}
+		return nil
+	}
+
+	existingPorts := make(map[string]SnapshotPort)
+	for _, p := range current.Ports {
+		existingPorts[p.Name] = p
+	}
+	for _, p := range snapshot.Ports {
+		row := p.row(vrsConnection.capabilities)
+		condition := []string{ovsdb.NuagePortTableColumnName, "==", p.Name}
+		existing, exists := existingPorts[p.Name]
+		err := apply("port "+p.Name, exists, existing, p, func() error {
+			return vrsConnection.portTable.InsertRow(vrsConnection.ovsdbClient, &row)
+		}, func() error {
+			return vrsConnection.updateRow(vrsConnection.portTable, &row, portSnapshotColumns, condition)
+		})
+		if err != nil {
+			return result, err
+		}
+	}
+
+	existingEntities := make(map[string]SnapshotEntity)
+	for _, e := range current.Entities {
+		existingEntities[e.UUID] = e
+	}
+	for _, e := range snapshot.Entities {
+		row := e.row(vrsConnection.capabilities)
+		condition := []string{ovsdb.NuageVMTableColumnVMUUID, "==", e.UUID}
+		existing, exists := existingEntities[e.UUID]
+		err := apply("entity "+e.UUID, exists, existing, e, func() error {
+			return vrsConnection.vmTable.InsertRow(vrsConnection.ovsdbClient, &row)
+		}, func() error {
+			return vrsConnection.updateRow(vrsConnection.vmTable, &row, entitySnapshotColumns, condition)
+		})
+		if err != nil {
+			return result, err
+		}
+	}
+
+	existingAttachments := make(map[string]SnapshotAttachment)
+	for _, a := range current.Attachments {
+		existingAttachments[a.Port] = a
+	}
+	for _, a := range snapshot.Attachments {
+		info := EntityInfo{UUID: a.EntityUUID, Name: a.EntityName}
+		existing, exists := existingAttachments[a.Port]
+		err := apply("attachment "+a.Port, exists, existing, a, func() error {
+			return vrsConnection.AddPortToAlubr0(a.Port, info)
+		}, func() error {
+			if err := vrsConnection.RemovePortFromAlubr0(a.Port); err != nil {
+				return err
+			}
+			return vrsConnection.AddPortToAlubr0(a.Port, info)
+		})
+		if err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// updateRow replaces the given columns of the row matching condition with the ones of row, the other columns
+// are left unchanged. The columns the VRS does not support are absent from row and left out.
+func (vrsConnection *VRSConnection) updateRow(table ovsdb.ExtendedNuageTableOps, row ovsdb.NuageTableRow,
+	columns []string, condition []string) error {
+
+	ovsdbRow := make(map[string]interface{})
+	if err := row.CreateOVSDBRow(ovsdbRow); err != nil {
+		return err
+	}
+
+	changed := make(map[string]interface{})
+	for _, column := range columns {
+		if value, ok := ovsdbRow[column]; ok {
+			changed[column] = value
+		}
+	}
+
+	return table.UpdateRow(vrsConnection.ovsdbClient, changed, condition)
+}
+
+// sameSnapshotRow compares two rows of snapshots by their JSON encoding, for which absent and empty metadata
+// or ports are the same
+func sameSnapshotRow(a interface{}, b interface{}) bool {
+	encodedA, errA := json.Marshal(a)
+	encodedB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(encodedA, encodedB)
+}
+
+// snapshot reads the Nuage state of the VRS, sorted so that successive exports can be compared
+func (vrsConnection *VRSConnection) snapshot() (*Snapshot, error) {
+	snapshot := &Snapshot{
+		Version:     SnapshotVersion,
+		Schema:      vrsConnection.ovsdbClient.Schema[OvsDBName].Version,
+		Exported:    time.Now().UTC(),
+		Entities:    []SnapshotEntity{},
+		Ports:       []SnapshotPort{},
+		Attachments: []SnapshotAttachment{},
+	}
+
+	readRowArgs := ovsdb.ReadRowArgs{Condition: []string{ovsdb.NuageVMTableColumnVMUUID, "!=", "xxxx"}}
+	rows, err := vrsConnection.vmTable.ReadRows(vrsConnection.ovsdbClient, readRowArgs)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to obtain the entities %v", err)
+	}
+	for _, ovsdbRow := range rows {
+		var row ovsdb.NuageVMTableRow
+		if err := row.ReadOVSDBRow(ovsdbRow); err != nil {
+			return nil, fmt.Errorf("Unable to parse the entity row %v", err)
+		}
+		snapshot.Entities = append(snapshot.Entities, snapshotEntity(row))
+	}
+	sort.Slice(snapshot.Entities, func(i, j int) bool { return snapshot.Entities[i].UUID < snapshot.Entities[j].UUID })
+
+	readRowArgs = ovsdb.ReadRowArgs{Condition: []string{ovsdb.NuagePortTableColumnName, "!=", "xxxx"}}
+	rows, err = vrsConnection.portTable.ReadRows(vrsConnection.ovsdbClient, readRowArgs)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to obtain the ports %v", err)
+	}
+	for _, ovsdbRow := range rows {
+		var row ovsdb.NuagePortTableRow
+		if err := row.ReadOVSDBRow(ovsdbRow); err != nil {
+			return nil, fmt.Errorf("Unable to parse the port row %v", err)
+		}
+		snapshot.Ports = append(snapshot.Ports, snapshotPort(row))
+	}
+	sort.Slice(snapshot.Ports, func(i, j int) bool { return snapshot.Ports[i].Name < snapshot.Ports[j].Name })
+
+	if snapshot.Attachments, err = vrsConnection.getAlubr0Attachments(); err != nil {
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+func snapshotEntity(row ovsdb.NuageVMTableRow) SnapshotEntity {
+	return SnapshotEntity{
+		UUID:       row.VMUuid,
+		Name:       row.VMName,
+		Type:       entity.Type(row.Type),
+		Domain:     row.Domain,
+		User:       row.NuageUser,
+		Enterprise: row.NuageEnterprise,
+		Ports:      row.Ports,
+		Metadata:   row.Metadata,
+		Event:      row.Event,
+		EventType:  row.EventType,
+		State:      row.State,
+		Reason:     row.Reason,
+	}
+}
+
+func (e SnapshotEntity) row(capabilities ovsdb.Capabilities) ovsdb.NuageVMTableRow {
+	return ovsdb.NuageVMTableRow{
+		VMUuid:          e.UUID,
+		VMName:          e.Name,
+		Type:            int(e.Type),
+		Domain:          e.Domain,
+		NuageUser:       e.User,
+		NuageEnterprise: e.Enterprise,
+		Ports:           e.Ports,
+		Metadata:        e.Metadata,
+		Event:           e.Event,
+		EventType:       e.EventType,
+		State:           e.State,
+		Reason:          e.Reason,
+		Capabilities:    capabilities,
+	}
+}
+
+func snapshotPort(row ovsdb.NuagePortTableRow) SnapshotPort {
+	return SnapshotPort{
+		Name:        row.Name,
+		MAC:         row.Mac,
+		Bridge:      row.Bridge,
+		Platform:    row.VMDomain,
+		Alias:       row.Alias,
+		Domain:      row.NuageDomain,
+		Zone:        row.NuageZone,
+		Network:     row.NuageNetwork,
+		NetworkType: row.NuageNetworkType,
+		Metadata:    row.Metadata,
+	}
+}
+
+func (p SnapshotPort) row(capabilities ovsdb.Capabilities) ovsdb.NuagePortTableRow {
+	return ovsdb.NuagePortTableRow{
+		Name:             p.Name,
+		Mac:              p.MAC,
+		Bridge:           p.Bridge,
+		VMDomain:         p.Platform,
+		Alias:            p.Alias,
+		NuageDomain:      p.Domain,
+		NuageZone:        p.Zone,
+		NuageNetwork:     p.Network,
+		NuageNetworkType: p.NetworkType,
+		Metadata:         p.Metadata,
+		Capabilities:     capabilities,
+	}
+}
+
+// getAlubr0Attachments reads the ports of alubr0 along with the entity recorded in their external IDs
+func (vrsConnection *VRSConnection) getAlubr0Attachments() ([]SnapshotAttachment, error) {
+	bridgeOp := libovsdb.Operation{
+		Op:    "select",
+		Table: bridgeTable,
+		Where: []interface{}{libovsdb.NewCondition("name", "==", bridgeName)},
+	}
+	portOp := libovsdb.Operation{
+		Op:    "select",
+		Table: portTable,
+		Where: []interface{}{libovsdb.NewCondition("name", "!=", "")},
+	}
+
+	operations := []libovsdb.Operation{bridgeOp, portOp}
+	reply, err := vrsConnection.transact(context.Background(), "select", operations...)
+	if err != nil || len(reply) != len(operations) {
+		return nil, fmt.Errorf("Problem reading the alubr0 ports %v", err)
+	}
+
+	for _, result := range reply {
+		if result.Error != "" {
+			return nil, fmt.Errorf("Problem reading the alubr0 ports %s %s", result.Error, result.Details)
+		}
+	}
+
+	attachments := []SnapshotAttachment{}
+
+	// alubr0 does not exist on this VRS, it has no ports
+	if len(reply[0].Rows) != 1 {
+		return attachments, nil
+	}
+
+	attached, err := ovsdb.UnMarshallOVSUUIDSet(reply[0].Rows[0]["ports"])
+	if err != nil {
+		return nil, fmt.Errorf("Invalid alubr0 ports %v", err)
+	}
+	isAttached := make(map[string]bool)
+	for _, uuid := range attached {
+		isAttached[uuid] = true
+	}
+
+	for _, row := range reply[1].Rows {
+		uuids, err := ovsdb.UnMarshallOVSUUIDSet(row["_uuid"])
+		if err != nil || len(uuids) != 1 {
+			return nil, fmt.Errorf("Invalid port row %+v", row)
+		}
+		if !isAttached[uuids[0]] {
+			continue
+		}
+
+		attachment := SnapshotAttachment{}
+		attachment.Port, _ = row["name"].(string)
+		if externalIDs, err := ovsdb.UnMarshallOVSStringMap(row["external_ids"]); err == nil {
+			attachment.EntityUUID = externalIDs["vm-uuid"]
+			attachment.EntityName = externalIDs["vm-name"]
+		}
+		attachments = append(attachments, attachment)
+	}
+	sort.Slice(attachments, func(i, j int) bool { return attachments[i].Port < attachments[j].Port })
+
+	return attachments, nil
+}
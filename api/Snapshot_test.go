@@ -0,0 +1,154 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nuagenetworks/libvrsdk/api/entity"
+	"github.com/nuagenetworks/libvrsdk/api/port"
+	"github.com/nuagenetworks/libvrsdk/ovsdb"
+	"github.com/nuagenetworks/libvrsdk/ovsdb/ovsdbtest"
+	"github.com/socketplane/libovsdb"
+)
+
+// exportState exports the state of the VRS, without the export time so that exports can be compared
+func exportState(t *testing.T, vrsConnection VRSConnection) Snapshot {
+	var b bytes.Buffer
+	if err := vrsConnection.ExportState(&b); err != nil {
+		t.Fatalf("Unable to export the state %v", err)
+	}
+
+	var snapshot Snapshot
+	if err := json.Unmarshal(b.Bytes(), &snapshot); err != nil {
+		t.Fatalf("Unable to parse the snapshot %v", err)
+	}
+	if snapshot.Version != SnapshotVersion {
+		t.Fatalf("Unexpected snapshot version %d", snapshot.Version)
+	}
+	snapshot.Exported = time.Time{}
+	return snapshot
+}
+
+func createBridge(t testing.TB, server *ovsdbtest.Server) {
+	bridgeOp := libovsdb.Operation{
+		Op:    "insert",
+		Table: bridgeTable,
+		Row:   map[string]interface{}{"name": bridgeName},
+	}
+	if _, err := server.Transact(bridgeOp); err != nil {
+		t.Fatalf("Unable to create alubr0 %v", err)
+	}
+}
+
+func TestExportImportState(t *testing.T) {
+	source, sourceConnection := testConnection(t)
+	defer source.Close()
+	defer sourceConnection.Disconnect()
+	createBridge(t, source)
+
+	metadata := map[port.MetadataKey]string{port.MetadataKeyDomain: "d", port.MetadataKeyNetwork: "n",
+		port.MetadataKeyZone: "z"}
+	attributes := port.Attributes{MAC: "76:22:F6:70:4E:47", Platform: entity.Docker, Bridge: bridgeName}
+	if err := sourceConnection.CreatePort("p1", attributes, metadata); err != nil {
+		t.Fatalf("Unable to create the port %v", err)
+	}
+	info := EntityInfo{
+		UUID:     "vm",
+		Name:     "vm-name",
+		Type:     entity.Container,
+		Domain:   entity.Docker,
+		Ports:    []string{"p1"},
+		Metadata: map[entity.MetadataKey]string{entity.MetadataKeyUser: "user", "owner": "me"},
+	}
+	if err := sourceConnection.CreateEntity(info); err != nil {
+		t.Fatalf("Unable to create the entity %v", err)
+	}
+	if err := sourceConnection.AddPortToAlubr0("p1", info); err != nil {
+		t.Fatalf("Unable to add the port to alubr0 %v", err)
+	}
+
+	var exported bytes.Buffer
+	if err := sourceConnection.ExportState(&exported); err != nil {
+		t.Fatalf("Unable to export the state %v", err)
+	}
+	expected := exportState(t, sourceConnection)
+	if len(expected.Entities) != 1 || len(expected.Ports) != 1 || len(expected.Attachments) != 1 ||
+		expected.Entities[0].User != "user" || expected.Attachments[0].EntityName != "vm-name" {
+		t.Fatalf("Unexpected snapshot %+v", expected)
+	}
+
+	target, targetConnection := testConnection(t)
+	defer target.Close()
+	defer targetConnection.Disconnect()
+	createBridge(t, target)
+
+	result, err := targetConnection.ImportState(bytes.NewReader(exported.Bytes()), ImportOptions{DryRun: true})
+	if err != nil || len(result.Created) != 3 {
+		t.Fatalf("Unexpected dry run %+v %v", result, err)
+	}
+	if snapshot := exportState(t, targetConnection); len(snapshot.Ports) != 0 || len(snapshot.Entities) != 0 {
+		t.Fatalf("Dry run changed the VRS %+v", snapshot)
+	}
+
+	result, err = targetConnection.ImportState(bytes.NewReader(exported.Bytes()), ImportOptions{})
+	if err != nil || len(result.Created) != 3 {
+		t.Fatalf("Unexpected import %+v %v", result, err)
+	}
+	snapshot := exportState(t, targetConnection)
+	if !reflect.DeepEqual(snapshot, expected) {
+		t.Fatalf("Imported state differs %+v %+v", snapshot, expected)
+	}
+
+	if err := targetConnection.UpdatePortAttributes("p1", port.Attributes{MAC: "other", Bridge: bridgeName}); err != nil {
+		t.Fatalf("Unable to update the port %v", err)
+	}
+
+	result, err = targetConnection.ImportState(bytes.NewReader(exported.Bytes()), ImportOptions{})
+	if err != nil || len(result.Skipped) != 3 || len(result.Created) != 0 {
+		t.Fatalf("Unexpected import %+v %v", result, err)
+	}
+	if snapshot := exportState(t, targetConnection); snapshot.Ports[0].MAC != "other" {
+		t.Fatalf("Existing port overwritten %+v", snapshot.Ports[0])
+	}
+
+	// The columns resolved by the VRS survive the overwrite
+	if _, err := target.Transact(libovsdb.Operation{
+		Op:    "update",
+		Table: ovsdb.NuagePortTable,
+		Row: map[string]interface{}{"ip_addr": "10.0.0.2", "subnet_mask": "255.255.255.0", "gateway": "10.0.0.1",
+			"vrf_id": 7, "evpn_id": 8},
+		Where: []interface{}{libovsdb.NewCondition("name", "==", "p1")},
+	}); err != nil {
+		t.Fatalf("Unable to resolve the port %v", err)
+	}
+	if err := targetConnection.RemovePortFromAlubr0("p1"); err != nil {
+		t.Fatalf("Unable to remove the port from alubr0 %v", err)
+	}
+	if err := targetConnection.AddPortToAlubr0("p1", EntityInfo{UUID: "vm", Name: "renamed"}); err != nil {
+		t.Fatalf("Unable to add the port to alubr0 %v", err)
+	}
+
+	options := ImportOptions{Mode: ImportModeOverwrite}
+	result, err = targetConnection.ImportState(bytes.NewReader(exported.Bytes()), options)
+	if err != nil || len(result.Overwritten) != 2 || len(result.Skipped) != 1 || result.Skipped[0] != "entity vm" {
+		t.Fatalf("Unexpected import %+v %v", result, err)
+	}
+	snapshot = exportState(t, targetConnection)
+	if !reflect.DeepEqual(snapshot, expected) {
+		t.Fatalf("Overwritten state differs %+v %+v", snapshot, expected)
+	}
+	state, err := targetConnection.GetPortState("p1")
+	if err != nil || state[port.StateKeyIPAddress] != "10.0.0.2" || state[port.StateKeyGateway] != "10.0.0.1" ||
+		fmt.Sprint(state[port.StateKeyVrfID]) != "7" || fmt.Sprint(state[port.StateKeyEvpnID]) != "8" {
+		t.Fatalf("Resolution of the port overwritten %v %v", state, err)
+	}
+
+	newer := bytes.NewReader([]byte(`{"version": 2}`))
+	if _, err := targetConnection.ImportState(newer, ImportOptions{}); err == nil {
+		t.Fatalf("Imported a snapshot of a later version")
+	}
+}
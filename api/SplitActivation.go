@@ -0,0 +1,174 @@
+package api
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nuagenetworks/libvrsdk/api/entity"
+	"github.com/nuagenetworks/libvrsdk/api/port"
+)
+
+// EntityPortBinding places a port of an unbound entity in a Nuage domain, zone and network
+type EntityPortBinding struct {
+	Domain      string
+	Zone        string
+	Network     string
+	NetworkType port.NetworkType
+}
+
+// CreateUnboundEntity creates an entity and its ports for split activation. The ports are created without a
+// domain, zone and network and the entity is flagged with nuage-extension so that the VRS waits for the VSD
+// or a later BindEntityPorts to place it. The entity must not carry a user or an enterprise.
+// The ports created are destroyed if the entity cannot be created.
+func (vrsConnection *VRSConnection) CreateUnboundEntity(info EntityInfo, ports []PortSpec) error {
+	if user := info.Metadata[entity.MetadataKeyUser]; len(user) != 0 {
+		return fmt.Errorf("Unbound entity %s has user %s", info.UUID, user)
+	}
+
+	if enterprise := info.Metadata[entity.MetadataKeyEnterprise]; len(enterprise) != 0 {
+		return fmt.Errorf("Unbound entity %s has enterprise %s", info.UUID, enterprise)
+	}
+
+	if extension, ok := info.Metadata[entity.MetadataKeyExtension]; ok && extension != "true" {
+		return fmt.Errorf("Unbound entity %s has %s %s", info.UUID, entity.MetadataKeyExtension, extension)
+	}
+
+	unbound := []port.MetadataKey{port.MetadataKeyDomain, port.MetadataKeyZone, port.MetadataKeyNetwork,
+		port.MetadataKeyNetworkType}
+	for _, spec := range ports {
+		for _, key := range unbound {
+			if value := spec.Metadata[key]; len(value) != 0 {
+				return fmt.Errorf("Port %s of unbound entity %s has %s %s", spec.Name, info.UUID, key, value)
+			}
+		}
+	}
+
+	var created []string
+	for _, spec := range ports {
+		metadata := make(map[port.MetadataKey]string)
+		for k, v := range spec.Metadata {
+			metadata[k] = v
+		}
+		for _, key := range unbound {
+			metadata[key] = ""
+		}
+
+		if err := vrsConnection.CreatePort(spec.Name, spec.Attributes, metadata); err != nil {
+			vrsConnection.destroyPorts(created)
+			return err
+		}
+		created = append(created, spec.Name)
+	}
+
+	metadata := make(map[entity.MetadataKey]string)
+	for k, v := range info.Metadata {
+		metadata[k] = v
+	}
+	metadata[entity.MetadataKeyUser] = ""
+	metadata[entity.MetadataKeyEnterprise] = ""
+	metadata[entity.MetadataKeyExtension] = "true"
+	info.Metadata = metadata
+	info.Ports = append(append([]string{}, info.Ports...), created...)
+
+	if err := vrsConnection.CreateEntity(info); err != nil {
+		vrsConnection.destroyPorts(created)
+		return err
+	}
+
+	return nil
+}
+
+func (vrsConnection *VRSConnection) destroyPorts(names []string) {
+	for _, name := range names {
+		vrsConnection.DestroyPort(name)
+	}
+}
+
+// BindEntityPorts places the ports of an entity created with CreateUnboundEntity in their domain, zone and
+// network. Unless timeout is zero it then waits for the VRS to resolve every port and returns the resolved
+// IPv4 information of each port.
+func (vrsConnection *VRSConnection) BindEntityPorts(uuid string, bindings map[string]EntityPortBinding,
+	timeout time.Duration) (map[string]PortIPv4Info, error) {
+
+	entities, err := vrsConnection.GetAllEntityInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	var info *EntityInfo
+	for i := range entities {
+		if entities[i].UUID == uuid {
+			info = &entities[i]
+			break
+		}
+	}
+
+	if info == nil {
+		return nil, fmt.Errorf("Entity %s not found", uuid)
+	}
+
+	if info.Metadata[entity.MetadataKeyExtension] != "true" {
+		return nil, fmt.Errorf("Entity %s was not created for split activation", uuid)
+	}
+
+	entityPorts := make(map[string]bool)
+	for _, name := range info.Ports {
+		entityPorts[name] = true
+	}
+	for name, binding := range bindings {
+		if !entityPorts[name] {
+			return nil, fmt.Errorf("Port %s does not belong to entity %s", name, uuid)
+		}
+		if len(binding.Domain) == 0 || len(binding.Zone) == 0 || len(binding.Network) == 0 {
+			return nil, fmt.Errorf("Incomplete binding %+v for port %s", binding, name)
+		}
+	}
+
+	// Register before binding so that the resolution cannot be missed
+	channels := make(map[string]chan *PortIPv4Info)
+	if timeout != 0 {
+		for name := range bindings {
+			channels[name] = make(chan *PortIPv4Info, 1)
+			if err = vrsConnection.RegisterForPortUpdates(name, channels[name]); err != nil {
+				return nil, err
+			}
+			defer vrsConnection.DeregisterChannelForPortUpdates(name, channels[name])
+		}
+	}
+
+	for name, binding := range bindings {
+		metadata := map[port.MetadataKey]string{
+			port.MetadataKeyDomain:  binding.Domain,
+			port.MetadataKeyZone:    binding.Zone,
+			port.MetadataKeyNetwork: binding.Network,
+		}
+		if binding.NetworkType != port.NetworkTypeUnspecified {
+			metadata[port.MetadataKeyNetworkType] = string(binding.NetworkType)
+		}
+
+		if err = vrsConnection.PatchPortMetadata(name, metadata, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	if timeout == 0 {
+		return nil, nil
+	}
+
+	resolved := make(map[string]PortIPv4Info)
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for name, channel := range channels {
+		select {
+		case portInfo := <-channel:
+			if !portInfo.Registered {
+				return resolved, fmt.Errorf("Port %s was deleted before being resolved", name)
+			}
+			resolved[name] = *portInfo
+		case <-deadline.C:
+			return resolved, fmt.Errorf("Port %s of entity %s not resolved after %v", name, uuid, timeout)
+		}
+	}
+
+	return resolved, nil
+}
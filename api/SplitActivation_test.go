@@ -0,0 +1,83 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nuagenetworks/libvrsdk/api/entity"
+	"github.com/nuagenetworks/libvrsdk/api/port"
+	"github.com/nuagenetworks/libvrsdk/ovsdb"
+	"github.com/socketplane/libovsdb"
+)
+
+func TestSplitActivationWorkflow(t *testing.T) {
+	server, vrsConnection := testConnection(t)
+	defer server.Close()
+	defer vrsConnection.Disconnect()
+
+	info := EntityInfo{UUID: "vm", Name: "vm", Type: entity.Container, Domain: entity.Docker}
+	ports := []PortSpec{{
+		Name:       "p1",
+		Attributes: port.Attributes{MAC: "76:22:F6:70:4E:47", Platform: entity.Docker, Bridge: bridgeName},
+	}}
+
+	bound := info
+	bound.Metadata = map[entity.MetadataKey]string{entity.MetadataKeyEnterprise: "enterprise"}
+	if err := vrsConnection.CreateUnboundEntity(bound, ports); err == nil {
+		t.Fatalf("Created an unbound entity with an enterprise")
+	}
+
+	if err := vrsConnection.CreateUnboundEntity(info, ports); err != nil {
+		t.Fatalf("Unable to create the unbound entity %v", err)
+	}
+
+	entities, err := vrsConnection.GetAllEntityInfo()
+	if err != nil || len(entities) != 1 || entities[0].Metadata[entity.MetadataKeyExtension] != "true" ||
+		len(entities[0].Ports) != 1 {
+		t.Fatalf("Unexpected entities %+v %v", entities, err)
+	}
+
+	bindings := map[string]EntityPortBinding{"p1": {Domain: "domain", Zone: "zone", Network: "network"}}
+	if _, err = vrsConnection.BindEntityPorts("vm", map[string]EntityPortBinding{"p2": bindings["p1"]}, 0); err == nil {
+		t.Fatalf("Bound a port that does not belong to the entity")
+	}
+
+	// Another subscriber of the port keeps its registration
+	watcher := make(chan *PortIPv4Info, 2)
+	vrsConnection.RegisterForPortUpdates("p1", watcher)
+
+	// Resolve the port the way the VRS does once the VSD placed it
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		server.Transact(libovsdb.Operation{
+			Op:    "update",
+			Table: ovsdb.NuagePortTable,
+			Row:   map[string]interface{}{"ip_addr": "10.0.0.2", "subnet_mask": "255.255.255.0", "gateway": "10.0.0.1"},
+			Where: []interface{}{libovsdb.NewCondition("name", "==", "p1")},
+		})
+	}()
+
+	resolved, err := vrsConnection.BindEntityPorts("vm", bindings, 5*time.Second)
+	if err != nil || resolved["p1"].IPAddr != "10.0.0.2" {
+		t.Fatalf("Port not resolved %v %v", resolved, err)
+	}
+
+	state, err := vrsConnection.GetPortState("p1")
+	if err != nil || state[port.StateKeyNuageDomain] != "domain" || state[port.StateKeyNuageNetwork] != "network" {
+		t.Fatalf("Port not bound %v %v", state, err)
+	}
+
+	if err = vrsConnection.DestroyPort("p1"); err != nil {
+		t.Fatalf("Unable to destroy the port %v", err)
+	}
+	for _, registered := range []bool{true, false} {
+		select {
+		case update := <-watcher:
+			if update.Registered != registered {
+				t.Fatalf("Unexpected update %+v", update)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Subscriber deregistered by the binding")
+		}
+	}
+}
@@ -1,17 +1,78 @@
 package api
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
 
-	"github.com/golang/glog"
+	"github.com/nuagenetworks/libvrsdk/logging"
+	"github.com/nuagenetworks/libvrsdk/metrics"
 	"github.com/nuagenetworks/libvrsdk/ovsdb"
+	"github.com/nuagenetworks/libvrsdk/tracing"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/socketplane/libovsdb"
 )
 
-type portNameChannelMap map[string]chan *PortIPv4Info
+type portNameChannelMap map[string][]chan *PortIPv4Info
 
 type portNamePortInfoMap map[string]PortIPv4Info
 
+// portResolutions holds the spans and the creation times of the ports waiting for their resolution. Unlike the
+// other tables it is shared between the callers creating the ports and the monitor, hence the mutex.
+type portResolutions struct {
+	mutex   sync.Mutex
+	spans   map[string]tracing.Span
+	started map[string]time.Time
+}
+
+// start records the span and the creation time of the port, ending the span of a previous creation of the
+// same port
+func (resolutions *portResolutions) start(name string, span tracing.Span) {
+	resolutions.mutex.Lock()
+	defer resolutions.mutex.Unlock()
+
+	if previous, exists := resolutions.spans[name]; exists {
+		tracing.End(previous, fmt.Errorf("Port %s created again", name))
+	}
+	resolutions.spans[name] = span
+	resolutions.started[name] = time.Now()
+}
+
+// end ends the span of the port, if any, recording err. It returns the creation time of the port and true if
+// the port was waiting for its resolution.
+func (resolutions *portResolutions) end(name string, err error, attributes ...tracing.Attribute) (time.Time,
+	bool) {
+
+	resolutions.mutex.Lock()
+	defer resolutions.mutex.Unlock()
+
+	started, exists := resolutions.started[name]
+	if span, exists := resolutions.spans[name]; exists {
+		span.SetAttributes(attributes...)
+		tracing.End(span, err)
+		delete(resolutions.spans, name)
+	}
+	delete(resolutions.started, name)
+
+	return started, exists
+}
+
+// endAll ends the spans of all the ports, recording err
+func (resolutions *portResolutions) endAll(err error) {
+	resolutions.mutex.Lock()
+	defer resolutions.mutex.Unlock()
+
+	for name, span := range resolutions.spans {
+		tracing.End(span, err)
+		delete(resolutions.spans, name)
+	}
+	resolutions.started = make(map[string]time.Time)
+}
+
 // Registration will help to register for VRS
 // port table updates
 type Registration struct {
@@ -23,60 +84,205 @@ type Registration struct {
 // VRSConnection represent the OVSDB connection to the VRS
 type VRSConnection struct {
 	ovsdbClient         *libovsdb.OvsdbClient
-	vmTable             ovsdb.NuageTableOps
-	portTable           ovsdb.NuageTableOps
-	controllerTable     ovsdb.NuageTableOps
+	vmTable             ovsdb.ExtendedNuageTableOps
+	portTable           ovsdb.ExtendedNuageTableOps
+	controllerTable     ovsdb.ExtendedNuageTableOps
 	updatesChan         chan *libovsdb.TableUpdates
 	pncTable            portNameChannelMap
 	pnpTable            portNamePortInfoMap
-	stopChannel         chan bool
 	registrationChannel chan *Registration
+	metrics             *metrics.Metrics
+	logger              *logging.Switchable
+	tracer              *tracing.Switchable
+	resolutions         *portResolutions
+	capabilities        ovsdb.Capabilities
+	disconnected        chan struct{}
+	disconnectedOnce    *sync.Once
+	network             string
+	address             string
+	keepalive           *keepalive
+	orphans             *orphanAges
 }
 
-// Disconnected will retry connecting to OVSDB
-// and continue to register for OVSDB updates
+// Disconnected records the loss of the connection to OVSDB, reported by Connected
 func (vrsConnection VRSConnection) Disconnected(ovsClient *libovsdb.OvsdbClient) {
+	if vrsConnection.disconnectedOnce != nil {
+		vrsConnection.disconnectedOnce.Do(func() { close(vrsConnection.disconnected) })
+	}
 }
 
-// Locked is a placeholder function for table updates
-func (vrsConnection VRSConnection) Locked([]interface{}) {
+// Connected returns false once the connection to OVSDB is lost or closed, the connection must then be recreated
+func (vrsConnection VRSConnection) Connected() bool {
+	if vrsConnection.disconnected == nil {
+		return false
+	}
+
+	select {
+	case <-vrsConnection.disconnected:
+		return false
+	default:
+		return true
+	}
 }
 
-// Stolen is a placeholder function for table updates
-func (vrsConnection VRSConnection) Stolen([]interface{}) {
+// Locked is never called, the locks are held on OVSDB sessions of their own, see AcquireLock
+func (vrsConnection VRSConnection) Locked([]interface{}) {
 }
 
-// Echo is a placeholder function for table updates
-func (vrsConnection VRSConnection) Echo([]interface{}) {
+// Stolen is never called, the locks are held on OVSDB sessions of their own, see AcquireLock
+func (vrsConnection VRSConnection) Stolen([]interface{}) {
 }
 
 // Update will provide updates on OVSDB table updates
 func (vrsConnection VRSConnection) Update(context interface{}, tableUpdates libovsdb.TableUpdates) {
-	vrsConnection.updatesChan <- &tableUpdates
+	select {
+	case vrsConnection.updatesChan <- &tableUpdates:
+	case <-vrsConnection.disconnected:
+	}
 }
 
 // NewUnixSocketConnection creates a connection to the VRS Server using Unix sockets
 func NewUnixSocketConnection(socketfile string) (VRSConnection, error) {
+	ovsdbClient, err := libovsdb.ConnectWithUnixSocket(socketfile)
+	if err != nil {
+		return VRSConnection{}, err
+	}
+
+	return newConnection(ovsdbClient, "unix", socketfile)
+}
+
+// NewTCPConnection creates a connection to the VRS Server listening on the TCP port of host
+func NewTCPConnection(host string, port int) (VRSConnection, error) {
+	if host == "" {
+		host = libovsdb.DefaultAddress
+	}
+	if port <= 0 {
+		port = libovsdb.DefaultPort
+	}
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+
+	ovsdbClient, err := libovsdb.ConnectUsingProtocol("tcp", address)
+	if err != nil {
+		return VRSConnection{}, err
+	}
+
+	return newConnection(ovsdbClient, "tcp", address)
+}
+
+func newConnection(ovsdbClient *libovsdb.OvsdbClient, network string, address string) (VRSConnection, error) {
 	var vrsConnection VRSConnection
 	var err error
 
-	if vrsConnection.ovsdbClient, err = libovsdb.ConnectWithUnixSocket(socketfile); err != nil {
-		return vrsConnection, err
+	vrsConnection.ovsdbClient = ovsdbClient
+	vrsConnection.network = network
+	vrsConnection.address = address
+
+	schema, ok := vrsConnection.ovsdbClient.Schema[OvsDBName]
+	if !ok {
+		vrsConnection.ovsdbClient.Disconnect()
+		return vrsConnection, errors.New("Cannot read database schema")
 	}
 
-	vrsConnection.vmTable = &ovsdb.NuageTable{TableName: ovsdb.NuageVMTable}
-	vrsConnection.portTable = &ovsdb.NuageTable{TableName: ovsdb.NuagePortTable}
-	vrsConnection.controllerTable = &ovsdb.NuageTable{TableName: ovsdb.ControllerTable}
+	if vrsConnection.capabilities, err = ovsdb.CheckSchema(schema); err != nil {
+		vrsConnection.ovsdbClient.Disconnect()
+		return vrsConnection, fmt.Errorf("Incompatible VRS %v", err)
+	}
+
+	vrsConnection.metrics = metrics.New(nil)
+	vrsConnection.logger = &logging.Switchable{}
+	vrsConnection.tracer = &tracing.Switchable{}
+	vrsConnection.resolutions = &portResolutions{spans: make(map[string]tracing.Span),
+		started: make(map[string]time.Time)}
+	vrsConnection.vmTable = vrsConnection.newTable(ovsdb.NuageVMTable)
+	vrsConnection.portTable = vrsConnection.newTable(ovsdb.NuagePortTable)
+	vrsConnection.controllerTable = vrsConnection.newTable(ovsdb.ControllerTable)
 	vrsConnection.pncTable = make(portNameChannelMap)
 	vrsConnection.pnpTable = make(portNamePortInfoMap)
+	vrsConnection.metrics.SetControllerStateFunc(func() string {
+		state, _ := vrsConnection.GetControllerState()
+		return string(state)
+	})
 	vrsConnection.registrationChannel = make(chan *Registration)
 	vrsConnection.updatesChan = make(chan *libovsdb.TableUpdates)
-	vrsConnection.stopChannel = make(chan bool)
+	vrsConnection.disconnected = make(chan struct{})
+	vrsConnection.disconnectedOnce = &sync.Once{}
+	vrsConnection.keepalive = &keepalive{}
+	vrsConnection.orphans = &orphanAges{}
 	err = vrsConnection.monitorTable()
 
 	return vrsConnection, err
 }
 
+func (vrsConnection *VRSConnection) newTable(name string) *ovsdb.NuageTable {
+	return &ovsdb.NuageTable{TableName: name, Metrics: vrsConnection.metrics, Logger: vrsConnection.logger,
+		Tracer: vrsConnection.tracer}
+}
+
+// SetLogger sets the Logger of the connection, nil restores the package-level logging.Default. Wrap it with
+// logging.Redact to hide the values of the metadata.
+func (vrsConnection *VRSConnection) SetLogger(logger logging.Logger) {
+	vrsConnection.logger.Set(logger)
+}
+
+// Capabilities returns the columns of the Nuage tables supported by the VRS, checked when connecting. The
+// optional columns it does not support are not written.
+func (vrsConnection *VRSConnection) Capabilities() ovsdb.Capabilities {
+	return vrsConnection.capabilities
+}
+
+// SetTracer sets the Tracer of the connection, nil restores the package-level tracing.Default
+func (vrsConnection *VRSConnection) SetTracer(tracer tracing.Tracer) {
+	vrsConnection.tracer.Set(tracer)
+}
+
+// transact executes operations spanning several OVSDB tables in a span child of ctx. The transaction is logged
+// and recorded in the metrics under the table of its first operation. A failed wait is a guard whose failure
+// is interpreted by the caller, it is left out of the error metrics.
+func (vrsConnection *VRSConnection) transact(ctx context.Context, op string,
+	operations ...libovsdb.Operation) ([]libovsdb.OperationResult, error) {
+
+	_, span := vrsConnection.tracer.Start(ctx, "ovsdb "+op)
+	span.SetAttributes(tracing.A("db.system", "ovsdb"), tracing.A("db.name", OvsDBName),
+		tracing.A("db.operation", op))
+
+	start := time.Now()
+	reply, err := vrsConnection.ovsdbClient.Transact(OvsDBName, operations...)
+	duration := time.Since(start)
+	if err == nil && len(reply) < len(operations) {
+		span.RecordError(fmt.Errorf("Incomplete reply"))
+	}
+	tracing.End(span, err)
+
+	table := ""
+	if len(operations) != 0 {
+		table = operations[0].Table
+	}
+	vrsConnection.logger.Debug("OVSDB transaction", logging.F("table", table), logging.F("op", op),
+		logging.F("duration", duration), logging.Err(err))
+
+	// The transactions without operations, the pings, are not recorded
+	if len(operations) == 0 {
+		return reply, err
+	}
+
+	vrsConnection.metrics.ObserveTransaction(table, op, duration)
+	if err != nil || len(reply) < len(operations) {
+		vrsConnection.metrics.ObserveError(table, op, "transport")
+		return reply, err
+	}
+
+	for i, result := range reply {
+		if result.Error != "" {
+			if i >= len(operations) || operations[i].Op != "wait" {
+				vrsConnection.metrics.ObserveError(table, op, result.Error)
+			}
+			break
+		}
+	}
+
+	return reply, err
+}
+
 func (vrsConnection *VRSConnection) monitorTable() error {
 	// Setting a monitor on Nuage_Port_Table in VRS connection
 	vrsConnection.ovsdbClient.Register(vrsConnection)
@@ -117,14 +323,15 @@ func (vrsConnection *VRSConnection) monitorTable() error {
 			case registration := <-vrsConnection.registrationChannel:
 				err := vrsConnection.handlePortRegistration(registration)
 				if err != nil {
-					glog.Errorf("Error handling port registration from VRS: %s", err)
+					vrsConnection.logger.Error("Error handling port registration from VRS", logging.Err(err))
 				}
 			case currentUpdate := <-vrsConnection.updatesChan:
 				err := vrsConnection.processUpdates(currentUpdate)
 				if err != nil {
-					glog.Errorf("Error processing updates from VRS: %s", err)
+					vrsConnection.logger.Error("Error processing updates from VRS", logging.Err(err))
 				}
-			case <-vrsConnection.stopChannel:
+			case <-vrsConnection.disconnected:
+				vrsConnection.releaseRegistrations()
 				return
 			}
 		}
@@ -132,8 +339,21 @@ func (vrsConnection *VRSConnection) monitorTable() error {
 	return nil
 }
 
-// Disconnect closes the connection to the VRS server
+// Collector returns the metrics of the connection for the agent to register with Prometheus
+func (vrsConnection *VRSConnection) Collector() prometheus.Collector {
+	return vrsConnection.metrics
+}
+
+// SetMetricsLabels sets constant labels on the metrics of the connection, such as the host it is connected to,
+// so that the Collectors of several connections can be registered together. It is called before registering
+// the Collector, the values recorded so far are dropped.
+func (vrsConnection *VRSConnection) SetMetricsLabels(labels prometheus.Labels) {
+	vrsConnection.metrics.SetLabels(labels)
+}
+
+// Disconnect closes the connection to the VRS server, the monitoring of the port updates stops with it
 func (vrsConnection VRSConnection) Disconnect() {
 	vrsConnection.ovsdbClient.Disconnect()
-	vrsConnection.stopChannel <- true
+	vrsConnection.Disconnected(vrsConnection.ovsdbClient)
+	vrsConnection.resolutions.endAll(fmt.Errorf("Connection closed"))
 }
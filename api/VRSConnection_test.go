@@ -0,0 +1,379 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nuagenetworks/libvrsdk/api/port"
+	"github.com/nuagenetworks/libvrsdk/logging"
+	"github.com/nuagenetworks/libvrsdk/ovsdb"
+	"github.com/nuagenetworks/libvrsdk/ovsdb/ovsdbtest"
+	"github.com/nuagenetworks/libvrsdk/tracing"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/socketplane/libovsdb"
+)
+
+// gather returns the metrics of the collector indexed by name and label values, sorted by label name
+func gather(t *testing.T, collector prometheus.Collector) map[string]*dto.Metric {
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(collector)
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Unable to gather the metrics %v", err)
+	}
+
+	metrics := make(map[string]*dto.Metric)
+	for _, family := range families {
+		for _, metric := range family.Metric {
+			key := family.GetName()
+			for _, label := range metric.Label {
+				key += " " + label.GetValue()
+			}
+			metrics[key] = metric
+		}
+	}
+
+	return metrics
+}
+
+func TestMetrics(t *testing.T) {
+	server, vrsConnection := testConnection(t)
+	defer server.Close()
+	defer vrsConnection.Disconnect()
+
+	attributes := port.Attributes{MAC: "76:22:F6:70:4E:47", Bridge: bridgeName}
+	if err := vrsConnection.CreatePort("p1", attributes, nil); err != nil {
+		t.Fatalf("Unable to create the port %v", err)
+	}
+
+	updates := make(chan *PortIPv4Info, 1)
+	vrsConnection.RegisterForPortUpdates("p1", updates)
+
+	server.Transact(libovsdb.Operation{
+		Op:    "update",
+		Table: ovsdb.NuagePortTable,
+		Row:   map[string]interface{}{"ip_addr": "10.0.0.2", "subnet_mask": "255.255.255.0", "gateway": "10.0.0.1"},
+		Where: []interface{}{libovsdb.NewCondition("name", "==", "p1")},
+	})
+
+	select {
+	case <-updates:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Port not resolved")
+	}
+
+	if err := vrsConnection.DestroyPort("p2"); err == nil {
+		t.Fatalf("Destroyed a missing port")
+	}
+	if err := vrsConnection.AddEntityPort("missing", "p1"); err == nil {
+		t.Fatalf("Added a port to a missing entity")
+	}
+
+	gather(t, vrsConnection.Collector())
+	metrics := gather(t, vrsConnection.Collector())
+
+	if metric := metrics["libvrsdk_ovsdb_transactions_total insert Nuage_Port_Table"]; metric.GetCounter().GetValue() != 1 {
+		t.Fatalf("Unexpected port insertions %v", metric)
+	}
+	if metric := metrics["libvrsdk_ovsdb_errors_total delete Nuage_Port_Table not-found"]; metric.GetCounter().GetValue() != 1 {
+		t.Fatalf("Unexpected port deletion errors %v", metric)
+	}
+	if metric := metrics["libvrsdk_ovsdb_errors_total mutate Nuage_VM_Table not-found"]; metric.GetCounter().GetValue() != 1 {
+		t.Fatalf("Unexpected entity mutation errors %v", metric)
+	}
+	if metric, ok := metrics["libvrsdk_ovsdb_errors_total mutate Nuage_VM_Table timed out"]; ok {
+		t.Fatalf("Failed guard recorded as an error %v", metric)
+	}
+	if metric := metrics["libvrsdk_port_resolution_seconds"]; metric.GetHistogram().GetSampleCount() != 1 {
+		t.Fatalf("Unexpected port resolutions %v", metric)
+	}
+	if metric := metrics["libvrsdk_port_update_subscribers"]; metric.GetGauge().GetValue() != 1 {
+		t.Fatalf("Unexpected subscribers %v", metric)
+	}
+	if metric := metrics["libvrsdk_controller_connection_state disconnected"]; metric.GetGauge().GetValue() != 1 {
+		t.Fatalf("Unexpected controller state %v", metric)
+	}
+	if metric := metrics["libvrsdk_ovsdb_transactions_total select Controller"]; metric.GetCounter().GetValue() != 1 {
+		t.Fatalf("Controller state queried on each collection %v", metric)
+	}
+}
+
+// recordingLogger keeps the debug messages logged through it
+type recordingLogger struct {
+	logging.Logger
+	mutex    sync.Mutex
+	messages []map[string]interface{}
+}
+
+func (recorder *recordingLogger) Debug(msg string, fields ...logging.Field) {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	message := map[string]interface{}{"msg": msg}
+	for _, field := range fields {
+		message[field.Key] = field.Value
+	}
+	recorder.messages = append(recorder.messages, message)
+}
+
+func TestSetLogger(t *testing.T) {
+	server, vrsConnection := testConnection(t)
+	defer server.Close()
+	defer vrsConnection.Disconnect()
+
+	recorder := &recordingLogger{Logger: logging.Discard}
+	vrsConnection.SetLogger(logging.Redact(recorder))
+
+	metadata := map[port.MetadataKey]string{port.MetadataKeyDomain: "domain"}
+	attributes := port.Attributes{MAC: "76:22:F6:70:4E:47", Bridge: bridgeName}
+	if err := vrsConnection.CreatePort("p1", attributes, metadata); err != nil {
+		t.Fatalf("Unable to create the port %v", err)
+	}
+
+	var inserting, transaction map[string]interface{}
+	for _, message := range recorder.messages {
+		switch message["msg"] {
+		case "Inserting row":
+			inserting = message
+		case "OVSDB transaction":
+			transaction = message
+		}
+	}
+
+	if transaction == nil || transaction["table"] != ovsdb.NuagePortTable || transaction["op"] != "insert" {
+		t.Fatalf("Transaction not logged %v", recorder.messages)
+	}
+	if _, ok := transaction["duration"].(time.Duration); !ok {
+		t.Fatalf("Transaction duration not logged %v", transaction)
+	}
+
+	row, _ := inserting["row"].(map[string]interface{})
+	redacted, _ := row[ovsdb.NuagePortTableColumnMetadata].(logging.Metadata)
+	if redacted[string(port.MetadataKeyDomain)] != logging.Redacted {
+		t.Fatalf("Metadata not redacted %v", inserting)
+	}
+}
+
+// recordedSpan is a span started by the recordingTracer
+type recordedSpan struct {
+	name       string
+	parent     *recordedSpan
+	links      []*recordedSpan
+	attributes map[string]interface{}
+	err        error
+	ended      chan struct{}
+}
+
+func (span *recordedSpan) SetAttributes(attributes ...tracing.Attribute) {
+	for _, attribute := range attributes {
+		span.attributes[attribute.Key] = attribute.Value
+	}
+}
+
+func (span *recordedSpan) RecordError(err error) { span.err = err }
+func (span *recordedSpan) End()                  { close(span.ended) }
+
+type spanKey struct{}
+
+// recordingTracer keeps the spans started through it
+type recordingTracer struct {
+	mutex sync.Mutex
+	spans []*recordedSpan
+}
+
+func (recorder *recordingTracer) Start(ctx context.Context, name string,
+	links ...context.Context) (context.Context, tracing.Span) {
+
+	span := &recordedSpan{name: name, attributes: make(map[string]interface{}), ended: make(chan struct{})}
+	span.parent, _ = ctx.Value(spanKey{}).(*recordedSpan)
+	for _, link := range links {
+		if linked, ok := link.Value(spanKey{}).(*recordedSpan); ok {
+			span.links = append(span.links, linked)
+		}
+	}
+
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	recorder.spans = append(recorder.spans, span)
+
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func (recorder *recordingTracer) find(name string) *recordedSpan {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	for _, span := range recorder.spans {
+		if span.name == name {
+			return span
+		}
+	}
+	return nil
+}
+
+func TestSetTracer(t *testing.T) {
+	server, vrsConnection := testConnection(t)
+	defer server.Close()
+	defer vrsConnection.Disconnect()
+
+	recorder := &recordingTracer{}
+	vrsConnection.SetTracer(recorder)
+
+	ctx, request := recorder.Start(context.Background(), "request")
+	attributes := port.Attributes{MAC: "76:22:F6:70:4E:47", Bridge: bridgeName}
+	if err := vrsConnection.CreatePortContext(ctx, "p1", attributes, nil); err != nil {
+		t.Fatalf("Unable to create the port %v", err)
+	}
+
+	create := recorder.find("vrs.CreatePort")
+	if create == nil || create.parent != request || create.attributes["vrs.port"] != "p1" {
+		t.Fatalf("CreatePort not traced %v", create)
+	}
+	insert := recorder.find("ovsdb insert " + ovsdb.NuagePortTable)
+	if insert == nil || insert.parent != create || insert.attributes["db.operation"] != "insert" {
+		t.Fatalf("Insertion not traced %v", insert)
+	}
+
+	resolution := recorder.find("vrs.PortResolution")
+	if resolution == nil || resolution.parent != nil || len(resolution.links) != 1 || resolution.links[0] != create {
+		t.Fatalf("Resolution not linked to CreatePort %v", resolution)
+	}
+
+	server.Transact(libovsdb.Operation{
+		Op:    "update",
+		Table: ovsdb.NuagePortTable,
+		Row:   map[string]interface{}{"ip_addr": "10.0.0.2", "subnet_mask": "255.255.255.0", "gateway": "10.0.0.1"},
+		Where: []interface{}{libovsdb.NewCondition("name", "==", "p1")},
+	})
+
+	select {
+	case <-resolution.ended:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Resolution span not ended")
+	}
+	if resolution.err != nil || resolution.attributes["vrs.ip"] != "10.0.0.2" {
+		t.Fatalf("Unexpected resolution span %v", resolution)
+	}
+
+	if err := vrsConnection.RemovePortFromAlubr0Context(ctx, "p2"); err == nil {
+		t.Fatalf("Removed a missing port")
+	}
+	remove := recorder.find("vrs.RemovePortFromAlubr0")
+	if remove == nil || remove.parent != request || remove.err == nil {
+		t.Fatalf("RemovePortFromAlubr0 not traced %v", remove)
+	}
+	if selection := recorder.find("ovsdb select"); selection == nil || selection.parent != remove {
+		t.Fatalf("Bridge transaction not traced %v", selection)
+	}
+}
+
+// schemaWithout returns the VRS schema lacking the given columns of the table
+func schemaWithout(t *testing.T, table string, columns ...string) string {
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(ovsdbtest.VRSSchema), &schema); err != nil {
+		t.Fatalf("Unable to parse the schema %v", err)
+	}
+
+	tableColumns := schema["tables"].(map[string]interface{})[table].(map[string]interface{})["columns"]
+	for _, column := range columns {
+		delete(tableColumns.(map[string]interface{}), column)
+	}
+
+	text, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatalf("Unable to write the schema %v", err)
+	}
+	return string(text)
+}
+
+func TestCapabilities(t *testing.T) {
+	dir, err := ioutil.TempDir("", "capabilities")
+	if err != nil {
+		t.Fatalf("Unable to create the socket directory %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "old.sock")
+	schema := schemaWithout(t, ovsdb.NuagePortTable, ovsdb.NuagePortTableColumnNuageNetworkType,
+		ovsdb.NuagePortTableColumnVMDomain, ovsdb.NuagePortTableColumnDirty)
+	server, err := ovsdbtest.NewServerWithSchema("unix", socket, schema)
+	if err != nil {
+		t.Fatalf("Unable to start the OVSDB server %v", err)
+	}
+	defer server.Close()
+
+	vrsConnection, err := NewUnixSocketConnection(socket)
+	if err != nil {
+		t.Fatalf("Unable to connect to the VRS %v", err)
+	}
+	defer vrsConnection.Disconnect()
+
+	capabilities := vrsConnection.Capabilities()
+	if capabilities.Supports(ovsdb.NuagePortTable, ovsdb.NuagePortTableColumnVMDomain) ||
+		!capabilities.Supports(ovsdb.NuageVMTable, ovsdb.NuageVMTableColumnDirty) {
+		t.Fatalf("Unexpected capabilities %v", capabilities)
+	}
+
+	metadata := map[port.MetadataKey]string{port.MetadataKeyNetworkType: "ipv4"}
+	attributes := port.Attributes{MAC: "76:22:F6:70:4E:47", Bridge: bridgeName}
+	if err := vrsConnection.CreatePort("p1", attributes, metadata); err != nil {
+		t.Fatalf("Unable to create the port on an older VRS %v", err)
+	}
+	if err := vrsConnection.UpdatePortAttributes("p1", attributes); err != nil {
+		t.Fatalf("Unable to update the port on an older VRS %v", err)
+	}
+	if specs, err := vrsConnection.GetAllPortSpecs(); err != nil || len(specs) != 1 {
+		t.Fatalf("Unable to read the ports of an older VRS %v %v", specs, err)
+	}
+
+	socket = filepath.Join(dir, "broken.sock")
+	schema = schemaWithout(t, ovsdb.NuageVMTable, ovsdb.NuageVMTableColumnPorts)
+	broken, err := ovsdbtest.NewServerWithSchema("unix", socket, schema)
+	if err != nil {
+		t.Fatalf("Unable to start the OVSDB server %v", err)
+	}
+	defer broken.Close()
+
+	_, err = NewUnixSocketConnection(socket)
+	if err == nil || !strings.Contains(err.Error(), ovsdb.NuageVMTableColumnPorts) {
+		t.Fatalf("Connected to a VRS lacking a required column %v", err)
+	}
+}
+
+func TestConnected(t *testing.T) {
+	server, err := ovsdbtest.NewTCPServer()
+	if err != nil {
+		t.Fatalf("Unable to start the OVSDB server %v", err)
+	}
+
+	vrsConnection, err := NewTCPConnection(server.Host(), server.Port())
+	if err != nil {
+		server.Close()
+		t.Fatalf("Unable to connect to the VRS %v", err)
+	}
+	defer vrsConnection.Disconnect()
+
+	if !vrsConnection.Connected() {
+		t.Fatalf("Connection reported lost")
+	}
+	if _, err = vrsConnection.GetControllerState(); err != nil {
+		t.Fatalf("Unable to read the controller state %v", err)
+	}
+
+	server.Close()
+	for i := 0; vrsConnection.Connected(); i++ {
+		if i == 100 {
+			t.Fatalf("Loss of the connection not reported")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
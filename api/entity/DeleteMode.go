@@ -0,0 +1,45 @@
+package entity
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// DeleteMode defines how the VRS handles the ports of an entity that disappears from the hypervisor.
+// It is passed to the VRS in the nuage-delete-mode metadata.
+type DeleteMode string
+
+// Delete modes supported by the VRS
+const (
+	// DeleteModeImmediate deletes the ports of the entity as soon as it goes away, it is the default behaviour
+	DeleteModeImmediate DeleteMode = ""
+	// DeleteModeTimer keeps the ports of the entity for nuage-delete-expiry seconds after it goes away
+	DeleteModeTimer DeleteMode = "TIMER"
+)
+
+// ValidateDeleteMode validates the delete mode
+func ValidateDeleteMode(mode DeleteMode) bool {
+	return mode == DeleteModeImmediate || mode == DeleteModeTimer
+}
+
+// ValidateDeleteExpiry validates the delete expiry, which the VRS only takes in whole seconds
+func ValidateDeleteExpiry(expiry time.Duration) bool {
+	return expiry > 0 && expiry%time.Second == 0
+}
+
+// FormatDeleteExpiry converts an expiry to the value of the nuage-delete-expiry metadata, in seconds. The expiry
+// must be valid, see ValidateDeleteExpiry.
+func FormatDeleteExpiry(expiry time.Duration) string {
+	return strconv.FormatInt(int64(expiry/time.Second), 10)
+}
+
+// ParseDeleteExpiry converts the value of the nuage-delete-expiry metadata to a duration
+func ParseDeleteExpiry(value string) (time.Duration, error) {
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds <= 0 {
+		return 0, fmt.Errorf("Invalid delete expiry %q", value)
+	}
+
+	return time.Duration(seconds) * time.Second, nil
+}
@@ -9,7 +9,7 @@ const (
 	MetadataKeyUser            MetadataKey = "user"
 	MetadataKeyOrchestrationID MetadataKey = "nuage-orchestrationID"
 	MetadataKeySiteID          MetadataKey = "nuage-siteID"
-	MetadataKeyDeleteMode      MetadataKey = "nuage-delete-mode"
-	MetadataKeyDeleteExpiry    MetadataKey = "nuage-delete-expiry"
+	MetadataKeyDeleteMode      MetadataKey = "nuage-delete-mode"   // one of the DeleteMode constants
+	MetadataKeyDeleteExpiry    MetadataKey = "nuage-delete-expiry" // in seconds, see FormatDeleteExpiry
 	MetadataKeyExtension       MetadataKey = "nuage-extension"
 )
@@ -0,0 +1,16 @@
+package entity
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+)
+
+// NameUUID derives a name based (version 5) UUID from name, so that the entity of a container can be found again
+// from its name without keeping its UUID
+func NameUUID(name string) string {
+	sum := sha1.Sum([]byte(name))
+	sum[6] = sum[6]&0x0f | 0x50
+	sum[8] = sum[8]&0x3f | 0x80
+	s := hex.EncodeToString(sum[:16])
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:32]
+}
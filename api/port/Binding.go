@@ -0,0 +1,125 @@
+package port
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Protocol is the transport protocol of a port binding
+type Protocol string
+
+// Protocols supported in port bindings
+const (
+	ProtocolTCP  Protocol = "tcp"
+	ProtocolUDP  Protocol = "udp"
+	ProtocolSCTP Protocol = "sctp"
+)
+
+// Binding maps a port of the host to a port of the entity, it is stored in the nuage-port-mapping metadata
+type Binding struct {
+	HostPort      uint16
+	ContainerPort uint16
+	Protocol      Protocol
+}
+
+// String returns the canonical serialization of the binding, hostPort:containerPort/protocol
+func (binding Binding) String() string {
+	return fmt.Sprintf("%d:%d/%s", binding.HostPort, binding.ContainerPort, binding.Protocol)
+}
+
+// Validate checks that both ports are set and the protocol is supported
+func (binding Binding) Validate() error {
+	if binding.HostPort == 0 || binding.ContainerPort == 0 {
+		return fmt.Errorf("Invalid port in binding %s", binding)
+	}
+
+	switch binding.Protocol {
+	case ProtocolTCP, ProtocolUDP, ProtocolSCTP:
+	default:
+		return fmt.Errorf("Invalid protocol in binding %s", binding)
+	}
+
+	return nil
+}
+
+// Conflicts returns true if both bindings use the same port of the host
+func (binding Binding) Conflicts(other Binding) bool {
+	return binding.HostPort == other.HostPort && binding.Protocol == other.Protocol
+}
+
+// ParseBinding parses a single binding. Besides the canonical form it accepts a missing protocol, which
+// defaults to tcp, and protocols in upper case.
+func ParseBinding(value string) (Binding, error) {
+	var binding Binding
+
+	value = strings.TrimSpace(value)
+	ports := value
+	binding.Protocol = ProtocolTCP
+	if i := strings.LastIndex(value, "/"); i != -1 {
+		ports = value[:i]
+		binding.Protocol = Protocol(strings.ToLower(strings.TrimSpace(value[i+1:])))
+	}
+
+	fields := strings.Split(ports, ":")
+	if len(fields) != 2 {
+		return binding, fmt.Errorf("Invalid binding %q", value)
+	}
+
+	for i, field := range fields {
+		number, err := strconv.ParseUint(strings.TrimSpace(field), 10, 16)
+		if err != nil {
+			return binding, fmt.Errorf("Invalid binding %q %v", value, err)
+		}
+		if i == 0 {
+			binding.HostPort = uint16(number)
+		} else {
+			binding.ContainerPort = uint16(number)
+		}
+	}
+
+	return binding, binding.Validate()
+}
+
+// ParseBindings parses the value of the nuage-port-mapping metadata. Bindings may be separated by commas,
+// semicolons or white space.
+func ParseBindings(value string) ([]Binding, error) {
+	var bindings []Binding
+
+	separators := func(r rune) bool {
+		return r == ',' || r == ';' || r == ' ' || r == '\t' || r == '\n'
+	}
+
+	for _, field := range strings.FieldsFunc(value, separators) {
+		binding, err := ParseBinding(field)
+		if err != nil {
+			return nil, err
+		}
+		bindings = append(bindings, binding)
+	}
+
+	return bindings, nil
+}
+
+// FormatBindings returns the canonical value of the nuage-port-mapping metadata for the bindings: the
+// bindings sorted by protocol and host port, separated by commas
+func FormatBindings(bindings []Binding) string {
+	sorted := append([]Binding{}, bindings...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Protocol != sorted[j].Protocol {
+			return sorted[i].Protocol < sorted[j].Protocol
+		}
+		if sorted[i].HostPort != sorted[j].HostPort {
+			return sorted[i].HostPort < sorted[j].HostPort
+		}
+		return sorted[i].ContainerPort < sorted[j].ContainerPort
+	})
+
+	values := make([]string, len(sorted))
+	for i, binding := range sorted {
+		values[i] = binding.String()
+	}
+
+	return strings.Join(values, ",")
+}
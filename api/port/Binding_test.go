@@ -0,0 +1,27 @@
+package port
+
+import "testing"
+
+func TestParseBindings(t *testing.T) {
+	bindings, err := ParseBindings("8080:80/tcp; 53:53/UDP 2222:22")
+	if err != nil || len(bindings) != 3 {
+		t.Fatalf("Unable to parse the bindings %v %v", bindings, err)
+	}
+	if bindings[2] != (Binding{HostPort: 2222, ContainerPort: 22, Protocol: ProtocolTCP}) {
+		t.Fatalf("Unexpected default protocol %v", bindings[2])
+	}
+	if value := FormatBindings(bindings); value != "2222:22/tcp,8080:80/tcp,53:53/udp" {
+		t.Fatalf("Unexpected canonical form %s", value)
+	}
+
+	for _, value := range []string{"80", "0:80/tcp", "80:80/icmp", "70000:80", "a:b"} {
+		if _, err = ParseBinding(value); err == nil {
+			t.Fatalf("Parsed invalid binding %s", value)
+		}
+	}
+
+	if !bindings[0].Conflicts(Binding{HostPort: 8080, ContainerPort: 8080, Protocol: ProtocolTCP}) ||
+		bindings[0].Conflicts(Binding{HostPort: 8080, ContainerPort: 80, Protocol: ProtocolUDP}) {
+		t.Fatalf("Unexpected conflict detection")
+	}
+}
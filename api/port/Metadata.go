@@ -1,5 +1,12 @@
 package port
 
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
 // MetadataKey represent Nuage specific metadata key
 type MetadataKey string
 
@@ -20,3 +27,301 @@ const (
 	MetadataKeyPg                     MetadataKey = "pg"
 	MetadataKeyPortBindings           MetadataKey = "nuage-port-mapping"
 )
+
+// NetworkType is the IP version of the network a port is attached to
+type NetworkType string
+
+// Network types supported by the VRS
+const (
+	NetworkTypeUnspecified NetworkType = ""
+	NetworkTypeIPv4        NetworkType = "ipv4"
+	NetworkTypeIPv6        NetworkType = "ipv6"
+	NetworkTypeDualStack   NetworkType = "dualstack"
+)
+
+// listSeparator separates the elements of the list valued metadata such as the policy groups
+const listSeparator = ","
+
+// Metadata is a typed view of the metadata of a port. Keys without a field of their own are kept in Extra.
+type Metadata struct {
+	Domain             string
+	Network            string
+	Zone               string
+	NetworkType        NetworkType
+	StaticIP           net.IP
+	Subnet             *net.IPNet
+	Gateway            net.IP
+	PolicyGroups       []string
+	RedirectionTargets []string
+	VPortTag           string
+	PortBindings       []Binding
+	Extra              map[MetadataKey]string
+}
+
+// ValidationError lists all the problems found in the metadata of a port
+type ValidationError []string
+
+func (validationError ValidationError) Error() string {
+	return fmt.Sprintf("Invalid port metadata: %s", strings.Join(validationError, "; "))
+}
+
+func (validationError *ValidationError) add(format string, args ...interface{}) {
+	*validationError = append(*validationError, fmt.Sprintf(format, args...))
+}
+
+// err returns nil if no problem was found so that an empty ValidationError is not mistaken for an error
+func (validationError ValidationError) err() error {
+	if len(validationError) == 0 {
+		return nil
+	}
+	return validationError
+}
+
+// Validate checks the consistency of the metadata and returns a ValidationError listing every problem found
+func (metadata Metadata) Validate() error {
+	var problems ValidationError
+
+	switch metadata.NetworkType {
+	case NetworkTypeUnspecified, NetworkTypeIPv4, NetworkTypeIPv6, NetworkTypeDualStack:
+	default:
+		problems.add("unknown network type %q", metadata.NetworkType)
+	}
+
+	if metadata.Subnet != nil {
+		if !metadata.Subnet.IP.Equal(metadata.Subnet.IP.Mask(metadata.Subnet.Mask)) {
+			problems.add("subnet address %s has host bits set for mask %s", metadata.Subnet.IP, formatMask(metadata.Subnet.Mask))
+		}
+		if !networkTypeAllows(metadata.NetworkType, metadata.Subnet.IP) {
+			problems.add("subnet %s does not match network type %s", metadata.Subnet, metadata.NetworkType)
+		}
+	}
+
+	if metadata.StaticIP != nil {
+		if metadata.Subnet != nil && !metadata.Subnet.Contains(metadata.StaticIP) {
+			problems.add("static IP %s is outside of subnet %s", metadata.StaticIP, metadata.Subnet)
+		}
+		if metadata.Gateway != nil && metadata.StaticIP.Equal(metadata.Gateway) {
+			problems.add("static IP %s is the gateway of the subnet", metadata.StaticIP)
+		}
+		if !networkTypeAllows(metadata.NetworkType, metadata.StaticIP) {
+			problems.add("static IP %s does not match network type %s", metadata.StaticIP, metadata.NetworkType)
+		}
+	}
+
+	if metadata.Gateway != nil {
+		if metadata.Subnet == nil {
+			problems.add("gateway %s given without a subnet", metadata.Gateway)
+		} else if !metadata.Subnet.Contains(metadata.Gateway) {
+			problems.add("gateway %s is outside of subnet %s", metadata.Gateway, metadata.Subnet)
+		}
+	}
+
+	validateList(&problems, "policy group", metadata.PolicyGroups)
+	validateList(&problems, "redirection target", metadata.RedirectionTargets)
+
+	for i, binding := range metadata.PortBindings {
+		if err := binding.Validate(); err != nil {
+			problems.add("%v", err)
+		}
+		for _, other := range metadata.PortBindings[:i] {
+			if binding.Conflicts(other) {
+				problems.add("bindings %s and %s use the same host port", other, binding)
+			}
+		}
+	}
+
+	for key := range metadata.Extra {
+		if _, ok := typedKeys[key]; ok {
+			problems.add("key %s must be set through its field", key)
+		}
+	}
+
+	return problems.err()
+}
+
+func validateList(problems *ValidationError, name string, values []string) {
+	seen := make(map[string]bool)
+	for _, value := range values {
+		switch {
+		case len(strings.TrimSpace(value)) == 0:
+			problems.add("empty %s name", name)
+		case strings.Contains(value, listSeparator):
+			problems.add("%s %q contains %q", name, value, listSeparator)
+		case seen[value]:
+			problems.add("duplicate %s %q", name, value)
+		}
+		seen[value] = true
+	}
+}
+
+func networkTypeAllows(networkType NetworkType, ip net.IP) bool {
+	switch networkType {
+	case NetworkTypeIPv4:
+		return ip.To4() != nil
+	case NetworkTypeIPv6:
+		return ip.To4() == nil
+	}
+	return true
+}
+
+// typedKeys are the keys represented by a field of Metadata
+var typedKeys = map[MetadataKey]bool{
+	MetadataKeyDomain:                 true,
+	MetadataKeyNetwork:                true,
+	MetadataKeyZone:                   true,
+	MetadataKeyNetworkType:            true,
+	MetadataKeyStaticIP:               true,
+	MetadataNuageSubnetAddress:        true,
+	MetadataNuageSubnetMask:           true,
+	MetadataNuageSubnetGateway:        true,
+	MetadataNuagePolicyGroup:          true,
+	MetadataKeyNuageRedirectionTarget: true,
+	MetadataKeyNuageVPortTag:          true,
+	MetadataKeyPortBindings:           true,
+}
+
+// Map converts the metadata to the map expected by CreatePort. Empty fields are left out.
+func (metadata Metadata) Map() map[MetadataKey]string {
+	values := make(map[MetadataKey]string)
+	for k, v := range metadata.Extra {
+		values[k] = v
+	}
+
+	set := func(key MetadataKey, value string) {
+		if len(value) != 0 {
+			values[key] = value
+		}
+	}
+
+	set(MetadataKeyDomain, metadata.Domain)
+	set(MetadataKeyNetwork, metadata.Network)
+	set(MetadataKeyZone, metadata.Zone)
+	set(MetadataKeyNetworkType, string(metadata.NetworkType))
+	if metadata.StaticIP != nil {
+		set(MetadataKeyStaticIP, metadata.StaticIP.String())
+	}
+	if metadata.Subnet != nil {
+		set(MetadataNuageSubnetAddress, metadata.Subnet.IP.String())
+		set(MetadataNuageSubnetMask, formatMask(metadata.Subnet.Mask))
+	}
+	if metadata.Gateway != nil {
+		set(MetadataNuageSubnetGateway, metadata.Gateway.String())
+	}
+	set(MetadataNuagePolicyGroup, strings.Join(metadata.PolicyGroups, listSeparator))
+	set(MetadataKeyNuageRedirectionTarget, strings.Join(metadata.RedirectionTargets, listSeparator))
+	set(MetadataKeyNuageVPortTag, metadata.VPortTag)
+	set(MetadataKeyPortBindings, FormatBindings(metadata.PortBindings))
+
+	return values
+}
+
+// StringMap converts the metadata to the map expected by UpdatePortMetadata
+func (metadata Metadata) StringMap() map[string]string {
+	values := make(map[string]string)
+	for k, v := range metadata.Map() {
+		values[string(k)] = v
+	}
+	return values
+}
+
+// ParseMetadata converts the metadata map of a port to Metadata. Values that cannot be parsed are reported in
+// a ValidationError, the returned Metadata then holds every value that could be parsed.
+func ParseMetadata(values map[MetadataKey]string) (Metadata, error) {
+	var problems ValidationError
+	metadata := Metadata{
+		Domain:      values[MetadataKeyDomain],
+		Network:     values[MetadataKeyNetwork],
+		Zone:        values[MetadataKeyZone],
+		NetworkType: NetworkType(values[MetadataKeyNetworkType]),
+		VPortTag:    values[MetadataKeyNuageVPortTag],
+	}
+
+	parseIP := func(key MetadataKey) net.IP {
+		value, ok := values[key]
+		if !ok || len(value) == 0 {
+			return nil
+		}
+		ip := net.ParseIP(value)
+		if ip == nil {
+			problems.add("invalid %s %q", key, value)
+		}
+		return ip
+	}
+
+	metadata.StaticIP = parseIP(MetadataKeyStaticIP)
+	metadata.Gateway = parseIP(MetadataNuageSubnetGateway)
+
+	if address := parseIP(MetadataNuageSubnetAddress); address != nil {
+		mask, err := parseMask(values[MetadataNuageSubnetMask], address)
+		if err != nil {
+			problems.add("%v", err)
+		} else {
+			if ip4 := address.To4(); ip4 != nil {
+				address = ip4
+			}
+			metadata.Subnet = &net.IPNet{IP: address, Mask: mask}
+		}
+	} else if _, ok := values[MetadataNuageSubnetMask]; ok {
+		problems.add("%s given without %s", MetadataNuageSubnetMask, MetadataNuageSubnetAddress)
+	}
+
+	bindings, err := ParseBindings(values[MetadataKeyPortBindings])
+	if err != nil {
+		problems.add("%v", err)
+	}
+	metadata.PortBindings = bindings
+
+	if value := values[MetadataNuagePolicyGroup]; len(value) != 0 {
+		metadata.PolicyGroups = strings.Split(value, listSeparator)
+	}
+	if value := values[MetadataKeyNuageRedirectionTarget]; len(value) != 0 {
+		metadata.RedirectionTargets = strings.Split(value, listSeparator)
+	}
+
+	for k, v := range values {
+		if !typedKeys[k] {
+			if metadata.Extra == nil {
+				metadata.Extra = make(map[MetadataKey]string)
+			}
+			metadata.Extra[k] = v
+		}
+	}
+
+	return metadata, problems.err()
+}
+
+// formatMask prints IPv4 masks in dotted notation and IPv6 masks as a prefix length
+func formatMask(mask net.IPMask) string {
+	if len(mask) == net.IPv4len {
+		return net.IP(mask).String()
+	}
+	ones, _ := mask.Size()
+	return strconv.Itoa(ones)
+}
+
+// parseMask accepts a dotted IPv4 mask or a prefix length for the family of address
+func parseMask(value string, address net.IP) (net.IPMask, error) {
+	bits := 8 * net.IPv6len
+	if ip4 := address.To4(); ip4 != nil {
+		bits = 8 * net.IPv4len
+	}
+
+	if ones, err := strconv.Atoi(value); err == nil {
+		if ones < 0 || ones > bits {
+			return nil, fmt.Errorf("invalid %s %q", MetadataNuageSubnetMask, value)
+		}
+		return net.CIDRMask(ones, bits), nil
+	}
+
+	ip := net.ParseIP(value)
+	if ip == nil || ip.To4() == nil || bits != 8*net.IPv4len {
+		return nil, fmt.Errorf("invalid %s %q", MetadataNuageSubnetMask, value)
+	}
+
+	mask := net.IPMask(ip.To4())
+	if _, size := mask.Size(); size == 0 {
+		return nil, fmt.Errorf("non contiguous %s %q", MetadataNuageSubnetMask, value)
+	}
+
+	return mask, nil
+}
@@ -0,0 +1,76 @@
+package port
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestMetadataRoundTrip(t *testing.T) {
+	_, subnet, _ := net.ParseCIDR("192.168.100.0/24")
+	metadata := Metadata{
+		Domain:             "domain",
+		Network:            "network",
+		Zone:               "zone",
+		NetworkType:        NetworkTypeIPv4,
+		StaticIP:           net.ParseIP("192.168.100.101"),
+		Subnet:             subnet,
+		Gateway:            net.ParseIP("192.168.100.1"),
+		PolicyGroups:       []string{"pg1", "pg2"},
+		RedirectionTargets: []string{"rt1"},
+		VPortTag:           "tag",
+		Extra:              map[MetadataKey]string{MetadataKeyPg: "pg"},
+	}
+
+	if err := metadata.Validate(); err != nil {
+		t.Fatalf("Unexpected validation error %v", err)
+	}
+
+	values := metadata.Map()
+	if values[MetadataNuageSubnetMask] != "255.255.255.0" || values[MetadataNuagePolicyGroup] != "pg1,pg2" {
+		t.Fatalf("Unexpected metadata map %v", values)
+	}
+
+	parsed, err := ParseMetadata(values)
+	if err != nil {
+		t.Fatalf("Unable to parse the metadata %v", err)
+	}
+	if !reflect.DeepEqual(parsed.Map(), values) {
+		t.Fatalf("Metadata changed by a round trip %v %v", parsed.Map(), values)
+	}
+}
+
+func TestMetadataValidate(t *testing.T) {
+	_, subnet, _ := net.ParseCIDR("10.0.0.0/24")
+	metadata := Metadata{
+		NetworkType:  NetworkType("ipx"),
+		StaticIP:     net.ParseIP("10.0.1.5"),
+		Subnet:       subnet,
+		Gateway:      net.ParseIP("10.0.2.1"),
+		PolicyGroups: []string{"pg1", "pg1", "a,b", " "},
+		Extra:        map[MetadataKey]string{MetadataKeyStaticIP: "10.0.0.5"},
+	}
+
+	err := metadata.Validate()
+	problems, ok := err.(ValidationError)
+	if !ok || len(problems) != 7 {
+		t.Fatalf("Expected all problems to be reported %v", err)
+	}
+
+	_, err = ParseMetadata(map[MetadataKey]string{
+		MetadataKeyStaticIP:        "10.0.0.300",
+		MetadataNuageSubnetAddress: "10.0.0.0",
+		MetadataNuageSubnetMask:    "255.0.255.0",
+	})
+	if problems, ok = err.(ValidationError); !ok || len(problems) != 2 {
+		t.Fatalf("Expected the parse errors to be reported %v", err)
+	}
+
+	parsed, err := ParseMetadata(map[MetadataKey]string{
+		MetadataNuageSubnetAddress: "2001:db8::",
+		MetadataNuageSubnetMask:    "64",
+	})
+	if err != nil || parsed.Subnet.String() != "2001:db8::/64" {
+		t.Fatalf("Unable to parse an IPv6 subnet %v %v", parsed.Subnet, err)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/containernetworking/cni/pkg/skel"
+	"github.com/containernetworking/cni/pkg/types"
+	"github.com/containernetworking/cni/pkg/version"
+	"github.com/nuagenetworks/libvrsdk/api"
+	"github.com/nuagenetworks/libvrsdk/api/entity"
+	"github.com/nuagenetworks/libvrsdk/api/port"
+)
+
+// Defaults of the network configuration
+const (
+	defaultVRSSocket         = "/var/run/openvswitch/db.sock"
+	defaultResolutionTimeout = 30 * time.Second
+)
+
+// NetConf is the network configuration of the plugin
+type NetConf struct {
+	types.NetConf
+
+	VRSSocket         string            `json:"vrsSocket"`
+	Enterprise        string            `json:"enterprise"`
+	User              string            `json:"user"`
+	Domain            string            `json:"domain"`
+	Zone              string            `json:"zone"`
+	Network           string            `json:"network"`
+	Metadata          map[string]string `json:"metadata"`
+	MTU               int               `json:"mtu"`
+	ResolutionTimeout string            `json:"resolutionTimeout"`
+
+	resolutionTimeout time.Duration
+}
+
+// K8sArgs are the arguments passed by Kubernetes in CNI_ARGS
+type K8sArgs struct {
+	types.CommonArgs
+	K8S_POD_NAME      types.UnmarshallableString
+	K8S_POD_NAMESPACE types.UnmarshallableString
+}
+
+// loadNetConf parses the network configuration and fills in the defaults
+func loadNetConf(data []byte) (*NetConf, error) {
+	conf := &NetConf{}
+	if err := json.Unmarshal(data, conf); err != nil {
+		return nil, fmt.Errorf("Unable to parse the network configuration %v", err)
+	}
+
+	if err := version.ParsePrevResult(&conf.NetConf); err != nil {
+		return nil, fmt.Errorf("Unable to parse the previous result %v", err)
+	}
+
+	if conf.VRSSocket == "" {
+		conf.VRSSocket = defaultVRSSocket
+	}
+
+	conf.resolutionTimeout = defaultResolutionTimeout
+	if conf.ResolutionTimeout != "" {
+		timeout, err := time.ParseDuration(conf.ResolutionTimeout)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("Invalid resolution timeout %q", conf.ResolutionTimeout)
+		}
+		conf.resolutionTimeout = timeout
+	}
+
+	if _, err := conf.portMetadata(); err != nil {
+		return nil, err
+	}
+
+	return conf, nil
+}
+
+// portMetadata returns the metadata of the ports, checked by the SDK
+func (conf *NetConf) portMetadata() (map[port.MetadataKey]string, error) {
+	metadata := make(map[port.MetadataKey]string)
+	for k, v := range conf.Metadata {
+		metadata[port.MetadataKey(k)] = v
+	}
+	metadata[port.MetadataKeyDomain] = conf.Domain
+	metadata[port.MetadataKeyZone] = conf.Zone
+	metadata[port.MetadataKeyNetwork] = conf.Network
+
+	parsed, err := port.ParseMetadata(metadata)
+	if err == nil {
+		err = parsed.Validate()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return metadata, nil
+}
+
+// entityInfo returns the entity of the container. Its UUID is derived from the container ID so that DEL and
+// CHECK find the entity created by ADD.
+func (conf *NetConf) entityInfo(args *skel.CmdArgs) (api.EntityInfo, error) {
+	k8sArgs := K8sArgs{}
+	if err := types.LoadArgs(args.Args, &k8sArgs); err != nil {
+		return api.EntityInfo{}, fmt.Errorf("Unable to parse the CNI arguments %v", err)
+	}
+
+	name := args.ContainerID
+	if k8sArgs.K8S_POD_NAME != "" {
+		name = string(k8sArgs.K8S_POD_NAME)
+		if k8sArgs.K8S_POD_NAMESPACE != "" {
+			name = string(k8sArgs.K8S_POD_NAMESPACE) + "_" + name
+		}
+	}
+
+	return api.EntityInfo{
+		UUID:   entity.NameUUID(args.ContainerID),
+		Name:   name,
+		Type:   entity.Container,
+		Domain: entity.Docker,
+		Metadata: map[entity.MetadataKey]string{
+			entity.MetadataKeyEnterprise: conf.Enterprise,
+			entity.MetadataKeyUser:       conf.User,
+		},
+	}, nil
+}
+
+// portName returns the name of the host end of the veth pair, which is also the name of the Nuage port. It
+// must fit in the 15 characters of a kernel interface name.
+func portName(containerID string, ifName string) string {
+	sum := sha1.Sum([]byte(containerID + "/" + ifName))
+	return "nu" + hex.EncodeToString(sum[:])[:13]
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+)
+
+// Links manages the veth pairs connecting the containers to alubr0
+type Links interface {
+	// Setup creates the veth pair hostName/ifName, moves ifName into netnsPath and returns its MAC address
+	Setup(netnsPath string, ifName string, hostName string, mtu int) (string, error)
+	// Configure sets the address of ifName and its default route through gateway
+	Configure(netnsPath string, ifName string, address *net.IPNet, gateway net.IP) error
+	// Check verifies that ifName exists in netnsPath with address
+	Check(netnsPath string, ifName string, address *net.IPNet) error
+	// Teardown deletes the veth pair, if any
+	Teardown(netnsPath string, ifName string, hostName string) error
+}
+
+// netlinkLinks manages the veth pairs with netlink, without switching the network namespace of the thread
+type netlinkLinks struct{}
+
+// withNetns calls f with a netlink handle in the network namespace at netnsPath
+func withNetns(netnsPath string, f func(handle *netlink.Handle) error) error {
+	ns, err := netns.GetFromPath(netnsPath)
+	if err != nil {
+		return err
+	}
+	defer ns.Close()
+
+	handle, err := netlink.NewHandleAt(ns)
+	if err != nil {
+		return fmt.Errorf("Unable to open the network namespace %s %v", netnsPath, err)
+	}
+	defer handle.Delete()
+
+	return f(handle)
+}
+
+func (netlinkLinks) Setup(netnsPath string, ifName string, hostName string, mtu int) (string, error) {
+	ns, err := netns.GetFromPath(netnsPath)
+	if err != nil {
+		return "", fmt.Errorf("Unable to open the network namespace %s %v", netnsPath, err)
+	}
+	defer ns.Close()
+
+	// The peer is created in the host under a name unique to the pair and renamed once in the container
+	peerName := "np" + hostName[2:]
+	veth := &netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: hostName, MTU: mtu}, PeerName: peerName}
+	if err = netlink.LinkAdd(veth); err != nil {
+		return "", fmt.Errorf("Unable to create the veth pair %s %v", hostName, err)
+	}
+
+	var mac string
+	err = func() error {
+		peer, err := netlink.LinkByName(peerName)
+		if err != nil {
+			return err
+		}
+		if err = netlink.LinkSetNsFd(peer, int(ns)); err != nil {
+			return err
+		}
+
+		err = withNetns(netnsPath, func(handle *netlink.Handle) error {
+			if peer, err = handle.LinkByName(peerName); err != nil {
+				return err
+			}
+			if err = handle.LinkSetName(peer, ifName); err != nil {
+				return err
+			}
+			if err = handle.LinkSetUp(peer); err != nil {
+				return err
+			}
+			mac = peer.Attrs().HardwareAddr.String()
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		return netlink.LinkSetUp(veth)
+	}()
+	if err != nil {
+		netlink.LinkDel(veth)
+		return "", fmt.Errorf("Unable to set up the veth pair %s %v", hostName, err)
+	}
+
+	return mac, nil
+}
+
+func (netlinkLinks) Configure(netnsPath string, ifName string, address *net.IPNet, gateway net.IP) error {
+	return withNetns(netnsPath, func(handle *netlink.Handle) error {
+		link, err := handle.LinkByName(ifName)
+		if err != nil {
+			return err
+		}
+		if err = handle.AddrAdd(link, &netlink.Addr{IPNet: address}); err != nil {
+			return fmt.Errorf("Unable to set the address of %s %v", ifName, err)
+		}
+		route := &netlink.Route{LinkIndex: link.Attrs().Index, Gw: gateway}
+		if err = handle.RouteAdd(route); err != nil {
+			return fmt.Errorf("Unable to add the default route through %s %v", gateway, err)
+		}
+		return nil
+	})
+}
+
+func (netlinkLinks) Check(netnsPath string, ifName string, address *net.IPNet) error {
+	return withNetns(netnsPath, func(handle *netlink.Handle) error {
+		link, err := handle.LinkByName(ifName)
+		if err != nil {
+			return err
+		}
+		addrs, err := handle.AddrList(link, netlink.FAMILY_V4)
+		if err != nil {
+			return err
+		}
+		for _, addr := range addrs {
+			if addr.IPNet.String() == address.String() {
+				return nil
+			}
+		}
+		return fmt.Errorf("Address %s missing from %s", address, ifName)
+	})
+}
+
+func (netlinkLinks) Teardown(netnsPath string, ifName string, hostName string) error {
+	// Deleting either end deletes the pair, the host end remains when the namespace is already gone
+	if link, err := netlink.LinkByName(hostName); err == nil {
+		return netlink.LinkDel(link)
+	}
+
+	if netnsPath == "" {
+		return nil
+	}
+	err := withNetns(netnsPath, func(handle *netlink.Handle) error {
+		link, err := handle.LinkByName(ifName)
+		if err != nil {
+			if _, ok := err.(netlink.LinkNotFoundError); ok {
+				return nil
+			}
+			return err
+		}
+		return handle.LinkDel(link)
+	})
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
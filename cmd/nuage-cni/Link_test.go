@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+)
+
+func TestNetlinkLinks(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("Network namespaces require root")
+	}
+
+	// The host end is created in a namespace of the test rather than in the namespace of the machine
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	origin, err := netns.Get()
+	if err != nil {
+		t.Fatalf("Unable to get the network namespace %v", err)
+	}
+	defer origin.Close()
+	defer netns.Set(origin)
+
+	host, err := netns.New()
+	if err != nil {
+		t.Skipf("Unable to create a network namespace %v", err)
+	}
+	defer host.Close()
+
+	container, err := netns.New()
+	if err != nil {
+		t.Fatalf("Unable to create a network namespace %v", err)
+	}
+	defer container.Close()
+	if err = netns.Set(host); err != nil {
+		t.Fatalf("Unable to enter the network namespace %v", err)
+	}
+	netnsPath := fmt.Sprintf("/proc/self/fd/%d", int(container))
+
+	links := netlinkLinks{}
+	name := portName("container", "eth0")
+	mac, err := links.Setup(netnsPath, "eth0", name, 1450)
+	if err != nil {
+		t.Fatalf("Unable to set up the veth pair %v", err)
+	}
+	if _, err = net.ParseMAC(mac); err != nil {
+		t.Fatalf("Invalid MAC %q", mac)
+	}
+	if link, err := netlink.LinkByName(name); err != nil || link.Attrs().MTU != 1450 {
+		t.Fatalf("Host end missing %v", err)
+	}
+
+	address := &net.IPNet{IP: net.IPv4(10, 0, 0, 2).To4(), Mask: net.CIDRMask(24, 32)}
+	if err = links.Configure(netnsPath, "eth0", address, net.IPv4(10, 0, 0, 1)); err != nil {
+		t.Fatalf("Unable to configure the container end %v", err)
+	}
+	if err = links.Check(netnsPath, "eth0", address); err != nil {
+		t.Fatalf("Unable to check the container end %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err = links.Teardown(netnsPath, "eth0", name); err != nil {
+			t.Fatalf("Unable to tear down the veth pair %v", err)
+		}
+	}
+	if _, err = netlink.LinkByName(name); err == nil {
+		t.Fatalf("Host end left after teardown")
+	}
+	if err = links.Check(netnsPath, "eth0", address); err == nil {
+		t.Fatalf("Container end left after teardown")
+	}
+	if err = links.Teardown("/proc/self/fd/-1", "eth0", name); err != nil {
+		t.Fatalf("Teardown failed without a namespace %v", err)
+	}
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/containernetworking/cni/pkg/skel"
+	"github.com/containernetworking/cni/pkg/types"
+	"github.com/containernetworking/cni/pkg/types/current"
+	"github.com/nuagenetworks/libvrsdk/api"
+	"github.com/nuagenetworks/libvrsdk/api/entity"
+	"github.com/nuagenetworks/libvrsdk/api/port"
+)
+
+// Plugin implements the CNI commands on top of a VRS connection
+type Plugin struct {
+	Connect func(socket string) (api.VRSConnection, error)
+	Links   Links
+}
+
+// NewPlugin returns a Plugin connecting to the VRS over its Unix socket and managing veth pairs with netlink
+func NewPlugin() *Plugin {
+	return &Plugin{Connect: api.NewUnixSocketConnection, Links: netlinkLinks{}}
+}
+
+// connect parses the network configuration and connects to the VRS it names
+func (plugin *Plugin) connect(args *skel.CmdArgs) (*NetConf, api.VRSConnection, error) {
+	conf, err := loadNetConf(args.StdinData)
+	if err != nil {
+		return nil, api.VRSConnection{}, err
+	}
+
+	vrsConnection, err := plugin.Connect(conf.VRSSocket)
+	if err != nil {
+		return nil, vrsConnection, fmt.Errorf("Unable to connect to the VRS %v", err)
+	}
+
+	return conf, vrsConnection, nil
+}
+
+// Add attaches the container to the VRS and returns its resolved address
+func (plugin *Plugin) Add(args *skel.CmdArgs) (*current.Result, error) {
+	conf, vrsConnection, err := plugin.connect(args)
+	if err != nil {
+		return nil, err
+	}
+	defer vrsConnection.Disconnect()
+
+	info, err := conf.entityInfo(args)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := plugin.add(conf, vrsConnection, args, info)
+	if err != nil {
+		// Undo whatever part of ADD succeeded, the runtime does not call DEL after a failed ADD
+		plugin.del(vrsConnection, args, info)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (plugin *Plugin) add(conf *NetConf, vrsConnection api.VRSConnection, args *skel.CmdArgs,
+	info api.EntityInfo) (*current.Result, error) {
+
+	name := portName(args.ContainerID, args.IfName)
+	metadata, err := conf.portMetadata()
+	if err != nil {
+		return nil, err
+	}
+
+	mac, err := plugin.Links.Setup(args.Netns, args.IfName, name, conf.MTU)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := make(chan *api.PortIPv4Info, 1)
+	if err = vrsConnection.RegisterForPortUpdates(name, resolved); err != nil {
+		return nil, fmt.Errorf("Unable to register for the updates of port %s %v", name, err)
+	}
+	defer vrsConnection.DeregisterChannelForPortUpdates(name, resolved)
+
+	attributes := port.Attributes{MAC: mac, Platform: entity.Docker, Bridge: "alubr0"}
+	if err = vrsConnection.CreatePort(name, attributes, metadata); err != nil {
+		return nil, err
+	}
+
+	// A container with several interfaces is a single entity owning several ports
+	exists, err := vrsConnection.CheckEntityExists(info.UUID)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		err = vrsConnection.AddEntityPort(info.UUID, name)
+	} else {
+		info.Ports = []string{name}
+		err = vrsConnection.CreateEntity(info)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = vrsConnection.AddPortToAlubr0(name, info); err != nil {
+		return nil, err
+	}
+
+	var portInfo *api.PortIPv4Info
+	select {
+	case portInfo = <-resolved:
+	case <-time.After(conf.resolutionTimeout):
+		return nil, fmt.Errorf("Port %s not resolved by the VRS within %s", name, conf.resolutionTimeout)
+	}
+	if !portInfo.Registered {
+		return nil, fmt.Errorf("Port %s deleted before its resolution", name)
+	}
+
+	address, gateway, err := parsePortIPv4Info(portInfo)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid resolution of port %s %v", name, err)
+	}
+
+	if err = plugin.Links.Configure(args.Netns, args.IfName, address, gateway); err != nil {
+		return nil, err
+	}
+
+	return &current.Result{
+		CNIVersion: conf.CNIVersion,
+		Interfaces: []*current.Interface{
+			{Name: name},
+			{Name: args.IfName, Mac: mac, Sandbox: args.Netns},
+		},
+		IPs: []*current.IPConfig{
+			{Version: "4", Interface: current.Int(1), Address: *address, Gateway: gateway},
+		},
+		Routes: []*types.Route{
+			{Dst: net.IPNet{IP: net.IPv4zero, Mask: net.CIDRMask(0, 32)}, GW: gateway},
+		},
+		DNS: conf.DNS,
+	}, nil
+}
+
+// parsePortIPv4Info converts the address resolved by the VRS
+func parsePortIPv4Info(portInfo *api.PortIPv4Info) (*net.IPNet, net.IP, error) {
+	ip := net.ParseIP(portInfo.IPAddr).To4()
+	if ip == nil {
+		return nil, nil, fmt.Errorf("Invalid IP %q", portInfo.IPAddr)
+	}
+
+	mask := net.ParseIP(portInfo.Mask).To4()
+	if mask == nil {
+		return nil, nil, fmt.Errorf("Invalid subnet mask %q", portInfo.Mask)
+	}
+
+	gateway := net.ParseIP(portInfo.Gateway).To4()
+	if gateway == nil {
+		return nil, nil, fmt.Errorf("Invalid gateway %q", portInfo.Gateway)
+	}
+
+	return &net.IPNet{IP: ip, Mask: net.IPMask(mask)}, gateway, nil
+}
+
+// Del detaches the container from the VRS. The parts already detached are skipped, so that DEL can be retried.
+func (plugin *Plugin) Del(args *skel.CmdArgs) error {
+	conf, vrsConnection, err := plugin.connect(args)
+	if err != nil {
+		return err
+	}
+	defer vrsConnection.Disconnect()
+
+	info, err := conf.entityInfo(args)
+	if err != nil {
+		return err
+	}
+
+	return plugin.del(vrsConnection, args, info)
+}
+
+func (plugin *Plugin) del(vrsConnection api.VRSConnection, args *skel.CmdArgs, info api.EntityInfo) error {
+	name := portName(args.ContainerID, args.IfName)
+
+	bridgePorts, err := vrsConnection.GetAlubr0Ports()
+	if err != nil {
+		return err
+	}
+	if contains(bridgePorts, name) {
+		if err = vrsConnection.RemovePortFromAlubr0(name); err != nil {
+			return err
+		}
+	}
+
+	exists, err := vrsConnection.CheckEntityExists(info.UUID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		entityPorts, err := vrsConnection.GetEntityPorts(info.UUID)
+		if err != nil {
+			return err
+		}
+		if len(entityPorts) == 0 || (len(entityPorts) == 1 && entityPorts[0] == name) {
+			err = vrsConnection.DestroyEntity(info.UUID)
+		} else if contains(entityPorts, name) {
+			err = vrsConnection.RemoveEntityPort(info.UUID, name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	ports, err := vrsConnection.GetAllPorts()
+	if err != nil {
+		return err
+	}
+	if contains(ports, name) {
+		if err = vrsConnection.DestroyPort(name); err != nil {
+			return err
+		}
+	}
+
+	return plugin.Links.Teardown(args.Netns, args.IfName, name)
+}
+
+// Check verifies that the container is still attached to the VRS with the address returned by ADD
+func (plugin *Plugin) Check(args *skel.CmdArgs) error {
+	conf, vrsConnection, err := plugin.connect(args)
+	if err != nil {
+		return err
+	}
+	defer vrsConnection.Disconnect()
+
+	if conf.PrevResult == nil {
+		return fmt.Errorf("Previous result missing")
+	}
+	prevResult, err := current.NewResultFromResult(conf.PrevResult)
+	if err != nil {
+		return fmt.Errorf("Unable to convert the previous result %v", err)
+	}
+
+	info, err := conf.entityInfo(args)
+	if err != nil {
+		return err
+	}
+	name := portName(args.ContainerID, args.IfName)
+
+	ports, err := vrsConnection.GetAllPorts()
+	if err != nil {
+		return err
+	}
+	if !contains(ports, name) {
+		return fmt.Errorf("Port %s missing from the VRS", name)
+	}
+
+	entityPorts, err := vrsConnection.GetEntityPorts(info.UUID)
+	if err != nil {
+		return err
+	}
+	if !contains(entityPorts, name) {
+		return fmt.Errorf("Port %s missing from entity %s", name, info.UUID)
+	}
+
+	bridgePorts, err := vrsConnection.GetAlubr0Ports()
+	if err != nil {
+		return err
+	}
+	if !contains(bridgePorts, name) {
+		return fmt.Errorf("Port %s missing from alubr0", name)
+	}
+
+	for _, ip := range prevResult.IPs {
+		if err = plugin.Links.Check(args.Netns, args.IfName, &ip.Address); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
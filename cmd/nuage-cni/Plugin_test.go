@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/containernetworking/cni/pkg/skel"
+	"github.com/nuagenetworks/libvrsdk/api"
+	"github.com/nuagenetworks/libvrsdk/api/entity"
+	"github.com/nuagenetworks/libvrsdk/ovsdb"
+	"github.com/nuagenetworks/libvrsdk/ovsdb/ovsdbtest"
+	"github.com/socketplane/libovsdb"
+)
+
+// fakeLinks records the interfaces of the containers instead of creating them
+type fakeLinks struct {
+	addresses map[string]string
+}
+
+func (links *fakeLinks) Setup(netnsPath string, ifName string, hostName string, mtu int) (string, error) {
+	links.addresses[netnsPath+"/"+ifName] = ""
+	return "76:22:f6:70:4e:47", nil
+}
+
+func (links *fakeLinks) Configure(netnsPath string, ifName string, address *net.IPNet, gateway net.IP) error {
+	links.addresses[netnsPath+"/"+ifName] = address.String()
+	return nil
+}
+
+func (links *fakeLinks) Check(netnsPath string, ifName string, address *net.IPNet) error {
+	if links.addresses[netnsPath+"/"+ifName] != address.String() {
+		return fmt.Errorf("Address %s missing from %s", address, ifName)
+	}
+	return nil
+}
+
+func (links *fakeLinks) Teardown(netnsPath string, ifName string, hostName string) error {
+	delete(links.addresses, netnsPath+"/"+ifName)
+	return nil
+}
+
+// resolvePort plays the part of the VRS, giving an address to the port once it is created
+func resolvePort(server *ovsdbtest.Server, name string) {
+	for i := 0; i < 100; i++ {
+		for _, row := range server.Rows(ovsdb.NuagePortTable) {
+			if row[ovsdb.NuagePortTableColumnName] == name {
+				server.Transact(libovsdb.Operation{
+					Op:    "update",
+					Table: ovsdb.NuagePortTable,
+					Where: []interface{}{libovsdb.NewCondition(ovsdb.NuagePortTableColumnName, "==", name)},
+					Row: map[string]interface{}{"ip_addr": "10.0.0.2", "subnet_mask": "255.255.255.0",
+						"gateway": "10.0.0.1"},
+				})
+				return
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestPlugin(t *testing.T) {
+	server, err := ovsdbtest.NewServer()
+	if err != nil {
+		t.Fatalf("Unable to start the OVSDB server %v", err)
+	}
+	defer server.Close()
+
+	bridgeOp := libovsdb.Operation{Op: "insert", Table: "Bridge", Row: map[string]interface{}{"name": "alubr0"}}
+	if _, err = server.Transact(bridgeOp); err != nil {
+		t.Fatalf("Unable to create alubr0 %v", err)
+	}
+
+	links := &fakeLinks{addresses: make(map[string]string)}
+	plugin := &Plugin{Connect: api.NewUnixSocketConnection, Links: links}
+
+	conf := map[string]interface{}{
+		"cniVersion":        "0.4.0",
+		"name":              "nuage",
+		"type":              "nuage-cni",
+		"vrsSocket":         server.SocketPath(),
+		"enterprise":        "enterprise",
+		"user":              "user",
+		"domain":            "domain",
+		"zone":              "zone",
+		"network":           "network",
+		"resolutionTimeout": "5s",
+	}
+	stdin, _ := json.Marshal(conf)
+	args := &skel.CmdArgs{
+		ContainerID: "container",
+		Netns:       "/var/run/netns/container",
+		IfName:      "eth0",
+		Args:        "IgnoreUnknown=1;K8S_POD_NAMESPACE=default;K8S_POD_NAME=pod",
+		StdinData:   stdin,
+	}
+	name := portName(args.ContainerID, args.IfName)
+
+	go resolvePort(server, name)
+	result, err := plugin.Add(args)
+	if err != nil {
+		t.Fatalf("Unable to add the container %v", err)
+	}
+	if len(result.IPs) != 1 || result.IPs[0].Address.String() != "10.0.0.2/24" ||
+		result.IPs[0].Gateway.String() != "10.0.0.1" {
+		t.Fatalf("Unexpected IPs %v", result.IPs)
+	}
+	if len(result.Interfaces) != 2 || result.Interfaces[0].Name != name ||
+		result.Interfaces[1].Sandbox != args.Netns {
+		t.Fatalf("Unexpected interfaces %v", result.Interfaces)
+	}
+
+	vrsConnection, err := api.NewUnixSocketConnection(server.SocketPath())
+	if err != nil {
+		t.Fatalf("Unable to connect to the VRS %v", err)
+	}
+	defer vrsConnection.Disconnect()
+
+	entities, err := vrsConnection.GetAllEntityInfo()
+	if err != nil || len(entities) != 1 || entities[0].Name != "default_pod" ||
+		len(entities[0].Ports) != 1 || entities[0].Ports[0] != name {
+		t.Fatalf("Unexpected entities %+v %v", entities, err)
+	}
+
+	conf["prevResult"] = result
+	args.StdinData, _ = json.Marshal(conf)
+	if err = plugin.Check(args); err != nil {
+		t.Fatalf("Unable to check the container %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err = plugin.Del(args); err != nil {
+			t.Fatalf("Unable to delete the container %v", err)
+		}
+	}
+	if err = plugin.Check(args); err == nil {
+		t.Fatalf("Deleted container checked")
+	}
+
+	ports, err := vrsConnection.GetAllPorts()
+	if err != nil || len(ports) != 0 {
+		t.Fatalf("Ports left after DEL %v %v", ports, err)
+	}
+	bridgePorts, err := vrsConnection.GetAlubr0Ports()
+	if err != nil || len(bridgePorts) != 0 {
+		t.Fatalf("alubr0 ports left after DEL %v %v", bridgePorts, err)
+	}
+	if exists, err := vrsConnection.CheckEntityExists(entity.NameUUID(args.ContainerID)); err != nil || exists {
+		t.Fatalf("Entity left after DEL %v", err)
+	}
+	if len(links.addresses) != 0 {
+		t.Fatalf("Interfaces left after DEL %v", links.addresses)
+	}
+
+	// ADD undoes its changes when the port is not resolved
+	conf["resolutionTimeout"] = "100ms"
+	delete(conf, "prevResult")
+	args.StdinData, _ = json.Marshal(conf)
+	if _, err = plugin.Add(args); err == nil {
+		t.Fatalf("Unresolved container added")
+	}
+	if ports, err = vrsConnection.GetAllPorts(); err != nil || len(ports) != 0 {
+		t.Fatalf("Ports left after a failed ADD %v %v", ports, err)
+	}
+}
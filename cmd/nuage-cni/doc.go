@@ -0,0 +1,29 @@
+/*
+Nuage-cni is a CNI plugin attaching containers to the Nuage VRS through the SDK.
+
+ADD creates a veth pair whose container end is moved into the network namespace of the container, declares the
+host end as a Nuage port along with the container entity, attaches it to alubr0, waits for the VRS to resolve the
+port and configures the resolved IP address and default route in the container. DEL reverses these steps and
+succeeds when they were already undone. CHECK verifies that the port, the entity and the container interface are
+still in place.
+
+The network configuration holds the VRS socket and the Nuage metadata of the ports:
+
+	{
+		"cniVersion": "0.4.0",
+		"name": "nuage",
+		"type": "nuage-cni",
+		"vrsSocket": "/var/run/openvswitch/db.sock",
+		"enterprise": "enterprise",
+		"user": "user",
+		"domain": "domain",
+		"zone": "zone",
+		"network": "network",
+		"mtu": 1450,
+		"resolutionTimeout": "30s"
+	}
+
+Pods are named after the K8S_POD_NAMESPACE and K8S_POD_NAME arguments passed by Kubernetes, other containers after
+their ID.
+*/
+package main
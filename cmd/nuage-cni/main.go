@@ -0,0 +1,21 @@
+package main
+
+import (
+	"github.com/containernetworking/cni/pkg/skel"
+	"github.com/containernetworking/cni/pkg/version"
+)
+
+func main() {
+	plugin := NewPlugin()
+
+	cmdAdd := func(args *skel.CmdArgs) error {
+		result, err := plugin.Add(args)
+		if err != nil {
+			return err
+		}
+		return result.Print()
+	}
+
+	skel.PluginMain(cmdAdd, plugin.Check, plugin.Del, version.PluginSupports("0.3.0", "0.3.1", "0.4.0"),
+		"Nuage VRS CNI plugin")
+}
@@ -0,0 +1,402 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nuagenetworks/libvrsdk/api"
+	"github.com/nuagenetworks/libvrsdk/api/entity"
+	"github.com/nuagenetworks/libvrsdk/api/port"
+)
+
+// defaultResolutionTimeout is the default time to wait for the VRS to resolve a port
+const defaultResolutionTimeout = 30 * time.Second
+
+// endpointIDKey is the external ID of the alubr0 port recording the Docker endpoint it was created for
+const endpointIDKey = "docker-endpoint-id"
+
+// pluginContentType is the content type of the responses of the plugin
+const pluginContentType = "application/vnd.docker.plugins.v1.2+json"
+
+// shortOptions are the network options standing for the nuage-enterprise-* port metadata
+var shortOptions = map[string]port.MetadataKey{
+	"domain":       port.MetadataKeyDomain,
+	"zone":         port.MetadataKeyZone,
+	"network":      port.MetadataKeyNetwork,
+	"network-type": port.MetadataKeyNetworkType,
+}
+
+// entityOptions are the network options giving the metadata of the entities
+var entityOptions = map[string]entity.MetadataKey{
+	"enterprise": entity.MetadataKeyEnterprise,
+	"user":       entity.MetadataKeyUser,
+}
+
+// network is a Docker network, mapped to a Nuage subnet
+type network struct {
+	id             string
+	pool           *net.IPNet
+	gateway        net.IP
+	portMetadata   map[port.MetadataKey]string
+	entityMetadata map[entity.MetadataKey]string
+}
+
+// Driver is a libnetwork remote network and IPAM driver attaching the Docker endpoints to the VRS. The IPAM
+// driver creates the port and the entity of an endpoint so that the address resolved by the VRS is given to
+// Docker, the network driver then creates the veth pair of the endpoint and attaches it to alubr0.
+type Driver struct {
+	vrsConnection     api.VRSConnection
+	links             Links
+	mtu               int
+	resolutionTimeout time.Duration
+	stateFile         string
+
+	mutex    sync.Mutex
+	networks map[string]*network
+	pools    map[string]*network
+}
+
+// NewDriver returns a Driver creating the ports on the VRS of vrsConnection. The networks are saved in stateFile
+// and loaded from it, so that the endpoints of the networks created before a restart can be given addresses.
+func NewDriver(vrsConnection api.VRSConnection, links Links, mtu int, resolutionTimeout time.Duration,
+	stateFile string) (*Driver, error) {
+
+	driver := &Driver{
+		vrsConnection:     vrsConnection,
+		links:             links,
+		mtu:               mtu,
+		resolutionTimeout: resolutionTimeout,
+		stateFile:         stateFile,
+		networks:          make(map[string]*network),
+		pools:             make(map[string]*network),
+	}
+
+	networks, err := loadNetworks(stateFile)
+	if err != nil {
+		return nil, err
+	}
+	for _, nw := range networks {
+		driver.networks[nw.id] = nw
+		driver.pools[nw.pool.String()] = nw
+	}
+
+	return driver, nil
+}
+
+// handler decodes a request with decode and returns the response
+type handler func(decode func(request interface{}) error) (interface{}, error)
+
+func serve(h handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		decode := func(request interface{}) error {
+			if err := json.NewDecoder(r.Body).Decode(request); err != nil && err != io.EOF {
+				return fmt.Errorf("Invalid request %v", err)
+			}
+			return nil
+		}
+
+		response, err := h(decode)
+		w.Header().Set("Content-Type", pluginContentType)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			response = errorResponse{Err: err.Error()}
+		}
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// empty answers the requests the driver has nothing to do for
+func empty(decode func(interface{}) error) (interface{}, error) {
+	return struct{}{}, nil
+}
+
+// Handler returns the HTTP handler of the plugin
+func (driver *Driver) Handler() http.Handler {
+	mux := http.NewServeMux()
+	handlers := map[string]handler{
+		"/Plugin.Activate": func(decode func(interface{}) error) (interface{}, error) {
+			return activateResponse{Implements: []string{"NetworkDriver", "IpamDriver"}}, nil
+		},
+
+		"/NetworkDriver.GetCapabilities": func(decode func(interface{}) error) (interface{}, error) {
+			return capabilitiesResponse{Scope: "local", ConnectivityScope: "local"}, nil
+		},
+		"/NetworkDriver.CreateNetwork":               driver.createNetwork,
+		"/NetworkDriver.DeleteNetwork":               driver.deleteNetwork,
+		"/NetworkDriver.CreateEndpoint":              driver.createEndpoint,
+		"/NetworkDriver.EndpointOperInfo":            driver.endpointOperInfo,
+		"/NetworkDriver.DeleteEndpoint":              driver.deleteEndpoint,
+		"/NetworkDriver.Join":                        driver.join,
+		"/NetworkDriver.Leave":                       empty,
+		"/NetworkDriver.DiscoverNew":                 empty,
+		"/NetworkDriver.DiscoverDelete":              empty,
+		"/NetworkDriver.ProgramExternalConnectivity": empty,
+		"/NetworkDriver.RevokeExternalConnectivity":  empty,
+
+		"/IpamDriver.GetCapabilities": func(decode func(interface{}) error) (interface{}, error) {
+			return ipamCapabilitiesResponse{RequiresMACAddress: true}, nil
+		},
+		"/IpamDriver.GetDefaultAddressSpaces": func(decode func(interface{}) error) (interface{}, error) {
+			return addressSpacesResponse{LocalDefaultAddressSpace: "nuage-local",
+				GlobalDefaultAddressSpace: "nuage-global"}, nil
+		},
+		"/IpamDriver.RequestPool":    driver.requestPool,
+		"/IpamDriver.ReleasePool":    empty,
+		"/IpamDriver.RequestAddress": driver.requestAddress,
+		"/IpamDriver.ReleaseAddress": driver.releaseAddress,
+	}
+
+	for path, h := range handlers {
+		mux.HandleFunc(path, serve(h))
+	}
+
+	return mux
+}
+
+func (driver *Driver) createNetwork(decode func(interface{}) error) (interface{}, error) {
+	var request createNetworkRequest
+	if err := decode(&request); err != nil {
+		return nil, err
+	}
+
+	if len(request.IPv6Data) != 0 {
+		return nil, fmt.Errorf("IPv6 is not supported")
+	}
+	if len(request.IPv4Data) != 1 {
+		return nil, fmt.Errorf("A single IPv4 subnet is required")
+	}
+
+	nw := &network{
+		id:             request.NetworkID,
+		portMetadata:   make(map[port.MetadataKey]string),
+		entityMetadata: make(map[entity.MetadataKey]string),
+	}
+
+	var err error
+	if _, nw.pool, err = net.ParseCIDR(request.IPv4Data[0].Pool); err != nil {
+		return nil, fmt.Errorf("Invalid subnet %v", err)
+	}
+	if nw.gateway, _, err = net.ParseCIDR(request.IPv4Data[0].Gateway); err != nil {
+		return nil, fmt.Errorf("Invalid gateway %v", err)
+	}
+
+	generic, _ := request.Options[optionGeneric].(map[string]interface{})
+	for k, v := range generic {
+		value, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("Invalid option %s", k)
+		}
+		if key, ok := entityOptions[k]; ok {
+			nw.entityMetadata[key] = value
+		} else if key, ok := shortOptions[k]; ok {
+			nw.portMetadata[key] = value
+		} else {
+			nw.portMetadata[port.MetadataKey(k)] = value
+		}
+	}
+
+	metadata, err := port.ParseMetadata(nw.portMetadata)
+	if err == nil {
+		err = metadata.Validate()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	driver.mutex.Lock()
+	defer driver.mutex.Unlock()
+
+	if other, ok := driver.pools[nw.pool.String()]; ok {
+		return nil, fmt.Errorf("Subnet %s already used by network %s", nw.pool, other.id)
+	}
+	driver.networks[nw.id] = nw
+	driver.pools[nw.pool.String()] = nw
+	if err = saveNetworks(driver.stateFile, driver.networks); err != nil {
+		delete(driver.networks, nw.id)
+		delete(driver.pools, nw.pool.String())
+		return nil, err
+	}
+
+	return struct{}{}, nil
+}
+
+func (driver *Driver) deleteNetwork(decode func(interface{}) error) (interface{}, error) {
+	var request deleteNetworkRequest
+	if err := decode(&request); err != nil {
+		return nil, err
+	}
+
+	driver.mutex.Lock()
+	defer driver.mutex.Unlock()
+
+	nw, ok := driver.networks[request.NetworkID]
+	if !ok {
+		return struct{}{}, nil
+	}
+
+	delete(driver.pools, nw.pool.String())
+	delete(driver.networks, request.NetworkID)
+	if err := saveNetworks(driver.stateFile, driver.networks); err != nil {
+		driver.networks[nw.id] = nw
+		driver.pools[nw.pool.String()] = nw
+		return nil, err
+	}
+
+	return struct{}{}, nil
+}
+
+func (driver *Driver) createEndpoint(decode func(interface{}) error) (interface{}, error) {
+	var request createEndpointRequest
+	if err := decode(&request); err != nil {
+		return nil, err
+	}
+
+	if request.Interface == nil || request.Interface.MacAddress == "" {
+		return nil, fmt.Errorf("Network %s requires the nuage IPAM driver", request.NetworkID)
+	}
+	mac, err := net.ParseMAC(request.Interface.MacAddress)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid MAC address %v", err)
+	}
+
+	// The port and the entity are those created by the IPAM driver, the network does not need to be known so
+	// that the endpoints of the networks created before a restart can be attached
+	name := portName(mac)
+	ports, err := driver.vrsConnection.GetAllPorts()
+	if err != nil {
+		return nil, err
+	}
+	if !contains(ports, name) {
+		return nil, fmt.Errorf("Port %s of endpoint %s not created by the nuage IPAM driver", name,
+			request.EndpointID)
+	}
+	info := entityInfo(name, nil)
+
+	if err = driver.links.Setup(name, peerName(name), mac, driver.mtu); err != nil {
+		return nil, err
+	}
+
+	externalIDs := map[string]string{endpointIDKey: request.EndpointID}
+	if err = driver.vrsConnection.AddPortToAlubr0WithExternalIDs(name, info, externalIDs); err != nil {
+		driver.links.Teardown(name)
+		return nil, err
+	}
+
+	// The address and the MAC address were given by the IPAM driver, the interface must be left out
+	return createEndpointResponse{}, nil
+}
+
+// endpoint returns the port of the endpoint, or an empty name if the endpoint has no alubr0 port. The endpoint is
+// found from the external IDs of the port so that the endpoints created before a restart are found.
+func (driver *Driver) endpoint(id string) (string, error) {
+	return driver.vrsConnection.FindAlubr0Port(endpointIDKey, id)
+}
+
+func (driver *Driver) endpointOperInfo(decode func(interface{}) error) (interface{}, error) {
+	var request endpointRequest
+	if err := decode(&request); err != nil {
+		return nil, err
+	}
+
+	name, err := driver.endpoint(request.EndpointID)
+	if err != nil {
+		return nil, err
+	}
+	value := make(map[string]string)
+	if name != "" {
+		value["nuage-port"] = name
+	}
+
+	return endpointInfoResponse{Value: value}, nil
+}
+
+func (driver *Driver) deleteEndpoint(decode func(interface{}) error) (interface{}, error) {
+	var request endpointRequest
+	if err := decode(&request); err != nil {
+		return nil, err
+	}
+
+	name, err := driver.endpoint(request.EndpointID)
+	if err != nil || name == "" {
+		return struct{}{}, err
+	}
+
+	// The veth pair goes first, the port recording the endpoint is left for a retry if it cannot be deleted
+	if err = driver.links.Teardown(name); err != nil {
+		return nil, err
+	}
+	if err = driver.vrsConnection.RemovePortFromAlubr0(name); err != nil {
+		return nil, err
+	}
+
+	return struct{}{}, nil
+}
+
+func (driver *Driver) join(decode func(interface{}) error) (interface{}, error) {
+	var request joinRequest
+	if err := decode(&request); err != nil {
+		return nil, err
+	}
+
+	name, err := driver.endpoint(request.EndpointID)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		return nil, fmt.Errorf("Endpoint %s unknown", request.EndpointID)
+	}
+
+	// The gateway is the one resolved by the VRS for the port
+	state, err := driver.vrsConnection.GetPortState(name)
+	if err != nil {
+		return nil, err
+	}
+	gateway, _ := state[port.StateKeyGateway].(string)
+	if net.ParseIP(gateway) == nil {
+		return nil, fmt.Errorf("Invalid gateway %q of port %s", gateway, name)
+	}
+
+	return joinResponse{
+		InterfaceName: interfaceName{SrcName: peerName(name), DstPrefix: "eth"},
+		Gateway:       gateway,
+	}, nil
+}
+
+// entityInfo returns the entity of the port. Docker does not tell the driver the container of the endpoint,
+// each endpoint is an entity of its own whose UUID is derived from the name of the port.
+func entityInfo(name string, metadata map[entity.MetadataKey]string) api.EntityInfo {
+	return api.EntityInfo{
+		UUID:     entity.NameUUID(name),
+		Name:     name,
+		Type:     entity.Container,
+		Domain:   entity.Docker,
+		Ports:    []string{name},
+		Metadata: metadata,
+	}
+}
+
+// portName returns the name of the port of the endpoint using mac, which is also the name of the host end of
+// its veth pair
+func portName(mac net.HardwareAddr) string {
+	return "nu" + strings.Replace(mac.String(), ":", "", -1)
+}
+
+// peerName returns the name of the container end of the veth pair until Docker renames it
+func peerName(name string) string {
+	return "np" + name[2:]
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
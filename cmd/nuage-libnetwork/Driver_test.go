@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nuagenetworks/libvrsdk/api"
+	"github.com/nuagenetworks/libvrsdk/api/port"
+	"github.com/nuagenetworks/libvrsdk/ovsdb"
+	"github.com/nuagenetworks/libvrsdk/ovsdb/ovsdbtest"
+	"github.com/socketplane/libovsdb"
+)
+
+// fakeLinks records the veth pairs instead of creating them
+type fakeLinks map[string]string
+
+func (links fakeLinks) Setup(hostName string, peerName string, mac net.HardwareAddr, mtu int) error {
+	links[hostName] = mac.String()
+	return nil
+}
+
+func (links fakeLinks) Teardown(hostName string) error {
+	delete(links, hostName)
+	return nil
+}
+
+// resolvePort plays the part of the VRS, giving an address to the port once it is created
+func resolvePort(server *ovsdbtest.Server, name string, ip string) {
+	for i := 0; i < 100; i++ {
+		for _, row := range server.Rows(ovsdb.NuagePortTable) {
+			if row[ovsdb.NuagePortTableColumnName] == name {
+				server.Transact(libovsdb.Operation{
+					Op:    "update",
+					Table: ovsdb.NuagePortTable,
+					Where: []interface{}{libovsdb.NewCondition(ovsdb.NuagePortTableColumnName, "==", name)},
+					Row: map[string]interface{}{"ip_addr": ip, "subnet_mask": "255.255.255.0",
+						"gateway": "10.0.0.1"},
+				})
+				return
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// call posts request to the plugin and decodes the response, returning the error reported by the plugin
+func call(t *testing.T, url string, path string, request interface{}, response interface{}) string {
+	body, _ := json.Marshal(request)
+	resp, err := http.Post(url+path, pluginContentType, bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Unable to call %s %v", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var e errorResponse
+		json.NewDecoder(resp.Body).Decode(&e)
+		return e.Err
+	}
+	if response != nil {
+		if err = json.NewDecoder(resp.Body).Decode(response); err != nil {
+			t.Fatalf("Invalid response to %s %v", path, err)
+		}
+	}
+	return ""
+}
+
+// startPlugin serves a driver saving its networks in stateFile
+func startPlugin(t *testing.T, vrsConnection api.VRSConnection, links Links, stateFile string) *httptest.Server {
+	driver, err := NewDriver(vrsConnection, links, 0, 5*time.Second, stateFile)
+	if err != nil {
+		t.Fatalf("Unable to create the driver %v", err)
+	}
+	return httptest.NewServer(driver.Handler())
+}
+
+func TestDriver(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nuage-libnetwork")
+	if err != nil {
+		t.Fatalf("Unable to create the state directory %v", err)
+	}
+	defer os.RemoveAll(dir)
+	stateFile := filepath.Join(dir, "networks.json")
+
+	server, err := ovsdbtest.NewServer()
+	if err != nil {
+		t.Fatalf("Unable to start the OVSDB server %v", err)
+	}
+	defer server.Close()
+
+	bridgeOp := libovsdb.Operation{Op: "insert", Table: "Bridge", Row: map[string]interface{}{"name": "alubr0"}}
+	if _, err = server.Transact(bridgeOp); err != nil {
+		t.Fatalf("Unable to create alubr0 %v", err)
+	}
+
+	vrsConnection, err := api.NewUnixSocketConnection(server.SocketPath())
+	if err != nil {
+		t.Fatalf("Unable to connect to the VRS %v", err)
+	}
+	defer vrsConnection.Disconnect()
+
+	links := fakeLinks{}
+	plugin := startPlugin(t, vrsConnection, links, stateFile)
+	defer plugin.Close()
+
+	// docker network create
+	var pool requestPoolResponse
+	if e := call(t, plugin.URL, "/IpamDriver.RequestPool", requestPoolRequest{Pool: "10.0.0.0/24"}, &pool); e != "" {
+		t.Fatalf("Unable to request the pool %s", e)
+	}
+	var gateway requestAddressResponse
+	gatewayRequest := requestAddressRequest{PoolID: pool.PoolID,
+		Options: map[string]string{optionAddressType: addressTypeGW}}
+	if e := call(t, plugin.URL, "/IpamDriver.RequestAddress", gatewayRequest, &gateway); e != "" ||
+		gateway.Address != "10.0.0.1/24" {
+		t.Fatalf("Unable to request the gateway %s %s", gateway.Address, e)
+	}
+
+	options := map[string]interface{}{optionGeneric: map[string]interface{}{"enterprise": "enterprise",
+		"user": "user", "domain": "domain", "zone": "zone", "network": "network"}}
+	createNetwork := createNetworkRequest{NetworkID: "net", Options: options,
+		IPv4Data: []ipamData{{Pool: pool.Pool, Gateway: gateway.Address}}}
+	if e := call(t, plugin.URL, "/NetworkDriver.CreateNetwork", createNetwork, nil); e != "" {
+		t.Fatalf("Unable to create the network %s", e)
+	}
+
+	invalid := createNetworkRequest{NetworkID: "invalid", IPv4Data: createNetwork.IPv4Data,
+		Options: map[string]interface{}{optionGeneric: map[string]interface{}{"network-type": "ipv5"}}}
+	if e := call(t, plugin.URL, "/NetworkDriver.CreateNetwork", invalid, nil); e == "" {
+		t.Fatalf("Network created with invalid metadata")
+	}
+
+	// docker run --network
+	mac := "76:22:f6:70:4e:47"
+	name := portName(net.HardwareAddr{0x76, 0x22, 0xf6, 0x70, 0x4e, 0x47})
+	go resolvePort(server, name, "10.0.0.2")
+	var address requestAddressResponse
+	addressRequest := requestAddressRequest{PoolID: pool.PoolID, Options: map[string]string{optionMACAddress: mac}}
+	if e := call(t, plugin.URL, "/IpamDriver.RequestAddress", addressRequest, &address); e != "" ||
+		address.Address != "10.0.0.2/24" {
+		t.Fatalf("Unable to request the address %s %s", address.Address, e)
+	}
+
+	specs, err := vrsConnection.GetAllPortSpecs()
+	if err != nil || len(specs) != 1 || specs[0].Name != name ||
+		specs[0].Metadata[port.MetadataKeyDomain] != "domain" {
+		t.Fatalf("Unexpected ports %+v %v", specs, err)
+	}
+
+	createEndpoint := createEndpointRequest{NetworkID: "net", EndpointID: "ep",
+		Interface: &endpointInterface{Address: address.Address, MacAddress: mac}}
+	var endpoint createEndpointResponse
+	if e := call(t, plugin.URL, "/NetworkDriver.CreateEndpoint", createEndpoint, &endpoint); e != "" ||
+		endpoint.Interface != nil {
+		t.Fatalf("Unable to create the endpoint %+v %s", endpoint.Interface, e)
+	}
+	if links[name] != mac {
+		t.Fatalf("Unexpected veth pairs %v", links)
+	}
+
+	// The endpoints created before a restart are found from their alubr0 port, and the network is loaded from
+	// the state file, by a restarted plugin
+	restarted := startPlugin(t, vrsConnection, links, stateFile)
+	defer restarted.Close()
+
+	var info endpointInfoResponse
+	if e := call(t, restarted.URL, "/NetworkDriver.EndpointOperInfo",
+		endpointRequest{NetworkID: "net", EndpointID: "ep"}, &info); e != "" || info.Value["nuage-port"] != name {
+		t.Fatalf("Unexpected endpoint info %v %s", info.Value, e)
+	}
+
+	var join joinResponse
+	joinRequest := joinRequest{NetworkID: "net", EndpointID: "ep", SandboxKey: "/var/run/docker/netns/sandbox"}
+	if e := call(t, restarted.URL, "/NetworkDriver.Join", joinRequest, &join); e != "" ||
+		join.InterfaceName.SrcName != peerName(name) || join.Gateway != "10.0.0.1" {
+		t.Fatalf("Unable to join %+v %s", join, e)
+	}
+
+	otherMAC := "76:22:f6:70:4e:48"
+	otherName := portName(net.HardwareAddr{0x76, 0x22, 0xf6, 0x70, 0x4e, 0x48})
+	go resolvePort(server, otherName, "10.0.0.3")
+	var other requestAddressResponse
+	otherRequest := requestAddressRequest{PoolID: pool.PoolID,
+		Options: map[string]string{optionMACAddress: otherMAC}}
+	if e := call(t, restarted.URL, "/IpamDriver.RequestAddress", otherRequest, &other); e != "" ||
+		other.Address != "10.0.0.3/24" {
+		t.Fatalf("Unable to request an address after the restart %s %s", other.Address, e)
+	}
+
+	createOther := createEndpointRequest{NetworkID: "net", EndpointID: "other",
+		Interface: &endpointInterface{Address: other.Address, MacAddress: otherMAC}}
+	if e := call(t, restarted.URL, "/NetworkDriver.CreateEndpoint", createOther, nil); e != "" {
+		t.Fatalf("Unable to create an endpoint after the restart %s", e)
+	}
+
+	bridgePorts, err := vrsConnection.GetAlubr0Ports()
+	if err != nil || len(bridgePorts) != 2 || !contains(bridgePorts, name) || !contains(bridgePorts, otherName) {
+		t.Fatalf("Unexpected alubr0 ports %v %v", bridgePorts, err)
+	}
+
+	// docker rm
+	for _, endpoint := range []struct {
+		id      string
+		address string
+	}{{"ep", "10.0.0.2"}, {"other", "10.0.0.3"}} {
+		if e := call(t, restarted.URL, "/NetworkDriver.Leave",
+			endpointRequest{NetworkID: "net", EndpointID: endpoint.id}, nil); e != "" {
+			t.Fatalf("Unable to leave %s", e)
+		}
+		for i := 0; i < 2; i++ {
+			if e := call(t, restarted.URL, "/NetworkDriver.DeleteEndpoint",
+				endpointRequest{NetworkID: "net", EndpointID: endpoint.id}, nil); e != "" {
+				t.Fatalf("Unable to delete the endpoint %s", e)
+			}
+			if e := call(t, restarted.URL, "/IpamDriver.ReleaseAddress",
+				releaseAddressRequest{PoolID: pool.PoolID, Address: endpoint.address}, nil); e != "" {
+				t.Fatalf("Unable to release the address %s", e)
+			}
+		}
+	}
+
+	if ports, err := vrsConnection.GetAllPorts(); err != nil || len(ports) != 0 {
+		t.Fatalf("Ports left after release %v %v", ports, err)
+	}
+	if entities, err := vrsConnection.GetAllEntities(); err != nil || len(entities) != 0 {
+		t.Fatalf("Entities left after release %v %v", entities, err)
+	}
+	if bridgePorts, err = vrsConnection.GetAlubr0Ports(); err != nil || len(bridgePorts) != 0 {
+		t.Fatalf("alubr0 ports left after release %v %v", bridgePorts, err)
+	}
+	if len(links) != 0 {
+		t.Fatalf("veth pairs left after release %v", links)
+	}
+
+	// An address outside of the subnet is refused and its port destroyed
+	go resolvePort(server, name, "192.168.0.2")
+	if e := call(t, plugin.URL, "/IpamDriver.RequestAddress", addressRequest, nil); e == "" {
+		t.Fatalf("Address outside of the subnet given")
+	}
+	if ports, err := vrsConnection.GetAllPorts(); err != nil || len(ports) != 0 {
+		t.Fatalf("Ports left after a failed request %v %v", ports, err)
+	}
+
+	// The deleted network is removed from the state file
+	if e := call(t, restarted.URL, "/NetworkDriver.DeleteNetwork", deleteNetworkRequest{NetworkID: "net"},
+		nil); e != "" {
+		t.Fatalf("Unable to delete the network %s", e)
+	}
+	deleted := startPlugin(t, vrsConnection, links, stateFile)
+	defer deleted.Close()
+	if e := call(t, deleted.URL, "/IpamDriver.RequestAddress", otherRequest, nil); e == "" {
+		t.Fatalf("Address given in a deleted network")
+	}
+}
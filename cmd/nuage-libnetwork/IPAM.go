@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/nuagenetworks/libvrsdk/api"
+	"github.com/nuagenetworks/libvrsdk/api/entity"
+	"github.com/nuagenetworks/libvrsdk/api/port"
+)
+
+// The pools are the subnets given to docker network create, identified by their CIDR
+
+func (driver *Driver) requestPool(decode func(interface{}) error) (interface{}, error) {
+	var request requestPoolRequest
+	if err := decode(&request); err != nil {
+		return nil, err
+	}
+
+	if request.V6 {
+		return nil, fmt.Errorf("IPv6 is not supported")
+	}
+	if request.Pool == "" {
+		return nil, fmt.Errorf("The subnet of the Nuage network is required")
+	}
+
+	_, pool, err := net.ParseCIDR(request.Pool)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid subnet %v", err)
+	}
+
+	return requestPoolResponse{PoolID: pool.String(), Pool: pool.String(), Data: map[string]string{}}, nil
+}
+
+func (driver *Driver) requestAddress(decode func(interface{}) error) (interface{}, error) {
+	var request requestAddressRequest
+	if err := decode(&request); err != nil {
+		return nil, err
+	}
+
+	_, pool, err := net.ParseCIDR(request.PoolID)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid pool %v", err)
+	}
+
+	// The gateway is requested while the network is created, it is the first address unless given
+	if request.Options[optionAddressType] == addressTypeGW {
+		gateway := nextIP(pool.IP)
+		if request.Address != "" {
+			if gateway = net.ParseIP(request.Address); gateway == nil || !pool.Contains(gateway) {
+				return nil, fmt.Errorf("Invalid gateway %s", request.Address)
+			}
+		}
+		return requestAddressResponse{Address: (&net.IPNet{IP: gateway, Mask: pool.Mask}).String()}, nil
+	}
+
+	mac, err := net.ParseMAC(request.Options[optionMACAddress])
+	if err != nil {
+		return nil, fmt.Errorf("Invalid MAC address %v", err)
+	}
+
+	driver.mutex.Lock()
+	nw, ok := driver.pools[pool.String()]
+	driver.mutex.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("Network of subnet %s unknown", pool)
+	}
+
+	ip, err := driver.attach(nw, mac, request.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	return requestAddressResponse{Address: (&net.IPNet{IP: ip, Mask: pool.Mask}).String()}, nil
+}
+
+// attach creates the port and the entity of the endpoint using mac and returns the address resolved by the VRS
+func (driver *Driver) attach(nw *network, mac net.HardwareAddr, address string) (net.IP, error) {
+	name := portName(mac)
+	info := entityInfo(name, nw.entityMetadata)
+
+	metadata := make(map[port.MetadataKey]string)
+	for k, v := range nw.portMetadata {
+		metadata[k] = v
+	}
+	if address != "" {
+		metadata[port.MetadataKeyStaticIP] = address
+	}
+
+	resolved := make(chan *api.PortIPv4Info, 1)
+	if err := driver.vrsConnection.RegisterForPortUpdates(name, resolved); err != nil {
+		return nil, fmt.Errorf("Unable to register for the updates of port %s %v", name, err)
+	}
+	defer driver.vrsConnection.DeregisterChannelForPortUpdates(name, resolved)
+
+	ip, err := func() (net.IP, error) {
+		attributes := port.Attributes{MAC: mac.String(), Platform: entity.Docker, Bridge: "alubr0"}
+		if err := driver.vrsConnection.CreatePort(name, attributes, metadata); err != nil {
+			return nil, err
+		}
+		if err := driver.vrsConnection.CreateEntity(info); err != nil {
+			return nil, err
+		}
+
+		var portInfo *api.PortIPv4Info
+		select {
+		case portInfo = <-resolved:
+		case <-time.After(driver.resolutionTimeout):
+			return nil, fmt.Errorf("Port %s not resolved by the VRS within %s", name, driver.resolutionTimeout)
+		}
+		if !portInfo.Registered {
+			return nil, fmt.Errorf("Port %s deleted before its resolution", name)
+		}
+
+		ip := net.ParseIP(portInfo.IPAddr)
+		if ip == nil || !nw.pool.Contains(ip) {
+			return nil, fmt.Errorf("Address %q resolved by the VRS outside of subnet %s", portInfo.IPAddr, nw.pool)
+		}
+		return ip, nil
+	}()
+	if err != nil {
+		driver.detach(name)
+		return nil, err
+	}
+
+	return ip, nil
+}
+
+func (driver *Driver) releaseAddress(decode func(interface{}) error) (interface{}, error) {
+	var request releaseAddressRequest
+	if err := decode(&request); err != nil {
+		return nil, err
+	}
+
+	_, pool, err := net.ParseCIDR(request.PoolID)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid pool %v", err)
+	}
+	if !pool.Contains(net.ParseIP(request.Address)) {
+		return nil, fmt.Errorf("Address %s outside of subnet %s", request.Address, pool)
+	}
+
+	// The port is found from its address on the VRS, without the network, so that the addresses given before a
+	// restart can be released. The gateway and the addresses of the released ports have no port.
+	ports, err := driver.vrsConnection.GetAllPorts()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range ports {
+		if !strings.HasPrefix(name, "nu") {
+			continue
+		}
+		state, err := driver.vrsConnection.GetPortState(name)
+		if err != nil {
+			return nil, err
+		}
+		if state[port.StateKeyIPAddress] == request.Address {
+			return struct{}{}, driver.detach(name)
+		}
+	}
+
+	return struct{}{}, nil
+}
+
+// detach destroys the entity and the port of the endpoint, if any
+func (driver *Driver) detach(name string) error {
+	uuid := entity.NameUUID(name)
+
+	exists, err := driver.vrsConnection.CheckEntityExists(uuid)
+	if err != nil {
+		return err
+	}
+	if exists {
+		if err = driver.vrsConnection.DestroyEntity(uuid); err != nil {
+			return err
+		}
+	}
+
+	ports, err := driver.vrsConnection.GetAllPorts()
+	if err != nil {
+		return err
+	}
+	if contains(ports, name) {
+		return driver.vrsConnection.DestroyPort(name)
+	}
+
+	return nil
+}
+
+// nextIP returns the address following ip
+func nextIP(ip net.IP) net.IP {
+	next := make(net.IP, len(ip))
+	copy(next, ip)
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			break
+		}
+	}
+	return next
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/vishvananda/netlink"
+)
+
+// Links manages the veth pairs of the endpoints. Docker moves the peer into the sandbox on Join.
+type Links interface {
+	// Setup creates the veth pair hostName/peerName, the peer using mac
+	Setup(hostName string, peerName string, mac net.HardwareAddr, mtu int) error
+	// Teardown deletes the veth pair, if any
+	Teardown(hostName string) error
+}
+
+// netlinkLinks manages the veth pairs with netlink
+type netlinkLinks struct{}
+
+func (netlinkLinks) Setup(hostName string, peerName string, mac net.HardwareAddr, mtu int) error {
+	veth := &netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: hostName, MTU: mtu}, PeerName: peerName,
+		PeerHardwareAddr: mac}
+	if err := netlink.LinkAdd(veth); err != nil {
+		return fmt.Errorf("Unable to create the veth pair %s %v", hostName, err)
+	}
+
+	if err := netlink.LinkSetUp(veth); err != nil {
+		netlink.LinkDel(veth)
+		return fmt.Errorf("Unable to set up the veth pair %s %v", hostName, err)
+	}
+
+	return nil
+}
+
+func (netlinkLinks) Teardown(hostName string) error {
+	link, err := netlink.LinkByName(hostName)
+	if err != nil {
+		if _, ok := err.(netlink.LinkNotFoundError); ok {
+			return nil
+		}
+		return err
+	}
+
+	return netlink.LinkDel(link)
+}
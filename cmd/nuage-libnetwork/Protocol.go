@@ -0,0 +1,128 @@
+package main
+
+// The requests and responses of the libnetwork remote driver and remote IPAM protocols
+
+// Options set by Docker
+const (
+	optionGeneric     = "com.docker.network.generic"
+	optionMACAddress  = "com.docker.network.endpoint.macaddress"
+	optionAddressType = "RequestAddressType"
+	addressTypeGW     = "com.docker.network.gateway"
+)
+
+type errorResponse struct {
+	Err string
+}
+
+type activateResponse struct {
+	Implements []string
+}
+
+type capabilitiesResponse struct {
+	Scope             string
+	ConnectivityScope string
+}
+
+type ipamData struct {
+	AddressSpace string
+	Pool         string
+	Gateway      string
+	AuxAddresses map[string]string
+}
+
+type createNetworkRequest struct {
+	NetworkID string
+	Options   map[string]interface{}
+	IPv4Data  []ipamData
+	IPv6Data  []ipamData
+}
+
+type deleteNetworkRequest struct {
+	NetworkID string
+}
+
+type endpointInterface struct {
+	Address     string
+	AddressIPv6 string
+	MacAddress  string
+}
+
+type createEndpointRequest struct {
+	NetworkID  string
+	EndpointID string
+	Interface  *endpointInterface
+	Options    map[string]interface{}
+}
+
+type createEndpointResponse struct {
+	Interface *endpointInterface
+}
+
+type endpointRequest struct {
+	NetworkID  string
+	EndpointID string
+}
+
+type endpointInfoResponse struct {
+	Value map[string]string
+}
+
+type joinRequest struct {
+	NetworkID  string
+	EndpointID string
+	SandboxKey string
+	Options    map[string]interface{}
+}
+
+type interfaceName struct {
+	SrcName   string
+	DstPrefix string
+}
+
+type joinResponse struct {
+	InterfaceName interfaceName
+	Gateway       string
+}
+
+type ipamCapabilitiesResponse struct {
+	RequiresMACAddress bool
+}
+
+type addressSpacesResponse struct {
+	LocalDefaultAddressSpace  string
+	GlobalDefaultAddressSpace string
+}
+
+type requestPoolRequest struct {
+	AddressSpace string
+	Pool         string
+	SubPool      string
+	Options      map[string]string
+	V6           bool
+}
+
+type requestPoolResponse struct {
+	PoolID string
+	Pool   string
+	Data   map[string]string
+}
+
+type releasePoolRequest struct {
+	PoolID string
+}
+
+type requestAddressRequest struct {
+	PoolID  string
+	Address string
+	Options map[string]string
+}
+
+type requestAddressResponse struct {
+	Address string
+	Data    map[string]string
+}
+
+type releaseAddressRequest struct {
+	PoolID  string
+	Address string
+}
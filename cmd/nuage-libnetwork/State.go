@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/nuagenetworks/libvrsdk/api/entity"
+	"github.com/nuagenetworks/libvrsdk/api/port"
+)
+
+// networkState is a network as saved in the state file. Docker does not create the networks again when the
+// plugin restarts, the state file gives them back to the IPAM driver.
+type networkState struct {
+	ID             string                        `json:"id"`
+	Pool           string                        `json:"pool"`
+	Gateway        string                        `json:"gateway"`
+	PortMetadata   map[port.MetadataKey]string   `json:"portMetadata"`
+	EntityMetadata map[entity.MetadataKey]string `json:"entityMetadata"`
+}
+
+// loadNetworks reads the networks saved in the state file at path, none if it does not exist yet
+func loadNetworks(path string) ([]*network, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read the state file %v", err)
+	}
+
+	var states []networkState
+	if err = json.Unmarshal(data, &states); err != nil {
+		return nil, fmt.Errorf("Invalid state file %s %v", path, err)
+	}
+
+	networks := make([]*network, 0, len(states))
+	for _, state := range states {
+		nw := &network{id: state.ID, portMetadata: state.PortMetadata, entityMetadata: state.EntityMetadata}
+		if _, nw.pool, err = net.ParseCIDR(state.Pool); err != nil {
+			return nil, fmt.Errorf("Invalid subnet of network %s %v", state.ID, err)
+		}
+		if nw.gateway = net.ParseIP(state.Gateway); nw.gateway == nil {
+			return nil, fmt.Errorf("Invalid gateway %q of network %s", state.Gateway, state.ID)
+		}
+		if nw.portMetadata == nil {
+			nw.portMetadata = make(map[port.MetadataKey]string)
+		}
+		if nw.entityMetadata == nil {
+			nw.entityMetadata = make(map[entity.MetadataKey]string)
+		}
+		networks = append(networks, nw)
+	}
+
+	return networks, nil
+}
+
+// saveNetworks replaces the state file at path with the networks. The file is written aside and renamed so that
+// a crash leaves either the previous networks or the new ones.
+func saveNetworks(path string, networks map[string]*network) error {
+	states := make([]networkState, 0, len(networks))
+	for _, nw := range networks {
+		states = append(states, networkState{ID: nw.id, Pool: nw.pool.String(), Gateway: nw.gateway.String(),
+			PortMetadata: nw.portMetadata, EntityMetadata: nw.entityMetadata})
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].ID < states[j].ID })
+
+	data, err := json.MarshalIndent(states, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Unable to encode the networks %v", err)
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("Unable to create the state directory %v", err)
+	}
+	file, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".")
+	if err != nil {
+		return fmt.Errorf("Unable to write the state file %v", err)
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), path)
+	}
+	if err != nil {
+		return fmt.Errorf("Unable to write the state file %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,24 @@
+/*
+Nuage-libnetwork is a Docker libnetwork remote network and IPAM driver attaching the containers to the Nuage VRS
+through the SDK.
+
+The networks are created with the nuage driver for both the network and the IPAM, the subnet of the Nuage network
+and its metadata as options:
+
+	docker network create --driver nuage --ipam-driver nuage --subnet 10.0.0.0/24 \
+		-o enterprise=enterprise -o user=user -o domain=domain -o zone=zone -o network=network nuage
+
+The domain, zone, network and network-type options stand for the nuage-enterprise-* port metadata, enterprise
+and user for the entity metadata, any other option is passed as is in the port metadata.
+
+The IPAM driver creates the port and the entity of an endpoint, named after the MAC address generated by Docker,
+and gives Docker the address resolved by the VRS. The network driver then creates the veth pair of the endpoint,
+attaches its host end to alubr0 and hands its other end to Docker on Join. The entity and the port are destroyed
+when Docker releases the address.
+
+Docker does not create the networks again when the plugin restarts, they are saved in the file given by -state and
+loaded from it on start. The endpoints are attached, joined and released from the ports and the entities found on
+the VRS, the alubr0 port of an endpoint recording its ID in its external IDs, so that the containers started before
+a restart can still be connected and removed.
+*/
+package main
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/nuagenetworks/libvrsdk/api"
+	"github.com/nuagenetworks/libvrsdk/logging"
+)
+
+func main() {
+	socket := flag.String("socket", "/run/docker/plugins/nuage.sock", "Unix socket the plugin listens on")
+	vrsSocket := flag.String("vrs", "/var/run/openvswitch/db.sock", "Unix socket of the VRS OVSDB server")
+	mtu := flag.Int("mtu", 0, "MTU of the veth pairs, the default of the kernel if 0")
+	stateFile := flag.String("state", "/var/lib/nuage-libnetwork/networks.json",
+		"File the networks are saved in across restarts")
+	resolutionTimeout := flag.Duration("resolution-timeout", defaultResolutionTimeout,
+		"Time to wait for the VRS to resolve a port")
+	flag.Parse()
+
+	logger := logging.Default()
+
+	vrsConnection, err := api.NewUnixSocketConnection(*vrsSocket)
+	if err != nil {
+		logger.Error("Unable to connect to the VRS", logging.F("socket", *vrsSocket), logging.Err(err))
+		os.Exit(1)
+	}
+	defer vrsConnection.Disconnect()
+
+	driver, err := NewDriver(vrsConnection, netlinkLinks{}, *mtu, *resolutionTimeout, *stateFile)
+	if err != nil {
+		logger.Error("Unable to load the networks", logging.F("state", *stateFile), logging.Err(err))
+		os.Exit(1)
+	}
+
+	os.Remove(*socket)
+	listener, err := net.Listen("unix", *socket)
+	if err != nil {
+		logger.Error("Unable to listen", logging.F("socket", *socket), logging.Err(err))
+		os.Exit(1)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-signals
+		listener.Close()
+	}()
+
+	if err = http.Serve(listener, driver.Handler()); err != nil {
+		logger.Info("Plugin stopped", logging.Err(err))
+	}
+}
@@ -0,0 +1,390 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+)
+
+// ovsdbPackage holds the decoding helpers and the Capabilities used by the generated code
+const ovsdbPackage = "github.com/nuagenetworks/libvrsdk/ovsdb"
+
+// Config selects the tables to generate and overrides the names and types derived from the schema
+type Config struct {
+	Package string                 `json:"package"`
+	Tables  map[string]TableConfig `json:"tables"`
+}
+
+// TableConfig overrides the names of a table. Prefix names the table constant and starts the column
+// constants, Type names the row struct.
+type TableConfig struct {
+	File         string                  `json:"file"`
+	Prefix       string                  `json:"prefix"`
+	Type         string                  `json:"type"`
+	Imports      []string                `json:"imports"`
+	Capabilities bool                    `json:"capabilities"`
+	Columns      map[string]ColumnConfig `json:"columns"`
+}
+
+// ColumnConfig overrides the names and the Go type of a column
+type ColumnConfig struct {
+	Field    string `json:"field"`
+	Constant string `json:"constant"`
+	Type     string `json:"type"`
+	Skip     bool   `json:"skip"`
+}
+
+// initialisms are the words of the table and column names written in upper case
+var initialisms = map[string]bool{
+	"id": true, "ip": true, "mac": true, "uuid": true, "vm": true, "evpn": true, "vrf": true,
+}
+
+// camelCase converts an OVSDB name such as nuage_network_type to NuageNetworkType
+func camelCase(name string) string {
+	var b strings.Builder
+	for _, word := range strings.Split(name, "_") {
+		if word == "" {
+			continue
+		}
+		if initialisms[strings.ToLower(word)] {
+			b.WriteString(strings.ToUpper(word))
+		} else {
+			b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+	return b.String()
+}
+
+// atomic describes how an OVSDB atomic type maps to Go
+type atomic struct {
+	goType  string
+	zero    string
+	decoder string
+}
+
+var atomics = map[string]atomic{
+	"string":  {goType: "string", zero: `""`, decoder: "UnMarshallOVSString"},
+	"integer": {goType: "int", zero: "0", decoder: "UnMarshallOVSInt"},
+	"real":    {goType: "float64", zero: "0", decoder: "UnMarshallOVSReal"},
+	"boolean": {goType: "bool", zero: "false", decoder: "UnMarshallOVSBool"},
+	"uuid":    {goType: "string", zero: `""`, decoder: "UnMarshallOVSUUID"},
+}
+
+// column is a column being generated
+type column struct {
+	name     string
+	constant string
+	field    string
+	kind     string
+	key      string
+	value    string
+	goType   string
+	named    bool
+}
+
+// table is a table being generated
+type table struct {
+	name         string
+	prefix       string
+	typeName     string
+	imports      []string
+	capabilities bool
+	columns      []column
+}
+
+func newTable(name string, schema TableSchema, config TableConfig) (*table, error) {
+	t := &table{
+		name:         name,
+		prefix:       config.Prefix,
+		typeName:     config.Type,
+		imports:      config.Imports,
+		capabilities: config.Capabilities,
+	}
+	if t.prefix == "" {
+		t.prefix = camelCase(name)
+	}
+	if t.typeName == "" {
+		t.typeName = t.prefix + "Row"
+	}
+
+	for columnName := range config.Columns {
+		if _, ok := schema.Columns[columnName]; !ok {
+			return nil, fmt.Errorf("Column %s of table %s is not in the schema", columnName, name)
+		}
+	}
+
+	// The columns keep the order of the schema, the schemas built in memory have none and are sorted
+	names := schema.Order
+	if len(names) != len(schema.Columns) {
+		names = make([]string, 0, len(schema.Columns))
+		for columnName := range schema.Columns {
+			names = append(names, columnName)
+		}
+		sort.Strings(names)
+	}
+
+	for _, columnName := range names {
+		columnConfig := config.Columns[columnName]
+		if columnConfig.Skip {
+			continue
+		}
+
+		columnType := schema.Columns[columnName].Type
+		c := column{
+			name:     columnName,
+			constant: columnConfig.Constant,
+			field:    columnConfig.Field,
+			kind:     columnType.Kind(),
+			key:      columnType.Key.Type,
+		}
+		if c.constant == "" {
+			c.constant = t.prefix + "Column" + camelCase(columnName)
+		}
+		if c.field == "" {
+			c.field = camelCase(columnName)
+		}
+
+		if _, ok := atomics[c.key]; !ok {
+			return nil, fmt.Errorf("Column %s of table %s has an unknown type %s", columnName, name, c.key)
+		}
+		if columnType.Value != nil {
+			c.value = columnType.Value.Type
+			if _, ok := atomics[c.value]; !ok {
+				return nil, fmt.Errorf("Column %s of table %s has an unknown type %s", columnName, name, c.value)
+			}
+		}
+
+		switch c.kind {
+		case kindScalar, kindOptional:
+			c.goType = atomics[c.key].goType
+			if columnConfig.Type != "" {
+				c.goType = columnConfig.Type
+				c.named = true
+			}
+		case kindSet:
+			c.goType = "[]" + atomics[c.key].goType
+		case kindMap:
+			c.goType = "map[" + atomics[c.key].goType + "]" + atomics[c.value].goType
+		}
+		if columnConfig.Type != "" && !c.named {
+			return nil, fmt.Errorf("Column %s of table %s holds several values, its type cannot be replaced",
+				columnName, name)
+		}
+
+		t.columns = append(t.columns, c)
+	}
+
+	return t, nil
+}
+
+// generator writes the code of a table
+type generator struct {
+	b         bytes.Buffer
+	qualifier string
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.b, format, args...)
+}
+
+// encode returns the expression encoding the Go value of an atom of the given type
+func (g *generator) encode(atomicType string, value string, named bool) string {
+	if atomicType == "uuid" {
+		return "libovsdb.UUID{GoUUID: " + value + "}"
+	}
+	if named {
+		return atomics[atomicType].goType + "(" + value + ")"
+	}
+	return value
+}
+
+// decode writes the statements decoding the atom held by source into target, returning onError on failure
+func (g *generator) decode(atomicType string, source string, target string, onError string) {
+	g.printf("%s, err := %s%s(%s)\n", target, g.qualifier, atomics[atomicType].decoder, source)
+	g.printf("if err != nil {\nreturn %s\n}\n", onError)
+}
+
+// convert returns the expression converting a decoded atom to the type of the column
+func convert(c column, value string) string {
+	if c.named {
+		return c.goType + "(" + value + ")"
+	}
+	return value
+}
+
+// Generate returns the formatted source of the file describing a table
+func Generate(packageName string, schemaName string, t *table) ([]byte, error) {
+	g := &generator{}
+	if packageName != "ovsdb" {
+		g.qualifier = "ovsdb."
+	}
+
+	g.printf("// Code generated by ovsdbgen from %s. DO NOT EDIT.\n\npackage %s\n\n", schemaName, packageName)
+	g.writeImports(packageName, t)
+	g.writeConstants(t)
+	g.writeStruct(t)
+	g.writeEquals(t)
+	g.writeCreate(t)
+	g.writeRead(t)
+
+	source, err := format.Source(g.b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("Unable to format the code of table %s %v", t.name, err)
+	}
+
+	return source, nil
+}
+
+func (g *generator) writeImports(packageName string, t *table) {
+	standard := []string{"fmt"}
+	var others []string
+
+	var libovsdb, reflect bool
+	for _, c := range t.columns {
+		switch c.kind {
+		case kindOptional:
+			libovsdb = true
+		case kindSet, kindMap:
+			libovsdb, reflect = true, true
+		}
+		if c.key == "uuid" || c.value == "uuid" {
+			libovsdb = true
+		}
+	}
+
+	if reflect {
+		standard = append(standard, "reflect")
+	}
+	if libovsdb {
+		others = append(others, "github.com/socketplane/libovsdb")
+	}
+	if packageName != "ovsdb" {
+		others = append(others, ovsdbPackage)
+	}
+	others = append(others, t.imports...)
+	sort.Strings(others)
+
+	g.printf("import (\n")
+	for _, path := range standard {
+		g.printf("%q\n", path)
+	}
+	if len(others) != 0 {
+		g.printf("\n")
+	}
+	for _, path := range others {
+		g.printf("%q\n", path)
+	}
+	g.printf(")\n\n")
+}
+
+func (g *generator) writeConstants(t *table) {
+	g.printf("// These constants describe the %s\nconst (\n", t.name)
+	g.printf("%s = %q\n\n", t.prefix, t.name)
+	for _, c := range t.columns {
+		g.printf("%s = %q\n", c.constant, c.name)
+	}
+	g.printf(")\n\n")
+}
+
+func (g *generator) writeStruct(t *table) {
+	g.printf("// %s represents a row in the %s\ntype %s struct {\n", t.typeName, t.name, t.typeName)
+	for _, c := range t.columns {
+		g.printf("%s %s\n", c.field, c.goType)
+	}
+	if t.capabilities {
+		g.printf("\n// Capabilities of the VRS, the optional columns it does not support are left out of the OVSDB row\n")
+		g.printf("Capabilities %sCapabilities\n", g.qualifier)
+	}
+	g.printf("}\n\n")
+}
+
+func (g *generator) writeEquals(t *table) {
+	g.printf("// Equals checks for equality of two rows in the %s\n", t.name)
+	g.printf("func (row *%s) Equals(otherRow interface{}) bool {\n", t.typeName)
+	g.printf("other, ok := otherRow.(%s)\nif !ok {\n", t.typeName)
+	g.printf("pointer, ok := otherRow.(*%s)\nif !ok || pointer == nil {\nreturn false\n}\nother = *pointer\n}\n\n", t.typeName)
+	for _, c := range t.columns {
+		if c.kind == kindSet || c.kind == kindMap {
+			g.printf("if !reflect.DeepEqual(row.%s, other.%s) {\nreturn false\n}\n\n", c.field, c.field)
+		} else {
+			g.printf("if row.%s != other.%s {\nreturn false\n}\n\n", c.field, c.field)
+		}
+	}
+	g.printf("return true\n}\n\n")
+}
+
+func (g *generator) writeCreate(t *table) {
+	g.printf("// CreateOVSDBRow creates a OVSDB row for the %s\n", t.name)
+	g.printf("func (row *%s) CreateOVSDBRow(ovsdbRow map[string]interface{}) error {\n", t.typeName)
+	for _, c := range t.columns {
+		field := "row." + c.field
+		switch c.kind {
+		case kindScalar:
+			g.printf("ovsdbRow[%s] = %s\n", c.constant, g.encode(c.key, field, c.named))
+		case kindOptional:
+			g.printf("if %s == %s {\n", field, atomics[c.key].zero)
+			g.printf("ovsdbRow[%s] = libovsdb.OvsSet{GoSet: []interface{}{}}\n", c.constant)
+			g.printf("} else {\novsdbRow[%s] = %s\n}\n", c.constant, g.encode(c.key, field, c.named))
+		case kindSet:
+			g.printf("%s := libovsdb.OvsSet{GoSet: []interface{}{}}\n", local(c, "Set"))
+			g.printf("for _, element := range %s {\n", field)
+			g.printf("%s.GoSet = append(%s.GoSet, %s)\n}\n", local(c, "Set"), local(c, "Set"),
+				g.encode(c.key, "element", false))
+			g.printf("ovsdbRow[%s] = %s\n", c.constant, local(c, "Set"))
+		case kindMap:
+			g.printf("%s := libovsdb.OvsMap{GoMap: make(map[interface{}]interface{})}\n", local(c, "Map"))
+			g.printf("for key, value := range %s {\n", field)
+			g.printf("%s.GoMap[%s] = %s\n}\n", local(c, "Map"), g.encode(c.key, "key", false),
+				g.encode(c.value, "value", false))
+			g.printf("ovsdbRow[%s] = %s\n", c.constant, local(c, "Map"))
+		}
+	}
+	if t.capabilities {
+		g.printf("row.Capabilities.Omit(%s, ovsdbRow)\n", t.prefix)
+	}
+	g.printf("\nreturn nil\n}\n\n")
+}
+
+// local returns the name of a local variable holding the value of a column
+func local(c column, suffix string) string {
+	return strings.ToLower(c.field[:1]) + c.field[1:] + suffix
+}
+
+func (g *generator) writeRead(t *table) {
+	g.printf("// ReadOVSDBRow fills the row with the columns present in ovsdbRow, as returned by a select or a monitor\n")
+	g.printf("func (row *%s) ReadOVSDBRow(ovsdbRow map[string]interface{}) error {\n", t.typeName)
+	for _, c := range t.columns {
+		field := "row." + c.field
+		invalid := fmt.Sprintf("fmt.Errorf(\"Invalid %%s %%v\", %s, err)", c.constant)
+
+		g.printf("if data, ok := ovsdbRow[%s]; ok {\n", c.constant)
+		switch c.kind {
+		case kindScalar:
+			g.decode(c.key, "data", "value", invalid)
+			g.printf("%s = %s\n", field, convert(c, "value"))
+			g.printf("}\n\n")
+			continue
+		case kindOptional:
+			g.printf("%s = %s\n", field, atomics[c.key].zero)
+			g.printf("err := %sUnMarshallOVSSet(data, func(atom interface{}) error {\n", g.qualifier)
+			g.decode(c.key, "atom", "value", "err")
+			g.printf("%s = %s\nreturn nil\n})\n", field, convert(c, "value"))
+		case kindSet:
+			g.printf("%s = nil\n", field)
+			g.printf("err := %sUnMarshallOVSSet(data, func(atom interface{}) error {\n", g.qualifier)
+			g.decode(c.key, "atom", "value", "err")
+			g.printf("%s = append(%s, value)\nreturn nil\n})\n", field, field)
+		case kindMap:
+			g.printf("%s = make(%s)\n", field, c.goType)
+			g.printf("err := %sUnMarshallOVSMap(data, func(key interface{}, value interface{}) error {\n",
+				g.qualifier)
+			g.decode(c.key, "key", "k", "err")
+			g.decode(c.value, "value", "v", "err")
+			g.printf("%s[k] = v\nreturn nil\n})\n", field)
+		}
+		g.printf("if err != nil {\nreturn %s\n}\n}\n\n", invalid)
+	}
+	g.printf("return nil\n}\n")
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestOVSDBTables checks that the row types of the ovsdb package match what go generate writes
+func TestOVSDBTables(t *testing.T) {
+	dir := filepath.Join("..", "..", "ovsdb")
+	files, err := generateFiles(filepath.Join(dir, "vrs.ovsschema"), filepath.Join(dir, "ovsdbgen.json"))
+	if err != nil {
+		t.Fatalf("Unable to generate the tables %v", err)
+	}
+
+	for file, source := range files {
+		current, err := ioutil.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatalf("Unable to read %s %v", file, err)
+		}
+		if !bytes.Equal(current, source) {
+			t.Errorf("%s is out of date, run go generate in the ovsdb package", file)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	var schema TableSchema
+	err := json.Unmarshal([]byte(`{"columns": {
+		"name": {"type": "string"},
+		"ofport": {"type": {"key": "integer", "min": 0, "max": 1}},
+		"ratio": {"type": "real"},
+		"up": {"type": "boolean"},
+		"interfaces": {"type": {"key": {"type": "uuid", "refTable": "Interface"}, "min": 1, "max": "unlimited"}},
+		"options": {"type": {"key": "string", "value": "integer", "min": 0, "max": "unlimited"}}
+	}}`), &schema)
+	if err != nil {
+		t.Fatalf("Unable to parse the schema %v", err)
+	}
+
+	if _, err := newTable("Port", schema, TableConfig{Columns: map[string]ColumnConfig{"missing": {}}}); err == nil {
+		t.Fatalf("Configured a column missing from the schema")
+	}
+	config := TableConfig{Columns: map[string]ColumnConfig{"interfaces": {Type: "Interfaces"}}}
+	if _, err := newTable("Port", schema, config); err == nil {
+		t.Fatalf("Replaced the type of a set")
+	}
+
+	config = TableConfig{Columns: map[string]ColumnConfig{"up": {Field: "Enabled"}, "ratio": {Skip: true}}}
+	table, err := newTable("Port", schema, config)
+	if err != nil {
+		t.Fatalf("Unable to describe the table %v", err)
+	}
+
+	source, err := Generate("vswitch", "vswitch.ovsschema", table)
+	if err != nil {
+		t.Fatalf("Unable to generate the table %v", err)
+	}
+
+	// Whitespace is collapsed since gofmt aligns the declarations
+	code := strings.Join(strings.Fields(string(source)), " ")
+	for _, expected := range []string{
+		`"github.com/nuagenetworks/libvrsdk/ovsdb"`,
+		"PortColumnName = \"name\"",
+		"Enabled bool",
+		"Interfaces []string",
+		"Ofport int",
+		"Options map[string]int",
+		"interfacesSet.GoSet = append(interfacesSet.GoSet, libovsdb.UUID{GoUUID: element})",
+		"ovsdbRow[PortColumnOfport] = libovsdb.OvsSet{GoSet: []interface{}{}}",
+		"value, err := ovsdb.UnMarshallOVSUUID(atom)",
+		"v, err := ovsdb.UnMarshallOVSInt(value)",
+	} {
+		if !strings.Contains(code, expected) {
+			t.Errorf("Generated code lacks %s\n%s", expected, source)
+		}
+	}
+	// The fields follow the order of the schema
+	if strings.Index(code, "Name string") > strings.Index(code, "Interfaces []string") {
+		t.Errorf("Generated fields out of the schema order\n%s", source)
+	}
+	if strings.Contains(code, "Ratio") || strings.Contains(code, "Capabilities") {
+		t.Errorf("Generated code has unexpected fields\n%s", source)
+	}
+}
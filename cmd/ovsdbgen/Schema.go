@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Schema is the part of an OVSDB schema the generator needs
+type Schema struct {
+	Name    string                 `json:"name"`
+	Version string                 `json:"version"`
+	Tables  map[string]TableSchema `json:"tables"`
+}
+
+// TableSchema describes the columns of a table. Order lists the names of the columns in the order of the
+// schema, the fields of the generated rows follow it.
+type TableSchema struct {
+	Columns map[string]ColumnSchema `json:"columns"`
+	Order   []string                `json:"-"`
+}
+
+// ColumnSchema describes a column
+type ColumnSchema struct {
+	Type ColumnType `json:"type"`
+}
+
+// BaseType is the type of the keys or the values of a column
+type BaseType struct {
+	Type string `json:"type"`
+}
+
+// ColumnType is the type of a column, its atoms are keys, key-value pairs when Value is set, and it holds
+// between Min and Max of them. Max is -1 when unlimited.
+type ColumnType struct {
+	Key   BaseType
+	Value *BaseType
+	Min   int
+	Max   int
+}
+
+// Kinds of columns
+const (
+	kindScalar   = "scalar"
+	kindOptional = "optional"
+	kindSet      = "set"
+	kindMap      = "map"
+)
+
+// Kind tells how the column is represented in Go
+func (columnType ColumnType) Kind() string {
+	switch {
+	case columnType.Value != nil:
+		return kindMap
+	case columnType.Max == 1 && columnType.Min == 1:
+		return kindScalar
+	case columnType.Max == 1:
+		return kindOptional
+	}
+	return kindSet
+}
+
+// UnmarshalJSON reads the columns of the table along with their order
+func (tableSchema *TableSchema) UnmarshalJSON(b []byte) error {
+	var object struct {
+		Columns json.RawMessage `json:"columns"`
+	}
+	if err := json.Unmarshal(b, &object); err != nil {
+		return fmt.Errorf("Invalid table %s %v", b, err)
+	}
+
+	if err := json.Unmarshal(object.Columns, &tableSchema.Columns); err != nil {
+		return fmt.Errorf("Invalid columns %v", err)
+	}
+
+	order, err := objectKeys(object.Columns)
+	if err != nil {
+		return fmt.Errorf("Invalid columns %v", err)
+	}
+	tableSchema.Order = order
+
+	return nil
+}
+
+// objectKeys returns the keys of a JSON object in the order they are listed in
+func objectKeys(b []byte) ([]string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil, fmt.Errorf("Not an object %s", b)
+	}
+
+	var keys []string
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, token.(string))
+
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+	}
+
+	return keys, nil
+}
+
+// UnmarshalJSON reads the base type either as an atomic type name or as an object
+func (baseType *BaseType) UnmarshalJSON(b []byte) error {
+	var atomic string
+	if err := json.Unmarshal(b, &atomic); err == nil {
+		baseType.Type = atomic
+		return nil
+	}
+
+	var object struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(b, &object); err != nil {
+		return fmt.Errorf("Invalid base type %s %v", b, err)
+	}
+	baseType.Type = object.Type
+
+	return nil
+}
+
+// UnmarshalJSON reads the column type either as an atomic type name or as an object
+func (columnType *ColumnType) UnmarshalJSON(b []byte) error {
+	columnType.Min = 1
+	columnType.Max = 1
+
+	var atomic string
+	if err := json.Unmarshal(b, &atomic); err == nil {
+		columnType.Key.Type = atomic
+		return nil
+	}
+
+	var object struct {
+		Key   BaseType        `json:"key"`
+		Value *BaseType       `json:"value"`
+		Min   *int            `json:"min"`
+		Max   json.RawMessage `json:"max"`
+	}
+	if err := json.Unmarshal(b, &object); err != nil {
+		return fmt.Errorf("Invalid column type %s %v", b, err)
+	}
+
+	columnType.Key = object.Key
+	columnType.Value = object.Value
+	if object.Min != nil {
+		columnType.Min = *object.Min
+	}
+
+	if len(object.Max) != 0 {
+		var unlimited string
+		if err := json.Unmarshal(object.Max, &unlimited); err == nil {
+			if unlimited != "unlimited" {
+				return fmt.Errorf("Invalid max %s", object.Max)
+			}
+			columnType.Max = -1
+		} else if err := json.Unmarshal(object.Max, &columnType.Max); err != nil {
+			return fmt.Errorf("Invalid max %s %v", object.Max, err)
+		}
+	}
+
+	return nil
+}
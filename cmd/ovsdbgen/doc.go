@@ -0,0 +1,34 @@
+/*
+Command ovsdbgen generates the Go row types of OVSDB tables from an .ovsschema file.
+
+For each table listed in the configuration it writes a file holding the table and column name constants, a
+struct with one field per column in the order of the schema, and the Equals, CreateOVSDBRow and ReadOVSDBRow methods encoding the struct
+into an OVSDB row and decoding it back. It is run by go generate in the ovsdb package:
+
+	ovsdbgen -schema vrs.ovsschema -config ovsdbgen.json [-output dir]
+
+The configuration is a JSON object naming the package of the generated files and the tables to generate:
+
+	{
+	  "package": "ovsdb",
+	  "tables": {
+	    "Nuage_Port_Table": {
+	      "file": "NuagePortTableRow.go",
+	      "imports": ["github.com/nuagenetworks/libvrsdk/api/entity"],
+	      "capabilities": true,
+	      "columns": {
+	        "mac": {"field": "Mac"},
+	        "ip_addr": {"constant": "NuagePortTableColumnIPAddress"},
+	        "vm_domain": {"type": "entity.Domain"}
+	      }
+	    }
+	  }
+	}
+
+Names default to the camel case of the table and column names: the Nuage_Port_Table is described by the
+NuagePortTable constant and NuagePortTableRow type, its ip_addr column by the NuagePortTableColumnIPAddr
+constant and IPAddr field. The type of a column holding a single value can be replaced by a named type of the
+same underlying type. Columns can be left out with "skip". Tables with "capabilities" get a Capabilities
+field, the optional columns the VRS does not support are left out of the rows they create.
+*/
+package main
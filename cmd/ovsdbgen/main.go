@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// generateFiles returns the source of the files described by the configuration, indexed by file name
+func generateFiles(schemaPath string, configPath string) (map[string][]byte, error) {
+	var schema Schema
+	if err := readJSON(schemaPath, &schema); err != nil {
+		return nil, err
+	}
+
+	var config Config
+	if err := readJSON(configPath, &config); err != nil {
+		return nil, err
+	}
+	if config.Package == "" {
+		return nil, fmt.Errorf("No package in %s", configPath)
+	}
+
+	names := make([]string, 0, len(config.Tables))
+	for name := range config.Tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	files := make(map[string][]byte)
+	for _, name := range names {
+		tableSchema, ok := schema.Tables[name]
+		if !ok {
+			return nil, fmt.Errorf("Table %s is not in the schema %s", name, schemaPath)
+		}
+
+		t, err := newTable(name, tableSchema, config.Tables[name])
+		if err != nil {
+			return nil, err
+		}
+
+		source, err := Generate(config.Package, filepath.Base(schemaPath), t)
+		if err != nil {
+			return nil, err
+		}
+
+		file := config.Tables[name].File
+		if file == "" {
+			file = t.typeName + ".go"
+		}
+		if _, exists := files[file]; exists {
+			return nil, fmt.Errorf("Several tables are generated in %s", file)
+		}
+		files[file] = source
+	}
+
+	return files, nil
+}
+
+func readJSON(path string, value interface{}) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("Unable to read %s %v", path, err)
+	}
+
+	if err := json.Unmarshal(content, value); err != nil {
+		return fmt.Errorf("Unable to parse %s %v", path, err)
+	}
+
+	return nil
+}
+
+func main() {
+	schemaPath := flag.String("schema", "", "OVSDB schema to read the tables from")
+	configPath := flag.String("config", "", "configuration of the tables to generate")
+	output := flag.String("output", ".", "directory to write the files to")
+	flag.Parse()
+
+	if *schemaPath == "" || *configPath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	files, err := generateFiles(*schemaPath, *configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	for file, source := range files {
+		if err := ioutil.WriteFile(filepath.Join(*output, file), source, 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to write %s %v\n", file, err)
+			os.Exit(1)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/nuagenetworks/libvrsdk/cmd/vrs-agentd/agentpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// route maps a method and a path, whose * segments are passed to the handler, to the service
+type route struct {
+	method  string
+	pattern []string
+	handler func(w http.ResponseWriter, r *http.Request, params []string)
+}
+
+// Gateway maps the VRSAgent service to HTTP and JSON. The messages are encoded with the JSON mapping of
+// protobuf, the watches are streamed as one JSON object per line.
+type Gateway struct {
+	routes []route
+}
+
+// NewGateway returns a Gateway calling server
+func NewGateway(server *Server) *Gateway {
+	gateway := &Gateway{}
+
+	gateway.unary("POST", "/v1/entities", func(r *http.Request, params []string) (proto.Message, error) {
+		e := &agentpb.Entity{}
+		if err := decode(r, e); err != nil {
+			return nil, err
+		}
+		return server.CreateEntity(r.Context(), e)
+	})
+	gateway.unary("GET", "/v1/entities", func(r *http.Request, params []string) (proto.Message, error) {
+		return server.ListEntities(r.Context(), &agentpb.Empty{})
+	})
+	gateway.unary("DELETE", "/v1/entities/*", func(r *http.Request, params []string) (proto.Message, error) {
+		return server.DestroyEntity(r.Context(), &agentpb.EntityRef{Uuid: params[0]})
+	})
+	gateway.unary("PUT", "/v1/entities/*/state", func(r *http.Request, params []string) (proto.Message, error) {
+		state := &agentpb.EntityState{}
+		if err := decode(r, state); err != nil {
+			return nil, err
+		}
+		state.Uuid = params[0]
+		return server.SetEntityState(r.Context(), state)
+	})
+	gateway.unary("POST", "/v1/entities/*/events", func(r *http.Request, params []string) (proto.Message, error) {
+		event := &agentpb.EntityEventRequest{}
+		if err := decode(r, event); err != nil {
+			return nil, err
+		}
+		event.Uuid = params[0]
+		return server.PostEntityEvent(r.Context(), event)
+	})
+	gateway.unary("POST", "/v1/entities/*/ports", func(r *http.Request, params []string) (proto.Message, error) {
+		ports := &agentpb.EntityPorts{}
+		if err := decode(r, ports); err != nil {
+			return nil, err
+		}
+		ports.Uuid = params[0]
+		return server.AddEntityPorts(r.Context(), ports)
+	})
+	gateway.unary("DELETE", "/v1/entities/*/ports/*", func(r *http.Request, params []string) (proto.Message, error) {
+		return server.RemoveEntityPorts(r.Context(), &agentpb.EntityPorts{Uuid: params[0], Ports: []string{params[1]}})
+	})
+
+	gateway.unary("POST", "/v1/ports", func(r *http.Request, params []string) (proto.Message, error) {
+		p := &agentpb.Port{}
+		if err := decode(r, p); err != nil {
+			return nil, err
+		}
+		return server.CreatePort(r.Context(), p)
+	})
+	gateway.unary("GET", "/v1/ports", func(r *http.Request, params []string) (proto.Message, error) {
+		return server.ListPorts(r.Context(), &agentpb.Empty{})
+	})
+	gateway.unary("DELETE", "/v1/ports/*", func(r *http.Request, params []string) (proto.Message, error) {
+		return server.DestroyPort(r.Context(), &agentpb.PortRef{Name: params[0]})
+	})
+	gateway.unary("GET", "/v1/ports/*/resolution", func(r *http.Request, params []string) (proto.Message, error) {
+		return server.GetPortResolution(r.Context(), &agentpb.PortRef{Name: params[0]})
+	})
+	gateway.unary("POST", "/v1/bridge/ports", func(r *http.Request, params []string) (proto.Message, error) {
+		bridgePort := &agentpb.BridgePort{}
+		if err := decode(r, bridgePort); err != nil {
+			return nil, err
+		}
+		return server.AddPortToBridge(r.Context(), bridgePort)
+	})
+	gateway.unary("DELETE", "/v1/bridge/ports/*", func(r *http.Request, params []string) (proto.Message, error) {
+		return server.RemovePortFromBridge(r.Context(), &agentpb.PortRef{Name: params[0]})
+	})
+
+	gateway.unary("GET", "/v1/controller", func(r *http.Request, params []string) (proto.Message, error) {
+		return server.GetControllerState(r.Context(), &agentpb.Empty{})
+	})
+
+	gateway.stream("GET", "/v1/watch/ports/*", func(stream *httpStream, params []string) error {
+		return server.WatchPortResolution(&agentpb.PortRef{Name: params[0]}, portResolutionStream{stream})
+	})
+	gateway.stream("GET", "/v1/watch/entities", func(stream *httpStream, params []string) error {
+		return server.WatchEntities(&agentpb.Empty{}, entityEventStream{stream})
+	})
+	gateway.stream("GET", "/v1/watch/controller", func(stream *httpStream, params []string) error {
+		return server.WatchControllerState(&agentpb.Empty{}, controllerStateStream{stream})
+	})
+
+	return gateway
+}
+
+// unary adds the route of a unary call
+func (gateway *Gateway) unary(method string, path string,
+	call func(r *http.Request, params []string) (proto.Message, error)) {
+
+	gateway.routes = append(gateway.routes, route{method, strings.Split(strings.Trim(path, "/"), "/"),
+		func(w http.ResponseWriter, r *http.Request, params []string) {
+			response, err := call(r, params)
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+			b, err := protojson.MarshalOptions{EmitUnpopulated: true}.Marshal(response)
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(b)
+		}})
+}
+
+// stream adds the route of a watch
+func (gateway *Gateway) stream(method string, path string, call func(stream *httpStream, params []string) error) {
+	gateway.routes = append(gateway.routes, route{method, strings.Split(strings.Trim(path, "/"), "/"),
+		func(w http.ResponseWriter, r *http.Request, params []string) {
+			// The headers are sent at once so that the clients do not wait for the first message, an error then
+			// ends the stream as a last line
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			w.WriteHeader(http.StatusOK)
+			if flusher, ok := w.(http.Flusher); ok {
+				flusher.Flush()
+			}
+
+			stream := &httpStream{ctx: r.Context(), w: w}
+			if err := call(stream, params); err != nil {
+				st, _ := status.FromError(err)
+				json.NewEncoder(w).Encode(errorBody{Code: st.Code().String(), Message: st.Message()})
+			}
+		}})
+}
+
+// ServeHTTP calls the handler of the route matching the request
+func (gateway *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	pathFound := false
+	for _, route := range gateway.routes {
+		params, ok := match(route.pattern, segments)
+		if !ok {
+			continue
+		}
+		pathFound = true
+		if route.method == r.Method {
+			route.handler(w, r, params)
+			return
+		}
+	}
+
+	if pathFound {
+		writeError(w, status.Errorf(codes.Unimplemented, "Method %s not allowed", r.Method))
+		return
+	}
+	writeError(w, status.Errorf(codes.NotFound, "Path %s not found", r.URL.Path))
+}
+
+// match returns the * segments of the path if it matches pattern
+func match(pattern []string, segments []string) ([]string, bool) {
+	if len(pattern) != len(segments) {
+		return nil, false
+	}
+
+	var params []string
+	for i, p := range pattern {
+		if p == "*" {
+			params = append(params, segments[i])
+		} else if p != segments[i] {
+			return nil, false
+		}
+	}
+
+	return params, true
+}
+
+// decode reads the message from the body of the request
+func decode(r *http.Request, m proto.Message) error {
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "Unable to read the request %v", err)
+	}
+	if err = protojson.Unmarshal(b, m); err != nil {
+		return status.Errorf(codes.InvalidArgument, "Invalid request %v", err)
+	}
+	return nil
+}
+
+// errorBody is the body of the responses to the failed calls
+type errorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// httpStatus maps the gRPC codes to the HTTP status
+var httpStatus = map[codes.Code]int{
+	codes.InvalidArgument:   http.StatusBadRequest,
+	codes.NotFound:          http.StatusNotFound,
+	codes.AlreadyExists:     http.StatusConflict,
+	codes.ResourceExhausted: http.StatusTooManyRequests,
+	codes.Unimplemented:     http.StatusMethodNotAllowed,
+	codes.Unavailable:       http.StatusServiceUnavailable,
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	st, _ := status.FromError(err)
+	code, ok := httpStatus[st.Code()]
+	if !ok {
+		code = http.StatusInternalServerError
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(errorBody{Code: st.Code().String(), Message: st.Message()})
+}
+
+// httpStream is the grpc.ServerStream of a watch streamed over HTTP
+type httpStream struct {
+	ctx context.Context
+	w   http.ResponseWriter
+}
+
+func (stream *httpStream) SetHeader(metadata.MD) error  { return nil }
+func (stream *httpStream) SendHeader(metadata.MD) error { return nil }
+func (stream *httpStream) SetTrailer(metadata.MD)       {}
+func (stream *httpStream) Context() context.Context     { return stream.ctx }
+func (stream *httpStream) RecvMsg(m interface{}) error  { return io.EOF }
+
+// SendMsg writes the message on a line of its own and flushes it to the client
+func (stream *httpStream) SendMsg(m interface{}) error {
+	b, err := protojson.Marshal(m.(proto.Message))
+	if err != nil {
+		return err
+	}
+
+	if _, err = stream.w.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	if flusher, ok := stream.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+type portResolutionStream struct{ *httpStream }
+
+func (stream portResolutionStream) Send(m *agentpb.PortResolution) error { return stream.SendMsg(m) }
+
+type entityEventStream struct{ *httpStream }
+
+func (stream entityEventStream) Send(m *agentpb.EntityEvent) error { return stream.SendMsg(m) }
+
+type controllerStateStream struct{ *httpStream }
+
+func (stream controllerStateStream) Send(m *agentpb.ControllerState) error { return stream.SendMsg(m) }
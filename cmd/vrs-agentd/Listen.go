@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"syscall"
+)
+
+// listenUnix listens on the Unix socket at path, only reachable by the users allowed by mode and group. The
+// socket is created without any permission so that no client can connect before the permissions are set.
+func listenUnix(path string, mode os.FileMode, group string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("Unable to create the directory of %s %v", path, err)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("Unable to remove the stale socket %s %v", path, err)
+	}
+
+	umask := syscall.Umask(0777)
+	listener, err := net.Listen("unix", path)
+	syscall.Umask(umask)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to listen on %s %v", path, err)
+	}
+
+	if group != "" {
+		g, err := user.LookupGroup(group)
+		if err != nil {
+			listener.Close()
+			return nil, fmt.Errorf("Unable to find the group %s %v", group, err)
+		}
+		gid, _ := strconv.Atoi(g.Gid)
+		if err = os.Chown(path, -1, gid); err != nil {
+			listener.Close()
+			return nil, fmt.Errorf("Unable to change the group of %s %v", path, err)
+		}
+	}
+
+	if err = os.Chmod(path, mode); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("Unable to change the mode of %s %v", path, err)
+	}
+
+	return listener, nil
+}
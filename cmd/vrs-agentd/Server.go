@@ -0,0 +1,326 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/nuagenetworks/libvrsdk/api"
+	"github.com/nuagenetworks/libvrsdk/api/entity"
+	"github.com/nuagenetworks/libvrsdk/api/port"
+	"github.com/nuagenetworks/libvrsdk/cmd/vrs-agentd/agentpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Server implements the VRSAgent service on top of a VRS connection
+type Server struct {
+	agentpb.UnimplementedVRSAgentServer
+
+	vrsConnection api.VRSConnection
+	pollInterval  time.Duration
+	resolutions   *resolutionHub
+	entities      *entityHub
+
+	// mutex serializes the changes made to the entities with the polling of the entities changed by other
+	// agents, so that the changes are reported once
+	mutex sync.Mutex
+	stop  chan struct{}
+}
+
+// NewServer returns a Server polling the VRS every pollInterval for the changes made by other agents
+func NewServer(vrsConnection api.VRSConnection, pollInterval time.Duration) (*Server, error) {
+	server := &Server{
+		vrsConnection: vrsConnection,
+		pollInterval:  pollInterval,
+		resolutions:   newResolutionHub(vrsConnection),
+		entities:      newEntityHub(),
+		stop:          make(chan struct{}),
+	}
+
+	infos, err := vrsConnection.GetAllEntityInfo()
+	if err != nil {
+		return nil, err
+	}
+	server.entities.sync(infos, false)
+
+	go server.pollEntities()
+
+	return server, nil
+}
+
+// Close stops the polling of the VRS and ends the watches
+func (server *Server) Close() {
+	close(server.stop)
+	server.entities.close()
+}
+
+// vrsError reports an error of the SDK
+func vrsError(err error) error {
+	return status.Error(codes.Internal, err.Error())
+}
+
+func entityFromPB(e *agentpb.Entity) api.EntityInfo {
+	metadata := make(map[entity.MetadataKey]string)
+	for k, v := range e.Metadata {
+		metadata[entity.MetadataKey(k)] = v
+	}
+
+	return api.EntityInfo{
+		UUID:     e.Uuid,
+		Name:     e.Name,
+		Type:     entity.Type(e.Type),
+		Domain:   entity.Domain(e.Domain),
+		Ports:    e.Ports,
+		Metadata: metadata,
+	}
+}
+
+func entityToPB(info api.EntityInfo) *agentpb.Entity {
+	metadata := make(map[string]string)
+	for k, v := range info.Metadata {
+		metadata[string(k)] = v
+	}
+
+	return &agentpb.Entity{
+		Uuid:     info.UUID,
+		Name:     info.Name,
+		Type:     int32(info.Type),
+		Domain:   int32(info.Domain),
+		Ports:    info.Ports,
+		Metadata: metadata,
+	}
+}
+
+// CreateEntity creates the entity
+func (server *Server) CreateEntity(ctx context.Context, e *agentpb.Entity) (*agentpb.Empty, error) {
+	if e.Uuid == "" || e.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "The UUID and the name of the entity are required")
+	}
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	if err := server.vrsConnection.CreateEntityContext(ctx, entityFromPB(e)); err != nil {
+		return nil, vrsError(err)
+	}
+	server.entities.publish(&agentpb.EntityEvent{Kind: agentpb.EntityEvent_CREATED, Entity: e})
+
+	return &agentpb.Empty{}, nil
+}
+
+// DestroyEntity destroys the entity
+func (server *Server) DestroyEntity(ctx context.Context, ref *agentpb.EntityRef) (*agentpb.Empty, error) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	exists, err := server.vrsConnection.CheckEntityExists(ref.Uuid)
+	if err != nil {
+		return nil, vrsError(err)
+	}
+	if !exists {
+		return nil, status.Errorf(codes.NotFound, "Entity %s not found", ref.Uuid)
+	}
+
+	if err := server.vrsConnection.DestroyEntityContext(ctx, ref.Uuid); err != nil {
+		return nil, vrsError(err)
+	}
+	server.entities.publish(&agentpb.EntityEvent{Kind: agentpb.EntityEvent_DESTROYED,
+		Entity: &agentpb.Entity{Uuid: ref.Uuid}})
+
+	return &agentpb.Empty{}, nil
+}
+
+// ListEntities lists the entities of the VRS
+func (server *Server) ListEntities(ctx context.Context, _ *agentpb.Empty) (*agentpb.EntityList, error) {
+	infos, err := server.vrsConnection.GetAllEntityInfo()
+	if err != nil {
+		return nil, vrsError(err)
+	}
+
+	list := &agentpb.EntityList{}
+	for _, info := range infos {
+		list.Entities = append(list.Entities, entityToPB(info))
+	}
+
+	return list, nil
+}
+
+// SetEntityState sets the state of the entity
+func (server *Server) SetEntityState(ctx context.Context, state *agentpb.EntityState) (*agentpb.Empty, error) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	err := server.vrsConnection.SetEntityState(state.Uuid, entity.State(state.State), entity.SubState(state.SubState))
+	if err != nil {
+		return nil, vrsError(err)
+	}
+	server.entities.publish(&agentpb.EntityEvent{Kind: agentpb.EntityEvent_STATE_CHANGED,
+		Entity: &agentpb.Entity{Uuid: state.Uuid}, State: state.State, SubState: state.SubState})
+
+	return &agentpb.Empty{}, nil
+}
+
+// PostEntityEvent posts an event of the entity
+func (server *Server) PostEntityEvent(ctx context.Context, event *agentpb.EntityEventRequest) (*agentpb.Empty, error) {
+	category, evt := entity.EventCategory(event.Category), entity.Event(event.Event)
+	if !entity.ValidateEvent(category, evt) {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid event %d of category %d", event.Event,
+			event.Category)
+	}
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	if err := server.vrsConnection.PostEntityEvent(event.Uuid, category, evt); err != nil {
+		return nil, vrsError(err)
+	}
+	server.entities.publish(&agentpb.EntityEvent{Kind: agentpb.EntityEvent_EVENT_POSTED,
+		Entity: &agentpb.Entity{Uuid: event.Uuid}, Category: event.Category, Event: event.Event})
+
+	return &agentpb.Empty{}, nil
+}
+
+// AddEntityPorts adds ports to the entity
+func (server *Server) AddEntityPorts(ctx context.Context, ports *agentpb.EntityPorts) (*agentpb.Empty, error) {
+	return server.mutateEntityPorts(ports, server.vrsConnection.AddEntityPorts)
+}
+
+// RemoveEntityPorts removes ports from the entity
+func (server *Server) RemoveEntityPorts(ctx context.Context, ports *agentpb.EntityPorts) (*agentpb.Empty, error) {
+	return server.mutateEntityPorts(ports, server.vrsConnection.RemoveEntityPorts)
+}
+
+func (server *Server) mutateEntityPorts(ports *agentpb.EntityPorts,
+	mutate func(uuid string, portNames []string) error) (*agentpb.Empty, error) {
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	if err := mutate(ports.Uuid, ports.Ports); err != nil {
+		return nil, vrsError(err)
+	}
+
+	names, err := server.vrsConnection.GetEntityPorts(ports.Uuid)
+	if err != nil {
+		return nil, vrsError(err)
+	}
+	server.entities.publish(&agentpb.EntityEvent{Kind: agentpb.EntityEvent_PORTS_CHANGED,
+		Entity: &agentpb.Entity{Uuid: ports.Uuid, Ports: names}})
+
+	return &agentpb.Empty{}, nil
+}
+
+// CreatePort creates the port
+func (server *Server) CreatePort(ctx context.Context, p *agentpb.Port) (*agentpb.Empty, error) {
+	if p.Name == "" || p.Mac == "" {
+		return nil, status.Error(codes.InvalidArgument, "The name and the MAC address of the port are required")
+	}
+
+	metadata := make(map[port.MetadataKey]string)
+	for k, v := range p.Metadata {
+		metadata[port.MetadataKey(k)] = v
+	}
+	parsed, err := port.ParseMetadata(metadata)
+	if err == nil {
+		err = parsed.Validate()
+	}
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	attributes := port.Attributes{MAC: p.Mac, Platform: entity.Domain(p.Platform), Bridge: p.Bridge}
+	if err = server.vrsConnection.CreatePortContext(ctx, p.Name, attributes, metadata); err != nil {
+		return nil, vrsError(err)
+	}
+
+	return &agentpb.Empty{}, nil
+}
+
+// DestroyPort destroys the port
+func (server *Server) DestroyPort(ctx context.Context, ref *agentpb.PortRef) (*agentpb.Empty, error) {
+	if err := server.vrsConnection.DestroyPortContext(ctx, ref.Name); err != nil {
+		return nil, vrsError(err)
+	}
+
+	return &agentpb.Empty{}, nil
+}
+
+// ListPorts lists the ports of the VRS
+func (server *Server) ListPorts(ctx context.Context, _ *agentpb.Empty) (*agentpb.PortList, error) {
+	specs, err := server.vrsConnection.GetAllPortSpecs()
+	if err != nil {
+		return nil, vrsError(err)
+	}
+
+	list := &agentpb.PortList{}
+	for _, spec := range specs {
+		metadata := make(map[string]string)
+		for k, v := range spec.Metadata {
+			metadata[string(k)] = v
+		}
+		list.Ports = append(list.Ports, &agentpb.Port{
+			Name:     spec.Name,
+			Mac:      spec.Attributes.MAC,
+			Platform: int32(spec.Attributes.Platform),
+			Bridge:   spec.Attributes.Bridge,
+			Metadata: metadata,
+		})
+	}
+
+	return list, nil
+}
+
+// GetPortResolution returns the current resolution of the port
+func (server *Server) GetPortResolution(ctx context.Context, ref *agentpb.PortRef) (*agentpb.PortResolution, error) {
+	resolution, err := server.portResolution(ref.Name)
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "Port %s not found", ref.Name)
+	}
+
+	return resolution, nil
+}
+
+// portResolution reads the current resolution of the port
+func (server *Server) portResolution(name string) (*agentpb.PortResolution, error) {
+	state, err := server.vrsConnection.GetPortState(name)
+	if err != nil {
+		return nil, err
+	}
+
+	resolution := &agentpb.PortResolution{Name: name, Registered: true}
+	resolution.Ip, _ = state[port.StateKeyIPAddress].(string)
+	resolution.Mask, _ = state[port.StateKeySubnetMask].(string)
+	resolution.Gateway, _ = state[port.StateKeyGateway].(string)
+
+	return resolution, nil
+}
+
+// AddPortToBridge adds the port to alubr0
+func (server *Server) AddPortToBridge(ctx context.Context, bridgePort *agentpb.BridgePort) (*agentpb.Empty, error) {
+	info := api.EntityInfo{UUID: bridgePort.EntityUuid, Name: bridgePort.EntityName}
+	if err := server.vrsConnection.AddPortToAlubr0Context(ctx, bridgePort.Port, info); err != nil {
+		return nil, vrsError(err)
+	}
+
+	return &agentpb.Empty{}, nil
+}
+
+// RemovePortFromBridge removes the port from alubr0
+func (server *Server) RemovePortFromBridge(ctx context.Context, ref *agentpb.PortRef) (*agentpb.Empty, error) {
+	if err := server.vrsConnection.RemovePortFromAlubr0Context(ctx, ref.Name); err != nil {
+		return nil, vrsError(err)
+	}
+
+	return &agentpb.Empty{}, nil
+}
+
+// GetControllerState returns the state of the connection of the VRS to its controller
+func (server *Server) GetControllerState(ctx context.Context, _ *agentpb.Empty) (*agentpb.ControllerState, error) {
+	state, err := server.vrsConnection.GetControllerState()
+	if err != nil {
+		return nil, vrsError(err)
+	}
+
+	return &agentpb.ControllerState{State: string(state)}, nil
+}
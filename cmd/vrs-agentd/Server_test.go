@@ -0,0 +1,320 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nuagenetworks/libvrsdk/api"
+	"github.com/nuagenetworks/libvrsdk/api/entity"
+	"github.com/nuagenetworks/libvrsdk/api/port"
+	"github.com/nuagenetworks/libvrsdk/cmd/vrs-agentd/agentpb"
+	"github.com/nuagenetworks/libvrsdk/ovsdb"
+	"github.com/nuagenetworks/libvrsdk/ovsdb/ovsdbtest"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/socketplane/libovsdb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// startAgent serves the agent on a Unix socket of a temporary directory
+func startAgent(t *testing.T, server *ovsdbtest.Server) (agentpb.VRSAgentClient, *Server, func()) {
+	vrsConnection, err := api.NewUnixSocketConnection(server.SocketPath())
+	if err != nil {
+		t.Fatalf("Unable to connect to the VRS %v", err)
+	}
+
+	agent, err := NewServer(vrsConnection, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Unable to start the agent %v", err)
+	}
+
+	dir, err := ioutil.TempDir("", "vrs-agentd")
+	if err != nil {
+		t.Fatalf("Unable to create the socket directory %v", err)
+	}
+	path := filepath.Join(dir, "grpc.sock")
+	listener, err := listenUnix(path, 0660, "")
+	if err != nil {
+		t.Fatalf("Unable to listen %v", err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0660 {
+		t.Fatalf("Unexpected permissions of the socket %v %v", info.Mode(), err)
+	}
+
+	grpcServer := grpc.NewServer()
+	agentpb.RegisterVRSAgentServer(grpcServer, agent)
+	go grpcServer.Serve(listener)
+
+	conn, err := grpc.Dial("unix://"+path, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("Unable to dial the agent %v", err)
+	}
+
+	return agentpb.NewVRSAgentClient(conn), agent, func() {
+		conn.Close()
+		grpcServer.Stop()
+		agent.Close()
+		vrsConnection.Disconnect()
+		os.RemoveAll(dir)
+	}
+}
+
+func receiveEntityEvent(t *testing.T, stream agentpb.VRSAgent_WatchEntitiesClient, kind agentpb.EntityEvent_Kind,
+	uuid string) {
+
+	event, err := stream.Recv()
+	if err != nil || event.Kind != kind || event.Entity.Uuid != uuid {
+		t.Fatalf("Unexpected entity event %v %v", event, err)
+	}
+}
+
+func TestServer(t *testing.T) {
+	server, err := ovsdbtest.NewServer()
+	if err != nil {
+		t.Fatalf("Unable to start the OVSDB server %v", err)
+	}
+	defer server.Close()
+
+	client, _, stop := startAgent(t, server)
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	entities, err := client.WatchEntities(ctx, &agentpb.Empty{})
+	if err != nil {
+		t.Fatalf("Unable to watch the entities %v", err)
+	}
+	resolutions, err := client.WatchPortResolution(ctx, &agentpb.PortRef{Name: "port1"})
+	if err != nil {
+		t.Fatalf("Unable to watch the port %v", err)
+	}
+
+	if _, err = client.CreatePort(ctx, &agentpb.Port{Name: "port1", Mac: "00:11:22:33:44:55",
+		Platform: int32(entity.Docker), Bridge: "alubr0"}); err != nil {
+		t.Fatalf("Unable to create the port %v", err)
+	}
+	_, err = client.CreatePort(ctx, &agentpb.Port{Name: "port2", Mac: "00:11:22:33:44:56",
+		Metadata: map[string]string{string(port.MetadataKeyNetworkType): "ipv5"}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Port created with invalid metadata %v", err)
+	}
+
+	e := &agentpb.Entity{Uuid: "uuid1", Name: "entity1", Type: int32(entity.Container),
+		Domain: int32(entity.Docker), Ports: []string{"port1"}}
+	if _, err = client.CreateEntity(ctx, e); err != nil {
+		t.Fatalf("Unable to create the entity %v", err)
+	}
+	receiveEntityEvent(t, entities, agentpb.EntityEvent_CREATED, "uuid1")
+
+	list, err := client.ListEntities(ctx, &agentpb.Empty{})
+	if err != nil || len(list.Entities) != 1 || list.Entities[0].Name != "entity1" {
+		t.Fatalf("Unexpected entities %v %v", list, err)
+	}
+
+	_, err = client.PostEntityEvent(ctx, &agentpb.EntityEventRequest{Uuid: "uuid1",
+		Category: int32(entity.EventCategoryStarted), Event: 1000})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Invalid event posted %v", err)
+	}
+
+	server.Transact(libovsdb.Operation{
+		Op:    "update",
+		Table: ovsdb.NuagePortTable,
+		Where: []interface{}{libovsdb.NewCondition(ovsdb.NuagePortTableColumnName, "==", "port1")},
+		Row:   map[string]interface{}{"ip_addr": "10.0.0.2", "subnet_mask": "255.255.255.0", "gateway": "10.0.0.1"},
+	})
+	for {
+		resolution, err := resolutions.Recv()
+		if err != nil {
+			t.Fatalf("Unable to receive the resolution %v", err)
+		}
+		if resolution.Ip == "10.0.0.2" {
+			if resolution.Gateway != "10.0.0.1" || !resolution.Registered {
+				t.Fatalf("Unexpected resolution %v", resolution)
+			}
+			break
+		}
+	}
+
+	// An entity created by another agent is reported once the VRS is polled
+	other, err := api.NewUnixSocketConnection(server.SocketPath())
+	if err != nil {
+		t.Fatalf("Unable to connect to the VRS %v", err)
+	}
+	defer other.Disconnect()
+	if err = other.CreateEntity(api.EntityInfo{UUID: "uuid2", Name: "entity2", Type: entity.Container,
+		Domain: entity.Docker}); err != nil {
+		t.Fatalf("Unable to create the entity %v", err)
+	}
+	receiveEntityEvent(t, entities, agentpb.EntityEvent_CREATED, "uuid2")
+
+	if _, err = client.DestroyEntity(ctx, &agentpb.EntityRef{Uuid: "uuid2"}); err != nil {
+		t.Fatalf("Unable to destroy the entity %v", err)
+	}
+	receiveEntityEvent(t, entities, agentpb.EntityEvent_DESTROYED, "uuid2")
+	if _, err = client.DestroyEntity(ctx, &agentpb.EntityRef{Uuid: "uuid2"}); status.Code(err) != codes.NotFound {
+		t.Fatalf("Missing entity destroyed %v", err)
+	}
+}
+
+func TestGateway(t *testing.T) {
+	server, err := ovsdbtest.NewServer()
+	if err != nil {
+		t.Fatalf("Unable to start the OVSDB server %v", err)
+	}
+	defer server.Close()
+
+	_, agent, stop := startAgent(t, server)
+	defer stop()
+
+	gateway := httptest.NewServer(NewGateway(agent))
+	defer gateway.Close()
+
+	watch, err := http.Get(gateway.URL + "/v1/watch/entities")
+	if err != nil {
+		t.Fatalf("Unable to watch the entities %v", err)
+	}
+	defer watch.Body.Close()
+	events := bufio.NewScanner(watch.Body)
+
+	body := `{"uuid": "uuid1", "name": "entity1", "type": 4, "domain": 4}`
+	resp, err := http.Post(gateway.URL+"/v1/entities", "application/json", strings.NewReader(body))
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Unable to create the entity %v %v", resp, err)
+	}
+	resp.Body.Close()
+
+	if !events.Scan() || !strings.Contains(events.Text(), `"CREATED"`) {
+		t.Fatalf("Unexpected event %s %v", events.Text(), events.Err())
+	}
+
+	resp, err = http.Get(gateway.URL + "/v1/entities")
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Unable to list the entities %v %v", resp, err)
+	}
+	var list struct {
+		Entities []struct {
+			UUID string `json:"uuid"`
+			Name string `json:"name"`
+		} `json:"entities"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&list)
+	resp.Body.Close()
+	if err != nil || len(list.Entities) != 1 || list.Entities[0].Name != "entity1" {
+		t.Fatalf("Unexpected entities %+v %v", list, err)
+	}
+
+	resp, err = http.Post(gateway.URL+"/v1/entities", "application/json", strings.NewReader("{"))
+	if err != nil || resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Invalid request accepted %v %v", resp, err)
+	}
+	var e errorBody
+	json.NewDecoder(resp.Body).Decode(&e)
+	resp.Body.Close()
+	if e.Code != codes.InvalidArgument.String() {
+		t.Fatalf("Unexpected error %+v", e)
+	}
+
+	request, _ := http.NewRequest("DELETE", gateway.URL+"/v1/entities/uuid1", nil)
+	if resp, err = http.DefaultClient.Do(request); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Unable to destroy the entity %v %v", resp, err)
+	}
+	resp.Body.Close()
+	if !events.Scan() || !strings.Contains(events.Text(), `"DESTROYED"`) {
+		t.Fatalf("Unexpected event %s %v", events.Text(), events.Err())
+	}
+
+	request, _ = http.NewRequest("PATCH", gateway.URL+"/v1/entities", nil)
+	if resp, err = http.DefaultClient.Do(request); err != nil || resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("Unexpected response to an unknown method %v %v", resp, err)
+	}
+	resp.Body.Close()
+}
+
+// subscribers returns the number of channels registered for port updates on the connection
+func subscribers(t *testing.T, vrsConnection api.VRSConnection) float64 {
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(vrsConnection.Collector())
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Unable to gather the metrics %v", err)
+	}
+	for _, family := range families {
+		if strings.HasSuffix(family.GetName(), "port_update_subscribers") {
+			return family.Metric[0].GetGauge().GetValue()
+		}
+	}
+	return 0
+}
+
+func TestResolutionHubLaggingWatcher(t *testing.T) {
+	server, err := ovsdbtest.NewServer()
+	if err != nil {
+		t.Fatalf("Unable to start the OVSDB server %v", err)
+	}
+	defer server.Close()
+
+	vrsConnection, err := api.NewUnixSocketConnection(server.SocketPath())
+	if err != nil {
+		t.Fatalf("Unable to connect to the VRS %v", err)
+	}
+	defer vrsConnection.Disconnect()
+
+	if err = vrsConnection.CreatePort("port1", port.Attributes{MAC: "00:11:22:33:44:55", Platform: entity.Docker,
+		Bridge: "alubr0"}, nil); err != nil {
+		t.Fatalf("Unable to create the port %v", err)
+	}
+
+	// The only watcher never reads its updates, it is dropped and the port released once it lags behind
+	hub := newResolutionHub(vrsConnection)
+	watcher, err := hub.subscribe("port1")
+	if err != nil {
+		t.Fatalf("Unable to subscribe %v", err)
+	}
+	for i := 0; i < 3*watchBuffer; i++ {
+		server.Transact(libovsdb.Operation{
+			Op:    "update",
+			Table: ovsdb.NuagePortTable,
+			Where: []interface{}{libovsdb.NewCondition(ovsdb.NuagePortTableColumnName, "==", "port1")},
+			Row: map[string]interface{}{"ip_addr": fmt.Sprintf("10.0.0.%d", i+2), "subnet_mask": "255.255.255.0",
+				"gateway": "10.0.0.1"},
+		})
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		hub.mutex.Lock()
+		watched := len(hub.ports)
+		hub.mutex.Unlock()
+		if watched == 0 && subscribers(t, vrsConnection) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Port still watched after its last watcher was dropped %d", watched)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	for range watcher {
+	}
+	hub.unsubscribe("port1", watcher)
+
+	// The port is not watched once the registration fails
+	vrsConnection.Disconnect()
+	if _, err = hub.subscribe("port1"); err == nil || len(hub.ports) != 0 {
+		t.Fatalf("Subscribed on a closed connection %v %d", err, len(hub.ports))
+	}
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/nuagenetworks/libvrsdk/api"
+	"github.com/nuagenetworks/libvrsdk/cmd/vrs-agentd/agentpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// watchBuffer is the number of updates a watcher can lag behind before it is dropped
+const watchBuffer = 64
+
+// portWatch holds the watchers of a port and the channel registered for its updates
+type portWatch struct {
+	watchers map[chan *api.PortIPv4Info]bool
+	updates  chan *api.PortIPv4Info
+	stop     chan struct{}
+}
+
+// resolutionHub registers a single channel per port with the SDK and forwards its updates to the watchers of
+// the port. Each watcher has its own buffer, a watcher lagging behind is dropped and told so rather than missing
+// the updates the SDK does not deliver to a full channel.
+type resolutionHub struct {
+	vrsConnection api.VRSConnection
+	mutex         sync.Mutex
+	ports         map[string]*portWatch
+}
+
+func newResolutionHub(vrsConnection api.VRSConnection) *resolutionHub {
+	return &resolutionHub{vrsConnection: vrsConnection, ports: make(map[string]*portWatch)}
+}
+
+// subscribe returns a channel receiving the updates of the port, closed when the port is deleted or the watcher
+// is dropped for lagging behind
+func (hub *resolutionHub) subscribe(name string) (chan *api.PortIPv4Info, error) {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	watch, ok := hub.ports[name]
+	if !ok {
+		watch = &portWatch{
+			watchers: make(map[chan *api.PortIPv4Info]bool),
+			updates:  make(chan *api.PortIPv4Info, watchBuffer),
+			stop:     make(chan struct{}),
+		}
+		if err := hub.vrsConnection.RegisterForPortUpdates(name, watch.updates); err != nil {
+			return nil, err
+		}
+
+		hub.ports[name] = watch
+		go hub.dispatch(name, watch)
+	}
+
+	watcher := make(chan *api.PortIPv4Info, watchBuffer)
+	watch.watchers[watcher] = true
+
+	return watcher, nil
+}
+
+// unsubscribe removes the watcher of the port, unless it was already dropped
+func (hub *resolutionHub) unsubscribe(name string, watcher chan *api.PortIPv4Info) {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	if watch, ok := hub.ports[name]; ok && watch.watchers[watcher] {
+		hub.removeLocked(name, watch, watcher)
+	}
+}
+
+// removeLocked closes the watcher of the port, the registration ends with the last watcher whichever way it was
+// removed
+func (hub *resolutionHub) removeLocked(name string, watch *portWatch, watcher chan *api.PortIPv4Info) {
+	delete(watch.watchers, watcher)
+	close(watcher)
+	if len(watch.watchers) == 0 {
+		close(watch.stop)
+		delete(hub.ports, name)
+		hub.vrsConnection.DeregisterChannelForPortUpdates(name, watch.updates)
+	}
+}
+
+// dispatch forwards the updates of the port to its watchers until the port is deleted or its last watcher is
+// removed
+func (hub *resolutionHub) dispatch(name string, watch *portWatch) {
+	for {
+		select {
+		case update := <-watch.updates:
+			hub.mutex.Lock()
+			for watcher := range watch.watchers {
+				select {
+				case watcher <- update:
+				default:
+					hub.removeLocked(name, watch, watcher)
+				}
+			}
+			// The SDK drops the registration of a deleted port
+			if !update.Registered {
+				for watcher := range watch.watchers {
+					hub.removeLocked(name, watch, watcher)
+				}
+			}
+			hub.mutex.Unlock()
+		case <-watch.stop:
+			return
+		}
+	}
+}
+
+// WatchPortResolution streams the resolution of the port
+func (server *Server) WatchPortResolution(ref *agentpb.PortRef,
+	stream agentpb.VRSAgent_WatchPortResolutionServer) error {
+
+	watcher, err := server.resolutions.subscribe(ref.Name)
+	if err != nil {
+		return vrsError(err)
+	}
+	defer server.resolutions.unsubscribe(ref.Name, watcher)
+
+	// The port may not exist yet, its resolution is then sent once it is created and resolved
+	if resolution, err := server.portResolution(ref.Name); err == nil && resolution.Ip != "" {
+		if err = stream.Send(resolution); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case update, ok := <-watcher:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "Watcher lagging behind")
+			}
+			resolution := &agentpb.PortResolution{Name: ref.Name, Ip: update.IPAddr, Mask: update.Mask,
+				Gateway: update.Gateway, Mac: update.MAC, Registered: update.Registered}
+			if err := stream.Send(resolution); err != nil {
+				return err
+			}
+			if !update.Registered {
+				return nil
+			}
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+// entityHub broadcasts the changes made to the entities
+type entityHub struct {
+	mutex    sync.Mutex
+	watchers map[chan *agentpb.EntityEvent]bool
+	known    map[string]bool
+}
+
+func newEntityHub() *entityHub {
+	return &entityHub{watchers: make(map[chan *agentpb.EntityEvent]bool), known: make(map[string]bool)}
+}
+
+// subscribe returns a channel receiving the changes, closed when the watcher is dropped for lagging behind
+func (hub *entityHub) subscribe() chan *agentpb.EntityEvent {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	watcher := make(chan *agentpb.EntityEvent, watchBuffer)
+	hub.watchers[watcher] = true
+	return watcher
+}
+
+func (hub *entityHub) unsubscribe(watcher chan *agentpb.EntityEvent) {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	if hub.watchers[watcher] {
+		delete(hub.watchers, watcher)
+		close(watcher)
+	}
+}
+
+func (hub *entityHub) close() {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	for watcher := range hub.watchers {
+		delete(hub.watchers, watcher)
+		close(watcher)
+	}
+}
+
+func (hub *entityHub) publish(event *agentpb.EntityEvent) {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	hub.publishLocked(event)
+}
+
+func (hub *entityHub) publishLocked(event *agentpb.EntityEvent) {
+	switch event.Kind {
+	case agentpb.EntityEvent_CREATED:
+		hub.known[event.Entity.Uuid] = true
+	case agentpb.EntityEvent_DESTROYED:
+		delete(hub.known, event.Entity.Uuid)
+	}
+
+	for watcher := range hub.watchers {
+		select {
+		case watcher <- event:
+		default:
+			delete(hub.watchers, watcher)
+			close(watcher)
+		}
+	}
+}
+
+// sync records the entities present in the VRS and, if publish is set, publishes the entities created and
+// destroyed since the previous sync
+func (hub *entityHub) sync(infos []api.EntityInfo, publish bool) {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	present := make(map[string]bool)
+	for _, info := range infos {
+		present[info.UUID] = true
+		if !hub.known[info.UUID] {
+			hub.known[info.UUID] = true
+			if publish {
+				hub.publishLocked(&agentpb.EntityEvent{Kind: agentpb.EntityEvent_CREATED, Entity: entityToPB(info)})
+			}
+		}
+	}
+
+	for uuid := range hub.known {
+		if !present[uuid] {
+			delete(hub.known, uuid)
+			if publish {
+				hub.publishLocked(&agentpb.EntityEvent{Kind: agentpb.EntityEvent_DESTROYED,
+					Entity: &agentpb.Entity{Uuid: uuid}})
+			}
+		}
+	}
+}
+
+// pollEntities publishes the entities created and destroyed by the other agents
+func (server *Server) pollEntities() {
+	ticker := time.NewTicker(server.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			server.mutex.Lock()
+			if infos, err := server.vrsConnection.GetAllEntityInfo(); err == nil {
+				server.entities.sync(infos, true)
+			}
+			server.mutex.Unlock()
+		case <-server.stop:
+			return
+		}
+	}
+}
+
+// WatchEntities streams the changes made to the entities, through the agent or by other agents
+func (server *Server) WatchEntities(_ *agentpb.Empty, stream agentpb.VRSAgent_WatchEntitiesServer) error {
+	watcher := server.entities.subscribe()
+	defer server.entities.unsubscribe(watcher)
+
+	for {
+		select {
+		case event, ok := <-watcher:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "Watcher lagging behind or agent stopping")
+			}
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+// WatchControllerState streams the state of the connection of the VRS to its controller
+func (server *Server) WatchControllerState(_ *agentpb.Empty, stream agentpb.VRSAgent_WatchControllerStateServer) error {
+	ticker := time.NewTicker(server.pollInterval)
+	defer ticker.Stop()
+
+	var last api.ControllerState
+	for {
+		state, err := server.vrsConnection.GetControllerState()
+		if err != nil {
+			return vrsError(err)
+		}
+		if state != last {
+			if err = stream.Send(&agentpb.ControllerState{State: string(state)}); err != nil {
+				return err
+			}
+			last = state
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stream.Context().Done():
+			return nil
+		case <-server.stop:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,771 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package agentpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// VRSAgentClient is the client API for VRSAgent service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type VRSAgentClient interface {
+	// Entities
+	CreateEntity(ctx context.Context, in *Entity, opts ...grpc.CallOption) (*Empty, error)
+	DestroyEntity(ctx context.Context, in *EntityRef, opts ...grpc.CallOption) (*Empty, error)
+	ListEntities(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*EntityList, error)
+	SetEntityState(ctx context.Context, in *EntityState, opts ...grpc.CallOption) (*Empty, error)
+	PostEntityEvent(ctx context.Context, in *EntityEventRequest, opts ...grpc.CallOption) (*Empty, error)
+	AddEntityPorts(ctx context.Context, in *EntityPorts, opts ...grpc.CallOption) (*Empty, error)
+	RemoveEntityPorts(ctx context.Context, in *EntityPorts, opts ...grpc.CallOption) (*Empty, error)
+	// Ports
+	CreatePort(ctx context.Context, in *Port, opts ...grpc.CallOption) (*Empty, error)
+	DestroyPort(ctx context.Context, in *PortRef, opts ...grpc.CallOption) (*Empty, error)
+	ListPorts(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*PortList, error)
+	GetPortResolution(ctx context.Context, in *PortRef, opts ...grpc.CallOption) (*PortResolution, error)
+	AddPortToBridge(ctx context.Context, in *BridgePort, opts ...grpc.CallOption) (*Empty, error)
+	RemovePortFromBridge(ctx context.Context, in *PortRef, opts ...grpc.CallOption) (*Empty, error)
+	// Controller
+	GetControllerState(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ControllerState, error)
+	// Watches. WatchPortResolution sends the current resolution of the port, if any, then every update until
+	// the port is deleted. WatchEntities sends the changes made to the entities. WatchControllerState sends the
+	// current state then every change.
+	WatchPortResolution(ctx context.Context, in *PortRef, opts ...grpc.CallOption) (VRSAgent_WatchPortResolutionClient, error)
+	WatchEntities(ctx context.Context, in *Empty, opts ...grpc.CallOption) (VRSAgent_WatchEntitiesClient, error)
+	WatchControllerState(ctx context.Context, in *Empty, opts ...grpc.CallOption) (VRSAgent_WatchControllerStateClient, error)
+}
+
+type vRSAgentClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewVRSAgentClient(cc grpc.ClientConnInterface) VRSAgentClient {
+	return &vRSAgentClient{cc}
+}
+
+func (c *vRSAgentClient) CreateEntity(ctx context.Context, in *Entity, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/nuage.vrsagent.v1.VRSAgent/CreateEntity", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vRSAgentClient) DestroyEntity(ctx context.Context, in *EntityRef, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/nuage.vrsagent.v1.VRSAgent/DestroyEntity", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vRSAgentClient) ListEntities(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*EntityList, error) {
+	out := new(EntityList)
+	err := c.cc.Invoke(ctx, "/nuage.vrsagent.v1.VRSAgent/ListEntities", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vRSAgentClient) SetEntityState(ctx context.Context, in *EntityState, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/nuage.vrsagent.v1.VRSAgent/SetEntityState", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vRSAgentClient) PostEntityEvent(ctx context.Context, in *EntityEventRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/nuage.vrsagent.v1.VRSAgent/PostEntityEvent", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vRSAgentClient) AddEntityPorts(ctx context.Context, in *EntityPorts, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/nuage.vrsagent.v1.VRSAgent/AddEntityPorts", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vRSAgentClient) RemoveEntityPorts(ctx context.Context, in *EntityPorts, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/nuage.vrsagent.v1.VRSAgent/RemoveEntityPorts", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vRSAgentClient) CreatePort(ctx context.Context, in *Port, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/nuage.vrsagent.v1.VRSAgent/CreatePort", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vRSAgentClient) DestroyPort(ctx context.Context, in *PortRef, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/nuage.vrsagent.v1.VRSAgent/DestroyPort", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vRSAgentClient) ListPorts(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*PortList, error) {
+	out := new(PortList)
+	err := c.cc.Invoke(ctx, "/nuage.vrsagent.v1.VRSAgent/ListPorts", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vRSAgentClient) GetPortResolution(ctx context.Context, in *PortRef, opts ...grpc.CallOption) (*PortResolution, error) {
+	out := new(PortResolution)
+	err := c.cc.Invoke(ctx, "/nuage.vrsagent.v1.VRSAgent/GetPortResolution", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vRSAgentClient) AddPortToBridge(ctx context.Context, in *BridgePort, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/nuage.vrsagent.v1.VRSAgent/AddPortToBridge", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vRSAgentClient) RemovePortFromBridge(ctx context.Context, in *PortRef, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/nuage.vrsagent.v1.VRSAgent/RemovePortFromBridge", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vRSAgentClient) GetControllerState(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ControllerState, error) {
+	out := new(ControllerState)
+	err := c.cc.Invoke(ctx, "/nuage.vrsagent.v1.VRSAgent/GetControllerState", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vRSAgentClient) WatchPortResolution(ctx context.Context, in *PortRef, opts ...grpc.CallOption) (VRSAgent_WatchPortResolutionClient, error) {
+	stream, err := c.cc.NewStream(ctx, &VRSAgent_ServiceDesc.Streams[0], "/nuage.vrsagent.v1.VRSAgent/WatchPortResolution", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &vRSAgentWatchPortResolutionClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type VRSAgent_WatchPortResolutionClient interface {
+	Recv() (*PortResolution, error)
+	grpc.ClientStream
+}
+
+type vRSAgentWatchPortResolutionClient struct {
+	grpc.ClientStream
+}
+
+func (x *vRSAgentWatchPortResolutionClient) Recv() (*PortResolution, error) {
+	m := new(PortResolution)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *vRSAgentClient) WatchEntities(ctx context.Context, in *Empty, opts ...grpc.CallOption) (VRSAgent_WatchEntitiesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &VRSAgent_ServiceDesc.Streams[1], "/nuage.vrsagent.v1.VRSAgent/WatchEntities", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &vRSAgentWatchEntitiesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type VRSAgent_WatchEntitiesClient interface {
+	Recv() (*EntityEvent, error)
+	grpc.ClientStream
+}
+
+type vRSAgentWatchEntitiesClient struct {
+	grpc.ClientStream
+}
+
+func (x *vRSAgentWatchEntitiesClient) Recv() (*EntityEvent, error) {
+	m := new(EntityEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *vRSAgentClient) WatchControllerState(ctx context.Context, in *Empty, opts ...grpc.CallOption) (VRSAgent_WatchControllerStateClient, error) {
+	stream, err := c.cc.NewStream(ctx, &VRSAgent_ServiceDesc.Streams[2], "/nuage.vrsagent.v1.VRSAgent/WatchControllerState", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &vRSAgentWatchControllerStateClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type VRSAgent_WatchControllerStateClient interface {
+	Recv() (*ControllerState, error)
+	grpc.ClientStream
+}
+
+type vRSAgentWatchControllerStateClient struct {
+	grpc.ClientStream
+}
+
+func (x *vRSAgentWatchControllerStateClient) Recv() (*ControllerState, error) {
+	m := new(ControllerState)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// VRSAgentServer is the server API for VRSAgent service.
+// All implementations must embed UnimplementedVRSAgentServer
+// for forward compatibility
+type VRSAgentServer interface {
+	// Entities
+	CreateEntity(context.Context, *Entity) (*Empty, error)
+	DestroyEntity(context.Context, *EntityRef) (*Empty, error)
+	ListEntities(context.Context, *Empty) (*EntityList, error)
+	SetEntityState(context.Context, *EntityState) (*Empty, error)
+	PostEntityEvent(context.Context, *EntityEventRequest) (*Empty, error)
+	AddEntityPorts(context.Context, *EntityPorts) (*Empty, error)
+	RemoveEntityPorts(context.Context, *EntityPorts) (*Empty, error)
+	// Ports
+	CreatePort(context.Context, *Port) (*Empty, error)
+	DestroyPort(context.Context, *PortRef) (*Empty, error)
+	ListPorts(context.Context, *Empty) (*PortList, error)
+	GetPortResolution(context.Context, *PortRef) (*PortResolution, error)
+	AddPortToBridge(context.Context, *BridgePort) (*Empty, error)
+	RemovePortFromBridge(context.Context, *PortRef) (*Empty, error)
+	// Controller
+	GetControllerState(context.Context, *Empty) (*ControllerState, error)
+	// Watches. WatchPortResolution sends the current resolution of the port, if any, then every update until
+	// the port is deleted. WatchEntities sends the changes made to the entities. WatchControllerState sends the
+	// current state then every change.
+	WatchPortResolution(*PortRef, VRSAgent_WatchPortResolutionServer) error
+	WatchEntities(*Empty, VRSAgent_WatchEntitiesServer) error
+	WatchControllerState(*Empty, VRSAgent_WatchControllerStateServer) error
+	mustEmbedUnimplementedVRSAgentServer()
+}
+
+// UnimplementedVRSAgentServer must be embedded to have forward compatible implementations.
+type UnimplementedVRSAgentServer struct {
+}
+
+func (UnimplementedVRSAgentServer) CreateEntity(context.Context, *Entity) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateEntity not implemented")
+}
+func (UnimplementedVRSAgentServer) DestroyEntity(context.Context, *EntityRef) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DestroyEntity not implemented")
+}
+func (UnimplementedVRSAgentServer) ListEntities(context.Context, *Empty) (*EntityList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListEntities not implemented")
+}
+func (UnimplementedVRSAgentServer) SetEntityState(context.Context, *EntityState) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetEntityState not implemented")
+}
+func (UnimplementedVRSAgentServer) PostEntityEvent(context.Context, *EntityEventRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PostEntityEvent not implemented")
+}
+func (UnimplementedVRSAgentServer) AddEntityPorts(context.Context, *EntityPorts) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddEntityPorts not implemented")
+}
+func (UnimplementedVRSAgentServer) RemoveEntityPorts(context.Context, *EntityPorts) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveEntityPorts not implemented")
+}
+func (UnimplementedVRSAgentServer) CreatePort(context.Context, *Port) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreatePort not implemented")
+}
+func (UnimplementedVRSAgentServer) DestroyPort(context.Context, *PortRef) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DestroyPort not implemented")
+}
+func (UnimplementedVRSAgentServer) ListPorts(context.Context, *Empty) (*PortList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPorts not implemented")
+}
+func (UnimplementedVRSAgentServer) GetPortResolution(context.Context, *PortRef) (*PortResolution, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPortResolution not implemented")
+}
+func (UnimplementedVRSAgentServer) AddPortToBridge(context.Context, *BridgePort) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddPortToBridge not implemented")
+}
+func (UnimplementedVRSAgentServer) RemovePortFromBridge(context.Context, *PortRef) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemovePortFromBridge not implemented")
+}
+func (UnimplementedVRSAgentServer) GetControllerState(context.Context, *Empty) (*ControllerState, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetControllerState not implemented")
+}
+func (UnimplementedVRSAgentServer) WatchPortResolution(*PortRef, VRSAgent_WatchPortResolutionServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchPortResolution not implemented")
+}
+func (UnimplementedVRSAgentServer) WatchEntities(*Empty, VRSAgent_WatchEntitiesServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchEntities not implemented")
+}
+func (UnimplementedVRSAgentServer) WatchControllerState(*Empty, VRSAgent_WatchControllerStateServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchControllerState not implemented")
+}
+func (UnimplementedVRSAgentServer) mustEmbedUnimplementedVRSAgentServer() {}
+
+// UnsafeVRSAgentServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to VRSAgentServer will
+// result in compilation errors.
+type UnsafeVRSAgentServer interface {
+	mustEmbedUnimplementedVRSAgentServer()
+}
+
+func RegisterVRSAgentServer(s grpc.ServiceRegistrar, srv VRSAgentServer) {
+	s.RegisterService(&VRSAgent_ServiceDesc, srv)
+}
+
+func _VRSAgent_CreateEntity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Entity)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VRSAgentServer).CreateEntity(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/nuage.vrsagent.v1.VRSAgent/CreateEntity",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VRSAgentServer).CreateEntity(ctx, req.(*Entity))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VRSAgent_DestroyEntity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EntityRef)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VRSAgentServer).DestroyEntity(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/nuage.vrsagent.v1.VRSAgent/DestroyEntity",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VRSAgentServer).DestroyEntity(ctx, req.(*EntityRef))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VRSAgent_ListEntities_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VRSAgentServer).ListEntities(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/nuage.vrsagent.v1.VRSAgent/ListEntities",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VRSAgentServer).ListEntities(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VRSAgent_SetEntityState_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EntityState)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VRSAgentServer).SetEntityState(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/nuage.vrsagent.v1.VRSAgent/SetEntityState",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VRSAgentServer).SetEntityState(ctx, req.(*EntityState))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VRSAgent_PostEntityEvent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EntityEventRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VRSAgentServer).PostEntityEvent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/nuage.vrsagent.v1.VRSAgent/PostEntityEvent",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VRSAgentServer).PostEntityEvent(ctx, req.(*EntityEventRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VRSAgent_AddEntityPorts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EntityPorts)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VRSAgentServer).AddEntityPorts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/nuage.vrsagent.v1.VRSAgent/AddEntityPorts",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VRSAgentServer).AddEntityPorts(ctx, req.(*EntityPorts))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VRSAgent_RemoveEntityPorts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EntityPorts)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VRSAgentServer).RemoveEntityPorts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/nuage.vrsagent.v1.VRSAgent/RemoveEntityPorts",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VRSAgentServer).RemoveEntityPorts(ctx, req.(*EntityPorts))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VRSAgent_CreatePort_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Port)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VRSAgentServer).CreatePort(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/nuage.vrsagent.v1.VRSAgent/CreatePort",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VRSAgentServer).CreatePort(ctx, req.(*Port))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VRSAgent_DestroyPort_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PortRef)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VRSAgentServer).DestroyPort(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/nuage.vrsagent.v1.VRSAgent/DestroyPort",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VRSAgentServer).DestroyPort(ctx, req.(*PortRef))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VRSAgent_ListPorts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VRSAgentServer).ListPorts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/nuage.vrsagent.v1.VRSAgent/ListPorts",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VRSAgentServer).ListPorts(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VRSAgent_GetPortResolution_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PortRef)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VRSAgentServer).GetPortResolution(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/nuage.vrsagent.v1.VRSAgent/GetPortResolution",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VRSAgentServer).GetPortResolution(ctx, req.(*PortRef))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VRSAgent_AddPortToBridge_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BridgePort)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VRSAgentServer).AddPortToBridge(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/nuage.vrsagent.v1.VRSAgent/AddPortToBridge",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VRSAgentServer).AddPortToBridge(ctx, req.(*BridgePort))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VRSAgent_RemovePortFromBridge_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PortRef)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VRSAgentServer).RemovePortFromBridge(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/nuage.vrsagent.v1.VRSAgent/RemovePortFromBridge",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VRSAgentServer).RemovePortFromBridge(ctx, req.(*PortRef))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VRSAgent_GetControllerState_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VRSAgentServer).GetControllerState(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/nuage.vrsagent.v1.VRSAgent/GetControllerState",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VRSAgentServer).GetControllerState(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VRSAgent_WatchPortResolution_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(PortRef)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(VRSAgentServer).WatchPortResolution(m, &vRSAgentWatchPortResolutionServer{stream})
+}
+
+type VRSAgent_WatchPortResolutionServer interface {
+	Send(*PortResolution) error
+	grpc.ServerStream
+}
+
+type vRSAgentWatchPortResolutionServer struct {
+	grpc.ServerStream
+}
+
+func (x *vRSAgentWatchPortResolutionServer) Send(m *PortResolution) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _VRSAgent_WatchEntities_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Empty)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(VRSAgentServer).WatchEntities(m, &vRSAgentWatchEntitiesServer{stream})
+}
+
+type VRSAgent_WatchEntitiesServer interface {
+	Send(*EntityEvent) error
+	grpc.ServerStream
+}
+
+type vRSAgentWatchEntitiesServer struct {
+	grpc.ServerStream
+}
+
+func (x *vRSAgentWatchEntitiesServer) Send(m *EntityEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _VRSAgent_WatchControllerState_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Empty)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(VRSAgentServer).WatchControllerState(m, &vRSAgentWatchControllerStateServer{stream})
+}
+
+type VRSAgent_WatchControllerStateServer interface {
+	Send(*ControllerState) error
+	grpc.ServerStream
+}
+
+type vRSAgentWatchControllerStateServer struct {
+	grpc.ServerStream
+}
+
+func (x *vRSAgentWatchControllerStateServer) Send(m *ControllerState) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// VRSAgent_ServiceDesc is the grpc.ServiceDesc for VRSAgent service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var VRSAgent_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "nuage.vrsagent.v1.VRSAgent",
+	HandlerType: (*VRSAgentServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateEntity",
+			Handler:    _VRSAgent_CreateEntity_Handler,
+		},
+		{
+			MethodName: "DestroyEntity",
+			Handler:    _VRSAgent_DestroyEntity_Handler,
+		},
+		{
+			MethodName: "ListEntities",
+			Handler:    _VRSAgent_ListEntities_Handler,
+		},
+		{
+			MethodName: "SetEntityState",
+			Handler:    _VRSAgent_SetEntityState_Handler,
+		},
+		{
+			MethodName: "PostEntityEvent",
+			Handler:    _VRSAgent_PostEntityEvent_Handler,
+		},
+		{
+			MethodName: "AddEntityPorts",
+			Handler:    _VRSAgent_AddEntityPorts_Handler,
+		},
+		{
+			MethodName: "RemoveEntityPorts",
+			Handler:    _VRSAgent_RemoveEntityPorts_Handler,
+		},
+		{
+			MethodName: "CreatePort",
+			Handler:    _VRSAgent_CreatePort_Handler,
+		},
+		{
+			MethodName: "DestroyPort",
+			Handler:    _VRSAgent_DestroyPort_Handler,
+		},
+		{
+			MethodName: "ListPorts",
+			Handler:    _VRSAgent_ListPorts_Handler,
+		},
+		{
+			MethodName: "GetPortResolution",
+			Handler:    _VRSAgent_GetPortResolution_Handler,
+		},
+		{
+			MethodName: "AddPortToBridge",
+			Handler:    _VRSAgent_AddPortToBridge_Handler,
+		},
+		{
+			MethodName: "RemovePortFromBridge",
+			Handler:    _VRSAgent_RemovePortFromBridge_Handler,
+		},
+		{
+			MethodName: "GetControllerState",
+			Handler:    _VRSAgent_GetControllerState_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchPortResolution",
+			Handler:       _VRSAgent_WatchPortResolution_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WatchEntities",
+			Handler:       _VRSAgent_WatchEntities_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WatchControllerState",
+			Handler:       _VRSAgent_WatchControllerState_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "agent.proto",
+}
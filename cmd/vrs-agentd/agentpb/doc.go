@@ -0,0 +1,6 @@
+/*
+Package agentpb holds the gRPC service of vrs-agentd, generated from agent.proto.
+*/
+package agentpb
+
+//go:generate protoc --go_out=paths=source_relative:. --go-grpc_out=paths=source_relative:. agent.proto
@@ -0,0 +1,37 @@
+/*
+Vrs-agentd exposes the SDK to the agents not written in Go. It holds a single connection to the VRS and serves the
+VRSAgent gRPC service, defined in agentpb/agent.proto, and its HTTP/JSON mapping:
+
+	vrs-agentd -vrs /var/run/openvswitch/db.sock -grpc-socket /var/run/vrs-agentd/grpc.sock \
+		-http-socket /var/run/vrs-agentd/http.sock -socket-mode 0660 -socket-group nuage
+
+Both services listen on local Unix sockets, the clients are authorized by the permissions of the sockets: only the
+owner of the agent and the members of the group given by -socket-group can connect with the default mode.
+
+The resolution of the ports, the changes made to the entities and the state of the controller are streamed.
+WatchEntities reports the changes made through the agent as they are made and the changes made by other agents
+when the VRS is polled, every -poll-interval. The watchers lagging behind are dropped with ResourceExhausted.
+
+The HTTP gateway encodes the messages with the JSON mapping of protobuf and reports the errors as
+{"code": "...", "message": "..."} with the matching HTTP status. The watches are streamed as one JSON object per
+line.
+
+	POST   /v1/entities                   CreateEntity
+	GET    /v1/entities                   ListEntities
+	DELETE /v1/entities/{uuid}            DestroyEntity
+	PUT    /v1/entities/{uuid}/state      SetEntityState
+	POST   /v1/entities/{uuid}/events     PostEntityEvent
+	POST   /v1/entities/{uuid}/ports      AddEntityPorts
+	DELETE /v1/entities/{uuid}/ports/{p}  RemoveEntityPorts
+	POST   /v1/ports                      CreatePort
+	GET    /v1/ports                      ListPorts
+	DELETE /v1/ports/{name}               DestroyPort
+	GET    /v1/ports/{name}/resolution    GetPortResolution
+	POST   /v1/bridge/ports               AddPortToBridge
+	DELETE /v1/bridge/ports/{name}        RemovePortFromBridge
+	GET    /v1/controller                 GetControllerState
+	GET    /v1/watch/ports/{name}         WatchPortResolution
+	GET    /v1/watch/entities             WatchEntities
+	GET    /v1/watch/controller           WatchControllerState
+*/
+package main
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/nuagenetworks/libvrsdk/api"
+	"github.com/nuagenetworks/libvrsdk/cmd/vrs-agentd/agentpb"
+	"github.com/nuagenetworks/libvrsdk/logging"
+	"google.golang.org/grpc"
+)
+
+func main() {
+	vrsSocket := flag.String("vrs", "/var/run/openvswitch/db.sock", "Unix socket of the VRS OVSDB server")
+	grpcSocket := flag.String("grpc-socket", "/var/run/vrs-agentd/grpc.sock", "Unix socket of the gRPC service")
+	httpSocket := flag.String("http-socket", "/var/run/vrs-agentd/http.sock",
+		"Unix socket of the HTTP gateway, disabled if empty")
+	socketMode := flag.String("socket-mode", "0660", "Permissions of the sockets")
+	socketGroup := flag.String("socket-group", "", "Group owning the sockets, the group of the agent if empty")
+	pollInterval := flag.Duration("poll-interval", 5*time.Second,
+		"Interval of the polling of the entities changed by other agents")
+	flag.Parse()
+
+	logger := logging.Default()
+
+	mode, err := strconv.ParseUint(*socketMode, 8, 32)
+	if err != nil {
+		logger.Error("Invalid socket mode", logging.F("mode", *socketMode), logging.Err(err))
+		os.Exit(1)
+	}
+
+	vrsConnection, err := api.NewUnixSocketConnection(*vrsSocket)
+	if err != nil {
+		logger.Error("Unable to connect to the VRS", logging.F("socket", *vrsSocket), logging.Err(err))
+		os.Exit(1)
+	}
+	defer vrsConnection.Disconnect()
+
+	server, err := NewServer(vrsConnection, *pollInterval)
+	if err != nil {
+		logger.Error("Unable to read the entities of the VRS", logging.Err(err))
+		os.Exit(1)
+	}
+	defer server.Close()
+
+	grpcListener, err := listenUnix(*grpcSocket, os.FileMode(mode), *socketGroup)
+	if err != nil {
+		logger.Error("Unable to listen", logging.Err(err))
+		os.Exit(1)
+	}
+	grpcServer := grpc.NewServer()
+	agentpb.RegisterVRSAgentServer(grpcServer, server)
+
+	var httpServer *http.Server
+	if *httpSocket != "" {
+		httpListener, err := listenUnix(*httpSocket, os.FileMode(mode), *socketGroup)
+		if err != nil {
+			logger.Error("Unable to listen", logging.Err(err))
+			os.Exit(1)
+		}
+		httpServer = &http.Server{Handler: NewGateway(server)}
+		go func() {
+			if err := httpServer.Serve(httpListener); err != http.ErrServerClosed {
+				logger.Error("HTTP gateway stopped", logging.Err(err))
+			}
+		}()
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-signals
+		if httpServer != nil {
+			httpServer.Close()
+		}
+		grpcServer.Stop()
+	}()
+
+	logger.Info("Agent started", logging.F("grpc", *grpcSocket), logging.F("http", *httpSocket))
+	if err = grpcServer.Serve(grpcListener); err != nil {
+		logger.Error("gRPC service stopped", logging.Err(err))
+	}
+}
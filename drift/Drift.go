@@ -0,0 +1,196 @@
+package drift
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nuagenetworks/libvrsdk/api"
+	"github.com/nuagenetworks/libvrsdk/api/entity"
+	"github.com/nuagenetworks/libvrsdk/api/port"
+	"github.com/nuagenetworks/libvrsdk/vsd"
+	"github.com/nuagenetworks/vspk-go/vspk"
+)
+
+// Scope selects the ports of the VRS and the interfaces of the VSD compared by CompareWithVSD
+type Scope struct {
+	Enterprise string
+	Domain     string
+
+	// Exclusive is set when the VRS is the only one serving the domain. Interfaces of the VSD that belong to
+	// entities unknown to the VRS are then reported as missing on the VRS instead of being ignored.
+	Exclusive bool
+}
+
+// Kind identifies the way a port differs between the VRS and the VSD
+type Kind string
+
+// Kinds of drift reported by CompareWithVSD
+const (
+	KindMissingOnVSD   Kind = "missing-on-vsd"
+	KindMissingOnVRS   Kind = "missing-on-vrs"
+	KindIPMismatch     Kind = "ip-mismatch"
+	KindMACMismatch    Kind = "mac-mismatch"
+	KindZoneMismatch   Kind = "zone-mismatch"
+	KindSubnetMismatch Kind = "subnet-mismatch"
+)
+
+// Drift is a difference between a port of the VRS and the interface of the VSD with the same name. VRS and
+// VSD hold the differing values, they are empty for the missing side.
+type Drift struct {
+	Kind   Kind
+	Port   string
+	Entity string
+	VRS    string
+	VSD    string
+}
+
+// String describes the drift in a human readable way
+func (drift Drift) String() string {
+	switch drift.Kind {
+	case KindMissingOnVSD:
+		return fmt.Sprintf("Port %s of entity %s missing on VSD", drift.Port, drift.Entity)
+	case KindMissingOnVRS:
+		return fmt.Sprintf("Interface %s of entity %s missing on VRS", drift.Port, drift.Entity)
+	}
+	return fmt.Sprintf("Port %s of entity %s %s: %s on VRS, %s on VSD", drift.Port, drift.Entity, drift.Kind,
+		drift.VRS, drift.VSD)
+}
+
+// Report is the outcome of CompareWithVSD
+type Report struct {
+	Scope      Scope
+	Ports      int
+	Interfaces int
+	Drifts     []Drift
+}
+
+// InSync returns true if no drift was found
+func (report *Report) InSync() bool {
+	return len(report.Drifts) == 0
+}
+
+// vrsPort is a port of the VRS within the scope along with its resolution state
+type vrsPort struct {
+	spec   api.PortSpec
+	entity string
+	state  map[port.StateKey]interface{}
+}
+
+// CompareWithVSD walks the ports of the VRS belonging to entities of the enterprise and attached to the domain
+// of the scope and compares them with the VM and container interfaces of the domain on the VSD
+func CompareWithVSD(conn *api.VRSConnection, root *vspk.Me, scope Scope) (*Report, error) {
+	entities, err := conn.GetAllEntityInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	specs, err := conn.GetAllPortSpecs()
+	if err != nil {
+		return nil, err
+	}
+
+	resolved, err := vsd.Resolve(root, vsd.Path{Enterprise: scope.Enterprise, Domain: scope.Domain})
+	if err != nil {
+		return nil, err
+	}
+
+	interfaces, err := resolved.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	knownEntities := make(map[string]bool)
+	portEntities := make(map[string]string)
+	for _, info := range entities {
+		if info.Metadata[entity.MetadataKeyEnterprise] != scope.Enterprise {
+			continue
+		}
+		knownEntities[info.UUID] = true
+		for _, name := range info.Ports {
+			portEntities[name] = info.UUID
+		}
+	}
+
+	var ports []vrsPort
+	for _, spec := range specs {
+		uuid, ok := portEntities[spec.Name]
+		if !ok || spec.Metadata[port.MetadataKeyDomain] != scope.Domain {
+			continue
+		}
+
+		state, err := conn.GetPortState(spec.Name)
+		if err != nil {
+			return nil, err
+		}
+		ports = append(ports, vrsPort{spec: spec, entity: uuid, state: state})
+	}
+
+	return compare(scope, ports, interfaces, knownEntities), nil
+}
+
+// compare builds the drift report of the ports of the VRS against the interfaces of the VSD
+func compare(scope Scope, ports []vrsPort, interfaces []*vsd.Interface, knownEntities map[string]bool) *Report {
+	report := &Report{Scope: scope, Ports: len(ports), Interfaces: len(interfaces)}
+
+	byName := make(map[string]*vsd.Interface)
+	for _, iface := range interfaces {
+		byName[iface.Name] = iface
+	}
+
+	seen := make(map[string]bool)
+	for _, vrs := range ports {
+		name := vrs.spec.Name
+		seen[name] = true
+
+		iface, ok := byName[name]
+		if !ok {
+			report.Drifts = append(report.Drifts, Drift{Kind: KindMissingOnVSD, Port: name, Entity: vrs.entity})
+			continue
+		}
+
+		ip := ""
+		if value, ok := vrs.state[port.StateKeyIPAddress]; ok && value != nil {
+			ip = fmt.Sprintf("%v", value)
+		}
+
+		fields := []struct {
+			kind Kind
+			vrs  string
+			vsd  string
+		}{
+			{KindIPMismatch, ip, iface.IPAddress},
+			{KindMACMismatch, vrs.spec.Attributes.MAC, iface.MAC},
+			{KindZoneMismatch, vrs.spec.Metadata[port.MetadataKeyZone], iface.ZoneName},
+			{KindSubnetMismatch, vrs.spec.Metadata[port.MetadataKeyNetwork], iface.NetworkName},
+		}
+		for _, field := range fields {
+			if !sameValue(field.kind, field.vrs, field.vsd) {
+				report.Drifts = append(report.Drifts, Drift{Kind: field.kind, Port: name, Entity: vrs.entity,
+					VRS: field.vrs, VSD: field.vsd})
+			}
+		}
+	}
+
+	for _, iface := range interfaces {
+		if seen[iface.Name] || (!scope.Exclusive && !knownEntities[iface.EntityUUID]) {
+			continue
+		}
+		report.Drifts = append(report.Drifts, Drift{Kind: KindMissingOnVRS, Port: iface.Name,
+			Entity: iface.EntityUUID})
+	}
+
+	sort.SliceStable(report.Drifts, func(i, j int) bool {
+		return report.Drifts[i].Port < report.Drifts[j].Port
+	})
+
+	return report
+}
+
+// sameValue compares a field of the VRS and of the VSD, MAC addresses are compared regardless of their case
+func sameValue(kind Kind, vrs string, vsd string) bool {
+	if kind == KindMACMismatch {
+		return strings.EqualFold(vrs, vsd)
+	}
+	return vrs == vsd
+}
@@ -0,0 +1,136 @@
+package drift
+
+import (
+	"testing"
+
+	"github.com/nuagenetworks/libvrsdk/api"
+	"github.com/nuagenetworks/libvrsdk/api/entity"
+	"github.com/nuagenetworks/libvrsdk/api/port"
+	"github.com/nuagenetworks/libvrsdk/ovsdb"
+	"github.com/nuagenetworks/libvrsdk/ovsdb/ovsdbtest"
+	"github.com/nuagenetworks/libvrsdk/vsd"
+	"github.com/nuagenetworks/libvrsdk/vsd/vsdtest"
+	"github.com/nuagenetworks/vspk-go/vspk"
+	"github.com/socketplane/libovsdb"
+)
+
+func TestCompare(t *testing.T) {
+	spec := func(name string, mac string, zone string) api.PortSpec {
+		return api.PortSpec{
+			Name:       name,
+			Attributes: port.Attributes{MAC: mac},
+			Metadata:   map[port.MetadataKey]string{port.MetadataKeyZone: zone, port.MetadataKeyNetwork: "n1"},
+		}
+	}
+	resolvedState := map[port.StateKey]interface{}{port.StateKeyIPAddress: "10.0.0.2"}
+
+	ports := []vrsPort{
+		{spec: spec("p1", "AA:BB:CC:00:00:01", "z1"), entity: "e1", state: resolvedState},
+		{spec: spec("p2", "aa:bb:cc:00:00:02", "z1"), entity: "e1", state: resolvedState},
+		{spec: spec("p3", "aa:bb:cc:00:00:03", "z1"), entity: "e1", state: resolvedState},
+	}
+	interfaces := []*vsd.Interface{
+		{Name: "p1", EntityUUID: "e1", MAC: "aa:bb:cc:00:00:01", IPAddress: "10.0.0.2", ZoneName: "z1", NetworkName: "n1"},
+		{Name: "p2", EntityUUID: "e1", MAC: "aa:bb:cc:00:00:09", IPAddress: "10.0.0.3", ZoneName: "z2", NetworkName: "n1"},
+		{Name: "p4", EntityUUID: "e1"},
+		{Name: "p5", EntityUUID: "e2"},
+	}
+	known := map[string]bool{"e1": true}
+
+	report := compare(Scope{Enterprise: "ent", Domain: "dom"}, ports, interfaces, known)
+	expected := []Kind{KindIPMismatch, KindMACMismatch, KindZoneMismatch, KindMissingOnVSD, KindMissingOnVRS}
+	if len(report.Drifts) != len(expected) {
+		t.Fatalf("Unexpected drifts %v", report.Drifts)
+	}
+	for i, kind := range expected {
+		if report.Drifts[i].Kind != kind {
+			t.Fatalf("Unexpected drift %v, expected %s", report.Drifts[i], kind)
+		}
+	}
+
+	report = compare(Scope{Enterprise: "ent", Domain: "dom", Exclusive: true}, ports, interfaces, known)
+	if last := report.Drifts[len(report.Drifts)-1]; last.Kind != KindMissingOnVRS || last.Port != "p5" {
+		t.Fatalf("Interface of unknown entity not reported in exclusive scope %v", report.Drifts)
+	}
+}
+
+func TestCompareWithVSD(t *testing.T) {
+	server, err := ovsdbtest.NewServer()
+	if err != nil {
+		t.Fatalf("Unable to start the OVSDB server %v", err)
+	}
+	defer server.Close()
+
+	vrsConnection, err := api.NewUnixSocketConnection(server.SocketPath())
+	if err != nil {
+		t.Fatalf("Unable to connect to the VRS %v", err)
+	}
+	defer vrsConnection.Disconnect()
+
+	vsdServer := vsdtest.NewServer()
+	defer vsdServer.Close()
+
+	_, root, err := vsdServer.Session()
+	if err != nil {
+		t.Fatalf("Unable to start the session %v", err)
+	}
+
+	enterpriseID := vsdServer.MustAdd("", &vspk.Enterprise{Name: "enterprise"})
+	domainID := vsdServer.MustAdd(enterpriseID, &vspk.Domain{Name: "domain"})
+	zoneID := vsdServer.MustAdd(domainID, &vspk.Zone{Name: "z1"})
+	subnetID := vsdServer.MustAdd(zoneID, &vspk.Subnet{Name: "n1"})
+	vportID := vsdServer.MustAdd(subnetID, &vspk.VPort{Name: "vport", Type: "CONTAINER"})
+	vsdServer.MustAdd(vportID, &vspk.ContainerInterface{Name: "p1", MAC: "aa:bb:cc:00:00:01", IPAddress: "10.0.0.2",
+		ZoneName: "z1", NetworkName: "n1", ContainerUUID: "vm"})
+	vsdServer.MustAdd(vportID, &vspk.ContainerInterface{Name: "p4", ContainerUUID: "vm"})
+	vsdServer.MustAdd(vportID, &vspk.ContainerInterface{Name: "p5", ContainerUUID: "unknown"})
+
+	for name, domain := range map[string]string{"p1": "domain", "p2": "domain", "p3": "other"} {
+		attributes := port.Attributes{MAC: "AA:BB:CC:00:00:01", Platform: entity.Docker, Bridge: "alubr0"}
+		metadata := map[port.MetadataKey]string{port.MetadataKeyDomain: domain, port.MetadataKeyZone: "z1",
+			port.MetadataKeyNetwork: "n1"}
+		if err = vrsConnection.CreatePort(name, attributes, metadata); err != nil {
+			t.Fatalf("Unable to create the port %s %v", name, err)
+		}
+	}
+	info := api.EntityInfo{
+		UUID:     "vm",
+		Name:     "vm",
+		Type:     entity.Container,
+		Domain:   entity.Docker,
+		Ports:    []string{"p1", "p2", "p3"},
+		Metadata: map[entity.MetadataKey]string{entity.MetadataKeyEnterprise: "enterprise"},
+	}
+	if err = vrsConnection.CreateEntity(info); err != nil {
+		t.Fatalf("Unable to create the entity %v", err)
+	}
+	if _, err = server.Transact(libovsdb.Operation{
+		Op:    "update",
+		Table: ovsdb.NuagePortTable,
+		Row:   map[string]interface{}{"ip_addr": "10.0.0.2", "subnet_mask": "255.255.255.0", "gateway": "10.0.0.1"},
+		Where: []interface{}{libovsdb.NewCondition("name", "==", "p1")},
+	}); err != nil {
+		t.Fatalf("Unable to resolve the port %v", err)
+	}
+
+	report, err := CompareWithVSD(&vrsConnection, root, Scope{Enterprise: "enterprise", Domain: "domain"})
+	if err != nil {
+		t.Fatalf("Unable to compare with the VSD %v", err)
+	}
+	expected := []Drift{
+		{Kind: KindMissingOnVSD, Port: "p2", Entity: "vm"},
+		{Kind: KindMissingOnVRS, Port: "p4", Entity: "vm"},
+	}
+	if report.Ports != 2 || report.Interfaces != 3 || len(report.Drifts) != len(expected) {
+		t.Fatalf("Unexpected report %+v", report)
+	}
+	for i := range expected {
+		if report.Drifts[i] != expected[i] {
+			t.Fatalf("Unexpected drift %v, expected %v", report.Drifts[i], expected[i])
+		}
+	}
+
+	if _, err = CompareWithVSD(&vrsConnection, root, Scope{Enterprise: "enterprise", Domain: "missing"}); err == nil {
+		t.Fatalf("Compared with a missing domain")
+	}
+}
@@ -0,0 +1,9 @@
+/*
+Package drift reports the differences between the ports of a Nuage VRS and the interfaces the VSD holds for them.
+
+Failed deletions and resolutions leave ports in Nuage_Port_Table with no interface on the VSD, or interfaces on the
+VSD with no port left on the VRS. CompareWithVSD walks the ports of the entities of an enterprise attached to a
+domain, looks up the VM and container interfaces of the domain with the vsd package and matches them by name. The
+Report lists the ports missing on either side and those whose IP address, MAC address, zone or subnet differ.
+*/
+package drift
@@ -0,0 +1,391 @@
+package fleet
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nuagenetworks/libvrsdk/api"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Endpoint locates the OVSDB server of a VRS, either a Unix socket or a TCP port
+type Endpoint struct {
+	Socket string
+	Host   string
+	Port   int
+}
+
+func (endpoint Endpoint) String() string {
+	if endpoint.Socket != "" {
+		return "unix:" + endpoint.Socket
+	}
+	return fmt.Sprintf("tcp:%s:%d", endpoint.Host, endpoint.Port)
+}
+
+// Dial connects to the VRS at endpoint
+func Dial(endpoint Endpoint) (api.VRSConnection, error) {
+	if endpoint.Socket != "" {
+		return api.NewUnixSocketConnection(endpoint.Socket)
+	}
+	return api.NewTCPConnection(endpoint.Host, endpoint.Port)
+}
+
+// Health is the state of a host as last seen by the fleet
+type Health struct {
+	// Connected is true while the fleet holds a live connection to the host
+	Connected bool
+	// ControllerState is the state of the connection of the VRS to its controller, read on connect and by Check
+	ControllerState api.ControllerState
+	// LastError is the error of the last failed connection attempt or of the last lost connection, nil once
+	// the host is reached again
+	LastError error
+	// Failures counts the connection attempts failed since the host was last reached
+	Failures int
+	// LastSeen is the time the host last answered
+	LastSeen time.Time
+}
+
+// Healthy returns true if the host is connected and its VRS connected to its controller
+func (health Health) Healthy() bool {
+	return health.Connected && health.ControllerState == api.ControllerConnected
+}
+
+// host holds the connection to a VRS of the fleet
+type host struct {
+	name     string
+	endpoint Endpoint
+
+	// dial serializes the connection attempts, mutex guards the connection and the health
+	dial        sync.Mutex
+	mutex       sync.Mutex
+	connection  *api.VRSConnection
+	health      Health
+	lastAttempt time.Time
+	removed     bool
+}
+
+// Operation is run by the fleet on the connection to each selected host
+type Operation func(ctx context.Context, name string, vrsConnection *api.VRSConnection) error
+
+// Fleet keeps named connections to many VRS. The hosts are connected on first use and reconnected on the next
+// use after their connection is lost.
+type Fleet struct {
+	// Dial connects to the hosts, Dial by default
+	Dial func(endpoint Endpoint) (api.VRSConnection, error)
+
+	// RetryInterval is the time a host stays unavailable after a failed connection attempt, so that a dead
+	// host does not slow down each operation with a new attempt
+	RetryInterval time.Duration
+
+	// KeepaliveInterval and KeepaliveMisses configure the keepalive of the connections, see
+	// api.VRSConnection.SetKeepalive. A connection declared dead by its keepalive is recreated on next use.
+	KeepaliveInterval time.Duration
+	KeepaliveMisses   int
+
+	mutex sync.Mutex
+	hosts map[string]*host
+	slots chan struct{}
+}
+
+// New creates an empty fleet running at most concurrency operations, connection attempts included, at a time
+func New(concurrency int) *Fleet {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	return &Fleet{
+		Dial:  Dial,
+		hosts: make(map[string]*host),
+		slots: make(chan struct{}, concurrency),
+	}
+}
+
+// Add adds a host to the fleet, it is connected on first use
+func (fleet *Fleet) Add(name string, endpoint Endpoint) error {
+	fleet.mutex.Lock()
+	defer fleet.mutex.Unlock()
+
+	if _, exists := fleet.hosts[name]; exists {
+		return fmt.Errorf("Host %s already in the fleet", name)
+	}
+	fleet.hosts[name] = &host{name: name, endpoint: endpoint}
+
+	return nil
+}
+
+// Remove removes a host from the fleet and closes its connection, failing the operations running on it
+func (fleet *Fleet) Remove(name string) error {
+	fleet.mutex.Lock()
+	h, exists := fleet.hosts[name]
+	delete(fleet.hosts, name)
+	fleet.mutex.Unlock()
+
+	if !exists {
+		return fmt.Errorf("Unknown host %s", name)
+	}
+	h.close()
+
+	return nil
+}
+
+// Close closes the connections to all the hosts and empties the fleet
+func (fleet *Fleet) Close() {
+	fleet.mutex.Lock()
+	hosts := fleet.hosts
+	fleet.hosts = make(map[string]*host)
+	fleet.mutex.Unlock()
+
+	for _, h := range hosts {
+		h.close()
+	}
+}
+
+// Names returns the sorted names of the hosts of the fleet
+func (fleet *Fleet) Names() []string {
+	fleet.mutex.Lock()
+	defer fleet.mutex.Unlock()
+
+	names := make([]string, 0, len(fleet.hosts))
+	for name := range fleet.hosts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Select returns the sorted names of the hosts whose health satisfies filter
+func (fleet *Fleet) Select(filter func(name string, health Health) bool) []string {
+	var names []string
+	for _, name := range fleet.Names() {
+		if health, err := fleet.Health(name); err == nil && filter(name, health) {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// Health returns the health of the host
+func (fleet *Fleet) Health(name string) (Health, error) {
+	h, err := fleet.host(name)
+	if err != nil {
+		return Health{}, err
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.refresh()
+	return h.health, nil
+}
+
+// Connection returns the connection to the host, connecting it if it is not connected yet or lost its
+// connection. The connection must not be disconnected by the caller, it is shared with the other callers.
+func (fleet *Fleet) Connection(name string) (*api.VRSConnection, error) {
+	h, err := fleet.host(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return fleet.connect(h)
+}
+
+// Run runs op on each host of names, at most the concurrency of the fleet at a time, and returns the error of
+// each host. The hosts not reached before ctx is done report the error of ctx.
+func (fleet *Fleet) Run(ctx context.Context, names []string, op Operation) Results {
+	results := make(Results)
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, name := range names {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			err := fleet.run(ctx, name, op)
+			mutex.Lock()
+			results[name] = err
+			mutex.Unlock()
+		}(name)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// RunAll runs op on all the hosts of the fleet
+func (fleet *Fleet) RunAll(ctx context.Context, op Operation) Results {
+	return fleet.Run(ctx, fleet.Names(), op)
+}
+
+// Check connects to the hosts of names and reads the state of their controller, updating their health
+func (fleet *Fleet) Check(ctx context.Context, names []string) Results {
+	return fleet.Run(ctx, names, func(ctx context.Context, name string, vrsConnection *api.VRSConnection) error {
+		state, err := vrsConnection.GetControllerState()
+		if err != nil {
+			return fmt.Errorf("Unable to read the controller state %v", err)
+		}
+
+		if h, err := fleet.host(name); err == nil {
+			h.mutex.Lock()
+			h.health.ControllerState = state
+			h.mutex.Unlock()
+		}
+		return nil
+	})
+}
+
+// Collector returns the metrics of the connections of the fleet, labelled with the name of their host, for the
+// control plane to register with Prometheus. The metrics of a host restart from zero when it is reconnected.
+func (fleet *Fleet) Collector() prometheus.Collector {
+	return fleetCollector{fleet: fleet}
+}
+
+// fleetCollector collects the metrics of the hosts connected at the time of the collection. It describes no
+// metric, the hosts of the fleet changing after its registration.
+type fleetCollector struct {
+	fleet *Fleet
+}
+
+// Describe implements prometheus.Collector
+func (collector fleetCollector) Describe(ch chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector
+func (collector fleetCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, name := range collector.fleet.Names() {
+		h, err := collector.fleet.host(name)
+		if err != nil {
+			continue
+		}
+
+		h.mutex.Lock()
+		vrsConnection := h.connection
+		h.mutex.Unlock()
+
+		if vrsConnection != nil {
+			vrsConnection.Collector().Collect(ch)
+		}
+	}
+}
+
+func (fleet *Fleet) run(ctx context.Context, name string, op Operation) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	select {
+	case fleet.slots <- struct{}{}:
+		defer func() { <-fleet.slots }()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	h, err := fleet.host(name)
+	if err != nil {
+		return err
+	}
+
+	vrsConnection, err := fleet.connect(h)
+	if err != nil {
+		return err
+	}
+
+	err = op(ctx, name, vrsConnection)
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if err == nil {
+		h.health.LastSeen = time.Now()
+	} else {
+		h.refresh()
+	}
+
+	return err
+}
+
+func (fleet *Fleet) host(name string) (*host, error) {
+	fleet.mutex.Lock()
+	defer fleet.mutex.Unlock()
+
+	h, exists := fleet.hosts[name]
+	if !exists {
+		return nil, fmt.Errorf("Unknown host %s", name)
+	}
+
+	return h, nil
+}
+
+// connect returns the live connection of the host, reconnecting it if needed
+func (fleet *Fleet) connect(h *host) (*api.VRSConnection, error) {
+	h.dial.Lock()
+	defer h.dial.Unlock()
+
+	h.mutex.Lock()
+	h.refresh()
+	if h.connection != nil {
+		defer h.mutex.Unlock()
+		return h.connection, nil
+	}
+	if h.health.LastError != nil && time.Since(h.lastAttempt) < fleet.RetryInterval {
+		defer h.mutex.Unlock()
+		return nil, fmt.Errorf("Host %s unavailable %v", h.endpoint, h.health.LastError)
+	}
+	h.lastAttempt = time.Now()
+	h.mutex.Unlock()
+
+	vrsConnection, err := fleet.Dial(h.endpoint)
+	var state api.ControllerState
+	if err == nil {
+		if state, err = vrsConnection.GetControllerState(); err != nil {
+			vrsConnection.Disconnect()
+		}
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if err != nil {
+		h.health.LastError = err
+		h.health.Failures++
+		return nil, fmt.Errorf("Unable to connect to %s %v", h.endpoint, err)
+	}
+	if h.removed {
+		vrsConnection.Disconnect()
+		return nil, fmt.Errorf("Host %s removed from the fleet", h.endpoint)
+	}
+
+	vrsConnection.SetMetricsLabels(prometheus.Labels{"host": h.name})
+	vrsConnection.SetKeepalive(fleet.KeepaliveInterval, fleet.KeepaliveMisses)
+	h.connection = &vrsConnection
+	h.health = Health{Connected: true, ControllerState: state, LastSeen: time.Now()}
+
+	return h.connection, nil
+}
+
+// refresh drops the connection of the host if it was lost, the caller holds the mutex of the host
+func (h *host) refresh() {
+	if h.connection == nil || h.connection.Connected() {
+		return
+	}
+
+	h.connection.Disconnect()
+	h.connection = nil
+	h.health.Connected = false
+	h.health.LastError = fmt.Errorf("Connection to %s lost", h.endpoint)
+}
+
+func (h *host) close() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.removed = true
+
+	if h.connection != nil {
+		h.connection.Disconnect()
+		h.connection = nil
+		h.health.Connected = false
+	}
+}
@@ -0,0 +1,287 @@
+package fleet
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nuagenetworks/libvrsdk/api"
+	"github.com/nuagenetworks/libvrsdk/api/entity"
+	"github.com/nuagenetworks/libvrsdk/metrics"
+	"github.com/nuagenetworks/libvrsdk/ovsdb/ovsdbtest"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/socketplane/libovsdb"
+)
+
+func startServer(t *testing.T, path string) *ovsdbtest.Server {
+	server, err := ovsdbtest.NewServerWithSchema("unix", path, ovsdbtest.VRSSchema)
+	if err != nil {
+		t.Fatalf("Unable to start the OVSDB server %v", err)
+	}
+	return server
+}
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fleet")
+	if err != nil {
+		t.Fatalf("Unable to create the socket directory %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	hv1 := startServer(t, filepath.Join(dir, "hv1.sock"))
+	defer hv1.Close()
+	hv2, err := ovsdbtest.NewTCPServer()
+	if err != nil {
+		t.Fatalf("Unable to start the OVSDB server %v", err)
+	}
+	defer hv2.Close()
+
+	// hv2 is connected to its controller
+	controllerOp := libovsdb.Operation{Op: "insert", Table: "Controller", UUIDName: "controller",
+		Row: map[string]interface{}{"target": "tcp:10.0.0.1:6633", "role": api.MasterController}}
+	bridgeOp := libovsdb.Operation{Op: "insert", Table: "Bridge",
+		Row: map[string]interface{}{"name": "alubr0", "controller": libovsdb.UUID{GoUUID: "controller"}}}
+	if _, err = hv2.Transact(controllerOp, bridgeOp); err != nil {
+		t.Fatalf("Unable to connect hv2 to its controller %v", err)
+	}
+
+	f := New(1)
+	defer f.Close()
+	f.RetryInterval = time.Hour
+	f.Add("hv1", Endpoint{Socket: hv1.SocketPath()})
+	f.Add("hv2", Endpoint{Host: hv2.Host(), Port: hv2.Port()})
+	f.Add("hv3", Endpoint{Socket: filepath.Join(dir, "hv3.sock")})
+	if err = f.Add("hv1", Endpoint{}); err == nil {
+		t.Fatalf("Host added twice")
+	}
+
+	var mutex sync.Mutex
+	running, maxRunning := 0, 0
+	results := f.RunAll(context.Background(), func(ctx context.Context, name string,
+		vrsConnection *api.VRSConnection) error {
+
+		mutex.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mutex.Unlock()
+		defer func() {
+			mutex.Lock()
+			running--
+			mutex.Unlock()
+		}()
+
+		time.Sleep(10 * time.Millisecond)
+		return vrsConnection.CreateEntity(api.EntityInfo{UUID: "uuid-" + name, Name: name, Type: entity.Container,
+			Domain: entity.Docker})
+	})
+
+	if maxRunning != 1 {
+		t.Fatalf("Concurrency exceeded %d", maxRunning)
+	}
+	if succeeded := results.Succeeded(); fmt.Sprint(succeeded) != "[hv1 hv2]" {
+		t.Fatalf("Unexpected successes %v", succeeded)
+	}
+	if failed := results.Failed(); fmt.Sprint(failed) != "[hv3]" {
+		t.Fatalf("Unexpected failures %v", failed)
+	}
+	if _, ok := results.Err().(*Error); !ok {
+		t.Fatalf("Unexpected error %v", results.Err())
+	}
+
+	health, _ := f.Health("hv3")
+	if health.Connected || health.LastError == nil || health.Failures != 1 {
+		t.Fatalf("Unexpected health of hv3 %+v", health)
+	}
+	// hv3 is not retried before the retry interval
+	f.Run(context.Background(), []string{"hv3"}, func(context.Context, string, *api.VRSConnection) error {
+		return nil
+	})
+	if health, _ = f.Health("hv3"); health.Failures != 1 {
+		t.Fatalf("hv3 retried before the retry interval %+v", health)
+	}
+
+	f.Check(context.Background(), f.Names())
+	healthy := f.Select(func(name string, health Health) bool { return health.Healthy() })
+	if fmt.Sprint(healthy) != "[hv2]" {
+		t.Fatalf("Unexpected healthy hosts %v", healthy)
+	}
+
+	if err = f.Remove("hv2"); err != nil {
+		t.Fatalf("Unable to remove hv2 %v", err)
+	}
+	if _, err = f.Connection("hv2"); err == nil {
+		t.Fatalf("Removed host connected")
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fleet")
+	if err != nil {
+		t.Fatalf("Unable to create the socket directory %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "hv1.sock")
+	server := startServer(t, path)
+
+	f := New(4)
+	defer f.Close()
+	f.Add("hv1", Endpoint{Socket: path})
+
+	first, err := f.Connection("hv1")
+	if err != nil {
+		t.Fatalf("Unable to connect %v", err)
+	}
+	if again, _ := f.Connection("hv1"); again != first {
+		t.Fatalf("Connection not reused")
+	}
+
+	// The VRS restarts
+	server.Close()
+	for i := 0; first.Connected(); i++ {
+		if i == 100 {
+			t.Fatalf("Loss of the connection not reported")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if health, _ := f.Health("hv1"); health.Connected || health.LastError == nil {
+		t.Fatalf("Lost connection reported healthy %+v", health)
+	}
+
+	server = startServer(t, path)
+	defer server.Close()
+
+	results := f.Run(context.Background(), []string{"hv1"}, func(ctx context.Context, name string,
+		vrsConnection *api.VRSConnection) error {
+
+		if vrsConnection == first {
+			return fmt.Errorf("Lost connection reused")
+		}
+		_, err := vrsConnection.GetControllerState()
+		return err
+	})
+	if err = results.Err(); err != nil {
+		t.Fatalf("Unable to reconnect %v", err)
+	}
+	if health, _ := f.Health("hv1"); !health.Connected || health.LastError != nil ||
+		health.ControllerState != api.ControllerDisconnected {
+		t.Fatalf("Unexpected health after reconnecting %+v", health)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results = f.Run(ctx, []string{"hv1"}, func(context.Context, string, *api.VRSConnection) error { return nil })
+	if results["hv1"] != context.Canceled {
+		t.Fatalf("Operation run after its context was canceled %v", results["hv1"])
+	}
+}
+
+func TestKeepalive(t *testing.T) {
+	server, err := ovsdbtest.NewTCPServer()
+	if err != nil {
+		t.Fatalf("Unable to start the OVSDB server %v", err)
+	}
+	defer server.Close()
+
+	f := New(4)
+	defer f.Close()
+	f.KeepaliveInterval = 10 * time.Millisecond
+	f.KeepaliveMisses = 2
+	f.Add("hv1", Endpoint{Host: server.Host(), Port: server.Port()})
+
+	first, err := f.Connection("hv1")
+	if err != nil {
+		t.Fatalf("Unable to connect %v", err)
+	}
+
+	// The connection is left half-open
+	server.Pause()
+	for i := 0; first.Connected(); i++ {
+		if i == 500 {
+			server.Resume()
+			t.Fatalf("Dead connection not detected")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	server.Resume()
+
+	if second, err := f.Connection("hv1"); err != nil || second == first {
+		t.Fatalf("Dead connection not recreated %v", err)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fleet")
+	if err != nil {
+		t.Fatalf("Unable to create the socket directory %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	f := New(4)
+	defer f.Close()
+	for _, name := range []string{"hv1", "hv2"} {
+		server := startServer(t, filepath.Join(dir, name))
+		defer server.Close()
+		f.Add(name, Endpoint{Socket: filepath.Join(dir, name)})
+	}
+
+	results := f.RunAll(context.Background(), func(ctx context.Context, name string,
+		vrsConnection *api.VRSConnection) error {
+		_, err := vrsConnection.GetControllerState()
+		return err
+	})
+	if err := results.Err(); err != nil {
+		t.Fatalf("Unable to reach the hosts %v", err)
+	}
+
+	// The connections of the hosts are registered together, they are told apart by their host label
+	registry := prometheus.NewPedanticRegistry()
+	for _, name := range f.Names() {
+		vrsConnection, err := f.Connection(name)
+		if err != nil {
+			t.Fatalf("Unable to connect to %s %v", name, err)
+		}
+		if err := registry.Register(vrsConnection.Collector()); err != nil {
+			t.Fatalf("Unable to register the metrics of %s %v", name, err)
+		}
+	}
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Unable to gather the metrics %v", err)
+	}
+
+	hosts := make(map[string]bool)
+	for _, family := range families {
+		if family.GetName() != metrics.Namespace+"_ovsdb_transactions_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "host" {
+					hosts[label.GetValue()] = true
+				}
+			}
+		}
+	}
+	if !hosts["hv1"] || !hosts["hv2"] || len(hosts) != 2 {
+		t.Fatalf("Unexpected hosts in the metrics %v", hosts)
+	}
+
+	// The collector of the fleet gathers the same metrics
+	fleetRegistry := prometheus.NewRegistry()
+	fleetRegistry.MustRegister(f.Collector())
+	fleetFamilies, err := fleetRegistry.Gather()
+	if err != nil {
+		t.Fatalf("Unable to gather the metrics of the fleet %v", err)
+	}
+	if len(fleetFamilies) != len(families) {
+		t.Fatalf("Unexpected metrics of the fleet %d, expected %d", len(fleetFamilies), len(families))
+	}
+}
@@ -0,0 +1,62 @@
+package fleet
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Results holds the error of each host an operation was run on, nil for the hosts it succeeded on
+type Results map[string]error
+
+// Succeeded returns the sorted names of the hosts the operation succeeded on
+func (results Results) Succeeded() []string {
+	var names []string
+	for name, err := range results {
+		if err == nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Failed returns the sorted names of the hosts the operation failed on
+func (results Results) Failed() []string {
+	var names []string
+	for name, err := range results {
+		if err != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Err returns an *Error if the operation failed on any host, nil otherwise
+func (results Results) Err() error {
+	failed := results.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+
+	return &Error{Results: results, Failed: failed}
+}
+
+// Error is returned when an operation failed on some of the hosts it was run on
+type Error struct {
+	Results Results
+	Failed  []string
+}
+
+func (e *Error) Error() string {
+	failures := make([]string, len(e.Failed))
+	for i, name := range e.Failed {
+		failures[i] = fmt.Sprintf("%s: %v", name, e.Results[name])
+	}
+
+	return fmt.Sprintf("Operation failed on %d of %d hosts %s", len(e.Failed), len(e.Results),
+		strings.Join(failures, "; "))
+}
@@ -0,0 +1,30 @@
+/*
+Package fleet manages the connections of a control plane to the VRS of many hypervisors.
+
+A Fleet holds one connection per named host. The hosts are connected on first use rather than when they are
+added, and a host whose connection is lost is reconnected on its next use, so that the hypervisors being
+rebooted or upgraded do not need any handling from the caller. A host that could not be reached is not retried
+before RetryInterval, its operations fail at once in the meantime. Setting KeepaliveInterval detects the
+connections left half-open by a hypervisor gone away without waiting for an operation to hang on them.
+
+The fleet records the health of each host, whether it is connected, the state of its VRS controller and its
+last error, which Select uses to pick the hosts of an operation:
+
+	f := fleet.New(32)
+	f.Add("hv1", fleet.Endpoint{Host: "10.0.0.1", Port: 6640})
+	f.Add("hv2", fleet.Endpoint{Host: "10.0.0.2", Port: 6640})
+
+	f.Check(ctx, f.Names())
+	healthy := f.Select(func(name string, health fleet.Health) bool { return health.Healthy() })
+	results := f.Run(ctx, healthy, func(ctx context.Context, name string, c *api.VRSConnection) error {
+		return c.CreateEntity(info)
+	})
+	if err := results.Err(); err != nil {
+		...
+	}
+
+Run runs the operation on the hosts in parallel, never more than the concurrency given to New across all the
+calls of the fleet, and reports the error of each host. The metrics of the connections are labelled with the
+name of their host and exported together by registering the fleet's Collector.
+*/
+package fleet
@@ -6,15 +6,20 @@ require (
 	github.com/ccding/go-config-reader v0.0.0-20130817225950-8b6c2b50197f // indirect
 	github.com/ccding/go-logging v0.0.0-20160801042505-0ce5ba613fdd // indirect
 	github.com/cenk/hub v1.0.1-0.20160321223918-b864404b5f99 // indirect
-	github.com/cenk/rpc2 v0.0.0-20160427170138-7ab76d2e88c7 // indirect
+	github.com/cenk/rpc2 v0.0.0-20160427170138-7ab76d2e88c7
 	github.com/cenkalti/hub v1.0.1 // indirect
+	github.com/containernetworking/cni v0.8.1
 	github.com/docker/distribution v2.5.0-rc.1.0.20160926232829-99cb7c0946d2+incompatible
-	github.com/golang/glog v0.0.0-20141105023935-44145f04b68c
 	github.com/kardianos/osext v0.0.0-20150410034420-8fef92e41e22 // indirect
 	github.com/nuagenetworks/go-bambou v1.0.1
 	github.com/nuagenetworks/vspk-go v6.0.4+incompatible
-	github.com/sirupsen/logrus v1.4.2 // indirect
+	github.com/prometheus/client_golang v1.11.1
+	github.com/prometheus/client_model v0.2.0
 	github.com/smartystreets/goconvey v1.6.4 // indirect
 	github.com/socketplane/libovsdb v0.0.0-20160607151822-5113f8fb4d9d
-	golang.org/x/crypto v0.0.0-20190308221718-c2843e01d9a2
+	github.com/vishvananda/netlink v1.1.0
+	github.com/vishvananda/netns v0.0.0-20191106174202-0a2b9b5464df
+	golang.org/x/crypto v0.0.0-20200622213623-75b288015ac9
+	google.golang.org/grpc v1.38.0
+	google.golang.org/protobuf v1.26.0
 )
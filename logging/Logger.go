@@ -0,0 +1,197 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Field is a named value attached to a log message
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// F returns the field with the given key and value
+func F(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Err returns the field carrying an error
+func Err(err error) Field {
+	return Field{Key: "error", Value: err}
+}
+
+// Metadata is the value of a field holding Nuage metadata, the values of its keys can be redacted
+type Metadata map[string]string
+
+// Logger receives the messages logged by the SDK. Adapters for zap, logrus or glog only have to map the levels
+// and the fields.
+type Logger interface {
+	Debug(msg string, fields ...Field)
+	Info(msg string, fields ...Field)
+	Warn(msg string, fields ...Field)
+	Error(msg string, fields ...Field)
+}
+
+// Level is the severity of a message
+type Level int
+
+// Levels of the messages, from the most verbose
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (level Level) String() string {
+	switch level {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	}
+	return "ERROR"
+}
+
+// stdLogger writes the messages at or above a level with the standard log package, fields as key=value pairs
+type stdLogger struct {
+	logger *log.Logger
+	level  Level
+}
+
+// NewStdLogger returns a Logger writing the messages at or above level to w
+func NewStdLogger(w io.Writer, level Level) Logger {
+	return &stdLogger{logger: log.New(w, "", log.LstdFlags), level: level}
+}
+
+func (std *stdLogger) write(level Level, msg string, fields []Field) {
+	if level < std.level {
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString(level.String())
+	b.WriteString(" ")
+	b.WriteString(msg)
+	for _, field := range fields {
+		fmt.Fprintf(&b, " %s=%s", field.Key, formatValue(field.Value))
+	}
+
+	std.logger.Output(3, b.String())
+}
+
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case Metadata:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		pairs := make([]string, len(keys))
+		for i, k := range keys {
+			pairs[i] = k + ":" + v[k]
+		}
+		return "{" + strings.Join(pairs, ",") + "}"
+	case time.Duration:
+		return v.String()
+	case string:
+		if strings.ContainsAny(v, " \t\"") {
+			return fmt.Sprintf("%q", v)
+		}
+		return v
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+func (std *stdLogger) Debug(msg string, fields ...Field) { std.write(LevelDebug, msg, fields) }
+func (std *stdLogger) Info(msg string, fields ...Field)  { std.write(LevelInfo, msg, fields) }
+func (std *stdLogger) Warn(msg string, fields ...Field)  { std.write(LevelWarn, msg, fields) }
+func (std *stdLogger) Error(msg string, fields ...Field) { std.write(LevelError, msg, fields) }
+
+// discard drops all the messages
+type discard struct{}
+
+func (discard) Debug(string, ...Field) {}
+func (discard) Info(string, ...Field)  {}
+func (discard) Warn(string, ...Field)  {}
+func (discard) Error(string, ...Field) {}
+
+// Discard is a Logger dropping all the messages
+var Discard Logger = discard{}
+
+var (
+	defaultMutex  sync.RWMutex
+	defaultLogger = NewStdLogger(os.Stderr, LevelError)
+)
+
+// Default returns the package-level Logger used by connections without a Logger of their own. Unless replaced
+// with SetDefault it writes the errors to the standard error.
+func Default() Logger {
+	defaultMutex.RLock()
+	defer defaultMutex.RUnlock()
+	return defaultLogger
+}
+
+// SetDefault replaces the package-level Logger
+func SetDefault(logger Logger) {
+	defaultMutex.Lock()
+	defer defaultMutex.Unlock()
+	defaultLogger = logger
+}
+
+// Switchable is a Logger forwarding the messages to a Logger that can be replaced at any time, to Default
+// until one is set. It lets copies of a connection share their Logger.
+type Switchable struct {
+	mutex  sync.RWMutex
+	target Logger
+}
+
+// Set replaces the Logger the messages are forwarded to, nil restores Default
+func (switchable *Switchable) Set(logger Logger) {
+	switchable.mutex.Lock()
+	defer switchable.mutex.Unlock()
+	switchable.target = logger
+}
+
+func (switchable *Switchable) current() Logger {
+	if switchable == nil {
+		return Default()
+	}
+
+	switchable.mutex.RLock()
+	defer switchable.mutex.RUnlock()
+	if switchable.target == nil {
+		return Default()
+	}
+	return switchable.target
+}
+
+// Debug implements Logger
+func (switchable *Switchable) Debug(msg string, fields ...Field) {
+	switchable.current().Debug(msg, fields...)
+}
+
+// Info implements Logger
+func (switchable *Switchable) Info(msg string, fields ...Field) {
+	switchable.current().Info(msg, fields...)
+}
+
+// Warn implements Logger
+func (switchable *Switchable) Warn(msg string, fields ...Field) {
+	switchable.current().Warn(msg, fields...)
+}
+
+// Error implements Logger
+func (switchable *Switchable) Error(msg string, fields ...Field) {
+	switchable.current().Error(msg, fields...)
+}
@@ -0,0 +1,51 @@
+package logging
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := Redact(NewStdLogger(&buffer, LevelInfo), "secret")
+
+	row := map[string]interface{}{"metadata": Metadata{"secret": "s3cr3t", "zone": "z1"}}
+	logger.Debug("hidden", F("table", "Nuage_Port_Table"))
+	logger.Info("row", F("table", "Nuage_Port_Table"), F("row", row), F("metadata", Metadata{"secret": "s3cr3t"}))
+
+	output := buffer.String()
+	if strings.Contains(output, "hidden") {
+		t.Fatalf("Debug message logged at info level %s", output)
+	}
+	if strings.Contains(output, "s3cr3t") || !strings.Contains(output, "zone:z1") ||
+		!strings.Contains(output, "table=Nuage_Port_Table") {
+		t.Fatalf("Unexpected output %s", output)
+	}
+	if row["metadata"].(Metadata)["secret"] != "s3cr3t" {
+		t.Fatalf("Redaction modified the logged row")
+	}
+
+	buffer.Reset()
+	Redact(NewStdLogger(&buffer, LevelInfo)).Info("all", F("metadata", Metadata{"zone": "z1"}))
+	if !strings.Contains(buffer.String(), "zone:"+Redacted) {
+		t.Fatalf("Unexpected output %s", buffer.String())
+	}
+}
+
+func TestSwitchable(t *testing.T) {
+	var buffer bytes.Buffer
+	switchable := &Switchable{}
+
+	previous := Default()
+	defer SetDefault(previous)
+	SetDefault(Discard)
+
+	switchable.Error("dropped")
+	switchable.Set(NewStdLogger(&buffer, LevelError))
+	switchable.Error("logged")
+
+	if output := buffer.String(); strings.Contains(output, "dropped") || !strings.Contains(output, "ERROR logged") {
+		t.Fatalf("Unexpected output %s", output)
+	}
+}
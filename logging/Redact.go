@@ -0,0 +1,61 @@
+package logging
+
+// Redacted replaces the values of the redacted metadata keys
+const Redacted = "<redacted>"
+
+// redacting replaces metadata values before forwarding the messages
+type redacting struct {
+	logger Logger
+	keys   map[string]bool
+}
+
+// Redact returns a Logger forwarding the messages to logger with the values of the given metadata keys, or of
+// all the keys if none is given, replaced by Redacted. Metadata fields are redacted as well as Metadata values
+// within row fields, maps of column names to values.
+func Redact(logger Logger, keys ...string) Logger {
+	r := &redacting{logger: logger}
+	if len(keys) != 0 {
+		r.keys = make(map[string]bool)
+		for _, key := range keys {
+			r.keys[key] = true
+		}
+	}
+	return r
+}
+
+func (r *redacting) metadata(metadata Metadata) Metadata {
+	redacted := make(Metadata)
+	for k, v := range metadata {
+		if r.keys == nil || r.keys[k] {
+			v = Redacted
+		}
+		redacted[k] = v
+	}
+	return redacted
+}
+
+func (r *redacting) fields(fields []Field) []Field {
+	redacted := make([]Field, len(fields))
+	for i, field := range fields {
+		switch value := field.Value.(type) {
+		case Metadata:
+			field.Value = r.metadata(value)
+		case map[string]interface{}:
+			row := make(map[string]interface{})
+			for column, v := range value {
+				if metadata, ok := v.(Metadata); ok {
+					v = r.metadata(metadata)
+				}
+				row[column] = v
+			}
+			field.Value = row
+		}
+		redacted[i] = field
+	}
+	return redacted
+}
+
+func (r *redacting) Debug(msg string, fields ...Field) { r.logger.Debug(msg, r.fields(fields)...) }
+func (r *redacting) Info(msg string, fields ...Field)  { r.logger.Info(msg, r.fields(fields)...) }
+func (r *redacting) Warn(msg string, fields ...Field)  { r.logger.Warn(msg, r.fields(fields)...) }
+func (r *redacting) Error(msg string, fields ...Field) { r.logger.Error(msg, r.fields(fields)...) }
@@ -0,0 +1,10 @@
+/*
+Package logging defines the Logger the SDK writes its messages to.
+
+Messages carry structured fields, such as the table, the operation, the condition and the duration of an OVSDB
+transaction, so that agents can forward them to zap, logrus or any other structured logger through a small
+adapter. Each connection logs to its own Logger, set with SetLogger, and falls back to the package-level Default,
+which writes errors to the standard error. Metadata is logged as Metadata fields whose values can be hidden by
+wrapping the Logger with Redact.
+*/
+package logging
@@ -0,0 +1,231 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Namespace of the metrics exported by the SDK
+const Namespace = "libvrsdk"
+
+// Controller connection states, they match the api.ControllerState values
+var controllerStates = []string{"connected", "disconnected", "unknown"}
+
+// controllerStateTTL is how long the controller connection state queried is reused by the collections
+const controllerStateTTL = 10 * time.Second
+
+// Metrics records the operations of a VRS connection. The methods of a nil *Metrics do nothing so that
+// instrumented code does not need to check whether metrics are collected.
+type Metrics struct {
+	// mutex guards the collectors against SetLabels replacing them
+	mutex             sync.RWMutex
+	transactions      *prometheus.CounterVec
+	latency           *prometheus.HistogramVec
+	errors            *prometheus.CounterVec
+	resolution        prometheus.Histogram
+	droppedUpdates    *prometheus.CounterVec
+	subscribers       prometheus.Gauge
+	controllerState   *prometheus.GaugeVec
+	controllerStateFn func() string
+	// controllerStateMutex serializes the concurrent collections querying the controller connection state
+	controllerStateMutex sync.Mutex
+	controllerStateAt    time.Time
+}
+
+// New creates the metrics of a VRS connection with the constant labels, nil for none. They are not registered,
+// the agent registers the Metrics as a prometheus.Collector if it wants to export them. The metrics of several
+// connections registered together need labels telling the connections apart.
+func New(labels prometheus.Labels) *Metrics {
+	metrics := &Metrics{}
+	metrics.build(labels)
+	return metrics
+}
+
+// SetLabels replaces the constant labels of the metrics. The values recorded so far are dropped, the labels are
+// meant to be set before the Metrics is registered.
+func (metrics *Metrics) SetLabels(labels prometheus.Labels) {
+	if metrics == nil {
+		return
+	}
+
+	metrics.controllerStateMutex.Lock()
+	defer metrics.controllerStateMutex.Unlock()
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+
+	metrics.build(labels)
+	metrics.controllerStateAt = time.Time{}
+}
+
+// build creates the collectors with the constant labels, the caller holds the mutex or owns the Metrics
+func (metrics *Metrics) build(labels prometheus.Labels) {
+	metrics.transactions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   Namespace,
+		Name:        "ovsdb_transactions_total",
+		Help:        "Number of OVSDB transactions by table and operation.",
+		ConstLabels: labels,
+	}, []string{"table", "op"})
+	metrics.latency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace:   Namespace,
+		Name:        "ovsdb_transaction_duration_seconds",
+		Help:        "Latency of the OVSDB transactions by table and operation.",
+		Buckets:     prometheus.ExponentialBuckets(0.0005, 2, 14),
+		ConstLabels: labels,
+	}, []string{"table", "op"})
+	metrics.errors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   Namespace,
+		Name:        "ovsdb_errors_total",
+		Help:        "Number of failed OVSDB transactions by table, operation and type of error.",
+		ConstLabels: labels,
+	}, []string{"table", "op", "type"})
+	metrics.resolution = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace:   Namespace,
+		Name:        "port_resolution_seconds",
+		Help:        "Time between the creation of a port and the VRS resolving its address.",
+		Buckets:     prometheus.ExponentialBuckets(0.05, 2, 12),
+		ConstLabels: labels,
+	})
+	metrics.droppedUpdates = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   Namespace,
+		Name:        "port_updates_dropped_total",
+		Help:        "Number of port updates not delivered because the subscriber channel was full.",
+		ConstLabels: labels,
+	}, []string{"kind"})
+	metrics.subscribers = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace:   Namespace,
+		Name:        "port_update_subscribers",
+		Help:        "Number of ports registered for updates.",
+		ConstLabels: labels,
+	})
+	metrics.controllerState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   Namespace,
+		Name:        "controller_connection_state",
+		Help:        "State of the connection of the VRS to its master controller, 1 for the current state.",
+		ConstLabels: labels,
+	}, []string{"state"})
+}
+
+// SetControllerStateFunc sets the function queried for the controller connection state by the collections. The
+// state is queried again once it is older than 10 seconds, not on each collection.
+func (metrics *Metrics) SetControllerStateFunc(fn func() string) {
+	if metrics == nil {
+		return
+	}
+
+	metrics.controllerStateMutex.Lock()
+	defer metrics.controllerStateMutex.Unlock()
+
+	metrics.controllerStateFn = fn
+	metrics.controllerStateAt = time.Time{}
+}
+
+// ObserveTransaction records a transaction on the table
+func (metrics *Metrics) ObserveTransaction(table string, op string, duration time.Duration) {
+	if metrics == nil {
+		return
+	}
+
+	metrics.mutex.RLock()
+	defer metrics.mutex.RUnlock()
+
+	metrics.transactions.WithLabelValues(table, op).Inc()
+	metrics.latency.WithLabelValues(table, op).Observe(duration.Seconds())
+}
+
+// ObserveError records a failed transaction on the table. errorType is "transport" when the transaction
+// could not be sent, the error reported by the OVSDB server or "not-found" when no row matched.
+func (metrics *Metrics) ObserveError(table string, op string, errorType string) {
+	if metrics == nil {
+		return
+	}
+
+	metrics.mutex.RLock()
+	defer metrics.mutex.RUnlock()
+	metrics.errors.WithLabelValues(table, op, errorType).Inc()
+}
+
+// ObservePortResolution records the time it took the VRS to resolve a port since its creation
+func (metrics *Metrics) ObservePortResolution(duration time.Duration) {
+	if metrics == nil {
+		return
+	}
+
+	metrics.mutex.RLock()
+	defer metrics.mutex.RUnlock()
+	metrics.resolution.Observe(duration.Seconds())
+}
+
+// DropPortUpdate records a port update, of kind "resolved" or "deleted", that could not be delivered
+func (metrics *Metrics) DropPortUpdate(kind string) {
+	if metrics == nil {
+		return
+	}
+
+	metrics.mutex.RLock()
+	defer metrics.mutex.RUnlock()
+	metrics.droppedUpdates.WithLabelValues(kind).Inc()
+}
+
+// SetSubscribers records the number of ports registered for updates
+func (metrics *Metrics) SetSubscribers(count int) {
+	if metrics == nil {
+		return
+	}
+
+	metrics.mutex.RLock()
+	defer metrics.mutex.RUnlock()
+	metrics.subscribers.Set(float64(count))
+}
+
+func (metrics *Metrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{metrics.transactions, metrics.latency, metrics.errors, metrics.resolution,
+		metrics.droppedUpdates, metrics.subscribers, metrics.controllerState}
+}
+
+// Describe implements prometheus.Collector
+func (metrics *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	metrics.mutex.RLock()
+	defer metrics.mutex.RUnlock()
+
+	for _, collector := range metrics.collectors() {
+		collector.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector. The controller connection state is queried at that time if the
+// one queried last is outdated.
+func (metrics *Metrics) Collect(ch chan<- prometheus.Metric) {
+	metrics.refreshControllerState(time.Now())
+
+	metrics.mutex.RLock()
+	defer metrics.mutex.RUnlock()
+
+	for _, collector := range metrics.collectors() {
+		collector.Collect(ch)
+	}
+}
+
+// refreshControllerState queries the controller connection state if the one queried last is older than
+// controllerStateTTL
+func (metrics *Metrics) refreshControllerState(now time.Time) {
+	metrics.controllerStateMutex.Lock()
+	defer metrics.controllerStateMutex.Unlock()
+
+	if metrics.controllerStateFn == nil || now.Sub(metrics.controllerStateAt) < controllerStateTTL {
+		return
+	}
+
+	current := metrics.controllerStateFn()
+	metrics.mutex.RLock()
+	defer metrics.mutex.RUnlock()
+	for _, state := range controllerStates {
+		value := 0.0
+		if state == current {
+			value = 1
+		}
+		metrics.controllerState.WithLabelValues(state).Set(value)
+	}
+	metrics.controllerStateAt = now
+}
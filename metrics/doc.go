@@ -0,0 +1,11 @@
+/*
+Package metrics instruments the operations of the VRS SDK for Prometheus.
+
+A Metrics is created with every VRS connection. It counts the OVSDB transactions issued on the Nuage tables along
+with their latency and errors, tracks the time the VRS takes to resolve ports, the port updates dropped because a
+subscriber was not ready and the number of subscribers, and reports the state of the connection to the controller.
+Nothing is registered by default: an agent exports the metrics by registering the connection's Collector. The
+Collectors of several connections registered together need constant labels set by SetMetricsLabels, the fleet
+labels the connections of its hosts with their name.
+*/
+package metrics
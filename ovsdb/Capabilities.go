@@ -0,0 +1,97 @@
+package ovsdb
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/socketplane/libovsdb"
+)
+
+// requiredColumns are the columns of the Nuage tables the SDK cannot work without
+var requiredColumns = map[string][]string{
+	NuageVMTable: {NuageVMTableColumnVMUUID, NuageVMTableColumnVMName, NuageVMTableColumnPorts,
+		NuageVMTableColumnState, NuageVMTableColumnReason, NuageVMTableColumnEventCategory,
+		NuageVMTableColumnEventType, NuageVMTableColumnMetadata, NuageVMTableColumnType, NuageVMTableColumnDomain,
+		NuageVMTableColumnUser, NuageVMTableColumnEnterprise},
+	NuagePortTable: {NuagePortTableColumnName, NuagePortTableColumnMAC, NuagePortTableColumnBridge,
+		NuagePortTableColumnNuageDomain, NuagePortTableColumnNuageZone, NuagePortTableColumnNuageNetwork,
+		NuagePortTableColumnIPAddress, NuagePortTableColumnSubnetMask, NuagePortTableColumnGateway,
+		NuagePortTableColumnVRFId, NuagePortTableColumnEVPNID, NuagePortTableColumnMetadata},
+}
+
+// optionalColumns are the columns of the Nuage tables added by later VRS releases. They are only written
+// when the VRS has them.
+var optionalColumns = map[string][]string{
+	NuageVMTable: {NuageVMTableColumnDirty},
+	NuagePortTable: {NuagePortTableColumnAlias, NuagePortTableColumnNuageNetworkType, NuagePortTableColumnVMDomain,
+		NuagePortTableColumnDirty},
+}
+
+// Capabilities is the set of the columns of the Nuage tables supported by the VRS, indexed by table. The
+// nil Capabilities supports all the columns.
+type Capabilities map[string]map[string]bool
+
+// CheckSchema checks that the Nuage tables of the schema have all the required columns and returns the
+// columns they support
+func CheckSchema(schema libovsdb.DatabaseSchema) (Capabilities, error) {
+	capabilities := make(Capabilities)
+
+	tables := make([]string, 0, len(requiredColumns))
+	for table := range requiredColumns {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+
+	for _, table := range tables {
+		tableSchema, ok := schema.Tables[table]
+		if !ok {
+			return nil, fmt.Errorf("The VRS schema %s has no table %s", schema.Version, table)
+		}
+
+		columns := make(map[string]bool)
+		for column := range tableSchema.Columns {
+			columns[column] = true
+		}
+		capabilities[table] = columns
+
+		var missing []string
+		for _, column := range requiredColumns[table] {
+			if !columns[column] {
+				missing = append(missing, column)
+			}
+		}
+		if len(missing) != 0 {
+			return nil, fmt.Errorf("The table %s of the VRS schema %s lacks the required columns %s", table,
+				schema.Version, strings.Join(missing, ", "))
+		}
+	}
+
+	return capabilities, nil
+}
+
+// Supports tells whether the column of the table is supported
+func (capabilities Capabilities) Supports(table string, column string) bool {
+	if capabilities == nil {
+		return true
+	}
+	return capabilities[table][column]
+}
+
+// Unsupported returns the optional columns of the table that are not supported
+func (capabilities Capabilities) Unsupported(table string) []string {
+	var unsupported []string
+	for _, column := range optionalColumns[table] {
+		if !capabilities.Supports(table, column) {
+			unsupported = append(unsupported, column)
+		}
+	}
+	return unsupported
+}
+
+// Omit removes the optional columns that are not supported from a row of the table
+func (capabilities Capabilities) Omit(table string, ovsdbRow map[string]interface{}) {
+	for _, column := range capabilities.Unsupported(table) {
+		delete(ovsdbRow, column)
+	}
+}
@@ -1,26 +1,70 @@
+// Code generated by ovsdbgen from vrs.ovsschema. DO NOT EDIT.
+
 package ovsdb
 
+import (
+	"fmt"
+
+	"github.com/socketplane/libovsdb"
+)
+
+// These constants describe the Controller
 const (
-	//ControllerTable is the table name
 	ControllerTable = "Controller"
-	//ControllerTableColumnRole column role in controller table
+
 	ControllerTableColumnRole = "role"
 )
 
-//ControllerTableRow represents a row in Controller Table
+// ControllerTableRow represents a row in the Controller
 type ControllerTableRow struct {
 	Role string
 }
 
-//Equals checks for equality of two rows in Controller Table
+// Equals checks for equality of two rows in the Controller
 func (row *ControllerTableRow) Equals(otherRow interface{}) bool {
-	controllerTableRow, ok := otherRow.(ControllerTableRow)
+	other, ok := otherRow.(ControllerTableRow)
 	if !ok {
-		return false
+		pointer, ok := otherRow.(*ControllerTableRow)
+		if !ok || pointer == nil {
+			return false
+		}
+		other = *pointer
 	}
 
-	if row.Role != controllerTableRow.Role {
+	if row.Role != other.Role {
 		return false
 	}
+
 	return true
 }
+
+// CreateOVSDBRow creates a OVSDB row for the Controller
+func (row *ControllerTableRow) CreateOVSDBRow(ovsdbRow map[string]interface{}) error {
+	if row.Role == "" {
+		ovsdbRow[ControllerTableColumnRole] = libovsdb.OvsSet{GoSet: []interface{}{}}
+	} else {
+		ovsdbRow[ControllerTableColumnRole] = row.Role
+	}
+
+	return nil
+}
+
+// ReadOVSDBRow fills the row with the columns present in ovsdbRow, as returned by a select or a monitor
+func (row *ControllerTableRow) ReadOVSDBRow(ovsdbRow map[string]interface{}) error {
+	if data, ok := ovsdbRow[ControllerTableColumnRole]; ok {
+		row.Role = ""
+		err := UnMarshallOVSSet(data, func(atom interface{}) error {
+			value, err := UnMarshallOVSString(atom)
+			if err != nil {
+				return err
+			}
+			row.Role = value
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("Invalid %s %v", ControllerTableColumnRole, err)
+		}
+	}
+
+	return nil
+}
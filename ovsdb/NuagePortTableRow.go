@@ -1,38 +1,38 @@
+// Code generated by ovsdbgen from vrs.ovsschema. DO NOT EDIT.
+
 package ovsdb
 
 import (
+	"fmt"
 	"reflect"
-	"strings"
 
-	"github.com/golang/glog"
 	"github.com/nuagenetworks/libvrsdk/api/entity"
 	"github.com/socketplane/libovsdb"
 )
 
-// Constants needed to describe Nuage_Port_Table
+// These constants describe the Nuage_Port_Table
 const (
-	NuagePortTable           = "Nuage_Port_Table"
-	NuagePortTableColumnName = "name"
-
-	// Attributes
-	NuagePortTableColumnMAC      = "mac"
-	NuagePortTableColumnBridge   = "bridge"
-	NuagePortTableColumnVMDomain = "vm_domain"
-
-	// State
-	NuagePortTableColumnNuageDomain  = "nuage_domain"
-	NuagePortTableColumnNuageZone    = "nuage_zone"
-	NuagePortTableColumnNuageNetwork = "nuage_network"
-	NuagePortTableColumnIPAddress    = "ip_addr"
-	NuagePortTableColumnSubnetMask   = "subnet_mask"
-	NuagePortTableColumnGateway      = "gateway"
-	NuagePortTableColumnVRFId        = "vrf_id"
-	NuagePortTableColumnEVPNID       = "evpn_id"
-
-	NuagePortTableColumnMetadata = "metadata"
+	NuagePortTable = "Nuage_Port_Table"
+
+	NuagePortTableColumnName             = "name"
+	NuagePortTableColumnMAC              = "mac"
+	NuagePortTableColumnIPAddress        = "ip_addr"
+	NuagePortTableColumnSubnetMask       = "subnet_mask"
+	NuagePortTableColumnGateway          = "gateway"
+	NuagePortTableColumnBridge           = "bridge"
+	NuagePortTableColumnAlias            = "alias"
+	NuagePortTableColumnNuageDomain      = "nuage_domain"
+	NuagePortTableColumnNuageNetwork     = "nuage_network"
+	NuagePortTableColumnNuageZone        = "nuage_zone"
+	NuagePortTableColumnNuageNetworkType = "nuage_network_type"
+	NuagePortTableColumnEVPNID           = "evpn_id"
+	NuagePortTableColumnVRFId            = "vrf_id"
+	NuagePortTableColumnVMDomain         = "vm_domain"
+	NuagePortTableColumnMetadata         = "metadata"
+	NuagePortTableColumnDirty            = "dirty"
 )
 
-// NuagePortTableRow represents a row in Nuage_Port_Table
+// NuagePortTableRow represents a row in the Nuage_Port_Table
 type NuagePortTableRow struct {
 	Name             string
 	Mac              string
@@ -50,108 +50,256 @@ type NuagePortTableRow struct {
 	VMDomain         entity.Domain
 	Metadata         map[string]string
 	Dirty            int
+
+	// Capabilities of the VRS, the optional columns it does not support are left out of the OVSDB row
+	Capabilities Capabilities
 }
 
-// Equals checks for equality of two Nuage_Port_Table rows
+// Equals checks for equality of two rows in the Nuage_Port_Table
 func (row *NuagePortTableRow) Equals(otherRow interface{}) bool {
-
-	nuagePortTableRow, ok := otherRow.(NuagePortTableRow)
-
+	other, ok := otherRow.(NuagePortTableRow)
 	if !ok {
-		return false
+		pointer, ok := otherRow.(*NuagePortTableRow)
+		if !ok || pointer == nil {
+			return false
+		}
+		other = *pointer
 	}
 
-	if strings.Compare(row.Name, nuagePortTableRow.Name) != 0 {
+	if row.Name != other.Name {
 		return false
 	}
 
-	if strings.Compare(row.Mac, nuagePortTableRow.Mac) != 0 {
+	if row.Mac != other.Mac {
 		return false
 	}
 
-	if strings.Compare(row.IPAddr, nuagePortTableRow.IPAddr) != 0 {
+	if row.IPAddr != other.IPAddr {
 		return false
 	}
 
-	if strings.Compare(row.SubnetMask, nuagePortTableRow.SubnetMask) != 0 {
+	if row.SubnetMask != other.SubnetMask {
 		return false
 	}
 
-	if strings.Compare(row.Gateway, nuagePortTableRow.Gateway) != 0 {
+	if row.Gateway != other.Gateway {
 		return false
 	}
 
-	if strings.Compare(row.Bridge, nuagePortTableRow.Bridge) != 0 {
+	if row.Bridge != other.Bridge {
 		return false
 	}
 
-	if strings.Compare(row.Alias, nuagePortTableRow.Alias) != 0 {
+	if row.Alias != other.Alias {
 		return false
 	}
 
-	if strings.Compare(row.NuageDomain, nuagePortTableRow.NuageDomain) != 0 {
+	if row.NuageDomain != other.NuageDomain {
 		return false
 	}
 
-	if strings.Compare(row.NuageNetwork, nuagePortTableRow.NuageNetwork) != 0 {
+	if row.NuageNetwork != other.NuageNetwork {
 		return false
 	}
 
-	if strings.Compare(row.NuageNetworkType, nuagePortTableRow.NuageNetworkType) != 0 {
+	if row.NuageZone != other.NuageZone {
 		return false
 	}
 
-	if strings.Compare(row.NuageZone, nuagePortTableRow.NuageZone) != 0 {
+	if row.NuageNetworkType != other.NuageNetworkType {
 		return false
 	}
 
-	if row.EVPNId != nuagePortTableRow.EVPNId {
+	if row.EVPNId != other.EVPNId {
 		return false
 	}
 
-	if row.VRFId != nuagePortTableRow.VRFId {
+	if row.VRFId != other.VRFId {
 		return false
 	}
 
-	if row.VMDomain != nuagePortTableRow.VMDomain {
+	if row.VMDomain != other.VMDomain {
 		return false
 	}
 
-	if !reflect.DeepEqual(row.Metadata, nuagePortTableRow.Metadata) {
+	if !reflect.DeepEqual(row.Metadata, other.Metadata) {
 		return false
 	}
 
-	if row.Dirty != nuagePortTableRow.Dirty {
+	if row.Dirty != other.Dirty {
 		return false
 	}
 
 	return true
 }
 
-// CreateOVSDBRow creates a new row in Nuage_Port_Table using the data provided by the user
+// CreateOVSDBRow creates a OVSDB row for the Nuage_Port_Table
 func (row *NuagePortTableRow) CreateOVSDBRow(ovsdbRow map[string]interface{}) error {
+	ovsdbRow[NuagePortTableColumnName] = row.Name
+	ovsdbRow[NuagePortTableColumnMAC] = row.Mac
+	ovsdbRow[NuagePortTableColumnIPAddress] = row.IPAddr
+	ovsdbRow[NuagePortTableColumnSubnetMask] = row.SubnetMask
+	ovsdbRow[NuagePortTableColumnGateway] = row.Gateway
+	ovsdbRow[NuagePortTableColumnBridge] = row.Bridge
+	ovsdbRow[NuagePortTableColumnAlias] = row.Alias
+	ovsdbRow[NuagePortTableColumnNuageDomain] = row.NuageDomain
+	ovsdbRow[NuagePortTableColumnNuageNetwork] = row.NuageNetwork
+	ovsdbRow[NuagePortTableColumnNuageZone] = row.NuageZone
+	ovsdbRow[NuagePortTableColumnNuageNetworkType] = row.NuageNetworkType
+	ovsdbRow[NuagePortTableColumnEVPNID] = row.EVPNId
+	ovsdbRow[NuagePortTableColumnVRFId] = row.VRFId
+	ovsdbRow[NuagePortTableColumnVMDomain] = int(row.VMDomain)
+	metadataMap := libovsdb.OvsMap{GoMap: make(map[interface{}]interface{})}
+	for key, value := range row.Metadata {
+		metadataMap.GoMap[key] = value
+	}
+	ovsdbRow[NuagePortTableColumnMetadata] = metadataMap
+	ovsdbRow[NuagePortTableColumnDirty] = row.Dirty
+	row.Capabilities.Omit(NuagePortTable, ovsdbRow)
+
+	return nil
+}
+
+// ReadOVSDBRow fills the row with the columns present in ovsdbRow, as returned by a select or a monitor
+func (row *NuagePortTableRow) ReadOVSDBRow(ovsdbRow map[string]interface{}) error {
+	if data, ok := ovsdbRow[NuagePortTableColumnName]; ok {
+		value, err := UnMarshallOVSString(data)
+		if err != nil {
+			return fmt.Errorf("Invalid %s %v", NuagePortTableColumnName, err)
+		}
+		row.Name = value
+	}
+
+	if data, ok := ovsdbRow[NuagePortTableColumnMAC]; ok {
+		value, err := UnMarshallOVSString(data)
+		if err != nil {
+			return fmt.Errorf("Invalid %s %v", NuagePortTableColumnMAC, err)
+		}
+		row.Mac = value
+	}
+
+	if data, ok := ovsdbRow[NuagePortTableColumnIPAddress]; ok {
+		value, err := UnMarshallOVSString(data)
+		if err != nil {
+			return fmt.Errorf("Invalid %s %v", NuagePortTableColumnIPAddress, err)
+		}
+		row.IPAddr = value
+	}
+
+	if data, ok := ovsdbRow[NuagePortTableColumnSubnetMask]; ok {
+		value, err := UnMarshallOVSString(data)
+		if err != nil {
+			return fmt.Errorf("Invalid %s %v", NuagePortTableColumnSubnetMask, err)
+		}
+		row.SubnetMask = value
+	}
+
+	if data, ok := ovsdbRow[NuagePortTableColumnGateway]; ok {
+		value, err := UnMarshallOVSString(data)
+		if err != nil {
+			return fmt.Errorf("Invalid %s %v", NuagePortTableColumnGateway, err)
+		}
+		row.Gateway = value
+	}
+
+	if data, ok := ovsdbRow[NuagePortTableColumnBridge]; ok {
+		value, err := UnMarshallOVSString(data)
+		if err != nil {
+			return fmt.Errorf("Invalid %s %v", NuagePortTableColumnBridge, err)
+		}
+		row.Bridge = value
+	}
+
+	if data, ok := ovsdbRow[NuagePortTableColumnAlias]; ok {
+		value, err := UnMarshallOVSString(data)
+		if err != nil {
+			return fmt.Errorf("Invalid %s %v", NuagePortTableColumnAlias, err)
+		}
+		row.Alias = value
+	}
+
+	if data, ok := ovsdbRow[NuagePortTableColumnNuageDomain]; ok {
+		value, err := UnMarshallOVSString(data)
+		if err != nil {
+			return fmt.Errorf("Invalid %s %v", NuagePortTableColumnNuageDomain, err)
+		}
+		row.NuageDomain = value
+	}
+
+	if data, ok := ovsdbRow[NuagePortTableColumnNuageNetwork]; ok {
+		value, err := UnMarshallOVSString(data)
+		if err != nil {
+			return fmt.Errorf("Invalid %s %v", NuagePortTableColumnNuageNetwork, err)
+		}
+		row.NuageNetwork = value
+	}
+
+	if data, ok := ovsdbRow[NuagePortTableColumnNuageZone]; ok {
+		value, err := UnMarshallOVSString(data)
+		if err != nil {
+			return fmt.Errorf("Invalid %s %v", NuagePortTableColumnNuageZone, err)
+		}
+		row.NuageZone = value
+	}
+
+	if data, ok := ovsdbRow[NuagePortTableColumnNuageNetworkType]; ok {
+		value, err := UnMarshallOVSString(data)
+		if err != nil {
+			return fmt.Errorf("Invalid %s %v", NuagePortTableColumnNuageNetworkType, err)
+		}
+		row.NuageNetworkType = value
+	}
+
+	if data, ok := ovsdbRow[NuagePortTableColumnEVPNID]; ok {
+		value, err := UnMarshallOVSInt(data)
+		if err != nil {
+			return fmt.Errorf("Invalid %s %v", NuagePortTableColumnEVPNID, err)
+		}
+		row.EVPNId = value
+	}
+
+	if data, ok := ovsdbRow[NuagePortTableColumnVRFId]; ok {
+		value, err := UnMarshallOVSInt(data)
+		if err != nil {
+			return fmt.Errorf("Invalid %s %v", NuagePortTableColumnVRFId, err)
+		}
+		row.VRFId = value
+	}
+
+	if data, ok := ovsdbRow[NuagePortTableColumnVMDomain]; ok {
+		value, err := UnMarshallOVSInt(data)
+		if err != nil {
+			return fmt.Errorf("Invalid %s %v", NuagePortTableColumnVMDomain, err)
+		}
+		row.VMDomain = entity.Domain(value)
+	}
+
+	if data, ok := ovsdbRow[NuagePortTableColumnMetadata]; ok {
+		row.Metadata = make(map[string]string)
+		err := UnMarshallOVSMap(data, func(key interface{}, value interface{}) error {
+			k, err := UnMarshallOVSString(key)
+			if err != nil {
+				return err
+			}
+			v, err := UnMarshallOVSString(value)
+			if err != nil {
+				return err
+			}
+			row.Metadata[k] = v
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("Invalid %s %v", NuagePortTableColumnMetadata, err)
+		}
+	}
+
+	if data, ok := ovsdbRow[NuagePortTableColumnDirty]; ok {
+		value, err := UnMarshallOVSInt(data)
+		if err != nil {
+			return fmt.Errorf("Invalid %s %v", NuagePortTableColumnDirty, err)
+		}
+		row.Dirty = value
+	}
 
-	metadataMap, err := libovsdb.NewOvsMap(row.Metadata)
-	if err != nil {
-		glog.Errorf("error while creating metadataMap (%+v)", ovsdbRow)
-		return err
-	}
-
-	ovsdbRow["name"] = row.Name
-	ovsdbRow["mac"] = row.Mac
-	ovsdbRow["ip_addr"] = row.IPAddr
-	ovsdbRow["subnet_mask"] = row.SubnetMask
-	ovsdbRow["gateway"] = row.Gateway
-	ovsdbRow["bridge"] = row.Bridge
-	ovsdbRow["alias"] = row.Alias
-	ovsdbRow["nuage_domain"] = row.NuageDomain
-	ovsdbRow["nuage_network"] = row.NuageNetwork
-	ovsdbRow["nuage_zone"] = row.NuageZone
-	ovsdbRow["nuage_network_type"] = row.NuageNetworkType
-	ovsdbRow["evpn_id"] = row.EVPNId
-	ovsdbRow["vrf_id"] = row.VRFId
-	ovsdbRow["vm_domain"] = row.VMDomain
-	ovsdbRow["metadata"] = metadataMap
-	ovsdbRow["dirty"] = row.Dirty
 	return nil
 }
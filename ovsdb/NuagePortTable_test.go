@@ -1,6 +1,7 @@
 package ovsdb
 
 import (
+	"encoding/json"
 	"fmt"
 	"math/rand"
 	"strings"
@@ -217,3 +218,52 @@ func TestNuagePortTableMultipleReads(t *testing.T) {
 		}
 	}
 }
+
+// TestNuagePortTableRowEncoding checks that a row read back from its wire and monitor notations is unchanged
+func TestNuagePortTableRowEncoding(t *testing.T) {
+	nuagePortTableRow := createTestPortTableRow()
+
+	ovsdbRow := make(map[string]interface{})
+	if err := nuagePortTableRow.CreateOVSDBRow(ovsdbRow); err != nil {
+		t.Fatalf("Unable to create the OVSDB row %v", err)
+	}
+
+	wire, err := json.Marshal(ovsdbRow)
+	if err != nil {
+		t.Fatalf("Unable to encode the OVSDB row %v", err)
+	}
+
+	var selected map[string]interface{}
+	if err := json.Unmarshal(wire, &selected); err != nil {
+		t.Fatalf("Unable to decode the OVSDB row %v", err)
+	}
+	var monitored libovsdb.Row
+	if err := json.Unmarshal(wire, &monitored); err != nil {
+		t.Fatalf("Unable to decode the OVSDB row %v", err)
+	}
+
+	for _, decoded := range []map[string]interface{}{selected, monitored.Fields} {
+		var readRow NuagePortTableRow
+		if err := readRow.ReadOVSDBRow(decoded); err != nil {
+			t.Fatalf("Unable to read the OVSDB row %v", err)
+		}
+		if !readRow.Equals(&nuagePortTableRow) {
+			t.Fatalf("Row changed by the encoding %+v %+v", readRow, nuagePortTableRow)
+		}
+	}
+
+	capabilities := Capabilities{NuagePortTable: {NuagePortTableColumnName: true}}
+	nuagePortTableRow.Capabilities = capabilities
+	ovsdbRow = make(map[string]interface{})
+	if err := nuagePortTableRow.CreateOVSDBRow(ovsdbRow); err != nil {
+		t.Fatalf("Unable to create the OVSDB row %v", err)
+	}
+	for _, column := range capabilities.Unsupported(NuagePortTable) {
+		if _, written := ovsdbRow[column]; written {
+			t.Fatalf("Unsupported column %s written", column)
+		}
+	}
+	if _, written := ovsdbRow[NuagePortTableColumnMAC]; !written {
+		t.Fatalf("Required column %s not written", NuagePortTableColumnMAC)
+	}
+}
@@ -1,9 +1,13 @@
 package ovsdb
 
 import (
+	"context"
 	"fmt"
+	"time"
 
-	"github.com/golang/glog"
+	"github.com/nuagenetworks/libvrsdk/logging"
+	"github.com/nuagenetworks/libvrsdk/metrics"
+	"github.com/nuagenetworks/libvrsdk/tracing"
 	"github.com/socketplane/libovsdb"
 )
 
@@ -17,25 +21,155 @@ type NuageTableOps interface {
 	ReadRow(ovs *libovsdb.OvsdbClient, readRowArgs ReadRowArgs) (map[string]interface{}, error)
 	ReadRows(ovs *libovsdb.OvsdbClient, readRowArgs ReadRowArgs) ([]map[string]interface{}, error)
 	UpdateRow(ovs *libovsdb.OvsdbClient, ovsdbRow map[string]interface{}, condition []string) error
+	MutateRow(ovs *libovsdb.OvsdbClient, ovsdbRow map[string]interface{}, mutations []interface{}, condition []string) error
+}
+
+// ExtendedNuageTableOps adds to NuageTableOps the batch and conditional operations and the variants of the
+// operations taking a context. NuageTable implements both interfaces.
+type ExtendedNuageTableOps interface {
+	NuageTableOps
+	InsertRowContext(ctx context.Context, ovs *libovsdb.OvsdbClient, row NuageTableRow) error
+	InsertRows(ovs *libovsdb.OvsdbClient, rows []NuageTableRow, batchSize int) []error
+	InsertRowsContext(ctx context.Context, ovs *libovsdb.OvsdbClient, rows []NuageTableRow, batchSize int) []error
+	InsertRowIfAbsent(ovs *libovsdb.OvsdbClient, row NuageTableRow, column string) (bool, error)
+	InsertRowIfAbsentContext(ctx context.Context, ovs *libovsdb.OvsdbClient, row NuageTableRow, column string) (bool,
+		error)
+	DeleteRows(ovs *libovsdb.OvsdbClient, condition []string) (int, error)
+	DeleteRowsContext(ctx context.Context, ovs *libovsdb.OvsdbClient, condition []string) (int, error)
+	DeleteRowContext(ctx context.Context, ovs *libovsdb.OvsdbClient, condition []string) error
+	ReadRowContext(ctx context.Context, ovs *libovsdb.OvsdbClient, readRowArgs ReadRowArgs) (map[string]interface{},
+		error)
+	ReadRowsContext(ctx context.Context, ovs *libovsdb.OvsdbClient, readRowArgs ReadRowArgs) ([]map[string]interface{},
+		error)
+	UpdateRowContext(ctx context.Context, ovs *libovsdb.OvsdbClient, ovsdbRow map[string]interface{},
+		condition []string) error
+	MutateRowContext(ctx context.Context, ovs *libovsdb.OvsdbClient, ovsdbRow map[string]interface{},
+		mutations []interface{}, condition []string) error
 }
 
 // NuageTable represent a Nuage OVSDB table
 type NuageTable struct {
 	TableName string
+	Metrics   *metrics.Metrics
+	Logger    logging.Logger
+	Tracer    tracing.Tracer
+}
+
+// log returns the Logger of the table, the package-level one if none was set
+func (nuageTable *NuageTable) log() logging.Logger {
+	if nuageTable.Logger == nil {
+		return logging.Default()
+	}
+	return nuageTable.Logger
+}
+
+// tracer returns the Tracer of the table, the package-level one if none was set
+func (nuageTable *NuageTable) tracer() tracing.Tracer {
+	if nuageTable.Tracer == nil {
+		return tracing.Default()
+	}
+	return nuageTable.Tracer
+}
+
+// rowField returns the field logging an OVSDB row, its map columns are logged as redactable metadata
+func rowField(ovsdbRow map[string]interface{}) logging.Field {
+	row := make(map[string]interface{})
+	for column, value := range ovsdbRow {
+		var goMap map[interface{}]interface{}
+		switch ovsMap := value.(type) {
+		case libovsdb.OvsMap:
+			goMap = ovsMap.GoMap
+		case *libovsdb.OvsMap:
+			goMap = ovsMap.GoMap
+		default:
+			row[column] = value
+			continue
+		}
+
+		metadata := make(logging.Metadata)
+		for k, v := range goMap {
+			metadata[fmt.Sprintf("%v", k)] = fmt.Sprintf("%v", v)
+		}
+		row[column] = metadata
+	}
+	return logging.F("row", row)
+}
+
+// replyError returns the error of a transaction, err if it could not be sent or the first error reported
+// in the reply
+func replyError(err error, reply []libovsdb.OperationResult) error {
+	if err != nil {
+		return err
+	}
+
+	for _, result := range reply {
+		if result.Error != "" {
+			return fmt.Errorf("%s %s", result.Error, result.Details)
+		}
+	}
+
+	if len(reply) == 0 {
+		return fmt.Errorf("empty reply")
+	}
+
+	return nil
+}
+
+// transact executes the operations in a span child of ctx and records the transaction and its errors in the
+// metrics of the table
+func (nuageTable *NuageTable) transact(ctx context.Context, ovs *libovsdb.OvsdbClient, op string,
+	operations ...libovsdb.Operation) ([]libovsdb.OperationResult, error) {
+
+	_, span := nuageTable.tracer().Start(ctx, "ovsdb "+op+" "+nuageTable.TableName)
+	span.SetAttributes(tracing.A("db.system", "ovsdb"), tracing.A("db.name", OvsDBName),
+		tracing.A("db.operation", op), tracing.A("ovsdb.table", nuageTable.TableName))
+
+	start := time.Now()
+	reply, err := ovs.Transact(OvsDBName, operations...)
+	duration := time.Since(start)
+	tracing.End(span, replyError(err, reply))
+	nuageTable.Metrics.ObserveTransaction(nuageTable.TableName, op, duration)
+	nuageTable.log().Debug("OVSDB transaction", logging.F("table", nuageTable.TableName), logging.F("op", op),
+		logging.F("duration", duration), logging.Err(replyError(err, reply)))
+
+	if err != nil || len(reply) < len(operations) {
+		nuageTable.Metrics.ObserveError(nuageTable.TableName, op, "transport")
+		return reply, err
+	}
+
+	for _, result := range reply {
+		if result.Error != "" {
+			nuageTable.Metrics.ObserveError(nuageTable.TableName, op, result.Error)
+			break
+		}
+	}
+
+	return reply, err
+}
+
+// notFound records a transaction that did not match the expected number of rows
+func (nuageTable *NuageTable) notFound(op string) {
+	nuageTable.Metrics.ObserveError(nuageTable.TableName, op, "not-found")
 }
 
 // InsertRow enables insertion of a row into the Nuage OVSDB table
 func (nuageTable *NuageTable) InsertRow(ovs *libovsdb.OvsdbClient, row NuageTableRow) error {
+	return nuageTable.InsertRowContext(context.Background(), ovs, row)
+}
 
-	glog.V(2).Infof("Trying to insert (%+v) into the Nuage Table (%s)", row, nuageTable.TableName)
+// InsertRowContext is InsertRow with the transaction traced as a child of ctx
+func (nuageTable *NuageTable) InsertRowContext(ctx context.Context, ovs *libovsdb.OvsdbClient, row NuageTableRow) error {
 
 	ovsdbRow := make(map[string]interface{})
 	err := row.CreateOVSDBRow(ovsdbRow)
 	if err != nil {
-		glog.Errorf("Unable to create the OVSDB row %v", err)
+		nuageTable.log().Error("Unable to create the OVSDB row", logging.F("table", nuageTable.TableName),
+			logging.Err(err))
 		return err
 	}
 
+	nuageTable.log().Debug("Inserting row", logging.F("table", nuageTable.TableName), rowField(ovsdbRow))
+
 	insertOp := libovsdb.Operation{
 		Op:       "insert",
 		Table:    nuageTable.TableName,
@@ -44,22 +178,210 @@ func (nuageTable *NuageTable) InsertRow(ovs *libovsdb.OvsdbClient, row NuageTabl
 	}
 
 	operations := []libovsdb.Operation{insertOp}
-	reply, err := ovs.Transact(OvsDBName, operations...)
-
-	glog.V(2).Infof("reply : (%+v) err : (%+v)", reply, err)
+	reply, err := nuageTable.transact(ctx, ovs, "insert", operations...)
 
 	if err != nil || len(reply) != 1 || reply[0].Error != "" {
-		errStr := fmt.Errorf("Problem inserting row in the Nuage table row = "+
-			" (%+v) ovsdbrow (%+v) err (%+v) reply (%+v)", row, ovsdbRow, err, reply)
-		glog.Error(errStr)
-		return (errStr)
+		err = replyError(err, reply)
+		nuageTable.log().Error("Problem inserting row", logging.F("table", nuageTable.TableName), logging.Err(err))
+		return fmt.Errorf("Problem inserting row in the Nuage table %s %v", nuageTable.TableName, err)
+	}
+
+	nuageTable.log().Debug("Inserted row", logging.F("table", nuageTable.TableName), logging.F("uuid", reply[0].UUID))
+
+	return nil
+}
+
+// InsertRowIfAbsent inserts the row unless a row of the table has the same value in column, it returns false
+// without inserting the row in that case. The check and the insertion are a single transaction, of concurrent
+// callers inserting rows with the same value only one succeeds.
+func (nuageTable *NuageTable) InsertRowIfAbsent(ovs *libovsdb.OvsdbClient, row NuageTableRow,
+	column string) (bool, error) {
+	return nuageTable.InsertRowIfAbsentContext(context.Background(), ovs, row, column)
+}
+
+// InsertRowIfAbsentContext is InsertRowIfAbsent with the transaction traced as a child of ctx
+func (nuageTable *NuageTable) InsertRowIfAbsentContext(ctx context.Context, ovs *libovsdb.OvsdbClient,
+	row NuageTableRow, column string) (bool, error) {
+
+	ovsdbRow := make(map[string]interface{})
+	if err := row.CreateOVSDBRow(ovsdbRow); err != nil {
+		nuageTable.log().Error("Unable to create the OVSDB row", logging.F("table", nuageTable.TableName),
+			logging.Err(err))
+		return false, err
+	}
+
+	value, ok := ovsdbRow[column]
+	if !ok {
+		return false, fmt.Errorf("Column %s absent from the row", column)
 	}
 
-	glog.V(2).Infof("Insertion into Nuage VM Table succeeded with UUID %s", reply[0].UUID)
+	nuageTable.log().Debug("Inserting row if absent", logging.F("table", nuageTable.TableName),
+		logging.F("column", column), rowField(ovsdbRow))
+
+	// The wait fails, aborting the insertion, while a row has the value. A wait without timeout blocks until
+	// its condition holds, the smallest one is used to fail right away.
+	waitOp := libovsdb.Operation{
+		Op:      "wait",
+		Table:   nuageTable.TableName,
+		Where:   []interface{}{libovsdb.NewCondition(column, "==", value)},
+		Columns: []string{column},
+		Until:   "!=",
+		Rows:    []map[string]interface{}{{column: value}},
+		Timeout: 1,
+	}
+
+	insertOp := libovsdb.Operation{
+		Op:    "insert",
+		Table: nuageTable.TableName,
+		Row:   ovsdbRow,
+	}
+
+	operations := []libovsdb.Operation{waitOp, insertOp}
+	reply, err := nuageTable.transact(ctx, ovs, "insert", operations...)
+	if err == nil && len(reply) > 0 && reply[0].Error != "" {
+		nuageTable.log().Debug("Row present", logging.F("table", nuageTable.TableName),
+			logging.F("column", column), logging.F("value", value))
+		return false, nil
+	}
+
+	if err != nil || len(reply) < len(operations) || reply[1].Error != "" {
+		err = replyError(err, reply)
+		nuageTable.log().Error("Problem inserting row", logging.F("table", nuageTable.TableName), logging.Err(err))
+		return false, fmt.Errorf("Problem inserting row in the Nuage table %s %v", nuageTable.TableName, err)
+	}
+
+	nuageTable.log().Debug("Inserted row", logging.F("table", nuageTable.TableName), logging.F("uuid", reply[1].UUID))
+
+	return true, nil
+}
+
+// DefaultBatchSize is the number of inserts packed by InsertRows in a transaction when no size is given
+const DefaultBatchSize = 100
+
+// InsertRows inserts the rows in transactions of at most batchSize inserts and returns the error of each row,
+// nil for the rows inserted. A transaction is atomic, the rows of a transaction failing on one of its inserts
+// are inserted again without the failing row, so that only the rows in error are left out. The rows of a
+// transaction whose reply is lost are reported in error without being inserted again, they may have been
+// inserted.
+func (nuageTable *NuageTable) InsertRows(ovs *libovsdb.OvsdbClient, rows []NuageTableRow, batchSize int) []error {
+	return nuageTable.InsertRowsContext(context.Background(), ovs, rows, batchSize)
+}
+
+// InsertRowsContext is InsertRows with the transactions traced as children of ctx
+func (nuageTable *NuageTable) InsertRowsContext(ctx context.Context, ovs *libovsdb.OvsdbClient, rows []NuageTableRow,
+	batchSize int) []error {
+
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	errs := make([]error, len(rows))
+	operations := make([]libovsdb.Operation, len(rows))
+	var pending []int
+	for i, row := range rows {
+		ovsdbRow := make(map[string]interface{})
+		err := row.CreateOVSDBRow(ovsdbRow)
+		if err == nil {
+			err = nuageTable.checkColumns(ovs, ovsdbRow)
+		}
+		if err != nil {
+			nuageTable.log().Error("Unable to create the OVSDB row", logging.F("table", nuageTable.TableName),
+				logging.Err(err))
+			errs[i] = err
+			continue
+		}
+		operations[i] = libovsdb.Operation{Op: "insert", Table: nuageTable.TableName, Row: ovsdbRow}
+		pending = append(pending, i)
+	}
+
+	for start := 0; start < len(pending); start += batchSize {
+		end := start + batchSize
+		if end > len(pending) {
+			end = len(pending)
+		}
+		nuageTable.insertBatch(ctx, ovs, operations, pending[start:end], errs)
+	}
+
+	return errs
+}
+
+// checkColumns returns an error if the row has a column missing from the schema of the table. libovsdb refuses
+// to send such a row, refusing the whole transaction without telling which row is at fault.
+func (nuageTable *NuageTable) checkColumns(ovs *libovsdb.OvsdbClient, ovsdbRow map[string]interface{}) error {
+	table, ok := ovs.Schema[OvsDBName].Tables[nuageTable.TableName]
+	if !ok {
+		return fmt.Errorf("Unknown table %s", nuageTable.TableName)
+	}
+
+	for column := range ovsdbRow {
+		if _, ok := table.Columns[column]; !ok {
+			return fmt.Errorf("Unknown column %s of table %s", column, nuageTable.TableName)
+		}
+	}
 
 	return nil
 }
 
+// insertBatch inserts the rows of batch, indexes in operations, in a single transaction and records the error
+// of each row in errs
+func (nuageTable *NuageTable) insertBatch(ctx context.Context, ovs *libovsdb.OvsdbClient,
+	operations []libovsdb.Operation, batch []int, errs []error) {
+
+	for len(batch) > 0 {
+		batchOperations := make([]libovsdb.Operation, len(batch))
+		for i, index := range batch {
+			batchOperations[i] = operations[index]
+		}
+
+		nuageTable.log().Debug("Inserting rows", logging.F("table", nuageTable.TableName),
+			logging.F("count", len(batch)))
+		reply, err := nuageTable.transact(ctx, ovs, "insert", batchOperations...)
+
+		// The transaction could not be sent or its reply was lost, it may have been committed and inserting its
+		// rows again could duplicate them
+		if err != nil || len(reply) < len(batch) {
+			if err == nil {
+				err = fmt.Errorf("incomplete reply")
+			}
+			nuageTable.log().Error("Problem inserting rows", logging.F("table", nuageTable.TableName),
+				logging.F("count", len(batch)), logging.Err(err))
+			for _, index := range batch {
+				errs[index] = fmt.Errorf("Problem inserting row in the Nuage table %s, the row may have been "+
+					"inserted %v", nuageTable.TableName, err)
+			}
+			return
+		}
+		if err = replyError(nil, reply); err == nil {
+			return
+		}
+
+		// The insert reported in error is left out and the others inserted again. The error of a commit failing
+		// after all the inserts succeeded does not tell which rows are at fault, nothing was inserted and the rows
+		// are then inserted one at a time.
+		failed := -1
+		for i, result := range reply {
+			if i < len(batch) && result.Error != "" {
+				failed = i
+				break
+			}
+		}
+		if failed < 0 && len(batch) > 1 {
+			for _, index := range batch {
+				nuageTable.insertBatch(ctx, ovs, operations, []int{index}, errs)
+			}
+			return
+		}
+		if failed < 0 {
+			failed = 0
+		}
+
+		nuageTable.log().Error("Problem inserting row", logging.F("table", nuageTable.TableName), logging.Err(err))
+		errs[batch[failed]] = fmt.Errorf("Problem inserting row in the Nuage table %s %v", nuageTable.TableName,
+			err)
+		batch = append(batch[:failed:failed], batch[failed+1:]...)
+	}
+}
+
 // ReadRowArgs enables a user to specific a condition and the columns of data to be read from a Nuage OVSDB table
 type ReadRowArgs struct {
 	Condition []string
@@ -68,11 +390,17 @@ type ReadRowArgs struct {
 
 // ReadRows enables reading of multiple rows from a Nuage OVSDB table.
 func (nuageTable *NuageTable) ReadRows(ovs *libovsdb.OvsdbClient, readRowArgs ReadRowArgs) ([]map[string]interface{}, error) {
+	return nuageTable.ReadRowsContext(context.Background(), ovs, readRowArgs)
+}
+
+// ReadRowsContext is ReadRows with the transaction traced as a child of ctx
+func (nuageTable *NuageTable) ReadRowsContext(ctx context.Context, ovs *libovsdb.OvsdbClient,
+	readRowArgs ReadRowArgs) ([]map[string]interface{}, error) {
 
 	condition := readRowArgs.Condition
 	columns := readRowArgs.Columns
 
-	glog.V(2).Infof("Reading rows from table %s with condition (%+v)", nuageTable.TableName, condition)
+	nuageTable.log().Debug("Reading rows", logging.F("table", nuageTable.TableName), logging.F("condition", condition))
 
 	var selectOp libovsdb.Operation
 
@@ -112,12 +440,11 @@ func (nuageTable *NuageTable) ReadRows(ovs *libovsdb.OvsdbClient, readRowArgs Re
 	}
 
 	operations := []libovsdb.Operation{selectOp}
-	reply, err := ovs.Transact(OvsDBName, operations...)
-
-	glog.V(2).Infof("reply : (%+v) err : (%+v)", reply, err)
+	reply, err := nuageTable.transact(ctx, ovs, "select", operations...)
 
 	if err != nil || len(reply) != 1 || reply[0].Error != "" {
-		glog.Errorf("Problem reading row from the Nuage table %s %v %+v", nuageTable.TableName, err, reply)
+		nuageTable.log().Error("Problem reading rows", logging.F("table", nuageTable.TableName),
+			logging.F("condition", condition), logging.Err(replyError(err, reply)))
 		return nil, fmt.Errorf("Problem reading row from the Nuage table %s %v",
 			nuageTable.TableName, err)
 	}
@@ -127,14 +454,21 @@ func (nuageTable *NuageTable) ReadRows(ovs *libovsdb.OvsdbClient, readRowArgs Re
 
 // ReadRow enables reading of a single row from Nuage OVSDB table
 func (nuageTable *NuageTable) ReadRow(ovs *libovsdb.OvsdbClient, readRowArgs ReadRowArgs) (map[string]interface{}, error) {
+	return nuageTable.ReadRowContext(context.Background(), ovs, readRowArgs)
+}
+
+// ReadRowContext is ReadRow with the transaction traced as a child of ctx
+func (nuageTable *NuageTable) ReadRowContext(ctx context.Context, ovs *libovsdb.OvsdbClient,
+	readRowArgs ReadRowArgs) (map[string]interface{}, error) {
 
 	condition := readRowArgs.Condition
 	columns := readRowArgs.Columns
 
-	glog.V(2).Infof("Reading row from table %s with condition (%+v)", nuageTable.TableName, condition)
+	nuageTable.log().Debug("Reading row", logging.F("table", nuageTable.TableName), logging.F("condition", condition))
 
 	if len(condition) != 3 {
-		glog.Errorf("Invalid condition %v", condition)
+		nuageTable.log().Error("Invalid condition", logging.F("table", nuageTable.TableName),
+			logging.F("condition", condition))
 		return nil, fmt.Errorf("Invalid condition")
 	}
 
@@ -157,18 +491,19 @@ func (nuageTable *NuageTable) ReadRow(ovs *libovsdb.OvsdbClient, readRowArgs Rea
 	}
 
 	operations := []libovsdb.Operation{selectOp}
-	reply, err := ovs.Transact(OvsDBName, operations...)
-
-	glog.V(2).Infof("reply : (%+v) err : (%+v)", reply, err)
+	reply, err := nuageTable.transact(ctx, ovs, "select", operations...)
 
 	if err != nil || len(reply) != 1 || reply[0].Error != "" {
-		glog.Errorf("Problem reading row from the Nuage table %s %v", nuageTable.TableName, err)
+		nuageTable.log().Error("Problem reading row", logging.F("table", nuageTable.TableName),
+			logging.F("condition", condition), logging.Err(replyError(err, reply)))
 		return nil, fmt.Errorf("Problem reading row from the Nuage table %s %v",
 			nuageTable.TableName, err)
 	}
 
 	if len(reply[0].Rows) != 1 {
-		glog.Errorf("Did not find a Nuage Table entry for table %s condition %v", nuageTable.TableName, condition)
+		nuageTable.notFound("select")
+		nuageTable.log().Error("Row not found", logging.F("table", nuageTable.TableName),
+			logging.F("condition", condition))
 		return nil, fmt.Errorf("Did not find a Nuage Table entry for table %s condition %v",
 			nuageTable.TableName, condition)
 	}
@@ -180,11 +515,17 @@ func (nuageTable *NuageTable) ReadRow(ovs *libovsdb.OvsdbClient, readRowArgs Rea
 
 // DeleteRow is use to delete a row from the Nuage OVSDB table
 func (nuageTable *NuageTable) DeleteRow(ovs *libovsdb.OvsdbClient, condition []string) error {
+	return nuageTable.DeleteRowContext(context.Background(), ovs, condition)
+}
+
+// DeleteRowContext is DeleteRow with the transaction traced as a child of ctx
+func (nuageTable *NuageTable) DeleteRowContext(ctx context.Context, ovs *libovsdb.OvsdbClient, condition []string) error {
 
-	glog.V(2).Infof("Delete from table %s with condition (%+v)", nuageTable.TableName, condition)
+	nuageTable.log().Debug("Deleting row", logging.F("table", nuageTable.TableName), logging.F("condition", condition))
 
 	if len(condition) != 3 {
-		glog.Errorf("Invalid condition %v", condition)
+		nuageTable.log().Error("Invalid condition", logging.F("table", nuageTable.TableName),
+			logging.F("condition", condition))
 		return fmt.Errorf("Invalid condition")
 	}
 
@@ -196,19 +537,19 @@ func (nuageTable *NuageTable) DeleteRow(ovs *libovsdb.OvsdbClient, condition []s
 	}
 
 	operations := []libovsdb.Operation{deleteOp}
-	reply, err := ovs.Transact(OvsDBName, operations...)
-
-	glog.V(2).Infof("reply : (%+v) err : (%+v)", reply, err)
+	reply, err := nuageTable.transact(ctx, ovs, "delete", operations...)
 
 	if err != nil || len(reply) != 1 || reply[0].Error != "" {
-		errStr := fmt.Sprintf("Problem deleting row from the Nuage table %s (%+v) (%+v) (%+v)",
-			nuageTable.TableName, ovsdbCondition, err, reply)
-		glog.Errorf(errStr)
-		return fmt.Errorf(errStr)
+		err = replyError(err, reply)
+		nuageTable.log().Error("Problem deleting row", logging.F("table", nuageTable.TableName),
+			logging.F("condition", condition), logging.Err(err))
+		return fmt.Errorf("Problem deleting row from the Nuage table %s %v %v", nuageTable.TableName, condition, err)
 	}
 
 	if reply[0].Count != 1 {
-		glog.Errorf("Did not delete a Nuage Table entry for table %s condition %v", nuageTable.TableName, condition)
+		nuageTable.notFound("delete")
+		nuageTable.log().Error("Row not deleted", logging.F("table", nuageTable.TableName),
+			logging.F("condition", condition))
 		return fmt.Errorf("Did not delete a Nuage Table entry for table %s condition %v",
 			nuageTable.TableName, condition)
 	}
@@ -216,10 +557,52 @@ func (nuageTable *NuageTable) DeleteRow(ovs *libovsdb.OvsdbClient, condition []s
 	return nil
 }
 
+// DeleteRows deletes the rows matching condition and returns their number, none matching is not an error
+func (nuageTable *NuageTable) DeleteRows(ovs *libovsdb.OvsdbClient, condition []string) (int, error) {
+	return nuageTable.DeleteRowsContext(context.Background(), ovs, condition)
+}
+
+// DeleteRowsContext is DeleteRows with the transaction traced as a child of ctx
+func (nuageTable *NuageTable) DeleteRowsContext(ctx context.Context, ovs *libovsdb.OvsdbClient,
+	condition []string) (int, error) {
+
+	nuageTable.log().Debug("Deleting rows", logging.F("table", nuageTable.TableName), logging.F("condition", condition))
+
+	if len(condition) != 3 {
+		nuageTable.log().Error("Invalid condition", logging.F("table", nuageTable.TableName),
+			logging.F("condition", condition))
+		return 0, fmt.Errorf("Invalid condition")
+	}
+
+	deleteOp := libovsdb.Operation{
+		Op:    "delete",
+		Table: nuageTable.TableName,
+		Where: []interface{}{libovsdb.NewCondition(condition[0], condition[1], condition[2])},
+	}
+
+	reply, err := nuageTable.transact(ctx, ovs, "delete", deleteOp)
+	if err != nil || len(reply) != 1 || reply[0].Error != "" {
+		err = replyError(err, reply)
+		nuageTable.log().Error("Problem deleting rows", logging.F("table", nuageTable.TableName),
+			logging.F("condition", condition), logging.Err(err))
+		return 0, fmt.Errorf("Problem deleting rows from the Nuage table %s %v %v", nuageTable.TableName, condition,
+			err)
+	}
+
+	return reply[0].Count, nil
+}
+
 // UpdateRow updates the OVSDB table row
 func (nuageTable *NuageTable) UpdateRow(ovs *libovsdb.OvsdbClient, ovsdbRow map[string]interface{}, condition []string) error {
+	return nuageTable.UpdateRowContext(context.Background(), ovs, ovsdbRow, condition)
+}
 
-	glog.V(2).Infof("Trying to update the row (%+v) the Nuage Table (%s)", ovsdbRow, nuageTable.TableName)
+// UpdateRowContext is UpdateRow with the transaction traced as a child of ctx
+func (nuageTable *NuageTable) UpdateRowContext(ctx context.Context, ovs *libovsdb.OvsdbClient,
+	ovsdbRow map[string]interface{}, condition []string) error {
+
+	nuageTable.log().Debug("Updating row", logging.F("table", nuageTable.TableName),
+		logging.F("condition", condition), rowField(ovsdbRow))
 
 	ovsdbCondition := libovsdb.NewCondition(condition[0], condition[1], condition[2])
 	updateOp := libovsdb.Operation{
@@ -230,20 +613,125 @@ func (nuageTable *NuageTable) UpdateRow(ovs *libovsdb.OvsdbClient, ovsdbRow map[
 	}
 
 	operations := []libovsdb.Operation{updateOp}
-	reply, err := ovs.Transact(OvsDBName, operations...)
-
-	glog.V(2).Infof("reply : (%+v) err : (%+v)", reply, err)
+	reply, err := nuageTable.transact(ctx, ovs, "update", operations...)
 
 	if err != nil || len(reply) != 1 || reply[0].Error != "" {
-		glog.Errorf("Failed to update row in the Nuage table %s %v", nuageTable.TableName, err)
+		nuageTable.log().Error("Failed to update row", logging.F("table", nuageTable.TableName),
+			logging.F("condition", condition), logging.Err(replyError(err, reply)))
 		return fmt.Errorf("Failed to update row in the Nuage table %s %v", nuageTable.TableName, err)
 	}
 
 	if reply[0].Count != 1 {
-		glog.Errorf("Failed to update the Nuage Table entry for table %s condition %v", nuageTable.TableName, condition)
+		nuageTable.notFound("update")
+		nuageTable.log().Error("Row not updated", logging.F("table", nuageTable.TableName),
+			logging.F("condition", condition))
 		return fmt.Errorf("Failed to update the Nuage Table entry for table %s condition %v",
 			nuageTable.TableName, condition)
 	}
 
 	return nil
 }
+
+// MutateRow applies the mutations to the OVSDB table row. The columns of ovsdbRow, if any, are updated in the
+// same transaction so that both changes are applied atomically.
+func (nuageTable *NuageTable) MutateRow(ovs *libovsdb.OvsdbClient, ovsdbRow map[string]interface{},
+	mutations []interface{}, condition []string) error {
+	return nuageTable.MutateRowContext(context.Background(), ovs, ovsdbRow, mutations, condition)
+}
+
+// MutateRowContext is MutateRow with the transaction traced as a child of ctx
+func (nuageTable *NuageTable) MutateRowContext(ctx context.Context, ovs *libovsdb.OvsdbClient,
+	ovsdbRow map[string]interface{}, mutations []interface{}, condition []string) error {
+
+	nuageTable.log().Debug("Mutating row", logging.F("table", nuageTable.TableName),
+		logging.F("condition", condition), rowField(ovsdbRow), logging.F("mutations", len(mutations)))
+
+	if len(condition) != 3 {
+		nuageTable.log().Error("Invalid condition", logging.F("table", nuageTable.TableName),
+			logging.F("condition", condition))
+		return fmt.Errorf("Invalid condition")
+	}
+
+	ovsdbCondition := libovsdb.NewCondition(condition[0], condition[1], condition[2])
+
+	var operations []libovsdb.Operation
+	if len(ovsdbRow) != 0 {
+		operations = append(operations, libovsdb.Operation{
+			Op:    "update",
+			Table: nuageTable.TableName,
+			Row:   ovsdbRow,
+			Where: []interface{}{ovsdbCondition},
+		})
+	}
+
+	if len(mutations) != 0 {
+		operations = append(operations, libovsdb.Operation{
+			Op:        "mutate",
+			Table:     nuageTable.TableName,
+			Mutations: mutations,
+			Where:     []interface{}{ovsdbCondition},
+		})
+	}
+
+	if len(operations) == 0 {
+		return nil
+	}
+
+	reply, err := nuageTable.transact(ctx, ovs, "mutate", operations...)
+
+	if err != nil || len(reply) < len(operations) {
+		nuageTable.log().Error("Failed to mutate row", logging.F("table", nuageTable.TableName),
+			logging.F("condition", condition), logging.Err(err))
+		return fmt.Errorf("Failed to mutate row in the Nuage table %s %v", nuageTable.TableName, err)
+	}
+
+	for _, result := range reply {
+		if result.Error != "" {
+			nuageTable.log().Error("Failed to mutate row", logging.F("table", nuageTable.TableName),
+				logging.F("condition", condition), logging.F("error", result.Error), logging.F("details", result.Details))
+			return fmt.Errorf("Failed to mutate row in the Nuage table %s %s %s",
+				nuageTable.TableName, result.Error, result.Details)
+		}
+	}
+
+	for i := range operations {
+		if reply[i].Count != 1 {
+			nuageTable.notFound("mutate")
+			nuageTable.log().Error("Row not mutated", logging.F("table", nuageTable.TableName),
+				logging.F("condition", condition))
+			return fmt.Errorf("Failed to mutate the Nuage Table entry for table %s condition %v",
+				nuageTable.TableName, condition)
+		}
+	}
+
+	return nil
+}
+
+// MapMutations builds the mutations setting and removing keys of a map column. The keys being set are deleted
+// first since inserting a key already present in a map leaves its value unchanged.
+func MapMutations(column string, set map[string]string, remove []string) ([]interface{}, error) {
+	var mutations []interface{}
+
+	keys := append([]string{}, remove...)
+	for k := range set {
+		keys = append(keys, k)
+	}
+
+	if len(keys) != 0 {
+		keySet, err := libovsdb.NewOvsSet(keys)
+		if err != nil {
+			return nil, err
+		}
+		mutations = append(mutations, libovsdb.NewMutation(column, "delete", keySet))
+	}
+
+	if len(set) != 0 {
+		values, err := libovsdb.NewOvsMap(set)
+		if err != nil {
+			return nil, err
+		}
+		mutations = append(mutations, libovsdb.NewMutation(column, "insert", values))
+	}
+
+	return mutations, nil
+}
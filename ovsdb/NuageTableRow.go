@@ -3,6 +3,8 @@ package ovsdb
 import (
 	"fmt"
 	"strings"
+
+	"github.com/socketplane/libovsdb"
 )
 
 // NuageTableRow is an interface that all of the Nuage OVSDB table rows implement
@@ -47,3 +49,222 @@ func UnMarshallOVSStringSet(data interface{}) ([]string, error) {
 
 	return values, err
 }
+
+// UnMarshallOVSStringMap unmarshals a ovsdb column which is a map of strings
+func UnMarshallOVSStringMap(data interface{}) (map[string]string, error) {
+	values := make(map[string]string)
+
+	set, ok := data.([]interface{})
+	if !ok || len(set) != 2 {
+		return nil, fmt.Errorf("Invalid data")
+	}
+
+	if key, ok := set[0].(string); !ok || strings.Compare(key, "map") != 0 {
+		return nil, fmt.Errorf("Invalid keyword %+v", set[0])
+	}
+
+	if set[1] == nil {
+		return values, nil
+	}
+
+	pairs, ok := set[1].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Invalid type %+v", set)
+	}
+
+	for _, p := range pairs {
+		pair, ok := p.([]interface{})
+		if !ok || len(pair) != 2 {
+			return nil, fmt.Errorf("Invalid pair %+v", p)
+		}
+		key, keyOk := pair[0].(string)
+		value, valueOk := pair[1].(string)
+		if !keyOk || !valueOk {
+			return nil, fmt.Errorf("Invalid pair %+v", p)
+		}
+		values[key] = value
+	}
+
+	return values, nil
+}
+
+// UnMarshallOVSInt unmarshals a ovsdb column which is an integer
+func UnMarshallOVSInt(data interface{}) (int, error) {
+	switch value := data.(type) {
+	case float64:
+		return int(value), nil
+	case int:
+		return value, nil
+	}
+
+	return 0, fmt.Errorf("Invalid data %+v", data)
+}
+
+// UnMarshallOVSUUIDSet unmarshals a ovsdb column which is a set of UUIDs
+func UnMarshallOVSUUIDSet(data interface{}) ([]string, error) {
+	var values []string
+
+	set, ok := data.([]interface{})
+	if !ok || len(set) != 2 {
+		return nil, fmt.Errorf("Invalid data")
+	}
+
+	key, ok := set[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("Invalid type %+v", set)
+	}
+
+	switch key {
+	case "uuid":
+		uuid, ok := set[1].(string)
+		if !ok {
+			return nil, fmt.Errorf("Invalid uuid %+v", set)
+		}
+		values = append(values, uuid)
+	case "set":
+		if set[1] == nil {
+			return values, nil
+		}
+		elements, ok := set[1].([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("Invalid type %+v", set)
+		}
+		for _, element := range elements {
+			uuids, err := UnMarshallOVSUUIDSet(element)
+			if err != nil || len(uuids) != 1 {
+				return nil, fmt.Errorf("Invalid uuid %+v", element)
+			}
+			values = append(values, uuids[0])
+		}
+	default:
+		return nil, fmt.Errorf("Invalid keyword %s", key)
+	}
+
+	return values, nil
+}
+
+// UnMarshallOVSString unmarshals a ovsdb atom which is a string
+func UnMarshallOVSString(data interface{}) (string, error) {
+	if value, ok := data.(string); ok {
+		return value, nil
+	}
+
+	return "", fmt.Errorf("Invalid data %+v", data)
+}
+
+// UnMarshallOVSReal unmarshals a ovsdb atom which is a real
+func UnMarshallOVSReal(data interface{}) (float64, error) {
+	switch value := data.(type) {
+	case float64:
+		return value, nil
+	case int:
+		return float64(value), nil
+	}
+
+	return 0, fmt.Errorf("Invalid data %+v", data)
+}
+
+// UnMarshallOVSBool unmarshals a ovsdb atom which is a boolean
+func UnMarshallOVSBool(data interface{}) (bool, error) {
+	if value, ok := data.(bool); ok {
+		return value, nil
+	}
+
+	return false, fmt.Errorf("Invalid data %+v", data)
+}
+
+// UnMarshallOVSUUID unmarshals a ovsdb atom which is a UUID, either in the wire notation or as a libovsdb.UUID
+func UnMarshallOVSUUID(data interface{}) (string, error) {
+	switch value := data.(type) {
+	case libovsdb.UUID:
+		return value.GoUUID, nil
+	case []interface{}:
+		if len(value) == 2 && value[0] == "uuid" {
+			if uuid, ok := value[1].(string); ok {
+				return uuid, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("Invalid data %+v", data)
+}
+
+// UnMarshallOVSSet calls element with each atom of a ovsdb set, either in the wire notation, as a libovsdb.OvsSet
+// or as the single atom a set of one element is sent as
+func UnMarshallOVSSet(data interface{}, element func(atom interface{}) error) error {
+	var atoms []interface{}
+
+	switch value := data.(type) {
+	case libovsdb.OvsSet:
+		atoms = value.GoSet
+	case *libovsdb.OvsSet:
+		atoms = value.GoSet
+	case []interface{}:
+		if len(value) != 2 {
+			return fmt.Errorf("Invalid data %+v", data)
+		}
+		if value[0] != "set" {
+			atoms = []interface{}{value}
+			break
+		}
+		if value[1] != nil {
+			var ok bool
+			if atoms, ok = value[1].([]interface{}); !ok {
+				return fmt.Errorf("Invalid type %+v", data)
+			}
+		}
+	default:
+		atoms = []interface{}{value}
+	}
+
+	for _, atom := range atoms {
+		if err := element(atom); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UnMarshallOVSMap calls pair with each key and value of a ovsdb map, either in the wire notation or as a
+// libovsdb.OvsMap
+func UnMarshallOVSMap(data interface{}, pair func(key interface{}, value interface{}) error) error {
+	switch value := data.(type) {
+	case libovsdb.OvsMap:
+		return unMarshallGoMap(value.GoMap, pair)
+	case *libovsdb.OvsMap:
+		return unMarshallGoMap(value.GoMap, pair)
+	case []interface{}:
+		if len(value) != 2 || value[0] != "map" {
+			return fmt.Errorf("Invalid data %+v", data)
+		}
+		if value[1] == nil {
+			return nil
+		}
+		pairs, ok := value[1].([]interface{})
+		if !ok {
+			return fmt.Errorf("Invalid type %+v", data)
+		}
+		for _, p := range pairs {
+			keyValue, ok := p.([]interface{})
+			if !ok || len(keyValue) != 2 {
+				return fmt.Errorf("Invalid pair %+v", p)
+			}
+			if err := pair(keyValue[0], keyValue[1]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return fmt.Errorf("Invalid data %+v", data)
+}
+
+func unMarshallGoMap(goMap map[interface{}]interface{}, pair func(key interface{}, value interface{}) error) error {
+	for key, value := range goMap {
+		if err := pair(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package ovsdb
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nuagenetworks/libvrsdk/ovsdb/ovsdbtest"
+	"github.com/socketplane/libovsdb"
+)
+
+// invalidRow is a row the OVSDB server refuses
+type invalidRow struct{}
+
+func (row *invalidRow) Equals(otherRow interface{}) bool { return false }
+
+func (row *invalidRow) CreateOVSDBRow(ovsdbRow map[string]interface{}) error {
+	ovsdbRow[NuagePortTableColumnName] = 42
+	return nil
+}
+
+// unknownColumnRow is a row libovsdb refuses to send
+type unknownColumnRow struct{}
+
+func (row *unknownColumnRow) Equals(otherRow interface{}) bool { return false }
+
+func (row *unknownColumnRow) CreateOVSDBRow(ovsdbRow map[string]interface{}) error {
+	ovsdbRow["no_such_column"] = "value"
+	return nil
+}
+
+// unconvertibleRow is a row failing its conversion
+type unconvertibleRow struct{}
+
+func (row *unconvertibleRow) Equals(otherRow interface{}) bool { return false }
+
+func (row *unconvertibleRow) CreateOVSDBRow(ovsdbRow map[string]interface{}) error {
+	return fmt.Errorf("Invalid row")
+}
+
+func TestNuageTableInsertRows(t *testing.T) {
+	server, err := ovsdbtest.NewServer()
+	if err != nil {
+		t.Fatalf("Unable to start the OVSDB server %v", err)
+	}
+	defer server.Close()
+
+	ovs, err := libovsdb.ConnectWithUnixSocket(server.SocketPath())
+	if err != nil {
+		t.Fatalf("Unable to connect to the OVSDB server %v", err)
+	}
+	defer ovs.Disconnect()
+
+	nuagePortTable := &NuageTable{TableName: NuagePortTable}
+
+	var rows []NuageTableRow
+	for i := 0; i < 10; i++ {
+		switch i {
+		case 2:
+			rows = append(rows, &invalidRow{})
+		case 5:
+			rows = append(rows, &unknownColumnRow{})
+		case 7:
+			rows = append(rows, &unconvertibleRow{})
+		default:
+			row := createTestPortTableRow()
+			row.Name = fmt.Sprintf("port%d", i)
+			rows = append(rows, &row)
+		}
+	}
+
+	errs := nuagePortTable.InsertRows(ovs, rows, 4)
+	if len(errs) != len(rows) {
+		t.Fatalf("Unexpected number of results %d", len(errs))
+	}
+	for i, err := range errs {
+		if failed := i == 2 || i == 5 || i == 7; failed != (err != nil) {
+			t.Fatalf("Unexpected result of row %d %v", i, err)
+		}
+	}
+
+	inserted := server.Rows(NuagePortTable)
+	if len(inserted) != 7 {
+		t.Fatalf("Unexpected rows inserted %v", inserted)
+	}
+
+	if errs = nuagePortTable.InsertRows(ovs, nil, 0); len(errs) != 0 {
+		t.Fatalf("Unexpected results of an empty insert %v", errs)
+	}
+}
+
+func TestNuageTableInsertRowsLostReply(t *testing.T) {
+	server, err := ovsdbtest.NewServer()
+	if err != nil {
+		t.Fatalf("Unable to start the OVSDB server %v", err)
+	}
+	defer server.Close()
+
+	ovs, err := libovsdb.ConnectWithUnixSocket(server.SocketPath())
+	if err != nil {
+		t.Fatalf("Unable to connect to the OVSDB server %v", err)
+	}
+	defer ovs.Disconnect()
+
+	nuagePortTable := &NuageTable{TableName: NuagePortTable}
+
+	var rows []NuageTableRow
+	for i := 0; i < 4; i++ {
+		row := createTestPortTableRow()
+		row.Name = fmt.Sprintf("port%d", i)
+		rows = append(rows, &row)
+	}
+
+	// The session is dropped while the transaction waits for the server, which commits it after the client
+	// gave up on its reply
+	server.Pause()
+	done := make(chan []error)
+	go func() { done <- nuagePortTable.InsertRows(ovs, rows, 0) }()
+	time.Sleep(100 * time.Millisecond)
+	server.DropSessions()
+	errs := <-done
+	server.Resume()
+
+	for i, err := range errs {
+		if err == nil || !strings.Contains(err.Error(), "may have been inserted") {
+			t.Fatalf("Unexpected result of row %d without a reply %v", i, err)
+		}
+	}
+
+	// The rows are not inserted again one at a time
+	for start := time.Now(); len(server.Rows(NuagePortTable)) < len(rows) && time.Since(start) < time.Second; {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if inserted := server.Rows(NuagePortTable); len(inserted) > len(rows) {
+		t.Fatalf("Rows inserted twice %v", inserted)
+	}
+}
+
+func TestNuageTableInsertRowIfAbsent(t *testing.T) {
+	server, err := ovsdbtest.NewServer()
+	if err != nil {
+		t.Fatalf("Unable to start the OVSDB server %v", err)
+	}
+	defer server.Close()
+
+	ovs, err := libovsdb.ConnectWithUnixSocket(server.SocketPath())
+	if err != nil {
+		t.Fatalf("Unable to connect to the OVSDB server %v", err)
+	}
+	defer ovs.Disconnect()
+
+	nuagePortTable := &NuageTable{TableName: NuagePortTable}
+	row := createTestPortTableRow()
+	for _, expected := range []bool{true, false} {
+		inserted, err := nuagePortTable.InsertRowIfAbsent(ovs, &row, NuagePortTableColumnName)
+		if err != nil || inserted != expected {
+			t.Fatalf("Unexpected insertion %t %v", inserted, err)
+		}
+	}
+
+	condition := []string{NuagePortTableColumnName, "==", row.Name}
+	for _, expected := range []int{1, 0} {
+		if count, err := nuagePortTable.DeleteRows(ovs, condition); err != nil || count != expected {
+			t.Fatalf("Unexpected deletion %d %v", count, err)
+		}
+	}
+}
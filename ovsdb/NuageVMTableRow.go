@@ -1,8 +1,10 @@
+// Code generated by ovsdbgen from vrs.ovsschema. DO NOT EDIT.
+
 package ovsdb
 
 import (
+	"fmt"
 	"reflect"
-	"strings"
 
 	"github.com/nuagenetworks/libvrsdk/api/entity"
 	"github.com/socketplane/libovsdb"
@@ -10,15 +12,21 @@ import (
 
 // These constants describe the Nuage_VM_Table
 const (
-	NuageVMTable                    = "Nuage_VM_Table"
-	NuageVMTableColumnVMUUID        = "vm_uuid"
-	NuageVMTableColumnVMName        = "vm_name"
-	NuageVMTableColumnPorts         = "ports"
-	NuageVMTableColumnState         = "state"
-	NuageVMTableColumnReason        = "reason"
+	NuageVMTable = "Nuage_VM_Table"
+
+	NuageVMTableColumnType          = "type"
 	NuageVMTableColumnEventCategory = "event"
 	NuageVMTableColumnEventType     = "event_type"
+	NuageVMTableColumnState         = "state"
+	NuageVMTableColumnReason        = "reason"
+	NuageVMTableColumnVMUUID        = "vm_uuid"
+	NuageVMTableColumnVMName        = "vm_name"
+	NuageVMTableColumnDomain        = "domain"
+	NuageVMTableColumnUser          = "nuage_user"
+	NuageVMTableColumnEnterprise    = "nuage_enterprise"
 	NuageVMTableColumnMetadata      = "metadata"
+	NuageVMTableColumnPorts         = "ports"
+	NuageVMTableColumnDirty         = "dirty"
 )
 
 // NuageVMTableRow represents a row in the Nuage_VM_Table
@@ -36,98 +44,228 @@ type NuageVMTableRow struct {
 	Metadata        map[string]string
 	Ports           []string
 	Dirty           int
+
+	// Capabilities of the VRS, the optional columns it does not support are left out of the OVSDB row
+	Capabilities Capabilities
 }
 
 // Equals checks for equality of two rows in the Nuage_VM_Table
 func (row *NuageVMTableRow) Equals(otherRow interface{}) bool {
-
-	nuageVMTableRow, ok := otherRow.(NuageVMTableRow)
-
+	other, ok := otherRow.(NuageVMTableRow)
 	if !ok {
-		return false
+		pointer, ok := otherRow.(*NuageVMTableRow)
+		if !ok || pointer == nil {
+			return false
+		}
+		other = *pointer
 	}
 
-	if row.Type != nuageVMTableRow.Type {
+	if row.Type != other.Type {
 		return false
 	}
 
-	if row.Event != nuageVMTableRow.Event {
+	if row.Event != other.Event {
 		return false
 	}
 
-	if row.EventType != nuageVMTableRow.EventType {
+	if row.EventType != other.EventType {
 		return false
 	}
 
-	if row.State != nuageVMTableRow.State {
+	if row.State != other.State {
 		return false
 	}
 
-	if row.Reason != nuageVMTableRow.Reason {
+	if row.Reason != other.Reason {
 		return false
 	}
 
-	if strings.Compare(row.VMUuid, nuageVMTableRow.VMUuid) != 0 {
+	if row.VMUuid != other.VMUuid {
 		return false
 	}
 
-	if row.Domain != nuageVMTableRow.Domain {
+	if row.VMName != other.VMName {
 		return false
 	}
 
-	if strings.Compare(row.VMName, nuageVMTableRow.VMName) != 0 {
+	if row.Domain != other.Domain {
 		return false
 	}
 
-	if strings.Compare(row.NuageUser, nuageVMTableRow.NuageUser) != 0 {
+	if row.NuageUser != other.NuageUser {
 		return false
 	}
 
-	if strings.Compare(row.NuageEnterprise, nuageVMTableRow.NuageEnterprise) != 0 {
+	if row.NuageEnterprise != other.NuageEnterprise {
 		return false
 	}
 
-	if !reflect.DeepEqual(row.Metadata, nuageVMTableRow.Metadata) {
+	if !reflect.DeepEqual(row.Metadata, other.Metadata) {
 		return false
 	}
 
-	if !reflect.DeepEqual(row.Ports, nuageVMTableRow.Ports) {
+	if !reflect.DeepEqual(row.Ports, other.Ports) {
 		return false
 	}
 
-	if row.Dirty != nuageVMTableRow.Dirty {
+	if row.Dirty != other.Dirty {
 		return false
 	}
 
 	return true
 }
 
-// CreateOVSDBRow creates a OVSDB row for Nuage_VM_Table
+// CreateOVSDBRow creates a OVSDB row for the Nuage_VM_Table
 func (row *NuageVMTableRow) CreateOVSDBRow(ovsdbRow map[string]interface{}) error {
+	ovsdbRow[NuageVMTableColumnType] = row.Type
+	ovsdbRow[NuageVMTableColumnEventCategory] = row.Event
+	ovsdbRow[NuageVMTableColumnEventType] = row.EventType
+	ovsdbRow[NuageVMTableColumnState] = row.State
+	ovsdbRow[NuageVMTableColumnReason] = row.Reason
+	ovsdbRow[NuageVMTableColumnVMUUID] = row.VMUuid
+	ovsdbRow[NuageVMTableColumnVMName] = row.VMName
+	ovsdbRow[NuageVMTableColumnDomain] = int(row.Domain)
+	ovsdbRow[NuageVMTableColumnUser] = row.NuageUser
+	ovsdbRow[NuageVMTableColumnEnterprise] = row.NuageEnterprise
+	metadataMap := libovsdb.OvsMap{GoMap: make(map[interface{}]interface{})}
+	for key, value := range row.Metadata {
+		metadataMap.GoMap[key] = value
+	}
+	ovsdbRow[NuageVMTableColumnMetadata] = metadataMap
+	portsSet := libovsdb.OvsSet{GoSet: []interface{}{}}
+	for _, element := range row.Ports {
+		portsSet.GoSet = append(portsSet.GoSet, element)
+	}
+	ovsdbRow[NuageVMTableColumnPorts] = portsSet
+	ovsdbRow[NuageVMTableColumnDirty] = row.Dirty
+	row.Capabilities.Omit(NuageVMTable, ovsdbRow)
+
+	return nil
+}
+
+// ReadOVSDBRow fills the row with the columns present in ovsdbRow, as returned by a select or a monitor
+func (row *NuageVMTableRow) ReadOVSDBRow(ovsdbRow map[string]interface{}) error {
+	if data, ok := ovsdbRow[NuageVMTableColumnType]; ok {
+		value, err := UnMarshallOVSInt(data)
+		if err != nil {
+			return fmt.Errorf("Invalid %s %v", NuageVMTableColumnType, err)
+		}
+		row.Type = value
+	}
 
-	metadataMap, err := libovsdb.NewOvsMap(row.Metadata)
-	if err != nil {
-		return err
-	}
-
-	portSet, err := libovsdb.NewOvsSet(row.Ports)
-	if err != nil {
-		return err
-	}
-
-	ovsdbRow["type"] = row.Type
-	ovsdbRow["event"] = row.Event
-	ovsdbRow["event_type"] = row.EventType
-	ovsdbRow["state"] = row.State
-	ovsdbRow["reason"] = row.Reason
-	ovsdbRow["vm_uuid"] = row.VMUuid
-	ovsdbRow["domain"] = row.Domain
-	ovsdbRow["vm_name"] = row.VMName
-	ovsdbRow["nuage_user"] = row.NuageUser
-	ovsdbRow["nuage_enterprise"] = row.NuageEnterprise
-	ovsdbRow["metadata"] = metadataMap
-	ovsdbRow["ports"] = portSet
-	ovsdbRow["dirty"] = row.Dirty
+	if data, ok := ovsdbRow[NuageVMTableColumnEventCategory]; ok {
+		value, err := UnMarshallOVSInt(data)
+		if err != nil {
+			return fmt.Errorf("Invalid %s %v", NuageVMTableColumnEventCategory, err)
+		}
+		row.Event = value
+	}
+
+	if data, ok := ovsdbRow[NuageVMTableColumnEventType]; ok {
+		value, err := UnMarshallOVSInt(data)
+		if err != nil {
+			return fmt.Errorf("Invalid %s %v", NuageVMTableColumnEventType, err)
+		}
+		row.EventType = value
+	}
+
+	if data, ok := ovsdbRow[NuageVMTableColumnState]; ok {
+		value, err := UnMarshallOVSInt(data)
+		if err != nil {
+			return fmt.Errorf("Invalid %s %v", NuageVMTableColumnState, err)
+		}
+		row.State = value
+	}
+
+	if data, ok := ovsdbRow[NuageVMTableColumnReason]; ok {
+		value, err := UnMarshallOVSInt(data)
+		if err != nil {
+			return fmt.Errorf("Invalid %s %v", NuageVMTableColumnReason, err)
+		}
+		row.Reason = value
+	}
+
+	if data, ok := ovsdbRow[NuageVMTableColumnVMUUID]; ok {
+		value, err := UnMarshallOVSString(data)
+		if err != nil {
+			return fmt.Errorf("Invalid %s %v", NuageVMTableColumnVMUUID, err)
+		}
+		row.VMUuid = value
+	}
+
+	if data, ok := ovsdbRow[NuageVMTableColumnVMName]; ok {
+		value, err := UnMarshallOVSString(data)
+		if err != nil {
+			return fmt.Errorf("Invalid %s %v", NuageVMTableColumnVMName, err)
+		}
+		row.VMName = value
+	}
+
+	if data, ok := ovsdbRow[NuageVMTableColumnDomain]; ok {
+		value, err := UnMarshallOVSInt(data)
+		if err != nil {
+			return fmt.Errorf("Invalid %s %v", NuageVMTableColumnDomain, err)
+		}
+		row.Domain = entity.Domain(value)
+	}
+
+	if data, ok := ovsdbRow[NuageVMTableColumnUser]; ok {
+		value, err := UnMarshallOVSString(data)
+		if err != nil {
+			return fmt.Errorf("Invalid %s %v", NuageVMTableColumnUser, err)
+		}
+		row.NuageUser = value
+	}
+
+	if data, ok := ovsdbRow[NuageVMTableColumnEnterprise]; ok {
+		value, err := UnMarshallOVSString(data)
+		if err != nil {
+			return fmt.Errorf("Invalid %s %v", NuageVMTableColumnEnterprise, err)
+		}
+		row.NuageEnterprise = value
+	}
+
+	if data, ok := ovsdbRow[NuageVMTableColumnMetadata]; ok {
+		row.Metadata = make(map[string]string)
+		err := UnMarshallOVSMap(data, func(key interface{}, value interface{}) error {
+			k, err := UnMarshallOVSString(key)
+			if err != nil {
+				return err
+			}
+			v, err := UnMarshallOVSString(value)
+			if err != nil {
+				return err
+			}
+			row.Metadata[k] = v
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("Invalid %s %v", NuageVMTableColumnMetadata, err)
+		}
+	}
+
+	if data, ok := ovsdbRow[NuageVMTableColumnPorts]; ok {
+		row.Ports = nil
+		err := UnMarshallOVSSet(data, func(atom interface{}) error {
+			value, err := UnMarshallOVSString(atom)
+			if err != nil {
+				return err
+			}
+			row.Ports = append(row.Ports, value)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("Invalid %s %v", NuageVMTableColumnPorts, err)
+		}
+	}
+
+	if data, ok := ovsdbRow[NuageVMTableColumnDirty]; ok {
+		value, err := UnMarshallOVSInt(data)
+		if err != nil {
+			return fmt.Errorf("Invalid %s %v", NuageVMTableColumnDirty, err)
+		}
+		row.Dirty = value
+	}
 
 	return nil
 }
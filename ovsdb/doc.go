@@ -1,4 +1,11 @@
 /*
 Package ovsdb enables programming of the Nuage VRS by directly interacting with Nuage OVSDB tables.
+
+The row types of the tables are generated from vrs.ovsschema by ovsdbgen, configured by ovsdbgen.json.
+vrs.ovsschema is not the schema shipped with the VRS but a hand-written excerpt of it: it only describes the
+Nuage tables and the Controller columns used by the package, and lists the columns in the order of the fields
+of the row types. The schema of the VRS connected to is checked at run time, see CheckSchema.
 */
 package ovsdb
+
+//go:generate go run ../cmd/ovsdbgen -schema vrs.ovsschema -config ovsdbgen.json
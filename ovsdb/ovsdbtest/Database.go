@@ -0,0 +1,730 @@
+package ovsdbtest
+
+import (
+	"crypto/rand"
+	"fmt"
+	"sort"
+)
+
+// opError is an error reported in the result of a transact operation
+type opError struct {
+	kind    string
+	details string
+}
+
+func newError(kind string, format string, args ...interface{}) *opError {
+	return &opError{kind: kind, details: fmt.Sprintf(format, args...)}
+}
+
+func (e *opError) Error() string {
+	return e.kind + ": " + e.details
+}
+
+func (e *opError) result() map[string]interface{} {
+	return map[string]interface{}{"error": e.kind, "details": e.details}
+}
+
+func newUUID() uuid {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return uuid(fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]))
+}
+
+type row struct {
+	uuid    uuid
+	version uuid
+	columns map[string]datum
+}
+
+func (r *row) get(name string) datum {
+	switch name {
+	case "_uuid":
+		return datum{keys: []interface{}{r.uuid}}
+	case "_version":
+		return datum{keys: []interface{}{r.version}}
+	}
+	return r.columns[name]
+}
+
+func (r *row) clone() *row {
+	c := &row{uuid: r.uuid, version: r.version, columns: make(map[string]datum, len(r.columns))}
+	for k, v := range r.columns {
+		c.columns[k] = v.clone()
+	}
+	return c
+}
+
+// encode returns the JSON representation of the requested columns of the row
+func (r *row) encode(ts *tableSchema, columns []string) map[string]interface{} {
+	result := make(map[string]interface{}, len(columns))
+	for _, name := range columns {
+		column, err := ts.column(name)
+		if err != nil {
+			continue
+		}
+		result[name] = r.get(name).encode(column.typ)
+	}
+	return result
+}
+
+type table struct {
+	schema *tableSchema
+	rows   map[uuid]*row
+}
+
+func (t *table) sortedRows() []*row {
+	rows := make([]*row, 0, len(t.rows))
+	for _, r := range t.rows {
+		rows = append(rows, r)
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].uuid < rows[j].uuid })
+	return rows
+}
+
+// allColumns returns the names of all the columns of the table, optionally including the hidden ones
+func (ts *tableSchema) allColumns(hidden bool) []string {
+	var columns []string
+	if hidden {
+		columns = append(columns, "_uuid", "_version")
+	}
+	var names []string
+	for name := range ts.columns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return append(columns, names...)
+}
+
+type database struct {
+	schema *databaseSchema
+	tables map[string]*table
+}
+
+func newDatabase(schema *databaseSchema) *database {
+	db := &database{schema: schema, tables: make(map[string]*table)}
+	for name, ts := range schema.tables {
+		db.tables[name] = &table{schema: ts, rows: make(map[uuid]*row)}
+	}
+	return db
+}
+
+func (db *database) clone() *database {
+	c := &database{schema: db.schema, tables: make(map[string]*table, len(db.tables))}
+	for name, t := range db.tables {
+		ct := &table{schema: t.schema, rows: make(map[uuid]*row, len(t.rows))}
+		for id, r := range t.rows {
+			ct.rows[id] = r
+		}
+		c.tables[name] = ct
+	}
+	return c
+}
+
+// rowChange records the old and new contents of a row modified by a transaction
+type rowChange struct {
+	table string
+	old   *row
+	new   *row
+}
+
+// transaction executes operations against a private copy of the database
+type transaction struct {
+	db      *database
+	orig    *database
+	named   namedUUIDs
+	touched map[uuid]bool
+	owner   *session
+}
+
+func (txn *transaction) table(name string) (*table, error) {
+	t, ok := txn.db.tables[name]
+	if !ok {
+		return nil, newError("unknown table", "no table named %s", name)
+	}
+	return t, nil
+}
+
+// writable returns a copy of the row that may be modified by the transaction
+func (txn *transaction) writable(t *table, r *row) *row {
+	if txn.touched[r.uuid] {
+		return r
+	}
+	c := r.clone()
+	t.rows[r.uuid] = c
+	txn.touched[r.uuid] = true
+	return c
+}
+
+type condition struct {
+	column   *columnSchema
+	function string
+	value    datum
+}
+
+func (txn *transaction) parseWhere(ts *tableSchema, where interface{}) ([]condition, error) {
+	var conditions []condition
+	if where == nil {
+		return conditions, nil
+	}
+	clauses, ok := where.([]interface{})
+	if !ok {
+		return nil, newError("syntax error", "invalid where clause %v", where)
+	}
+	for _, c := range clauses {
+		clause, ok := c.([]interface{})
+		if !ok || len(clause) != 3 {
+			return nil, newError("syntax error", "invalid condition %v", c)
+		}
+		name, _ := clause[0].(string)
+		function, _ := clause[1].(string)
+		column, err := ts.column(name)
+		if err != nil {
+			return nil, err
+		}
+		ct := column.typ
+		switch function {
+		case "==", "!=", "includes", "excludes":
+			// Compare against a value of the column type with no size restriction
+			ct.min, ct.max = 0, -1
+		case "<", "<=", ">", ">=":
+			if !column.typ.isScalar() && !(column.typ.min == 0 && column.typ.max == 1) {
+				return nil, newError("syntax error", "%s is not applicable to column %s", function, name)
+			}
+		default:
+			return nil, newError("syntax error", "unknown function %s", function)
+		}
+		value, err := decodeDatum(ct, clause[2], txn.named)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition{column: column, function: function, value: value})
+	}
+	return conditions, nil
+}
+
+func (c condition) matches(r *row) bool {
+	v := r.get(c.column.name)
+	switch c.function {
+	case "==":
+		return v.equals(c.value)
+	case "!=":
+		return !v.equals(c.value)
+	case "includes":
+		for i, k := range c.value.keys {
+			j := v.find(k)
+			if j < 0 || (c.value.values != nil && compareAtoms(v.values[j], c.value.values[i]) != 0) {
+				return false
+			}
+		}
+		return true
+	case "excludes":
+		for i, k := range c.value.keys {
+			j := v.find(k)
+			if j >= 0 && (c.value.values == nil || compareAtoms(v.values[j], c.value.values[i]) == 0) {
+				return false
+			}
+		}
+		return true
+	}
+	if len(v.keys) != 1 || len(c.value.keys) != 1 {
+		return false
+	}
+	cmp := compareAtoms(v.keys[0], c.value.keys[0])
+	switch c.function {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+func (txn *transaction) selectRows(t *table, where interface{}) ([]*row, error) {
+	conditions, err := txn.parseWhere(t.schema, where)
+	if err != nil {
+		return nil, err
+	}
+	var rows []*row
+	for _, r := range t.sortedRows() {
+		matched := true
+		for _, c := range conditions {
+			if !c.matches(r) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			rows = append(rows, r)
+		}
+	}
+	return rows, nil
+}
+
+func (txn *transaction) setColumns(ts *tableSchema, r *row, values interface{}, insert bool) error {
+	if values == nil {
+		return nil
+	}
+	columns, ok := values.(map[string]interface{})
+	if !ok {
+		return newError("syntax error", "invalid row %v", values)
+	}
+	for name, value := range columns {
+		column, ok := ts.columns[name]
+		if !ok {
+			return newError("unknown column", "table %s has no column %s", ts.name, name)
+		}
+		if !insert && !column.mutable {
+			return newError("constraint violation", "cannot modify immutable column %s", name)
+		}
+		d, err := decodeDatum(column.typ, value, txn.named)
+		if err != nil {
+			return err
+		}
+		if err := d.checkSize(column.typ); err != nil {
+			return err
+		}
+		r.columns[name] = d
+	}
+	return nil
+}
+
+func stringList(v interface{}) []string {
+	var list []string
+	if items, ok := v.([]interface{}); ok {
+		for _, item := range items {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+	}
+	return list
+}
+
+// execute runs a single operation and returns its result
+func (txn *transaction) execute(op map[string]interface{}) (map[string]interface{}, error) {
+	name, _ := op["op"].(string)
+	switch name {
+	case "comment", "commit":
+		return map[string]interface{}{}, nil
+	case "abort":
+		return nil, newError("aborted", "aborted by request")
+	case "assert":
+		lock, _ := op["lock"].(string)
+		if txn.owner == nil || !txn.owner.owns(lock) {
+			return nil, newError("not owner", "lock %s is not owned by this session", lock)
+		}
+		return map[string]interface{}{}, nil
+	}
+
+	tableName, _ := op["table"].(string)
+	t, err := txn.table(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	switch name {
+	case "insert":
+		r := &row{uuid: newUUID(), version: newUUID(), columns: make(map[string]datum)}
+		for columnName, column := range t.schema.columns {
+			r.columns[columnName] = defaultDatum(column.typ)
+		}
+		if uuidName, ok := op["uuid-name"].(string); ok && uuidName != "" {
+			txn.named[uuidName] = r.uuid
+		}
+		if err := txn.setColumns(t.schema, r, op["row"], true); err != nil {
+			return nil, err
+		}
+		t.rows[r.uuid] = r
+		txn.touched[r.uuid] = true
+		return map[string]interface{}{"uuid": []interface{}{"uuid", string(r.uuid)}}, nil
+
+	case "select":
+		rows, err := txn.selectRows(t, op["where"])
+		if err != nil {
+			return nil, err
+		}
+		columns := stringList(op["columns"])
+		if _, ok := op["columns"]; !ok {
+			columns = t.schema.allColumns(true)
+		}
+		result := []interface{}{}
+		for _, r := range rows {
+			result = append(result, r.encode(t.schema, columns))
+		}
+		return map[string]interface{}{"rows": result}, nil
+
+	case "update":
+		rows, err := txn.selectRows(t, op["where"])
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rows {
+			w := txn.writable(t, r)
+			if err := txn.setColumns(t.schema, w, op["row"], false); err != nil {
+				return nil, err
+			}
+		}
+		return map[string]interface{}{"count": len(rows)}, nil
+
+	case "mutate":
+		rows, err := txn.selectRows(t, op["where"])
+		if err != nil {
+			return nil, err
+		}
+		mutations, _ := op["mutations"].([]interface{})
+		for _, r := range rows {
+			w := txn.writable(t, r)
+			for _, m := range mutations {
+				if err := txn.mutate(t.schema, w, m); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return map[string]interface{}{"count": len(rows)}, nil
+
+	case "delete":
+		rows, err := txn.selectRows(t, op["where"])
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rows {
+			delete(t.rows, r.uuid)
+		}
+		return map[string]interface{}{"count": len(rows)}, nil
+
+	case "wait":
+		return txn.wait(t, op)
+	}
+
+	return nil, newError("unknown operation", "operation %s is not supported", name)
+}
+
+func (txn *transaction) mutate(ts *tableSchema, r *row, m interface{}) error {
+	mutation, ok := m.([]interface{})
+	if !ok || len(mutation) != 3 {
+		return newError("syntax error", "invalid mutation %v", m)
+	}
+	name, _ := mutation[0].(string)
+	mutator, _ := mutation[1].(string)
+	column, ok := ts.columns[name]
+	if !ok {
+		return newError("unknown column", "table %s has no column %s", ts.name, name)
+	}
+	if !column.mutable {
+		return newError("constraint violation", "cannot mutate immutable column %s", name)
+	}
+	current := r.columns[name]
+
+	switch mutator {
+	case "insert", "delete":
+		if column.typ.value == nil && column.typ.max == 1 && column.typ.min == 1 {
+			return newError("syntax error", "%s is not applicable to scalar column %s", mutator, name)
+		}
+		unbounded := column.typ
+		unbounded.min, unbounded.max = 0, -1
+		var arg datum
+		var err error
+		keysOnly := false
+		if column.typ.isMap() && mutator == "delete" {
+			if _, isMap := isTagged(mutation[2], "map"); !isMap {
+				keysOnly = true
+				arg, err = decodeKeySet(column.typ, mutation[2], txn.named)
+			} else {
+				arg, err = decodeDatum(unbounded, mutation[2], txn.named)
+			}
+		} else {
+			arg, err = decodeDatum(unbounded, mutation[2], txn.named)
+		}
+		if err != nil {
+			return err
+		}
+
+		result := current.clone()
+		if column.typ.isMap() && result.values == nil {
+			result.values = []interface{}{}
+		}
+		if mutator == "insert" {
+			for i, k := range arg.keys {
+				if result.find(k) >= 0 {
+					continue
+				}
+				result.keys = append(result.keys, k)
+				if column.typ.isMap() {
+					result.values = append(result.values, arg.values[i])
+				}
+			}
+			result.normalize()
+		} else {
+			var kept datum
+			if column.typ.isMap() {
+				kept.values = []interface{}{}
+			}
+			for i, k := range result.keys {
+				j := arg.find(k)
+				remove := j >= 0
+				if remove && column.typ.isMap() && !keysOnly {
+					remove = compareAtoms(arg.values[j], result.values[i]) == 0
+				}
+				if remove {
+					continue
+				}
+				kept.keys = append(kept.keys, k)
+				if column.typ.isMap() {
+					kept.values = append(kept.values, result.values[i])
+				}
+			}
+			result = kept
+		}
+		if err := result.checkSize(column.typ); err != nil {
+			return err
+		}
+		r.columns[name] = result
+		return nil
+
+	case "+=", "-=", "*=", "/=", "%=":
+		if column.typ.isMap() || (column.typ.key.atomic != "integer" && column.typ.key.atomic != "real") {
+			return newError("syntax error", "%s is not applicable to column %s", mutator, name)
+		}
+		arg, err := decodeAtom(column.typ.key, mutation[2], txn.named)
+		if err != nil {
+			return err
+		}
+		result := current.clone()
+		for i, k := range result.keys {
+			switch x := k.(type) {
+			case int64:
+				y := arg.(int64)
+				switch mutator {
+				case "+=":
+					x += y
+				case "-=":
+					x -= y
+				case "*=":
+					x *= y
+				case "/=", "%=":
+					if y == 0 {
+						return newError("domain error", "division by zero")
+					}
+					if mutator == "/=" {
+						x /= y
+					} else {
+						x %= y
+					}
+				}
+				result.keys[i] = x
+			case float64:
+				y := arg.(float64)
+				switch mutator {
+				case "+=":
+					x += y
+				case "-=":
+					x -= y
+				case "*=":
+					x *= y
+				case "/=":
+					x /= y
+				default:
+					return newError("syntax error", "%s is not applicable to real column %s", mutator, name)
+				}
+				result.keys[i] = x
+			}
+		}
+		result.normalize()
+		r.columns[name] = result
+		return nil
+	}
+
+	return newError("syntax error", "unknown mutator %s", mutator)
+}
+
+func (txn *transaction) wait(t *table, op map[string]interface{}) (map[string]interface{}, error) {
+	rows, err := txn.selectRows(t, op["where"])
+	if err != nil {
+		return nil, err
+	}
+	columns := stringList(op["columns"])
+	until, _ := op["until"].(string)
+	expected, _ := op["rows"].([]interface{})
+
+	var want []map[string]datum
+	for _, e := range expected {
+		values, ok := e.(map[string]interface{})
+		if !ok {
+			return nil, newError("syntax error", "invalid row %v", e)
+		}
+		w := make(map[string]datum)
+		for _, name := range columns {
+			column, err := t.schema.column(name)
+			if err != nil {
+				return nil, err
+			}
+			d, err := decodeDatum(column.typ, values[name], txn.named)
+			if err != nil {
+				return nil, err
+			}
+			w[name] = d
+		}
+		want = append(want, w)
+	}
+
+	equal := len(rows) == len(want)
+	if equal {
+		used := make([]bool, len(want))
+		for _, r := range rows {
+			found := false
+			for i, w := range want {
+				if used[i] {
+					continue
+				}
+				same := true
+				for _, name := range columns {
+					if !r.get(name).equals(w[name]) {
+						same = false
+						break
+					}
+				}
+				if same {
+					used[i] = true
+					found = true
+					break
+				}
+			}
+			if !found {
+				equal = false
+				break
+			}
+		}
+	}
+
+	if (until == "==" && equal) || (until == "!=" && !equal) {
+		return map[string]interface{}{}, nil
+	}
+	// The test server never blocks, a wait that is not satisfied fails straight away
+	return nil, newError("timed out", "wait condition on table %s not satisfied", t.schema.name)
+}
+
+// commit checks the referential integrity and size constraints of the transaction's copy of the database,
+// garbage collects unreferenced rows of non-root tables and returns the list of changed rows
+func (txn *transaction) commit() ([]rowChange, error) {
+	// Garbage collect rows of non-root tables until the database is stable
+	for {
+		collected := false
+		referenced := make(map[uuid]bool)
+		for _, t := range txn.db.tables {
+			for _, r := range t.rows {
+				for name, column := range t.schema.columns {
+					ct := column.typ
+					if ct.key.refTable == "" && (ct.value == nil || ct.value.refTable == "") {
+						continue
+					}
+					d := r.columns[name]
+					for i := range d.keys {
+						if ct.key.refTable != "" {
+							referenced[d.keys[i].(uuid)] = true
+						}
+						if ct.value != nil && ct.value.refTable != "" {
+							referenced[d.values[i].(uuid)] = true
+						}
+					}
+				}
+			}
+		}
+		for _, t := range txn.db.tables {
+			if t.schema.isRoot {
+				continue
+			}
+			for id := range t.rows {
+				if !referenced[id] {
+					delete(t.rows, id)
+					collected = true
+				}
+			}
+		}
+		if !collected {
+			break
+		}
+	}
+
+	for name, t := range txn.db.tables {
+		if t.schema.maxRows > 0 && len(t.rows) > t.schema.maxRows {
+			return nil, newError("constraint violation", "too many rows in table %s", name)
+		}
+		for _, r := range t.rows {
+			for columnName, column := range t.schema.columns {
+				ct := column.typ
+				d := r.columns[columnName]
+				for i := range d.keys {
+					if ct.key.refTable != "" && !ct.key.refWeak {
+						if _, ok := txn.db.tables[ct.key.refTable].rows[d.keys[i].(uuid)]; !ok {
+							return nil, newError("referential integrity violation",
+								"table %s column %s refers to missing row %s", name, columnName, d.keys[i])
+						}
+					}
+					if ct.value != nil && ct.value.refTable != "" && !ct.value.refWeak {
+						if _, ok := txn.db.tables[ct.value.refTable].rows[d.values[i].(uuid)]; !ok {
+							return nil, newError("referential integrity violation",
+								"table %s column %s refers to missing row %s", name, columnName, d.values[i])
+						}
+					}
+				}
+			}
+		}
+		for _, index := range t.schema.indexes {
+			seen := make(map[string]uuid)
+			for _, r := range t.sortedRows() {
+				key := ""
+				for _, columnName := range index {
+					key += fmt.Sprintf("%v|", r.get(columnName).encode(t.schema.columns[columnName].typ))
+				}
+				if other, ok := seen[key]; ok {
+					return nil, newError("constraint violation", "rows %s and %s in table %s have the same %v",
+						other, r.uuid, name, index)
+				}
+				seen[key] = r.uuid
+			}
+		}
+	}
+
+	var changes []rowChange
+	for name, t := range txn.db.tables {
+		orig := txn.orig.tables[name]
+		for id, r := range t.rows {
+			old, existed := orig.rows[id]
+			if !existed {
+				changes = append(changes, rowChange{table: name, new: r})
+				continue
+			}
+			if old == r {
+				continue
+			}
+			modified := false
+			for columnName := range t.schema.columns {
+				if !old.columns[columnName].equals(r.columns[columnName]) {
+					modified = true
+					break
+				}
+			}
+			if modified {
+				r.version = newUUID()
+				changes = append(changes, rowChange{table: name, old: old, new: r})
+			} else {
+				t.rows[id] = old
+			}
+		}
+		for id, old := range orig.rows {
+			if _, ok := t.rows[id]; !ok {
+				changes = append(changes, rowChange{table: name, old: old})
+			}
+		}
+	}
+
+	return changes, nil
+}
@@ -0,0 +1,478 @@
+package ovsdbtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// uuid is the in-memory representation of an OVSDB uuid atom
+type uuid string
+
+type baseType struct {
+	atomic   string
+	refTable string
+	refWeak  bool
+}
+
+type columnType struct {
+	key   baseType
+	value *baseType
+	min   int
+	max   int // -1 stands for unlimited
+}
+
+func (ct columnType) isMap() bool {
+	return ct.value != nil
+}
+
+func (ct columnType) isScalar() bool {
+	return ct.value == nil && ct.min == 1 && ct.max == 1
+}
+
+type columnSchema struct {
+	name      string
+	typ       columnType
+	mutable   bool
+	ephemeral bool
+}
+
+type tableSchema struct {
+	name    string
+	columns map[string]*columnSchema
+	isRoot  bool
+	maxRows int
+	indexes [][]string
+}
+
+type databaseSchema struct {
+	name   string
+	tables map[string]*tableSchema
+	raw    interface{}
+}
+
+var uuidColumn = &columnSchema{name: "_uuid", typ: columnType{key: baseType{atomic: "uuid"}, min: 1, max: 1}}
+var versionColumn = &columnSchema{name: "_version", typ: columnType{key: baseType{atomic: "uuid"}, min: 1, max: 1}}
+
+func parseBaseType(v interface{}) (baseType, error) {
+	switch t := v.(type) {
+	case string:
+		return baseType{atomic: t}, nil
+	case map[string]interface{}:
+		bt := baseType{}
+		atomic, ok := t["type"].(string)
+		if !ok {
+			return bt, fmt.Errorf("base type without atomic type %v", t)
+		}
+		bt.atomic = atomic
+		if ref, ok := t["refTable"].(string); ok {
+			bt.refTable = ref
+			bt.refWeak = t["refType"] == "weak"
+		}
+		return bt, nil
+	}
+	return baseType{}, fmt.Errorf("invalid base type %v", v)
+}
+
+func parseColumnType(v interface{}) (columnType, error) {
+	ct := columnType{min: 1, max: 1}
+	switch t := v.(type) {
+	case string:
+		ct.key = baseType{atomic: t}
+		return ct, nil
+	case map[string]interface{}:
+		var err error
+		if ct.key, err = parseBaseType(t["key"]); err != nil {
+			return ct, err
+		}
+		if value, ok := t["value"]; ok {
+			vt, err := parseBaseType(value)
+			if err != nil {
+				return ct, err
+			}
+			ct.value = &vt
+		}
+		if min, ok := t["min"].(float64); ok {
+			ct.min = int(min)
+		}
+		switch max := t["max"].(type) {
+		case float64:
+			ct.max = int(max)
+		case string:
+			if max != "unlimited" {
+				return ct, fmt.Errorf("invalid max %s", max)
+			}
+			ct.max = -1
+		}
+		return ct, nil
+	}
+	return ct, fmt.Errorf("invalid column type %v", v)
+}
+
+func parseSchema(text string) (*databaseSchema, error) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal([]byte(text), &raw); err != nil {
+		return nil, err
+	}
+
+	schema := &databaseSchema{tables: make(map[string]*tableSchema), raw: raw}
+	schema.name, _ = raw["name"].(string)
+	tables, _ := raw["tables"].(map[string]interface{})
+	for tableName, t := range tables {
+		tableJSON, _ := t.(map[string]interface{})
+		table := &tableSchema{name: tableName, columns: make(map[string]*columnSchema)}
+		table.isRoot, _ = tableJSON["isRoot"].(bool)
+		if maxRows, ok := tableJSON["maxRows"].(float64); ok {
+			table.maxRows = int(maxRows)
+		}
+		if indexes, ok := tableJSON["indexes"].([]interface{}); ok {
+			for _, index := range indexes {
+				var columns []string
+				for _, column := range index.([]interface{}) {
+					columns = append(columns, column.(string))
+				}
+				table.indexes = append(table.indexes, columns)
+			}
+		}
+		columns, _ := tableJSON["columns"].(map[string]interface{})
+		for columnName, c := range columns {
+			columnJSON, _ := c.(map[string]interface{})
+			ct, err := parseColumnType(columnJSON["type"])
+			if err != nil {
+				return nil, fmt.Errorf("table %s column %s: %v", tableName, columnName, err)
+			}
+			column := &columnSchema{name: columnName, typ: ct, mutable: true}
+			if mutable, ok := columnJSON["mutable"].(bool); ok {
+				column.mutable = mutable
+			}
+			column.ephemeral, _ = columnJSON["ephemeral"].(bool)
+			table.columns[columnName] = column
+		}
+		schema.tables[tableName] = table
+	}
+
+	return schema, nil
+}
+
+func (ts *tableSchema) column(name string) (*columnSchema, error) {
+	switch name {
+	case "_uuid":
+		return uuidColumn, nil
+	case "_version":
+		return versionColumn, nil
+	}
+	column, ok := ts.columns[name]
+	if !ok {
+		return nil, newError("unknown column", "table %s has no column %s", ts.name, name)
+	}
+	return column, nil
+}
+
+// datum is the in-memory representation of a column value. Keys are kept sorted and unique,
+// values are only present for map columns.
+type datum struct {
+	keys   []interface{}
+	values []interface{}
+}
+
+func defaultAtom(bt baseType) interface{} {
+	switch bt.atomic {
+	case "integer":
+		return int64(0)
+	case "real":
+		return float64(0)
+	case "boolean":
+		return false
+	case "uuid":
+		return uuid("00000000-0000-0000-0000-000000000000")
+	}
+	return ""
+}
+
+func defaultDatum(ct columnType) datum {
+	if ct.min == 0 {
+		return datum{}
+	}
+	d := datum{keys: []interface{}{defaultAtom(ct.key)}}
+	if ct.isMap() {
+		d.values = []interface{}{defaultAtom(*ct.value)}
+	}
+	return d
+}
+
+func atomRank(a interface{}) int {
+	switch a.(type) {
+	case bool:
+		return 0
+	case int64:
+		return 1
+	case float64:
+		return 2
+	case string:
+		return 3
+	}
+	return 4
+}
+
+func compareAtoms(a, b interface{}) int {
+	if ra, rb := atomRank(a), atomRank(b); ra != rb {
+		return ra - rb
+	}
+	switch x := a.(type) {
+	case bool:
+		y := b.(bool)
+		if x == y {
+			return 0
+		} else if !x {
+			return -1
+		}
+		return 1
+	case int64:
+		y := b.(int64)
+		if x < y {
+			return -1
+		} else if x > y {
+			return 1
+		}
+		return 0
+	case float64:
+		y := b.(float64)
+		if x < y {
+			return -1
+		} else if x > y {
+			return 1
+		}
+		return 0
+	case string:
+		y := b.(string)
+		if x < y {
+			return -1
+		} else if x > y {
+			return 1
+		}
+		return 0
+	case uuid:
+		y := b.(uuid)
+		if x < y {
+			return -1
+		} else if x > y {
+			return 1
+		}
+		return 0
+	}
+	return 0
+}
+
+func (d datum) isMap() bool {
+	return d.values != nil
+}
+
+func (d datum) find(key interface{}) int {
+	i := sort.Search(len(d.keys), func(i int) bool { return compareAtoms(d.keys[i], key) >= 0 })
+	if i < len(d.keys) && compareAtoms(d.keys[i], key) == 0 {
+		return i
+	}
+	return -1
+}
+
+func (d datum) equals(o datum) bool {
+	if len(d.keys) != len(o.keys) {
+		return false
+	}
+	for i := range d.keys {
+		if compareAtoms(d.keys[i], o.keys[i]) != 0 {
+			return false
+		}
+		if d.values != nil && o.values != nil && compareAtoms(d.values[i], o.values[i]) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (d datum) clone() datum {
+	c := datum{keys: append([]interface{}(nil), d.keys...)}
+	if d.values != nil {
+		c.values = append([]interface{}{}, d.values...)
+	}
+	return c
+}
+
+// normalize sorts the datum and removes duplicate keys, keeping the first value seen for a key
+func (d *datum) normalize() {
+	type pair struct{ k, v interface{} }
+	pairs := make([]pair, len(d.keys))
+	for i := range d.keys {
+		pairs[i].k = d.keys[i]
+		if d.values != nil {
+			pairs[i].v = d.values[i]
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool { return compareAtoms(pairs[i].k, pairs[j].k) < 0 })
+	keys := d.keys[:0]
+	var values []interface{}
+	if d.values != nil {
+		values = d.values[:0]
+	}
+	for i, p := range pairs {
+		if i > 0 && compareAtoms(pairs[i-1].k, p.k) == 0 {
+			continue
+		}
+		keys = append(keys, p.k)
+		if d.values != nil {
+			values = append(values, p.v)
+		}
+	}
+	d.keys = keys
+	d.values = values
+}
+
+func (d datum) checkSize(ct columnType) error {
+	n := len(d.keys)
+	if n < ct.min || (ct.max >= 0 && n > ct.max) {
+		return newError("constraint violation", "%d values is outside the allowed range [%d, %d]", n, ct.min, ct.max)
+	}
+	return nil
+}
+
+// namedUUIDs resolves the uuid-name of rows inserted earlier in a transaction
+type namedUUIDs map[string]uuid
+
+func decodeAtom(bt baseType, v interface{}, named namedUUIDs) (interface{}, error) {
+	switch bt.atomic {
+	case "integer":
+		f, ok := v.(float64)
+		if !ok || f != math.Trunc(f) {
+			return nil, newError("syntax error", "expected integer, got %v", v)
+		}
+		return int64(f), nil
+	case "real":
+		f, ok := v.(float64)
+		if !ok {
+			return nil, newError("syntax error", "expected real, got %v", v)
+		}
+		return f, nil
+	case "boolean":
+		b, ok := v.(bool)
+		if !ok {
+			return nil, newError("syntax error", "expected boolean, got %v", v)
+		}
+		return b, nil
+	case "string":
+		s, ok := v.(string)
+		if !ok {
+			return nil, newError("syntax error", "expected string, got %v", v)
+		}
+		return s, nil
+	case "uuid":
+		a, ok := v.([]interface{})
+		if !ok || len(a) != 2 {
+			return nil, newError("syntax error", "expected uuid, got %v", v)
+		}
+		kind, _ := a[0].(string)
+		s, _ := a[1].(string)
+		switch kind {
+		case "uuid":
+			return uuid(s), nil
+		case "named-uuid":
+			if id, ok := named[s]; ok {
+				return id, nil
+			}
+			return nil, newError("syntax error", "unknown named-uuid %s", s)
+		}
+		return nil, newError("syntax error", "expected uuid, got %v", v)
+	}
+	return nil, newError("syntax error", "unknown atomic type %s", bt.atomic)
+}
+
+func isTagged(v interface{}, tag string) ([]interface{}, bool) {
+	a, ok := v.([]interface{})
+	if !ok || len(a) != 2 {
+		return nil, false
+	}
+	if s, ok := a[0].(string); !ok || s != tag {
+		return nil, false
+	}
+	if a[1] == nil {
+		return []interface{}{}, true
+	}
+	elements, ok := a[1].([]interface{})
+	return elements, ok
+}
+
+// decodeDatum converts the JSON representation of a value of the given type into a datum
+func decodeDatum(ct columnType, v interface{}, named namedUUIDs) (datum, error) {
+	var d datum
+	if ct.isMap() {
+		pairs, ok := isTagged(v, "map")
+		if !ok {
+			return d, newError("syntax error", "expected map, got %v", v)
+		}
+		d.values = []interface{}{}
+		for _, p := range pairs {
+			pair, ok := p.([]interface{})
+			if !ok || len(pair) != 2 {
+				return d, newError("syntax error", "invalid map pair %v", p)
+			}
+			key, err := decodeAtom(ct.key, pair[0], named)
+			if err != nil {
+				return d, err
+			}
+			value, err := decodeAtom(*ct.value, pair[1], named)
+			if err != nil {
+				return d, err
+			}
+			d.keys = append(d.keys, key)
+			d.values = append(d.values, value)
+		}
+	} else if elements, ok := isTagged(v, "set"); ok {
+		for _, e := range elements {
+			atom, err := decodeAtom(ct.key, e, named)
+			if err != nil {
+				return d, err
+			}
+			d.keys = append(d.keys, atom)
+		}
+	} else {
+		atom, err := decodeAtom(ct.key, v, named)
+		if err != nil {
+			return d, err
+		}
+		d.keys = []interface{}{atom}
+	}
+	d.normalize()
+	return d, nil
+}
+
+// decodeKeySet decodes a set of keys of a map column, as accepted by the map "delete" mutator
+func decodeKeySet(ct columnType, v interface{}, named namedUUIDs) (datum, error) {
+	keyType := columnType{key: ct.key, min: 0, max: -1}
+	return decodeDatum(keyType, v, named)
+}
+
+func encodeAtom(a interface{}) interface{} {
+	if id, ok := a.(uuid); ok {
+		return []interface{}{"uuid", string(id)}
+	}
+	return a
+}
+
+// encode converts the datum into its JSON representation
+func (d datum) encode(ct columnType) interface{} {
+	if ct.isMap() {
+		pairs := []interface{}{}
+		for i := range d.keys {
+			pairs = append(pairs, []interface{}{encodeAtom(d.keys[i]), encodeAtom(d.values[i])})
+		}
+		return []interface{}{"map", pairs}
+	}
+	if len(d.keys) == 1 {
+		return encodeAtom(d.keys[0])
+	}
+	elements := []interface{}{}
+	for _, k := range d.keys {
+		elements = append(elements, encodeAtom(k))
+	}
+	return []interface{}{"set", elements}
+}
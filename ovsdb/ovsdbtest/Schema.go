@@ -0,0 +1,92 @@
+package ovsdbtest
+
+// VRSSchema is the subset of the Nuage VRS Open_vSwitch schema served by the test server.
+// It covers the Nuage tables along with the switch tables touched when attaching ports to alubr0.
+const VRSSchema = `{
+  "name": "Open_vSwitch",
+  "version": "7.16.1",
+  "tables": {
+    "Open_vSwitch": {
+      "columns": {
+        "bridges": {"type": {"key": {"type": "uuid", "refTable": "Bridge"}, "min": 0, "max": "unlimited"}},
+        "ovs_version": {"type": {"key": "string", "min": 0, "max": 1}}
+      },
+      "isRoot": true,
+      "maxRows": 1
+    },
+    "Bridge": {
+      "columns": {
+        "name": {"type": "string", "mutable": false},
+        "ports": {"type": {"key": {"type": "uuid", "refTable": "Port"}, "min": 0, "max": "unlimited"}},
+        "controller": {"type": {"key": {"type": "uuid", "refTable": "Controller"}, "min": 0, "max": "unlimited"}},
+        "external_ids": {"type": {"key": "string", "value": "string", "min": 0, "max": "unlimited"}}
+      },
+      "isRoot": true,
+      "indexes": [["name"]]
+    },
+    "Port": {
+      "columns": {
+        "name": {"type": "string", "mutable": false},
+        "interfaces": {"type": {"key": {"type": "uuid", "refTable": "Interface"}, "min": 1, "max": "unlimited"}},
+        "external_ids": {"type": {"key": "string", "value": "string", "min": 0, "max": "unlimited"}}
+      },
+      "indexes": [["name"]]
+    },
+    "Interface": {
+      "columns": {
+        "name": {"type": "string", "mutable": false},
+        "type": {"type": "string"},
+        "ofport": {"type": {"key": "integer", "min": 0, "max": 1}},
+        "error": {"type": {"key": "string", "min": 0, "max": 1}},
+        "external_ids": {"type": {"key": "string", "value": "string", "min": 0, "max": "unlimited"}}
+      },
+      "indexes": [["name"]]
+    },
+    "Controller": {
+      "columns": {
+        "target": {"type": "string"},
+        "role": {"type": {"key": {"type": "string", "enum": ["set", ["other", "master", "slave"]]}, "min": 0, "max": 1}, "ephemeral": true},
+        "is_connected": {"type": "boolean", "ephemeral": true}
+      }
+    },
+    "Nuage_VM_Table": {
+      "columns": {
+        "vm_uuid": {"type": "string"},
+        "vm_name": {"type": "string"},
+        "type": {"type": "integer"},
+        "state": {"type": "integer"},
+        "reason": {"type": "integer"},
+        "event": {"type": "integer"},
+        "event_type": {"type": "integer"},
+        "domain": {"type": "integer"},
+        "nuage_user": {"type": "string"},
+        "nuage_enterprise": {"type": "string"},
+        "metadata": {"type": {"key": "string", "value": "string", "min": 0, "max": "unlimited"}},
+        "ports": {"type": {"key": "string", "min": 0, "max": "unlimited"}},
+        "dirty": {"type": "integer"}
+      },
+      "isRoot": true
+    },
+    "Nuage_Port_Table": {
+      "columns": {
+        "name": {"type": "string"},
+        "mac": {"type": "string"},
+        "ip_addr": {"type": "string"},
+        "subnet_mask": {"type": "string"},
+        "gateway": {"type": "string"},
+        "bridge": {"type": "string"},
+        "alias": {"type": "string"},
+        "nuage_domain": {"type": "string"},
+        "nuage_network": {"type": "string"},
+        "nuage_zone": {"type": "string"},
+        "nuage_network_type": {"type": "string"},
+        "evpn_id": {"type": "integer"},
+        "vrf_id": {"type": "integer"},
+        "vm_domain": {"type": "integer"},
+        "metadata": {"type": {"key": "string", "value": "string", "min": 0, "max": "unlimited"}},
+        "dirty": {"type": "integer"}
+      },
+      "isRoot": true
+    }
+  }
+}`
@@ -0,0 +1,584 @@
+package ovsdbtest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/cenk/rpc2"
+	"github.com/cenk/rpc2/jsonrpc"
+	"github.com/socketplane/libovsdb"
+)
+
+// Server is an in-memory OVSDB server
+type Server struct {
+	listener net.Listener
+	tempDir  string
+	schema   *databaseSchema
+	mutex    sync.Mutex
+	db       *database
+	sessions map[*session]bool
+	locks    map[string][]*session
+	wg       sync.WaitGroup
+
+	// paused is held for writing while the server is paused, the requests wait for it
+	paused sync.RWMutex
+}
+
+type monitorTable struct {
+	columns []string
+	initial bool
+	insert  bool
+	delete  bool
+	modify  bool
+}
+
+type monitor struct {
+	id     interface{}
+	tables map[string]*monitorTable
+}
+
+type session struct {
+	server   *Server
+	client   *rpc2.Client
+	conn     net.Conn
+	monitors map[string]*monitor
+}
+
+// NewServer starts a server serving the VRS schema on a Unix socket in a temporary directory
+func NewServer() (*Server, error) {
+	dir, err := ioutil.TempDir("", "ovsdbtest")
+	if err != nil {
+		return nil, err
+	}
+
+	server, err := NewServerWithSchema("unix", filepath.Join(dir, "db.sock"), VRSSchema)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	server.tempDir = dir
+
+	return server, nil
+}
+
+// NewTCPServer starts a server serving the VRS schema on a TCP port of the loopback interface
+func NewTCPServer() (*Server, error) {
+	return NewServerWithSchema("tcp", "127.0.0.1:0", VRSSchema)
+}
+
+// NewServerWithSchema starts a server serving the given schema on the given address
+func NewServerWithSchema(network string, address string, schemaText string) (*Server, error) {
+	schema, err := parseSchema(schemaText)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid schema %v", err)
+	}
+
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	server := &Server{
+		listener: listener,
+		schema:   schema,
+		db:       newDatabase(schema),
+		sessions: make(map[*session]bool),
+		locks:    make(map[string][]*session),
+	}
+
+	server.wg.Add(1)
+	go server.serve()
+
+	return server, nil
+}
+
+// SocketPath returns the path of the Unix socket the server listens on
+func (server *Server) SocketPath() string {
+	return server.listener.Addr().String()
+}
+
+// Host returns the address of the interface the TCP server listens on
+func (server *Server) Host() string {
+	host, _, _ := net.SplitHostPort(server.listener.Addr().String())
+	return host
+}
+
+// Port returns the port the TCP server listens on
+func (server *Server) Port() int {
+	if addr, ok := server.listener.Addr().(*net.TCPAddr); ok {
+		return addr.Port
+	}
+	return 0
+}
+
+// Close stops the server and drops all the client sessions
+func (server *Server) Close() {
+	server.listener.Close()
+	server.wg.Wait()
+
+	server.mutex.Lock()
+	var sessions []*session
+	for s := range server.sessions {
+		sessions = append(sessions, s)
+	}
+	server.mutex.Unlock()
+
+	for _, s := range sessions {
+		s.conn.Close()
+	}
+
+	if server.tempDir != "" {
+		os.RemoveAll(server.tempDir)
+	}
+}
+
+// DropSessions closes the connection of every client currently connected to the server
+func (server *Server) DropSessions() {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	for s := range server.sessions {
+		s.conn.Close()
+	}
+}
+
+// Pause makes the server stop answering the transactions and echo requests of the clients, as a hung server or
+// a half-open connection would, until Resume is called. The server must be resumed before it is closed.
+func (server *Server) Pause() {
+	server.paused.Lock()
+}
+
+// Resume answers the requests held since Pause
+func (server *Server) Resume() {
+	server.paused.Unlock()
+}
+
+// wait holds the request until the server is resumed
+func (server *Server) wait() {
+	server.paused.RLock()
+	server.paused.RUnlock()
+}
+
+func (server *Server) serve() {
+	defer server.wg.Done()
+	for {
+		conn, err := server.listener.Accept()
+		if err != nil {
+			return
+		}
+		server.newSession(conn)
+	}
+}
+
+// serialCodec serializes the writes of a codec. rpc2 replies to each request from a goroutine of its own while
+// the JSON codec encodes to the connection without a lock, concurrent replies and notifications would interleave.
+type serialCodec struct {
+	rpc2.Codec
+	mutex sync.Mutex
+}
+
+func (codec *serialCodec) WriteRequest(request *rpc2.Request, args interface{}) error {
+	codec.mutex.Lock()
+	defer codec.mutex.Unlock()
+	return codec.Codec.WriteRequest(request, args)
+}
+
+func (codec *serialCodec) WriteResponse(response *rpc2.Response, reply interface{}) error {
+	codec.mutex.Lock()
+	defer codec.mutex.Unlock()
+	return codec.Codec.WriteResponse(response, reply)
+}
+
+func (server *Server) newSession(conn net.Conn) {
+	s := &session{server: server, conn: conn, monitors: make(map[string]*monitor)}
+	s.client = rpc2.NewClientWithCodec(&serialCodec{Codec: jsonrpc.NewJSONCodec(conn)})
+	s.client.Handle("list_dbs", s.listDbs)
+	s.client.Handle("get_schema", s.getSchema)
+	s.client.Handle("transact", s.transact)
+	s.client.Handle("monitor", s.monitor)
+	s.client.Handle("monitor_cancel", s.monitorCancel)
+	s.client.Handle("lock", s.lock)
+	s.client.Handle("steal", s.steal)
+	s.client.Handle("unlock", s.unlock)
+	s.client.Handle("echo", s.echo)
+
+	server.mutex.Lock()
+	server.sessions[s] = true
+	server.mutex.Unlock()
+
+	go s.client.Run()
+	go func() {
+		<-s.client.DisconnectNotify()
+		server.dropSession(s)
+	}()
+}
+
+func (server *Server) dropSession(s *session) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	delete(server.sessions, s)
+	for name := range server.locks {
+		server.release(name, s)
+	}
+}
+
+func (server *Server) checkDatabase(args []interface{}) error {
+	if len(args) == 0 {
+		return errors.New("missing database name")
+	}
+	if name, _ := args[0].(string); name != server.schema.name {
+		return fmt.Errorf("unknown database %v", args[0])
+	}
+	return nil
+}
+
+func (s *session) listDbs(client *rpc2.Client, args []interface{}, reply *interface{}) error {
+	*reply = []interface{}{s.server.schema.name}
+	return nil
+}
+
+func (s *session) getSchema(client *rpc2.Client, args []interface{}, reply *interface{}) error {
+	if err := s.server.checkDatabase(args); err != nil {
+		return err
+	}
+	*reply = s.server.schema.raw
+	return nil
+}
+
+func (s *session) echo(client *rpc2.Client, args []interface{}, reply *interface{}) error {
+	s.server.wait()
+	if args == nil {
+		args = []interface{}{}
+	}
+	*reply = args
+	return nil
+}
+
+func (s *session) transact(client *rpc2.Client, args []interface{}, reply *interface{}) error {
+	s.server.wait()
+	if err := s.server.checkDatabase(args); err != nil {
+		return err
+	}
+	*reply = s.server.transact(s, args[1:])
+	return nil
+}
+
+// Transact executes the operations in a single transaction as if they were sent by a client and
+// returns the results the server would have replied with
+func (server *Server) Transact(operations ...libovsdb.Operation) ([]libovsdb.OperationResult, error) {
+	var ops []interface{}
+	if err := roundTrip(operations, &ops); err != nil {
+		return nil, err
+	}
+
+	var results []libovsdb.OperationResult
+	if err := roundTrip(server.transact(nil, ops), &results); err != nil {
+		return nil, err
+	}
+
+	for _, result := range results {
+		if result.Error != "" {
+			return results, fmt.Errorf("%s: %s", result.Error, result.Details)
+		}
+	}
+
+	return results, nil
+}
+
+// Rows returns all the rows of a table in the form returned by a select operation
+func (server *Server) Rows(table string) []map[string]interface{} {
+	results, err := server.Transact(libovsdb.Operation{Op: "select", Table: table, Where: []interface{}{}})
+	if err != nil || len(results) != 1 {
+		return nil
+	}
+	return results[0].Rows
+}
+
+func roundTrip(in interface{}, out interface{}) error {
+	b, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
+
+func (server *Server) transact(owner *session, ops []interface{}) []interface{} {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	txn := &transaction{
+		db:      server.db.clone(),
+		orig:    server.db,
+		named:   make(namedUUIDs),
+		touched: make(map[uuid]bool),
+		owner:   owner,
+	}
+
+	results := make([]interface{}, len(ops))
+	for i, o := range ops {
+		op, ok := o.(map[string]interface{})
+		if !ok {
+			results[i] = newError("syntax error", "invalid operation %v", o).result()
+			return results
+		}
+		result, err := txn.execute(op)
+		if err != nil {
+			if oe, ok := err.(*opError); ok {
+				results[i] = oe.result()
+			} else {
+				results[i] = newError("internal error", "%v", err).result()
+			}
+			return results
+		}
+		results[i] = result
+	}
+
+	changes, err := txn.commit()
+	if err != nil {
+		return append(results, err.(*opError).result())
+	}
+
+	server.db = txn.db
+	server.notify(changes)
+
+	return results
+}
+
+func (s *session) monitor(client *rpc2.Client, args []interface{}, reply *interface{}) error {
+	if err := s.server.checkDatabase(args); err != nil {
+		return err
+	}
+	if len(args) != 3 {
+		return errors.New("invalid monitor request")
+	}
+	requests, ok := args[2].(map[string]interface{})
+	if !ok {
+		return errors.New("invalid monitor request")
+	}
+
+	server := s.server
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	m := &monitor{id: args[1], tables: make(map[string]*monitorTable)}
+	for tableName, r := range requests {
+		t, ok := server.db.tables[tableName]
+		if !ok {
+			return fmt.Errorf("unknown table %s", tableName)
+		}
+		request, _ := r.(map[string]interface{})
+		mt := &monitorTable{initial: true, insert: true, delete: true, modify: true}
+		if columns, ok := request["columns"]; ok {
+			mt.columns = stringList(columns)
+		} else {
+			mt.columns = t.schema.allColumns(false)
+		}
+		if sel, ok := request["select"].(map[string]interface{}); ok {
+			for key, flag := range map[string]*bool{"initial": &mt.initial, "insert": &mt.insert,
+				"delete": &mt.delete, "modify": &mt.modify} {
+				if v, ok := sel[key].(bool); ok {
+					*flag = v
+				}
+			}
+		}
+		m.tables[tableName] = mt
+	}
+
+	key := fmt.Sprintf("%v", m.id)
+	if _, exists := s.monitors[key]; exists {
+		return errors.New("duplicate monitor ID")
+	}
+	s.monitors[key] = m
+
+	updates := make(map[string]interface{})
+	for tableName, mt := range m.tables {
+		if !mt.initial {
+			continue
+		}
+		t := server.db.tables[tableName]
+		rows := make(map[string]interface{})
+		for _, r := range t.sortedRows() {
+			rows[string(r.uuid)] = map[string]interface{}{"new": r.encode(t.schema, mt.columns)}
+		}
+		if len(rows) > 0 {
+			updates[tableName] = rows
+		}
+	}
+	*reply = updates
+
+	return nil
+}
+
+func (s *session) monitorCancel(client *rpc2.Client, args []interface{}, reply *interface{}) error {
+	if len(args) != 1 {
+		return errors.New("invalid monitor_cancel request")
+	}
+
+	s.server.mutex.Lock()
+	defer s.server.mutex.Unlock()
+
+	key := fmt.Sprintf("%v", args[0])
+	if _, ok := s.monitors[key]; !ok {
+		return errors.New("unknown monitor")
+	}
+	delete(s.monitors, key)
+	*reply = map[string]interface{}{}
+
+	return nil
+}
+
+// notify sends the update notifications for the committed changes, it is called with the server mutex held
+func (server *Server) notify(changes []rowChange) {
+	for s := range server.sessions {
+		for _, m := range s.monitors {
+			updates := make(map[string]interface{})
+			for _, change := range changes {
+				mt, ok := m.tables[change.table]
+				if !ok {
+					continue
+				}
+				ts := server.db.schema.tables[change.table]
+				var update map[string]interface{}
+				switch {
+				case change.old == nil && mt.insert:
+					update = map[string]interface{}{"new": change.new.encode(ts, mt.columns)}
+				case change.new == nil && mt.delete:
+					update = map[string]interface{}{"old": change.old.encode(ts, mt.columns)}
+				case change.old != nil && change.new != nil && mt.modify:
+					var changed []string
+					for _, name := range mt.columns {
+						if !change.old.get(name).equals(change.new.get(name)) {
+							changed = append(changed, name)
+						}
+					}
+					if len(changed) > 0 {
+						update = map[string]interface{}{
+							"old": change.old.encode(ts, changed),
+							"new": change.new.encode(ts, mt.columns),
+						}
+					}
+				}
+				if update == nil {
+					continue
+				}
+				rows, ok := updates[change.table].(map[string]interface{})
+				if !ok {
+					rows = make(map[string]interface{})
+					updates[change.table] = rows
+				}
+				id := change.old
+				if id == nil {
+					id = change.new
+				}
+				rows[string(id.uuid)] = update
+			}
+			if len(updates) > 0 {
+				s.client.Notify("update", []interface{}{m.id, updates})
+			}
+		}
+	}
+}
+
+func (s *session) owns(name string) bool {
+	owners := s.server.locks[name]
+	return len(owners) > 0 && owners[0] == s
+}
+
+func lockName(args []interface{}) (string, error) {
+	if len(args) != 1 {
+		return "", errors.New("invalid lock request")
+	}
+	name, ok := args[0].(string)
+	if !ok || name == "" {
+		return "", errors.New("invalid lock name")
+	}
+	return name, nil
+}
+
+func (s *session) lock(client *rpc2.Client, args []interface{}, reply *interface{}) error {
+	name, err := lockName(args)
+	if err != nil {
+		return err
+	}
+
+	s.server.mutex.Lock()
+	defer s.server.mutex.Unlock()
+
+	for _, waiter := range s.server.locks[name] {
+		if waiter == s {
+			return errors.New("lock already requested")
+		}
+	}
+	s.server.locks[name] = append(s.server.locks[name], s)
+	*reply = map[string]interface{}{"locked": s.owns(name)}
+
+	return nil
+}
+
+func (s *session) steal(client *rpc2.Client, args []interface{}, reply *interface{}) error {
+	name, err := lockName(args)
+	if err != nil {
+		return err
+	}
+
+	s.server.mutex.Lock()
+	defer s.server.mutex.Unlock()
+
+	owners := s.server.locks[name]
+	if len(owners) > 0 && owners[0] != s {
+		owners[0].client.Notify("stolen", []interface{}{name})
+	}
+	var others []*session
+	for _, waiter := range owners {
+		if waiter != s {
+			others = append(others, waiter)
+		}
+	}
+	s.server.locks[name] = append([]*session{s}, others...)
+	*reply = map[string]interface{}{"locked": true}
+
+	return nil
+}
+
+func (s *session) unlock(client *rpc2.Client, args []interface{}, reply *interface{}) error {
+	name, err := lockName(args)
+	if err != nil {
+		return err
+	}
+
+	s.server.mutex.Lock()
+	defer s.server.mutex.Unlock()
+
+	s.server.release(name, s)
+	*reply = map[string]interface{}{}
+
+	return nil
+}
+
+// release removes the session from the lock queue and hands the lock over to the next waiter,
+// it is called with the server mutex held
+func (server *Server) release(name string, s *session) {
+	owners := server.locks[name]
+	wasOwner := len(owners) > 0 && owners[0] == s
+	var remaining []*session
+	for _, waiter := range owners {
+		if waiter != s {
+			remaining = append(remaining, waiter)
+		}
+	}
+	if len(remaining) == 0 {
+		delete(server.locks, name)
+		return
+	}
+	server.locks[name] = remaining
+	if wasOwner {
+		remaining[0].client.Notify("locked", []interface{}{name})
+	}
+}
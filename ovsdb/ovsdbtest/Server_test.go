@@ -0,0 +1,146 @@
+package ovsdbtest
+
+import (
+	"testing"
+
+	"github.com/socketplane/libovsdb"
+)
+
+func connect(t *testing.T) (*Server, *libovsdb.OvsdbClient) {
+	server, err := NewServer()
+	if err != nil {
+		t.Fatalf("Unable to start the OVSDB server %v", err)
+	}
+
+	ovs, err := libovsdb.ConnectWithUnixSocket(server.SocketPath())
+	if err != nil {
+		server.Close()
+		t.Fatalf("Unable to connect to the OVSDB server %v", err)
+	}
+
+	return server, ovs
+}
+
+func TestTransact(t *testing.T) {
+	server, ovs := connect(t)
+	defer server.Close()
+	defer ovs.Disconnect()
+
+	metadata, _ := libovsdb.NewOvsMap(map[string]string{"m1": "v1"})
+	ports, _ := libovsdb.NewOvsSet([]string{"port1"})
+	insertOp := libovsdb.Operation{
+		Op:    "insert",
+		Table: "Nuage_VM_Table",
+		Row: map[string]interface{}{
+			"vm_uuid":  "uuid1",
+			"vm_name":  "vm1",
+			"metadata": metadata,
+			"ports":    ports,
+		},
+	}
+	reply, err := ovs.Transact("Open_vSwitch", insertOp)
+	if err != nil || len(reply) != 1 || reply[0].Error != "" {
+		t.Fatalf("Insert failed %v %+v", err, reply)
+	}
+
+	newPorts, _ := libovsdb.NewOvsSet([]string{"port2"})
+	mutateOp := libovsdb.Operation{
+		Op:        "mutate",
+		Table:     "Nuage_VM_Table",
+		Mutations: []interface{}{libovsdb.NewMutation("ports", "insert", newPorts)},
+		Where:     []interface{}{libovsdb.NewCondition("vm_uuid", "==", "uuid1")},
+	}
+	reply, err = ovs.Transact("Open_vSwitch", mutateOp)
+	if err != nil || len(reply) != 1 || reply[0].Count != 1 {
+		t.Fatalf("Mutate failed %v %+v", err, reply)
+	}
+
+	selectOp := libovsdb.Operation{
+		Op:      "select",
+		Table:   "Nuage_VM_Table",
+		Columns: []string{"ports"},
+		Where:   []interface{}{libovsdb.NewCondition("vm_uuid", "==", "uuid1")},
+	}
+	reply, err = ovs.Transact("Open_vSwitch", selectOp)
+	if err != nil || len(reply) != 1 || len(reply[0].Rows) != 1 {
+		t.Fatalf("Select failed %v %+v", err, reply)
+	}
+
+	var set libovsdb.OvsSet
+	if err := roundTrip(reply[0].Rows[0]["ports"], &set); err != nil || len(set.GoSet) != 2 {
+		t.Fatalf("Unexpected ports %+v", reply[0].Rows[0])
+	}
+}
+
+func TestTransactRollback(t *testing.T) {
+	server, ovs := connect(t)
+	defer server.Close()
+	defer ovs.Disconnect()
+
+	insertOp := libovsdb.Operation{
+		Op:    "insert",
+		Table: "Nuage_Port_Table",
+		Row:   map[string]interface{}{"name": "port1"},
+	}
+	waitOp := libovsdb.Operation{
+		Op:      "wait",
+		Table:   "Nuage_Port_Table",
+		Timeout: 1,
+		Where:   []interface{}{libovsdb.NewCondition("name", "==", "port2")},
+		Columns: []string{"name"},
+		Until:   "!=",
+		Rows:    []map[string]interface{}{},
+	}
+	reply, err := ovs.Transact("Open_vSwitch", insertOp, waitOp)
+	if err != nil || len(reply) != 2 || reply[1].Error != "timed out" {
+		t.Fatalf("Expected the wait to fail %v %+v", err, reply)
+	}
+
+	if rows := server.Rows("Nuage_Port_Table"); len(rows) != 0 {
+		t.Fatalf("Transaction was not rolled back %+v", rows)
+	}
+}
+
+func TestGarbageCollection(t *testing.T) {
+	server, ovs := connect(t)
+	defer server.Close()
+	defer ovs.Disconnect()
+
+	intfOp := libovsdb.Operation{
+		Op:       "insert",
+		Table:    "Interface",
+		Row:      map[string]interface{}{"name": "veth1"},
+		UUIDName: "intf",
+	}
+	portOp := libovsdb.Operation{
+		Op:       "insert",
+		Table:    "Port",
+		Row:      map[string]interface{}{"name": "veth1", "interfaces": libovsdb.UUID{GoUUID: "intf"}},
+		UUIDName: "port",
+	}
+	bridgeOp := libovsdb.Operation{
+		Op:    "insert",
+		Table: "Bridge",
+		Row:   map[string]interface{}{"name": "alubr0", "ports": libovsdb.UUID{GoUUID: "port"}},
+	}
+	if _, err := ovs.Transact("Open_vSwitch", intfOp, portOp, bridgeOp); err != nil {
+		t.Fatalf("Unable to create the bridge %v", err)
+	}
+	if len(server.Rows("Interface")) != 1 || len(server.Rows("Port")) != 1 {
+		t.Fatalf("Missing Port or Interface rows")
+	}
+
+	emptySet, _ := libovsdb.NewOvsSet([]libovsdb.UUID{})
+	updateOp := libovsdb.Operation{
+		Op:    "update",
+		Table: "Bridge",
+		Row:   map[string]interface{}{"ports": emptySet},
+		Where: []interface{}{libovsdb.NewCondition("name", "==", "alubr0")},
+	}
+	if _, err := server.Transact(updateOp); err != nil {
+		t.Fatalf("Unable to detach the port %v", err)
+	}
+	if len(server.Rows("Interface")) != 0 || len(server.Rows("Port")) != 0 {
+		t.Fatalf("Unreferenced Port and Interface rows were not collected")
+	}
+}
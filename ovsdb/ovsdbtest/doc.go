@@ -0,0 +1,6 @@
+/*
+Package ovsdbtest provides an in-memory OVSDB server implementing the subset of RFC 7047 used by the SDK.
+It serves the Nuage flavour of the Open_vSwitch database so that code built on top of a VRSConnection can be
+tested without a running VRS.
+*/
+package ovsdbtest
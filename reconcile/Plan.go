@@ -0,0 +1,241 @@
+package reconcile
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/nuagenetworks/libvrsdk/api"
+	"github.com/nuagenetworks/libvrsdk/api/entity"
+	"github.com/nuagenetworks/libvrsdk/api/port"
+)
+
+// State is a set of entities and ports, either desired by the user or found on the VRS
+type State struct {
+	Entities []api.EntityInfo
+	Ports    []api.PortSpec
+}
+
+// Action identifies the SDK operation performed by a step of a plan
+type Action string
+
+// Actions a plan is made of, listed in the order they are applied
+const (
+	CreatePort           Action = "create-port"
+	UpdatePortAttributes Action = "update-port-attributes"
+	UpdatePortMetadata   Action = "update-port-metadata"
+	CreateEntity         Action = "create-entity"
+	ReplaceEntity        Action = "replace-entity"
+	AddEntityPort        Action = "add-entity-port"
+	RemoveEntityPort     Action = "remove-entity-port"
+	SetEntityMetadata    Action = "set-entity-metadata"
+	PostEntityEvent      Action = "post-entity-event"
+	DestroyEntity        Action = "destroy-entity"
+	DestroyPort          Action = "destroy-port"
+)
+
+var actionOrder = map[Action]int{
+	CreatePort:           0,
+	UpdatePortAttributes: 1,
+	UpdatePortMetadata:   2,
+	CreateEntity:         3,
+	ReplaceEntity:        4,
+	AddEntityPort:        5,
+	RemoveEntityPort:     6,
+	SetEntityMetadata:    7,
+	PostEntityEvent:      8,
+	DestroyEntity:        9,
+	DestroyPort:          10,
+}
+
+// Step is a single operation of a plan. Target is the name of the port or the UUID of the entity the
+// step applies to, Port and Entity hold the desired state the step converges to.
+type Step struct {
+	Action   Action
+	Target   string
+	PortName string
+	Port     *api.PortSpec
+	Entity   *api.EntityInfo
+}
+
+// String describes the step in a human readable way
+func (step Step) String() string {
+	switch step.Action {
+	case CreatePort, UpdatePortAttributes:
+		return fmt.Sprintf("%s %s mac=%s bridge=%s platform=%d", step.Action, step.Target,
+			step.Port.Attributes.MAC, step.Port.Attributes.Bridge, step.Port.Attributes.Platform)
+	case UpdatePortMetadata:
+		return fmt.Sprintf("%s %s %s", step.Action, step.Target, formatMetadata(step.Port.Metadata))
+	case CreateEntity, ReplaceEntity:
+		return fmt.Sprintf("%s %s name=%s type=%d domain=%d ports=%v", step.Action, step.Target,
+			step.Entity.Name, step.Entity.Type, step.Entity.Domain, step.Entity.Ports)
+	case AddEntityPort, RemoveEntityPort:
+		return fmt.Sprintf("%s %s %s", step.Action, step.Target, step.PortName)
+	case SetEntityMetadata:
+		return fmt.Sprintf("%s %s %s", step.Action, step.Target, formatMetadata(step.Entity.AllMetadata()))
+	case PostEntityEvent:
+		events := step.Entity.Events
+		return fmt.Sprintf("%s %s category=%d event=%d state=%d reason=%d", step.Action, step.Target,
+			events.EntityEventCategory, events.EntityEventType, events.EntityState, events.EntityReason)
+	}
+	return fmt.Sprintf("%s %s", step.Action, step.Target)
+}
+
+func formatMetadata(metadata interface{}) string {
+	v := reflect.ValueOf(metadata)
+	var pairs []string
+	for _, key := range v.MapKeys() {
+		pairs = append(pairs, fmt.Sprintf("%v=%v", key.Interface(), v.MapIndex(key).Interface()))
+	}
+	sort.Strings(pairs)
+	return fmt.Sprintf("%v", pairs)
+}
+
+// Plan is the ordered list of steps that converges the VRS to the desired state
+type Plan struct {
+	Steps []Step
+}
+
+// Empty returns true if the VRS is already in the desired state
+func (plan Plan) Empty() bool {
+	return len(plan.Steps) == 0
+}
+
+// String prints one step per line, it is the output of a dry run
+func (plan Plan) String() string {
+	var buffer bytes.Buffer
+	for i, step := range plan.Steps {
+		fmt.Fprintf(&buffer, "%3d. %s\n", i+1, step)
+	}
+	return buffer.String()
+}
+
+// Compute returns the plan converging the current state to the desired state. Entities and ports that are
+// present on the VRS but not desired are destroyed only if the corresponding ownership filter accepts them,
+// a nil filter claims ownership of everything.
+func Compute(current State, desired State, ownsEntity func(api.EntityInfo) bool, ownsPort func(api.PortSpec) bool) Plan {
+	var steps []Step
+
+	currentPorts := make(map[string]api.PortSpec)
+	for _, spec := range current.Ports {
+		currentPorts[spec.Name] = spec
+	}
+	desiredPorts := make(map[string]bool)
+	for i := range desired.Ports {
+		spec := &desired.Ports[i]
+		desiredPorts[spec.Name] = true
+		existing, ok := currentPorts[spec.Name]
+		if !ok {
+			steps = append(steps, Step{Action: CreatePort, Target: spec.Name, Port: spec})
+			continue
+		}
+		if existing.Attributes != spec.Attributes {
+			steps = append(steps, Step{Action: UpdatePortAttributes, Target: spec.Name, Port: spec})
+		}
+		if !samePortMetadata(existing.Metadata, spec.Metadata) {
+			steps = append(steps, Step{Action: UpdatePortMetadata, Target: spec.Name, Port: spec})
+		}
+	}
+	for _, spec := range current.Ports {
+		if !desiredPorts[spec.Name] && (ownsPort == nil || ownsPort(spec)) {
+			steps = append(steps, Step{Action: DestroyPort, Target: spec.Name})
+		}
+	}
+
+	currentEntities := make(map[string]api.EntityInfo)
+	for _, info := range current.Entities {
+		currentEntities[info.UUID] = info
+	}
+	desiredEntities := make(map[string]bool)
+	for i := range desired.Entities {
+		info := &desired.Entities[i]
+		desiredEntities[info.UUID] = true
+		existing, ok := currentEntities[info.UUID]
+		if !ok {
+			steps = append(steps, Step{Action: CreateEntity, Target: info.UUID, Entity: info})
+			continue
+		}
+		if needsReplace(existing, *info) {
+			steps = append(steps, Step{Action: ReplaceEntity, Target: info.UUID, Entity: info})
+			continue
+		}
+		steps = append(steps, entityPortSteps(existing, info)...)
+		if !sameEntityMetadata(existing.AllMetadata(), info.AllMetadata()) {
+			steps = append(steps, Step{Action: SetEntityMetadata, Target: info.UUID, Entity: info})
+		}
+		if info.Events != nil && (existing.Events == nil || *existing.Events != *info.Events) {
+			steps = append(steps, Step{Action: PostEntityEvent, Target: info.UUID, Entity: info})
+		}
+	}
+	for _, info := range current.Entities {
+		if !desiredEntities[info.UUID] && (ownsEntity == nil || ownsEntity(info)) {
+			steps = append(steps, Step{Action: DestroyEntity, Target: info.UUID})
+		}
+	}
+
+	sort.SliceStable(steps, func(i, j int) bool {
+		if steps[i].Action != steps[j].Action {
+			return actionOrder[steps[i].Action] < actionOrder[steps[j].Action]
+		}
+		if steps[i].Target != steps[j].Target {
+			return steps[i].Target < steps[j].Target
+		}
+		return steps[i].PortName < steps[j].PortName
+	})
+
+	return Plan{Steps: steps}
+}
+
+// needsReplace returns true if the entities differ in a column that can only be set when creating the entity
+func needsReplace(existing api.EntityInfo, desired api.EntityInfo) bool {
+	return existing.Name != desired.Name ||
+		existing.Type != desired.Type ||
+		existing.Domain != desired.Domain ||
+		existing.Metadata[entity.MetadataKeyUser] != desired.Metadata[entity.MetadataKeyUser] ||
+		existing.Metadata[entity.MetadataKeyEnterprise] != desired.Metadata[entity.MetadataKeyEnterprise]
+}
+
+func entityPortSteps(existing api.EntityInfo, desired *api.EntityInfo) []Step {
+	var steps []Step
+
+	existingPorts := make(map[string]bool)
+	for _, name := range existing.Ports {
+		existingPorts[name] = true
+	}
+	desiredPorts := make(map[string]bool)
+	for _, name := range desired.Ports {
+		desiredPorts[name] = true
+		if !existingPorts[name] {
+			steps = append(steps, Step{Action: AddEntityPort, Target: desired.UUID, PortName: name, Entity: desired})
+		}
+	}
+	for _, name := range existing.Ports {
+		if !desiredPorts[name] {
+			steps = append(steps, Step{Action: RemoveEntityPort, Target: desired.UUID, PortName: name, Entity: desired})
+		}
+	}
+
+	return steps
+}
+
+func samePortMetadata(a map[port.MetadataKey]string, b map[port.MetadataKey]string) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// sameEntityMetadata compares the metadata stored in the metadata column, the user is kept in a column of its own
+func sameEntityMetadata(a map[entity.MetadataKey]string, b map[entity.MetadataKey]string) bool {
+	strip := func(m map[entity.MetadataKey]string) map[entity.MetadataKey]string {
+		stripped := make(map[entity.MetadataKey]string)
+		for k, v := range m {
+			if k != entity.MetadataKeyUser {
+				stripped[k] = v
+			}
+		}
+		return stripped
+	}
+	return reflect.DeepEqual(strip(a), strip(b))
+}
@@ -0,0 +1,127 @@
+package reconcile
+
+import (
+	"fmt"
+
+	"github.com/nuagenetworks/libvrsdk/api"
+	"github.com/nuagenetworks/libvrsdk/api/entity"
+)
+
+// Reconciler converges a VRS towards a desired state
+type Reconciler struct {
+	Connection *api.VRSConnection
+
+	// OwnsEntity and OwnsPort restrict the entities and ports that may be destroyed because they are absent
+	// from the desired state. A nil filter claims ownership of every row of the table.
+	OwnsEntity func(api.EntityInfo) bool
+	OwnsPort   func(api.PortSpec) bool
+
+	// DryRun makes Reconcile compute the plan without applying it
+	DryRun bool
+}
+
+// NewReconciler creates a reconciler for the given VRS connection
+func NewReconciler(vrsConnection *api.VRSConnection) *Reconciler {
+	return &Reconciler{Connection: vrsConnection}
+}
+
+// CurrentState reads the entities and ports present on the VRS
+func (reconciler *Reconciler) CurrentState() (State, error) {
+	var state State
+	var err error
+
+	if state.Ports, err = reconciler.Connection.GetAllPortSpecs(); err != nil {
+		return state, fmt.Errorf("Unable to read the ports %v", err)
+	}
+
+	if state.Entities, err = reconciler.Connection.GetAllEntityInfo(); err != nil {
+		return state, fmt.Errorf("Unable to read the entities %v", err)
+	}
+
+	return state, nil
+}
+
+// Plan computes the steps needed to converge the VRS to the desired state
+func (reconciler *Reconciler) Plan(desired State) (Plan, error) {
+	current, err := reconciler.CurrentState()
+	if err != nil {
+		return Plan{}, err
+	}
+
+	return Compute(current, desired, reconciler.OwnsEntity, reconciler.OwnsPort), nil
+}
+
+// Reconcile computes the plan converging the VRS to the desired state and applies it unless DryRun is set.
+// The plan is returned in both cases.
+func (reconciler *Reconciler) Reconcile(desired State) (Plan, error) {
+	plan, err := reconciler.Plan(desired)
+	if err != nil {
+		return plan, err
+	}
+
+	if reconciler.DryRun {
+		return plan, nil
+	}
+
+	return plan, reconciler.Apply(plan)
+}
+
+// Apply executes the steps of the plan in order, stopping at the first failure
+func (reconciler *Reconciler) Apply(plan Plan) error {
+	for i, step := range plan.Steps {
+		if err := reconciler.applyStep(step); err != nil {
+			return fmt.Errorf("Step %d (%s) failed %v", i+1, step, err)
+		}
+	}
+
+	return nil
+}
+
+func (reconciler *Reconciler) applyStep(step Step) error {
+	vrsConnection := reconciler.Connection
+
+	switch step.Action {
+	case CreatePort:
+		return vrsConnection.CreatePort(step.Port.Name, step.Port.Attributes, step.Port.Metadata)
+	case UpdatePortAttributes:
+		return vrsConnection.UpdatePortAttributes(step.Port.Name, step.Port.Attributes)
+	case UpdatePortMetadata:
+		metadata := make(map[string]string)
+		for k, v := range step.Port.Metadata {
+			metadata[string(k)] = v
+		}
+		return vrsConnection.UpdatePortMetadata(step.Port.Name, metadata)
+	case DestroyPort:
+		return vrsConnection.DestroyPort(step.Target)
+	case CreateEntity:
+		return vrsConnection.CreateEntity(*step.Entity)
+	case ReplaceEntity:
+		if err := vrsConnection.DestroyEntity(step.Target); err != nil {
+			return err
+		}
+		return vrsConnection.CreateEntity(*step.Entity)
+	case AddEntityPort:
+		return vrsConnection.AddEntityPort(step.Target, step.PortName)
+	case RemoveEntityPort:
+		return vrsConnection.RemoveEntityPort(step.Target, step.PortName)
+	case SetEntityMetadata:
+		// The user lives in a column of its own and is not part of the metadata column
+		metadata := make(map[entity.MetadataKey]string)
+		for k, v := range step.Entity.AllMetadata() {
+			if k != entity.MetadataKeyUser {
+				metadata[k] = v
+			}
+		}
+		return vrsConnection.SetEntityMetadata(step.Target, metadata)
+	case PostEntityEvent:
+		events := step.Entity.Events
+		if err := vrsConnection.PostEntityEvent(step.Target, events.EntityEventCategory, events.EntityEventType); err != nil {
+			return err
+		}
+		return vrsConnection.SetEntityState(step.Target, events.EntityState, events.EntityReason)
+	case DestroyEntity:
+		return vrsConnection.DestroyEntity(step.Target)
+	}
+
+	return fmt.Errorf("Unknown action %s", step.Action)
+}
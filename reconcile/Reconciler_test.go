@@ -0,0 +1,188 @@
+package reconcile
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nuagenetworks/libvrsdk/api"
+	"github.com/nuagenetworks/libvrsdk/api/entity"
+	"github.com/nuagenetworks/libvrsdk/api/port"
+	"github.com/nuagenetworks/libvrsdk/ovsdb/ovsdbtest"
+)
+
+func testPort(name string) api.PortSpec {
+	return api.PortSpec{
+		Name: name,
+		Attributes: port.Attributes{
+			MAC:      "76:22:F6:70:4E:47",
+			Platform: entity.Docker,
+			Bridge:   "alubr0",
+		},
+		Metadata: map[port.MetadataKey]string{
+			port.MetadataKeyDomain:  "domain",
+			port.MetadataKeyNetwork: "network",
+			port.MetadataKeyZone:    "zone",
+		},
+	}
+}
+
+func testEntity(uuid string, ports ...string) api.EntityInfo {
+	return api.EntityInfo{
+		UUID:   uuid,
+		Name:   "entity-" + uuid,
+		Type:   entity.Container,
+		Domain: entity.Docker,
+		Ports:  ports,
+		Metadata: map[entity.MetadataKey]string{
+			entity.MetadataKeyUser:       "user",
+			entity.MetadataKeyEnterprise: "enterprise",
+		},
+		Events: &entity.EntityEvents{
+			EntityEventCategory: entity.EventCategoryStarted,
+			EntityEventType:     entity.EventStartedBooted,
+			EntityState:         entity.Running,
+			EntityReason:        entity.RunningBooted,
+		},
+	}
+}
+
+func actions(plan Plan) []string {
+	var result []string
+	for _, step := range plan.Steps {
+		result = append(result, string(step.Action)+" "+step.Target+" "+step.PortName)
+	}
+	return result
+}
+
+func TestComputeOrder(t *testing.T) {
+	current := State{
+		Ports:    []api.PortSpec{testPort("old")},
+		Entities: []api.EntityInfo{testEntity("gone", "old")},
+	}
+	desired := State{
+		Ports:    []api.PortSpec{testPort("new")},
+		Entities: []api.EntityInfo{testEntity("vm", "new")},
+	}
+
+	plan := Compute(current, desired, nil, nil)
+	expected := []string{
+		"create-port new ",
+		"create-entity vm ",
+		"destroy-entity gone ",
+		"destroy-port old ",
+	}
+	if strings.Join(actions(plan), ",") != strings.Join(expected, ",") {
+		t.Fatalf("Unexpected plan\n%s", plan)
+	}
+}
+
+func TestComputeUpdates(t *testing.T) {
+	current := State{
+		Ports:    []api.PortSpec{testPort("p1"), testPort("p2")},
+		Entities: []api.EntityInfo{testEntity("vm", "p1")},
+	}
+
+	desired := State{
+		Ports:    []api.PortSpec{testPort("p1"), testPort("p2")},
+		Entities: []api.EntityInfo{testEntity("vm", "p2")},
+	}
+	desired.Ports[0].Attributes.MAC = "76:22:F6:70:4E:48"
+	desired.Ports[1].Metadata[port.MetadataNuagePolicyGroup] = "pg1"
+	desired.Entities[0].Metadata[entity.MetadataKeySiteID] = "site"
+	desired.Entities[0].Events = &entity.EntityEvents{
+		EntityEventCategory: entity.EventCategoryStopped,
+		EntityEventType:     entity.EventStoppedShutdown,
+		EntityState:         entity.Shutoff,
+		EntityReason:        entity.ShutoffShutdown,
+	}
+
+	plan := Compute(current, desired, nil, nil)
+	expected := []string{
+		"update-port-attributes p1 ",
+		"update-port-metadata p2 ",
+		"add-entity-port vm p2",
+		"remove-entity-port vm p1",
+		"set-entity-metadata vm ",
+		"post-entity-event vm ",
+	}
+	if strings.Join(actions(plan), ",") != strings.Join(expected, ",") {
+		t.Fatalf("Unexpected plan\n%s", plan)
+	}
+
+	desired.Entities[0].Name = "renamed"
+	plan = Compute(current, desired, nil, nil)
+	for _, step := range plan.Steps {
+		if step.Target == "vm" && step.Action != ReplaceEntity {
+			t.Fatalf("Expected the renamed entity to be replaced\n%s", plan)
+		}
+	}
+}
+
+func TestComputeOwnership(t *testing.T) {
+	current := State{
+		Ports: []api.PortSpec{testPort("mine"), testPort("theirs")},
+	}
+
+	plan := Compute(current, State{}, nil, func(spec api.PortSpec) bool { return spec.Name == "mine" })
+	if strings.Join(actions(plan), ",") != "destroy-port mine " {
+		t.Fatalf("Unexpected plan\n%s", plan)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	server, err := ovsdbtest.NewServer()
+	if err != nil {
+		t.Fatalf("Unable to start the OVSDB server %v", err)
+	}
+	defer server.Close()
+
+	vrsConnection, err := api.NewUnixSocketConnection(server.SocketPath())
+	if err != nil {
+		t.Fatalf("Unable to connect to the VRS %v", err)
+	}
+	defer vrsConnection.Disconnect()
+
+	reconciler := NewReconciler(&vrsConnection)
+	desired := State{
+		Ports:    []api.PortSpec{testPort("p1"), testPort("p2")},
+		Entities: []api.EntityInfo{testEntity("vm", "p1", "p2")},
+	}
+
+	reconciler.DryRun = true
+	plan, err := reconciler.Reconcile(desired)
+	if err != nil || len(plan.Steps) != 3 {
+		t.Fatalf("Unexpected dry run plan %v\n%s", err, plan)
+	}
+	if ports, _ := vrsConnection.GetAllPorts(); len(ports) != 0 {
+		t.Fatalf("Dry run modified the VRS %v", ports)
+	}
+
+	reconciler.DryRun = false
+	if _, err = reconciler.Reconcile(desired); err != nil {
+		t.Fatalf("Unable to reconcile %v", err)
+	}
+
+	plan, err = reconciler.Plan(desired)
+	if err != nil || !plan.Empty() {
+		t.Fatalf("VRS did not converge %v\n%s", err, plan)
+	}
+
+	desired.Ports = desired.Ports[:1]
+	desired.Entities[0].Ports = []string{"p1"}
+	desired.Entities[0].Metadata[entity.MetadataKeySiteID] = "site"
+	if _, err = reconciler.Reconcile(desired); err != nil {
+		t.Fatalf("Unable to reconcile %v", err)
+	}
+
+	plan, err = reconciler.Plan(desired)
+	if err != nil || !plan.Empty() {
+		t.Fatalf("VRS did not converge %v\n%s", err, plan)
+	}
+
+	if _, err = reconciler.Reconcile(State{}); err != nil {
+		t.Fatalf("Unable to reconcile %v", err)
+	}
+	if entities, _ := vrsConnection.GetAllEntities(); len(entities) != 0 {
+		t.Fatalf("Entities left on the VRS %v", entities)
+	}
+}
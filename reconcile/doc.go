@@ -0,0 +1,9 @@
+/*
+Package reconcile drives the Nuage VRS towards a declared set of entities and ports.
+
+The desired state is compared with the contents of Nuage_VM_Table and Nuage_Port_Table and the differences are
+turned into a Plan, the minimal list of SDK calls that brings the VRS in line with the desired state. Steps are
+ordered so that ports are created before the entities that use them and entities before the events posted to
+them, while deletions happen in the reverse order. A plan can be printed without being applied to get a dry run.
+*/
+package reconcile
@@ -13,7 +13,7 @@ import (
 	"strings"
 	"time"
 
-	"github.com/golang/glog"
+	"github.com/nuagenetworks/libvrsdk/logging"
 )
 
 const (
@@ -82,7 +82,7 @@ func GenerateMAC() string {
 	hostname, _ := os.Hostname()
 	_, err := io.WriteString(h, hostname)
 	if err != nil {
-		glog.Errorf("Error while generating MAC %v", err)
+		logging.Default().Error("Error while generating MAC", logging.Err(err))
 	}
 	hostnameHash := hex.EncodeToString(h.Sum(nil))
 	randbuf := make([]byte, 6)
@@ -2,108 +2,52 @@ package util
 
 import (
 	"github.com/nuagenetworks/go-bambou/bambou"
+	"github.com/nuagenetworks/libvrsdk/vsd"
 	"github.com/nuagenetworks/vspk-go/vspk"
 )
 
 // VerifyVSDPortResolution will verify if the given port is present on VSD. If yes, returns the IP
 func VerifyVSDPortResolution(root *vspk.Me, vsdEnterprise string, vsdDomain string, vsdZone string, vsdPort string) (string, *bambou.Error) {
 
-	var enterprise *vspk.Enterprise
-	var domain *vspk.Domain
-	var zone *vspk.Zone
-	var ipAddress string
-	var err *bambou.Error
-
-	//Fetching enterprise object
-	enterprise, err = FetchEnterprise(root, vsdEnterprise)
-	if err != nil {
-		return ipAddress, err
-	}
-
-	//Fetching domain object
-	domain, err = FetchDomain(enterprise, vsdDomain)
-	if err != nil {
-		return ipAddress, err
-	}
-
-	//Fetching zone object
-	zone, err = FetchZone(domain, vsdZone)
-	if err != nil {
-		return ipAddress, err
+	path := vsd.Path{Enterprise: vsdEnterprise, Domain: vsdDomain, Zone: vsdZone}
+	iface, err := vsd.FindInterface(root, path, vsdPort)
+	if notFound, ok := err.(*vsd.NotFoundError); ok && notFound.Kind == vsd.KindInterface {
+		return "", nil
 	}
-
-	//Fetching all port objects
-	var ports vspk.VPortsList
-	ports, err = FetchAllPorts(zone)
 	if err != nil {
-		return ipAddress, err
+		return "", toBambouError(err)
 	}
 
-	for i := 0; i < len(ports); i++ {
-
-		vmInterfacesFetchingInfo := &bambou.FetchingInfo{Filter: "name == \"" + vsdPort + "\""}
-		var vmInterfaces vspk.VMInterfacesList
-		vmInterfaces, err = ports[i].VMInterfaces(vmInterfacesFetchingInfo)
-
-		if err == nil && len(vmInterfaces) > 0 {
-			vmInterface := vmInterfaces[0]
-			ipAddress = vmInterface.IPAddress
-		}
-	}
-	return ipAddress, err
+	return iface.IPAddress, nil
 }
 
 // VerifyVSDPortDeletion will verify if the given port is removed from VSD or not
 func VerifyVSDPortDeletion(root *vspk.Me, vsdEnterprise string, vsdDomain string, vsdZone string, vsdPort string) (bool, *bambou.Error) {
 
-	var enterprise *vspk.Enterprise
-	var domain *vspk.Domain
-	var zone *vspk.Zone
-	var portDeletionFailure bool
-	var err *bambou.Error
-
-	// Fetching enterprise object
-	enterprise, err = FetchEnterprise(root, vsdEnterprise)
-	if err != nil {
-		return portDeletionFailure, err
-	}
-
-	// Fetching domain object
-	domain, err = FetchDomain(enterprise, vsdDomain)
-	if err != nil {
-		return portDeletionFailure, err
+	path := vsd.Path{Enterprise: vsdEnterprise, Domain: vsdDomain, Zone: vsdZone}
+	_, err := vsd.FindInterface(root, path, vsdPort)
+	if notFound, ok := err.(*vsd.NotFoundError); ok && notFound.Kind == vsd.KindInterface {
+		return false, nil
 	}
-
-	// Fetching zone object
-	zone, err = FetchZone(domain, vsdZone)
 	if err != nil {
-		return portDeletionFailure, err
+		return true, toBambouError(err)
 	}
 
-	// Fetching all port objects
-	var ports vspk.VPortsList
-	ports, err = FetchAllPorts(zone)
-	if err != nil {
-		return portDeletionFailure, err
-	}
-
-	for i := 0; i < len(ports); i++ {
-
-		vmInterfacesFetchingInfo := &bambou.FetchingInfo{Filter: "name == \"" + vsdPort + "\""}
-		var vmInterfaces vspk.VMInterfacesList
-		vmInterfaces, err = ports[i].VMInterfaces(vmInterfacesFetchingInfo)
+	return true, nil
+}
 
-		if err != nil || len(vmInterfaces) > 0 {
-			portDeletionFailure = true
-		}
+// toBambouError converts the errors of the vsd package to the errors returned by the helpers
+func toBambouError(err error) *bambou.Error {
+	if requestError, ok := err.(*vsd.RequestError); ok {
+		return requestError.Err
 	}
-	return portDeletionFailure, err
+	return bambou.NewBambouError("VSD lookup error", err.Error())
 }
 
 // FetchEnterprise fetches enterprise object
 func FetchEnterprise(root *vspk.Me, vsdEnterprise string) (*vspk.Enterprise, *bambou.Error) {
 
-	enterpriseFetchingInfo := &bambou.FetchingInfo{Filter: "name == \"" + vsdEnterprise + "\""}
+	enterpriseFetchingInfo := vsd.NameFilter(vsdEnterprise)
 	enterprises, enterpriseErr := root.Enterprises(enterpriseFetchingInfo)
 	if enterpriseErr != nil {
 		return nil, enterpriseErr
@@ -117,7 +61,7 @@ func FetchEnterprise(root *vspk.Me, vsdEnterprise string) (*vspk.Enterprise, *ba
 // FetchDomain fetches domain object
 func FetchDomain(enterprise *vspk.Enterprise, vsdDomain string) (*vspk.Domain, *bambou.Error) {
 
-	domainFetchingInfo := &bambou.FetchingInfo{Filter: "name == \"" + vsdDomain + "\""}
+	domainFetchingInfo := vsd.NameFilter(vsdDomain)
 	domains, domainErr := enterprise.Domains(domainFetchingInfo)
 	if domainErr != nil {
 		return nil, domainErr
@@ -131,7 +75,7 @@ func FetchDomain(enterprise *vspk.Enterprise, vsdDomain string) (*vspk.Domain, *
 // FetchZone fetches zone object
 func FetchZone(domain *vspk.Domain, vsdZone string) (*vspk.Zone, *bambou.Error) {
 
-	zoneFetchingInfo := &bambou.FetchingInfo{Filter: "name == \"" + vsdZone + "\""}
+	zoneFetchingInfo := vsd.NameFilter(vsdZone)
 	zones, zonesErr := domain.Zones(zoneFetchingInfo)
 	if zonesErr != nil {
 		return nil, zonesErr
@@ -145,7 +89,7 @@ func FetchZone(domain *vspk.Domain, vsdZone string) (*vspk.Zone, *bambou.Error)
 // FetchSubnet fetches subnet object
 func FetchSubnet(zone *vspk.Zone, vsdSubnet string) (*vspk.Subnet, *bambou.Error) {
 
-	subnetFetchingInfo := &bambou.FetchingInfo{Filter: "name == \"" + vsdSubnet + "\""}
+	subnetFetchingInfo := vsd.NameFilter(vsdSubnet)
 	subnets, subnetsErr := zone.Subnets(subnetFetchingInfo)
 	if subnetsErr != nil {
 		return nil, subnetsErr
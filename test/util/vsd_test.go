@@ -0,0 +1,50 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/nuagenetworks/libvrsdk/vsd/vsdtest"
+	"github.com/nuagenetworks/vspk-go/vspk"
+)
+
+func TestVerifyVSDPort(t *testing.T) {
+	server := vsdtest.NewServer()
+	defer server.Close()
+
+	_, root, err := server.Session()
+	if err != nil {
+		t.Fatalf("Unable to start the session %v", err)
+	}
+
+	enterpriseID := server.MustAdd("", &vspk.Enterprise{Name: "enterprise"})
+	domainID := server.MustAdd(enterpriseID, &vspk.Domain{Name: "domain"})
+	zoneID := server.MustAdd(domainID, &vspk.Zone{Name: "zone"})
+	subnetID := server.MustAdd(zoneID, &vspk.Subnet{Name: "subnet"})
+	vportID := server.MustAdd(subnetID, &vspk.VPort{Name: "vport", Type: "VM"})
+	interfaceID := server.MustAdd(vportID, &vspk.VMInterface{Name: "port1", IPAddress: "10.0.0.2", VMUUID: "vm1"})
+
+	ip, vsdErr := VerifyVSDPortResolution(root, "enterprise", "domain", "zone", "port1")
+	if vsdErr != nil || ip != "10.0.0.2" {
+		t.Fatalf("Unexpected resolution %s %v", ip, vsdErr)
+	}
+	if ip, vsdErr = VerifyVSDPortResolution(root, "enterprise", "domain", "zone", "port2"); vsdErr != nil || ip != "" {
+		t.Fatalf("Unexpected resolution of a missing port %s %v", ip, vsdErr)
+	}
+	if _, vsdErr = VerifyVSDPortResolution(root, "enterprise", "domain", "other", "port1"); vsdErr == nil {
+		t.Fatalf("Resolved a port of a missing zone")
+	}
+
+	present, vsdErr := VerifyVSDPortDeletion(root, "enterprise", "domain", "zone", "port1")
+	if vsdErr != nil || !present {
+		t.Fatalf("Unexpected deletion %t %v", present, vsdErr)
+	}
+	if err = server.Remove(interfaceID); err != nil {
+		t.Fatalf("Unable to remove the interface %v", err)
+	}
+	if present, vsdErr = VerifyVSDPortDeletion(root, "enterprise", "domain", "zone", "port1"); vsdErr != nil || present {
+		t.Fatalf("Unexpected deletion of a removed port %t %v", present, vsdErr)
+	}
+	if _, vsdErr = VerifyVSDPortDeletion(root, "other", "domain", "zone", "port1"); vsdErr == nil {
+		t.Fatalf("Verified the deletion of a port of a missing enterprise")
+	}
+}
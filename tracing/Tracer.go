@@ -0,0 +1,109 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+)
+
+// Attribute is a named value attached to a span
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// A returns the attribute with the given key and value
+func A(key string, value interface{}) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Span is an operation being traced
+type Span interface {
+	SetAttributes(attributes ...Attribute)
+	RecordError(err error)
+	End()
+}
+
+// Tracer starts the spans of the SDK. The span is a child of the span carried by ctx, if any, and is linked to
+// the spans carried by the links contexts. The returned context carries the new span.
+type Tracer interface {
+	Start(ctx context.Context, name string, links ...context.Context) (context.Context, Span)
+}
+
+// noopSpan ignores everything
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...Attribute) {}
+func (noopSpan) RecordError(error)          {}
+func (noopSpan) End()                       {}
+
+// noop starts spans that are never reported
+type noop struct{}
+
+func (noop) Start(ctx context.Context, name string, links ...context.Context) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+// Noop is a Tracer dropping all the spans
+var Noop Tracer = noop{}
+
+var (
+	defaultMutex  sync.RWMutex
+	defaultTracer = Noop
+)
+
+// Default returns the package-level Tracer used by connections without a Tracer of their own. Unless replaced
+// with SetDefault it drops the spans.
+func Default() Tracer {
+	defaultMutex.RLock()
+	defer defaultMutex.RUnlock()
+	return defaultTracer
+}
+
+// SetDefault replaces the package-level Tracer
+func SetDefault(tracer Tracer) {
+	defaultMutex.Lock()
+	defer defaultMutex.Unlock()
+	defaultTracer = tracer
+}
+
+// Switchable is a Tracer forwarding to a Tracer that can be replaced at any time, to Default until one is set.
+// It lets copies of a connection share their Tracer.
+type Switchable struct {
+	mutex  sync.RWMutex
+	target Tracer
+}
+
+// Set replaces the Tracer the spans are started with, nil restores Default
+func (switchable *Switchable) Set(tracer Tracer) {
+	switchable.mutex.Lock()
+	defer switchable.mutex.Unlock()
+	switchable.target = tracer
+}
+
+func (switchable *Switchable) current() Tracer {
+	if switchable == nil {
+		return Default()
+	}
+
+	switchable.mutex.RLock()
+	defer switchable.mutex.RUnlock()
+	if switchable.target == nil {
+		return Default()
+	}
+	return switchable.target
+}
+
+// Start implements Tracer
+func (switchable *Switchable) Start(ctx context.Context, name string,
+	links ...context.Context) (context.Context, Span) {
+
+	return switchable.current().Start(ctx, name, links...)
+}
+
+// End records err, if any, on the span and ends it
+func End(span Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
@@ -0,0 +1,12 @@
+/*
+Package tracing defines the Tracer the SDK reports its spans to.
+
+The SDK starts a span around every OVSDB transaction of a Nuage table and around the bridge operations, as
+children of the span carried by the context given to the Context variants of the API, such as
+CreatePortContext. Once a port is created, the wait for its resolution by the VRS is reported as a separate
+span linked to the creating call. The interfaces follow the shape of the OpenTelemetry trace API so that an
+adapter for an OpenTelemetry trace.Tracer only has to map the attributes and turn the linked contexts into
+trace.Links. Each connection reports to its own Tracer, set with SetTracer, and falls back to the package-level
+Default, which drops the spans.
+*/
+package tracing
@@ -0,0 +1,92 @@
+package vsd
+
+import (
+	"fmt"
+
+	"github.com/nuagenetworks/go-bambou/bambou"
+)
+
+// Kind identifies the type of a VSD object
+type Kind string
+
+// Kinds of VSD objects looked up by the package
+const (
+	KindEnterprise Kind = "enterprise"
+	KindDomain     Kind = "domain"
+	KindZone       Kind = "zone"
+	KindSubnet     Kind = "subnet"
+	KindVPort      Kind = "vport"
+	KindInterface  Kind = "interface"
+)
+
+// NotFoundError is returned when no VSD object of the given kind has the given name
+type NotFoundError struct {
+	Kind Kind
+	Name string
+}
+
+func (notFoundError *NotFoundError) Error() string {
+	return fmt.Sprintf("No %s %s found on VSD", notFoundError.Kind, notFoundError.Name)
+}
+
+// AmbiguousError is returned when several VSD objects of the given kind have the given name
+type AmbiguousError struct {
+	Kind  Kind
+	Name  string
+	Count int
+}
+
+func (ambiguousError *AmbiguousError) Error() string {
+	return fmt.Sprintf("%d %ss named %s found on VSD", ambiguousError.Count, ambiguousError.Kind, ambiguousError.Name)
+}
+
+// RequestError is returned when the VSD failed to answer a request for an object
+type RequestError struct {
+	Kind Kind
+	Name string
+	Err  *bambou.Error
+}
+
+func (requestError *RequestError) Error() string {
+	return fmt.Sprintf("Unable to fetch %s %s from VSD %v", requestError.Kind, requestError.Name, requestError.Err)
+}
+
+// Unwrap returns the error reported by the VSD
+func (requestError *RequestError) Unwrap() error {
+	return requestError.Err
+}
+
+// MismatchError is returned when a field of a port differs between the VRS and the VSD
+type MismatchError struct {
+	Port  string
+	Field string
+	VRS   string
+	VSD   string
+}
+
+func (mismatchError *MismatchError) Error() string {
+	return fmt.Sprintf("Port %s has %s %s on the VRS and %s on VSD", mismatchError.Port, mismatchError.Field,
+		mismatchError.VRS, mismatchError.VSD)
+}
+
+// IsNotFound returns true if the error reports a missing VSD object
+func IsNotFound(err error) bool {
+	_, ok := err.(*NotFoundError)
+	return ok
+}
+
+// checkCount converts the outcome of fetching the objects of a kind by name to an error
+func checkCount(kind Kind, name string, count int, err *bambou.Error) error {
+	if err != nil {
+		return &RequestError{Kind: kind, Name: name, Err: err}
+	}
+
+	switch count {
+	case 0:
+		return &NotFoundError{Kind: kind, Name: name}
+	case 1:
+		return nil
+	}
+
+	return &AmbiguousError{Kind: kind, Name: name, Count: count}
+}
@@ -0,0 +1,164 @@
+package vsd
+
+import (
+	"fmt"
+
+	"github.com/nuagenetworks/go-bambou/bambou"
+	"github.com/nuagenetworks/libvrsdk/api/port"
+	"github.com/nuagenetworks/vspk-go/vspk"
+)
+
+// Interface is the VSD view of the VM or container interface backing a port of the VRS
+type Interface struct {
+	ID                string
+	EntityUUID        string
+	Name              string
+	MAC               string
+	IPAddress         string
+	Netmask           string
+	Gateway           string
+	VPortID           string
+	VPortName         string
+	DomainName        string
+	ZoneName          string
+	NetworkName       string
+	AttachedNetworkID string
+	Container         bool
+}
+
+// interfaceParent is implemented by the VSD objects interfaces can be fetched from
+type interfaceParent interface {
+	VMInterfaces(info *bambou.FetchingInfo) (vspk.VMInterfacesList, *bambou.Error)
+	ContainerInterfaces(info *bambou.FetchingInfo) (vspk.ContainerInterfacesList, *bambou.Error)
+}
+
+// FindInterface resolves the path and looks up the interface of the port below it
+func FindInterface(root *vspk.Me, path Path, portName string) (*Interface, error) {
+	resolved, err := Resolve(root, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return resolved.FindInterface(portName)
+}
+
+// FindInterface looks up the VM or container interface named after the port below the deepest resolved object
+func (resolved *Resolved) FindInterface(portName string) (*Interface, error) {
+	interfaces, err := fetchInterfaces(resolved.deepest(), NameFilter(portName).Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkCount(KindInterface, portName, len(interfaces), nil); err != nil {
+		return nil, err
+	}
+
+	return interfaces[0], nil
+}
+
+// Interfaces returns all the VM and container interfaces below the deepest resolved object
+func (resolved *Resolved) Interfaces() ([]*Interface, error) {
+	return fetchInterfaces(resolved.deepest(), "")
+}
+
+// deepest returns the lowest object of the resolved path
+func (resolved *Resolved) deepest() interfaceParent {
+	switch {
+	case resolved.VPort != nil:
+		return resolved.VPort
+	case resolved.Subnet != nil:
+		return resolved.Subnet
+	case resolved.Zone != nil:
+		return resolved.Zone
+	}
+	return resolved.Domain
+}
+
+// fetchInterfaces returns the VM and container interfaces below parent matching the filter, all of them if the
+// filter is empty
+func fetchInterfaces(parent interfaceParent, filter string) ([]*Interface, error) {
+	var interfaces []*Interface
+	var vmInterfaces vspk.VMInterfacesList
+	var containerInterfaces vspk.ContainerInterfacesList
+
+	bambouErr := fetchAll(filter, func(info *bambou.FetchingInfo) (int, *bambou.Error) {
+		page, err := parent.VMInterfaces(info)
+		vmInterfaces = append(vmInterfaces, page...)
+		return len(page), err
+	})
+	if bambouErr != nil {
+		return nil, &RequestError{Kind: KindInterface, Name: filter, Err: bambouErr}
+	}
+	for _, vmInterface := range vmInterfaces {
+		interfaces = append(interfaces, &Interface{
+			ID:                vmInterface.ID,
+			EntityUUID:        vmInterface.VMUUID,
+			Name:              vmInterface.Name,
+			MAC:               vmInterface.MAC,
+			IPAddress:         vmInterface.IPAddress,
+			Netmask:           vmInterface.Netmask,
+			Gateway:           vmInterface.Gateway,
+			VPortID:           vmInterface.VPortID,
+			VPortName:         vmInterface.VPortName,
+			DomainName:        vmInterface.DomainName,
+			ZoneName:          vmInterface.ZoneName,
+			NetworkName:       vmInterface.NetworkName,
+			AttachedNetworkID: vmInterface.AttachedNetworkID,
+		})
+	}
+
+	bambouErr = fetchAll(filter, func(info *bambou.FetchingInfo) (int, *bambou.Error) {
+		page, err := parent.ContainerInterfaces(info)
+		containerInterfaces = append(containerInterfaces, page...)
+		return len(page), err
+	})
+	if bambouErr != nil {
+		return nil, &RequestError{Kind: KindInterface, Name: filter, Err: bambouErr}
+	}
+	for _, containerInterface := range containerInterfaces {
+		interfaces = append(interfaces, &Interface{
+			ID:                containerInterface.ID,
+			EntityUUID:        containerInterface.ContainerUUID,
+			Name:              containerInterface.Name,
+			MAC:               containerInterface.MAC,
+			IPAddress:         containerInterface.IPAddress,
+			Netmask:           containerInterface.Netmask,
+			Gateway:           containerInterface.Gateway,
+			VPortID:           containerInterface.VPortID,
+			VPortName:         containerInterface.VPortName,
+			DomainName:        containerInterface.DomainName,
+			ZoneName:          containerInterface.ZoneName,
+			NetworkName:       containerInterface.NetworkName,
+			AttachedNetworkID: containerInterface.AttachedNetworkID,
+			Container:         true,
+		})
+	}
+
+	return interfaces, nil
+}
+
+// CheckPortState compares the resolution state of a port returned by GetPortState with the interface and
+// returns a MismatchError for the first field that differs
+func (iface *Interface) CheckPortState(state map[port.StateKey]interface{}) error {
+	fields := []struct {
+		name string
+		key  port.StateKey
+		vsd  string
+	}{
+		{"IP address", port.StateKeyIPAddress, iface.IPAddress},
+		{"subnet mask", port.StateKeySubnetMask, iface.Netmask},
+		{"gateway", port.StateKeyGateway, iface.Gateway},
+	}
+
+	for _, field := range fields {
+		vrs := ""
+		if value, ok := state[field.key]; ok && value != nil {
+			vrs = fmt.Sprintf("%v", value)
+		}
+		if vrs != field.vsd {
+			return &MismatchError{Port: iface.Name, Field: field.name, VRS: vrs, VSD: field.vsd}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package vsd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/nuagenetworks/libvrsdk/api/port"
+	"github.com/nuagenetworks/libvrsdk/vsd/vsdtest"
+	"github.com/nuagenetworks/vspk-go/vspk"
+)
+
+func TestFindInterface(t *testing.T) {
+	server := vsdtest.NewServer()
+	defer server.Close()
+
+	_, root, err := server.Session()
+	if err != nil {
+		t.Fatalf("Unable to start the session %v", err)
+	}
+
+	enterpriseID := server.MustAdd("", &vspk.Enterprise{Name: "enterprise"})
+	domainID := server.MustAdd(enterpriseID, &vspk.Domain{Name: "domain"})
+	zoneID := server.MustAdd(domainID, &vspk.Zone{Name: "zone"})
+	subnetID := server.MustAdd(zoneID, &vspk.Subnet{Name: "subnet"})
+	vportID := server.MustAdd(subnetID, &vspk.VPort{Name: "vport", Type: "VM"})
+	server.MustAdd(vportID, &vspk.VMInterface{Name: "port1", IPAddress: "10.0.0.2", Netmask: "255.255.255.0",
+		Gateway: "10.0.0.1", VMUUID: "vm1"})
+	server.MustAdd(vportID, &vspk.ContainerInterface{Name: "port2", ContainerUUID: "container1"})
+	server.MustAdd(vportID, &vspk.ContainerInterface{Name: "port2", ContainerUUID: "container2"})
+
+	// More interfaces than fit in a page
+	for i := 0; i < pageSize; i++ {
+		server.MustAdd(vportID, &vspk.VMInterface{Name: fmt.Sprintf("bulk%d", i)})
+	}
+
+	path := Path{Enterprise: "enterprise", Domain: "domain", Zone: "zone"}
+	iface, err := FindInterface(root, path, "port1")
+	if err != nil || iface.IPAddress != "10.0.0.2" || iface.EntityUUID != "vm1" || iface.Container {
+		t.Fatalf("Unexpected interface %+v %v", iface, err)
+	}
+
+	state := map[port.StateKey]interface{}{port.StateKeyIPAddress: "10.0.0.2",
+		port.StateKeySubnetMask: "255.255.255.0", port.StateKeyGateway: "10.0.0.1"}
+	if err = iface.CheckPortState(state); err != nil {
+		t.Fatalf("Unexpected mismatch %v", err)
+	}
+	state[port.StateKeyIPAddress] = "10.0.0.3"
+	if mismatch, ok := iface.CheckPortState(state).(*MismatchError); !ok || mismatch.VSD != "10.0.0.2" {
+		t.Fatalf("Expected an address mismatch, got %v", mismatch)
+	}
+
+	if _, err = FindInterface(root, path, "port2"); err == nil {
+		t.Fatalf("Expected an ambiguous interface")
+	} else if ambiguous, ok := err.(*AmbiguousError); !ok || ambiguous.Count != 2 {
+		t.Fatalf("Expected an ambiguous interface, got %v", err)
+	}
+
+	if _, err = FindInterface(root, path, "port3"); !IsNotFound(err) {
+		t.Fatalf("Expected a missing interface, got %v", err)
+	}
+
+	if _, err = Resolve(root, Path{Enterprise: "enterprise", Domain: "other"}); !IsNotFound(err) {
+		t.Fatalf("Expected a missing domain, got %v", err)
+	}
+
+	resolved, err := Resolve(root, Path{Enterprise: "enterprise", Domain: "domain", Subnet: "subnet"})
+	if err != nil || resolved.Subnet == nil || resolved.Zone != nil {
+		t.Fatalf("Unexpected resolution %+v %v", resolved, err)
+	}
+
+	interfaces, err := resolved.Interfaces()
+	if err != nil || len(interfaces) != pageSize+3 {
+		t.Fatalf("Unexpected number of interfaces %d %v", len(interfaces), err)
+	}
+}
@@ -0,0 +1,127 @@
+package vsd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nuagenetworks/go-bambou/bambou"
+	"github.com/nuagenetworks/vspk-go/vspk"
+)
+
+// Path names a location in the VSD hierarchy. Enterprise and Domain are mandatory, the other levels are
+// optional and a subnet or a vport may be named without the zone above it.
+type Path struct {
+	Enterprise string
+	Domain     string
+	Zone       string
+	Subnet     string
+	VPort      string
+}
+
+// String returns the path in enterprise/domain/zone/subnet/vport form, omitting the levels not set
+func (path Path) String() string {
+	var levels []string
+	for _, level := range []string{path.Enterprise, path.Domain, path.Zone, path.Subnet, path.VPort} {
+		if len(level) != 0 {
+			levels = append(levels, level)
+		}
+	}
+	return strings.Join(levels, "/")
+}
+
+// Resolved holds the VSD objects a Path resolves to. The levels not named by the path are nil.
+type Resolved struct {
+	Enterprise *vspk.Enterprise
+	Domain     *vspk.Domain
+	Zone       *vspk.Zone
+	Subnet     *vspk.Subnet
+	VPort      *vspk.VPort
+}
+
+// NameFilter returns the fetching info selecting the objects with the given name
+func NameFilter(name string) *bambou.FetchingInfo {
+	return Filter("name", name)
+}
+
+// Filter returns the fetching info selecting the objects whose attribute equals value. The value is quoted
+// so that it cannot alter the filter expression.
+func Filter(attribute string, value string) *bambou.FetchingInfo {
+	escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value)
+	return &bambou.FetchingInfo{Filter: fmt.Sprintf(`%s == "%s"`, attribute, escaped)}
+}
+
+// pageSize is the number of objects requested at once when listing the children of an object
+const pageSize = 500
+
+// fetchAll calls fetch for the successive pages of the objects matching the filter until a page is not full
+func fetchAll(filter string, fetch func(info *bambou.FetchingInfo) (int, *bambou.Error)) *bambou.Error {
+	for page := 0; ; page++ {
+		count, err := fetch(&bambou.FetchingInfo{Filter: filter, Page: page, PageSize: pageSize})
+		if err != nil {
+			return err
+		}
+		if count < pageSize {
+			return nil
+		}
+	}
+}
+
+// Resolve fetches from the VSD the objects named by the path
+func Resolve(root *vspk.Me, path Path) (*Resolved, error) {
+	if len(path.Enterprise) == 0 || len(path.Domain) == 0 {
+		return nil, fmt.Errorf("Incomplete VSD path %s", path)
+	}
+
+	resolved := &Resolved{}
+
+	enterprises, bambouErr := root.Enterprises(NameFilter(path.Enterprise))
+	if err := checkCount(KindEnterprise, path.Enterprise, len(enterprises), bambouErr); err != nil {
+		return nil, err
+	}
+	resolved.Enterprise = enterprises[0]
+
+	domains, bambouErr := resolved.Enterprise.Domains(NameFilter(path.Domain))
+	if err := checkCount(KindDomain, path.Domain, len(domains), bambouErr); err != nil {
+		return nil, err
+	}
+	resolved.Domain = domains[0]
+
+	if len(path.Zone) != 0 {
+		zones, bambouErr := resolved.Domain.Zones(NameFilter(path.Zone))
+		if err := checkCount(KindZone, path.Zone, len(zones), bambouErr); err != nil {
+			return nil, err
+		}
+		resolved.Zone = zones[0]
+	}
+
+	if len(path.Subnet) != 0 {
+		var subnets vspk.SubnetsList
+		if resolved.Zone != nil {
+			subnets, bambouErr = resolved.Zone.Subnets(NameFilter(path.Subnet))
+		} else {
+			subnets, bambouErr = resolved.Domain.Subnets(NameFilter(path.Subnet))
+		}
+		if err := checkCount(KindSubnet, path.Subnet, len(subnets), bambouErr); err != nil {
+			return nil, err
+		}
+		resolved.Subnet = subnets[0]
+	}
+
+	if len(path.VPort) != 0 {
+		var vports vspk.VPortsList
+		switch {
+		case resolved.Subnet != nil:
+			vports, bambouErr = resolved.Subnet.VPorts(NameFilter(path.VPort))
+		case resolved.Zone != nil:
+			vports, bambouErr = resolved.Zone.VPorts(NameFilter(path.VPort))
+		default:
+			vports, bambouErr = resolved.Domain.VPorts(NameFilter(path.VPort))
+		}
+		if err := checkCount(KindVPort, path.VPort, len(vports), bambouErr); err != nil {
+			return nil, err
+		}
+		resolved.VPort = vports[0]
+	}
+
+	return resolved, nil
+}
@@ -0,0 +1,27 @@
+package vsd
+
+import (
+	"testing"
+
+	"github.com/nuagenetworks/go-bambou/bambou"
+)
+
+func TestFilter(t *testing.T) {
+	if filter := NameFilter(`a"b\c`).Filter; filter != `name == "a\"b\\c"` {
+		t.Fatalf("Unexpected filter %s", filter)
+	}
+
+	if path := (Path{Enterprise: "e", Domain: "d", VPort: "p"}).String(); path != "e/d/p" {
+		t.Fatalf("Unexpected path %s", path)
+	}
+
+	if err := checkCount(KindZone, "z", 0, nil); !IsNotFound(err) {
+		t.Fatalf("Expected a not found error, got %v", err)
+	}
+	if err, ok := checkCount(KindZone, "z", 2, nil).(*AmbiguousError); !ok || err.Count != 2 {
+		t.Fatalf("Expected an ambiguous error, got %v", err)
+	}
+	if err, ok := checkCount(KindZone, "z", 0, bambou.NewBambouError("e", "d")).(*RequestError); !ok || err.Err == nil {
+		t.Fatalf("Expected a request error, got %v", err)
+	}
+}
@@ -0,0 +1,12 @@
+/*
+Package vsd looks up on the Nuage VSD the objects backing the ports managed through the VRS SDK.
+
+A Path names an enterprise, a domain and optionally a zone, a subnet and a vport. Resolve walks the VSD hierarchy
+along the path and FindInterface returns the VM or container interface VSD holds for a port, so that an agent can
+confirm that the address VSD assigned to the port is the one the VRS reports with GetPortState.
+
+The lookups use the vspk session established by the caller. Failures are reported with typed errors: a
+NotFoundError or an AmbiguousError when the hierarchy does not match the path, a RequestError when the VSD
+could not be queried and a MismatchError when the VRS and the VSD disagree.
+*/
+package vsd
@@ -0,0 +1,139 @@
+package vsdtest
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// term compares an attribute of the objects with a value
+type term struct {
+	attribute string
+	operator  string
+	value     string
+}
+
+// filter is a disjunction of conjunctions of terms, the form of the VSD filters used by the SDK
+type filter [][]term
+
+// parseFilter parses an X-Nuage-Filter expression. Terms have the form attribute == value or
+// attribute != value where value is a double quoted string, a number or a boolean. Terms are combined with
+// and, which takes precedence, and or.
+func parseFilter(expression string) (filter, error) {
+	tokens, err := tokenize(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed filter
+	var conjunction []term
+	for len(tokens) != 0 {
+		if len(tokens) < 3 || tokens[0].quoted || tokens[1].quoted {
+			return nil, fmt.Errorf("Invalid filter %q", expression)
+		}
+
+		t := term{attribute: tokens[0].text, operator: tokens[1].text, value: tokens[2].text}
+		if t.operator != "==" && t.operator != "!=" {
+			return nil, fmt.Errorf("Unsupported operator %s in filter %q", t.operator, expression)
+		}
+		conjunction = append(conjunction, t)
+		tokens = tokens[3:]
+
+		if len(tokens) == 0 {
+			break
+		}
+		if len(tokens) == 1 || tokens[0].quoted {
+			return nil, fmt.Errorf("Invalid filter %q", expression)
+		}
+		switch strings.ToLower(tokens[0].text) {
+		case "and":
+		case "or":
+			parsed = append(parsed, conjunction)
+			conjunction = nil
+		default:
+			return nil, fmt.Errorf("Unsupported conjunction %s in filter %q", tokens[0].text, expression)
+		}
+		tokens = tokens[1:]
+	}
+
+	if len(conjunction) != 0 {
+		parsed = append(parsed, conjunction)
+	}
+
+	return parsed, nil
+}
+
+// matches returns true if the object satisfies the filter, an empty filter matches all objects
+func (f filter) matches(object map[string]interface{}) bool {
+	if len(f) == 0 {
+		return true
+	}
+
+	for _, conjunction := range f {
+		matched := true
+		for _, t := range conjunction {
+			value := ""
+			if v, ok := object[t.attribute]; ok && v != nil {
+				value = fmt.Sprintf("%v", v)
+			}
+			if (value == t.value) != (t.operator == "==") {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+type token struct {
+	text   string
+	quoted bool
+}
+
+// tokenize splits the expression in words, operators and quoted strings, removing the quotes and escapes
+func tokenize(expression string) ([]token, error) {
+	var tokens []token
+	runes := []rune(expression)
+
+	for i := 0; i < len(runes); {
+		switch r := runes[i]; {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"' || r == '\'':
+			var text []rune
+			i++
+			for ; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' {
+					i++
+					if i == len(runes) {
+						break
+					}
+				}
+				text = append(text, runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("Unterminated string in filter %q", expression)
+			}
+			i++
+			tokens = append(tokens, token{text: string(text), quoted: true})
+		case r == '=' || r == '!':
+			if i+1 == len(runes) || runes[i+1] != '=' {
+				return nil, fmt.Errorf("Invalid operator in filter %q", expression)
+			}
+			tokens = append(tokens, token{text: string(runes[i : i+2])})
+			i += 2
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune(`"'=!`, runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{text: string(runes[start:i])})
+		}
+	}
+
+	return tokens, nil
+}
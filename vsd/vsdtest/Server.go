@@ -0,0 +1,312 @@
+package vsdtest
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/nuagenetworks/go-bambou/bambou"
+	"github.com/nuagenetworks/vspk-go/vspk"
+)
+
+// Credentials accepted by a server started with NewServer
+const (
+	Username     = "csproot"
+	Password     = "csproot"
+	Organization = "csp"
+)
+
+// defaultPageSize is the page size used by the VSD when the request does not set one
+const defaultPageSize = 50
+
+// object is a VSD object stored by the server
+type object struct {
+	id         string
+	identity   bambou.Identity
+	parent     *object
+	attributes map[string]interface{}
+}
+
+// descendantOf returns true if ancestor is above the object in the hierarchy
+func (o *object) descendantOf(ancestor *object) bool {
+	for parent := o.parent; parent != nil; parent = parent.parent {
+		if parent == ancestor {
+			return true
+		}
+	}
+	return false
+}
+
+// Server is an in-memory VSD serving over HTTPS the subset of the REST API used by the vspk lookups:
+// reading an object and listing the children or descendants of an object with filtering and paging
+type Server struct {
+	server  *httptest.Server
+	mutex   sync.Mutex
+	root    *object
+	objects map[string]*object
+	order   []*object
+	apiKey  string
+}
+
+// NewServer starts a server accepting the Username, Password and Organization credentials
+func NewServer() *Server {
+	server := &Server{
+		objects: make(map[string]*object),
+		apiKey:  newID(),
+	}
+
+	server.root = &object{
+		id:       newID(),
+		identity: vspk.MeIdentity,
+		attributes: map[string]interface{}{
+			"userName":       Username,
+			"enterpriseName": Organization,
+		},
+	}
+
+	server.server = httptest.NewTLSServer(http.HandlerFunc(server.handle))
+
+	return server
+}
+
+// URL returns the URL to pass to vspk.NewSession
+func (server *Server) URL() string {
+	return server.server.URL
+}
+
+// Session returns a started session authenticated with the server along with its root object
+func (server *Server) Session() (*bambou.Session, *vspk.Me, error) {
+	session, root := vspk.NewSession(Username, Password, Organization, server.URL())
+	if err := session.Start(); err != nil {
+		return nil, nil, err
+	}
+
+	return session, root, nil
+}
+
+// Close shuts the server down
+func (server *Server) Close() {
+	server.server.Close()
+}
+
+// Add stores a copy of a vspk object below the object with the given ID, below the root object if parentID
+// is empty, and returns the ID assigned to the object
+func (server *Server) Add(parentID string, child bambou.Identifiable) (string, error) {
+	encoded, err := json.Marshal(child)
+	if err != nil {
+		return "", err
+	}
+
+	var attributes map[string]interface{}
+	if err = json.Unmarshal(encoded, &attributes); err != nil {
+		return "", err
+	}
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	parent := server.root
+	if len(parentID) != 0 {
+		var ok bool
+		if parent, ok = server.objects[parentID]; !ok {
+			return "", fmt.Errorf("Parent %s not found", parentID)
+		}
+	}
+
+	o := &object{id: newID(), identity: child.Identity(), parent: parent, attributes: attributes}
+	o.attributes["ID"] = o.id
+	o.attributes["parentID"] = parent.id
+	o.attributes["parentType"] = parent.identity.Name
+
+	server.objects[o.id] = o
+	server.order = append(server.order, o)
+
+	return o.id, nil
+}
+
+// MustAdd is Add for test setups, it panics if the object cannot be added
+func (server *Server) MustAdd(parentID string, child bambou.Identifiable) string {
+	id, err := server.Add(parentID, child)
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
+
+// Remove deletes the object with the given ID along with its descendants
+func (server *Server) Remove(id string) error {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	removed, ok := server.objects[id]
+	if !ok {
+		return fmt.Errorf("Object %s not found", id)
+	}
+
+	var remaining []*object
+	for _, o := range server.order {
+		if o == removed || o.descendantOf(removed) {
+			delete(server.objects, o.id)
+			continue
+		}
+		remaining = append(remaining, o)
+	}
+	server.order = remaining
+
+	return nil
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return fmt.Sprintf("%s-%s-%s-%s-%s", hex.EncodeToString(b[0:4]), hex.EncodeToString(b[4:6]),
+		hex.EncodeToString(b[6:8]), hex.EncodeToString(b[8:10]), hex.EncodeToString(b[10:]))
+}
+
+// authenticated checks the XREST credentials of the request, either the password or the API key
+func (server *Server) authenticated(request *http.Request) bool {
+	if request.Header.Get("X-Nuage-Organization") != Organization {
+		return false
+	}
+
+	authorization := request.Header.Get("Authorization")
+	for _, key := range []string{Password, server.apiKey} {
+		if authorization == "XREST "+base64.StdEncoding.EncodeToString([]byte(Username+":"+key)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (server *Server) handle(writer http.ResponseWriter, request *http.Request) {
+	if !server.authenticated(request) {
+		http.Error(writer, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if request.Method != http.MethodGet {
+		http.Error(writer, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Strip the API prefix and version, /nuage/api/v6
+	path := strings.TrimPrefix(request.URL.Path, "/"+vspk.SDKAPIPrefix+"/")
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) < 2 {
+		writeError(writer, http.StatusNotFound, "Not found", request.URL.Path)
+		return
+	}
+	segments = segments[1:]
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	switch len(segments) {
+	case 1:
+		if segments[0] == vspk.MeIdentity.Name {
+			server.writeObjects(writer, []*object{server.withAPIKey()})
+			return
+		}
+		server.listChildren(writer, request, server.root, segments[0])
+	case 2:
+		o, ok := server.objects[segments[1]]
+		if !ok || o.identity.Category != segments[0] {
+			writeError(writer, http.StatusNotFound, "Object not found", segments[1])
+			return
+		}
+		server.writeObjects(writer, []*object{o})
+	case 3:
+		o, ok := server.objects[segments[1]]
+		if !ok || o.identity.Category != segments[0] {
+			writeError(writer, http.StatusNotFound, "Object not found", segments[1])
+			return
+		}
+		server.listChildren(writer, request, o, segments[2])
+	default:
+		writeError(writer, http.StatusNotFound, "Not found", request.URL.Path)
+	}
+}
+
+// withAPIKey returns the root object along with the API key of the session
+func (server *Server) withAPIKey() *object {
+	attributes := map[string]interface{}{"ID": server.root.id, "APIKey": server.apiKey}
+	for k, v := range server.root.attributes {
+		attributes[k] = v
+	}
+	return &object{id: server.root.id, identity: server.root.identity, attributes: attributes}
+}
+
+// listChildren writes the page of the objects of the category below parent matching the filter of the
+// request. Like the VSD, objects are listed from their ancestors too, the vports of a domain are the vports
+// of all its subnets.
+func (server *Server) listChildren(writer http.ResponseWriter, request *http.Request, parent *object,
+	category string) {
+
+	f, err := parseFilter(request.Header.Get("X-Nuage-Filter"))
+	if err != nil {
+		writeError(writer, http.StatusBadRequest, "Invalid filter", err.Error())
+		return
+	}
+
+	var matching []*object
+	for _, o := range server.order {
+		if o.identity.Category == category && o.descendantOf(parent) && f.matches(o.attributes) {
+			matching = append(matching, o)
+		}
+	}
+
+	page, _ := strconv.Atoi(request.Header.Get("X-Nuage-Page"))
+	pageSize, err := strconv.Atoi(request.Header.Get("X-Nuage-PageSize"))
+	if err != nil || pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+
+	writer.Header().Set("X-Nuage-Count", strconv.Itoa(len(matching)))
+	writer.Header().Set("X-Nuage-Page", strconv.Itoa(page))
+	writer.Header().Set("X-Nuage-PageSize", strconv.Itoa(pageSize))
+	writer.Header().Set("X-Nuage-Filter", request.Header.Get("X-Nuage-Filter"))
+
+	start := page * pageSize
+	if start >= len(matching) {
+		writer.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	end := start + pageSize
+	if end > len(matching) {
+		end = len(matching)
+	}
+
+	server.writeObjects(writer, matching[start:end])
+}
+
+func (server *Server) writeObjects(writer http.ResponseWriter, objects []*object) {
+	list := make([]map[string]interface{}, len(objects))
+	for i, o := range objects {
+		list[i] = o.attributes
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(list)
+}
+
+// writeError writes an error in the format of the VSD so that bambou reports its title and description
+func writeError(writer http.ResponseWriter, status int, title string, description string) {
+	errors := bambou.VsdErrorList{
+		VsdErrors: []bambou.VsdError{
+			{Descriptions: []bambou.Error{{Title: title, Description: description}}},
+		},
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	json.NewEncoder(writer).Encode(errors)
+}
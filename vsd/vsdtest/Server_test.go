@@ -0,0 +1,87 @@
+package vsdtest
+
+import (
+	"testing"
+
+	"github.com/nuagenetworks/go-bambou/bambou"
+	"github.com/nuagenetworks/vspk-go/vspk"
+)
+
+func TestParseFilter(t *testing.T) {
+	object := map[string]interface{}{"name": `a "b"`, "type": "VM", "enabled": true}
+
+	expressions := map[string]bool{
+		`name == "a \"b\""`:                       true,
+		`name == 'a "b"' and type == "VM"`:        true,
+		`name == "x" or type == "VM"`:             true,
+		`name != "x" and type != "VM"`:            false,
+		`enabled == true AND type == "CONTAINER"`: false,
+		``: true,
+	}
+	for expression, expected := range expressions {
+		f, err := parseFilter(expression)
+		if err != nil {
+			t.Fatalf("Unable to parse the filter %s %v", expression, err)
+		}
+		if f.matches(object) != expected {
+			t.Fatalf("Filter %s returned %v", expression, !expected)
+		}
+	}
+
+	for _, expression := range []string{`name`, `name == "a`, `name > 3`, `name == "a" xor type == "b"`} {
+		if _, err := parseFilter(expression); err == nil {
+			t.Fatalf("Parsed invalid filter %s", expression)
+		}
+	}
+}
+
+func TestServer(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	if session, _ := vspk.NewSession(Username, "wrong", Organization, server.URL()); session.Start() == nil {
+		t.Fatalf("Session started with a wrong password")
+	}
+
+	_, root, err := server.Session()
+	if err != nil {
+		t.Fatalf("Unable to start the session %v", err)
+	}
+	if len(root.APIKey()) == 0 {
+		t.Fatalf("No API key returned")
+	}
+
+	enterpriseID := server.MustAdd("", &vspk.Enterprise{Name: "enterprise"})
+	domainID := server.MustAdd(enterpriseID, &vspk.Domain{Name: "domain"})
+	zoneID := server.MustAdd(domainID, &vspk.Zone{Name: "zone"})
+	for _, name := range []string{"vport1", "vport2", "vport3"} {
+		server.MustAdd(zoneID, &vspk.VPort{Name: name, Type: "VM"})
+	}
+
+	enterprises, bambouErr := root.Enterprises(&bambou.FetchingInfo{Filter: `name == "enterprise"`})
+	if bambouErr != nil || len(enterprises) != 1 || enterprises[0].ID != enterpriseID {
+		t.Fatalf("Unexpected enterprises %v %v", enterprises, bambouErr)
+	}
+
+	domains, bambouErr := enterprises[0].Domains(nil)
+	if bambouErr != nil || len(domains) != 1 {
+		t.Fatalf("Unexpected domains %v %v", domains, bambouErr)
+	}
+
+	// The vports of the zone are listed from the domain too
+	vports, bambouErr := domains[0].VPorts(&bambou.FetchingInfo{Page: 1, PageSize: 2})
+	if bambouErr != nil || len(vports) != 1 || vports[0].Name != "vport3" {
+		t.Fatalf("Unexpected second page of vports %v %v", vports, bambouErr)
+	}
+
+	if err = server.Remove(zoneID); err != nil {
+		t.Fatalf("Unable to remove the zone %v", err)
+	}
+	if vports, bambouErr = domains[0].VPorts(nil); bambouErr != nil || len(vports) != 0 {
+		t.Fatalf("Unexpected vports after removing the zone %v %v", vports, bambouErr)
+	}
+
+	if bambouErr = (&vspk.Zone{ID: zoneID}).Fetch(); bambouErr == nil {
+		t.Fatalf("Fetched a removed zone")
+	}
+}
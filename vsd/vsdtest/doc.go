@@ -0,0 +1,7 @@
+/*
+Package vsdtest provides an in-memory Nuage VSD serving the subset of the REST API read by the vsd package and the
+VSD helpers of test/util: the me, enterprises, domains, zones, subnets, vports, vminterfaces and
+containerinterfaces resources along with X-Nuage-Filter filtering and paging. Objects are populated with the vspk
+types so that code looking up the VSD can be tested without a Nuage lab.
+*/
+package vsdtest